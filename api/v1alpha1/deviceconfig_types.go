@@ -34,6 +34,7 @@ package v1alpha1
 
 import (
 	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -82,10 +83,48 @@ type DeviceConfigSpec struct {
 	// +optional
 	Selector map[string]string `json:"selector,omitempty"`
 
+	// NodeFeatureRule makes the operator reconcile the NodeFeatureRule labelling the AMD GPU nodes for Node Feature Discovery
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="NodeFeatureRule",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:nodeFeatureRule"}
+	// +optional
+	NodeFeatureRule NodeFeatureRuleSpec `json:"nodeFeatureRule,omitempty"`
+
 	// remediation workflow
 	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="RemediationWorkflow",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:remediationWorkflow"}
 	// +optional
 	RemediationWorkflow RemediationWorkflowSpec `json:"remediationWorkflow,omitempty"`
+
+	// MaintenanceWindows restrict when disruptive operations may start. New node driver upgrades and the drain and reboot steps
+	// of remediation workflows only start while one of the windows is open, operations already in flight are allowed to finish.
+	// If not specified, these operations start at any time
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="MaintenanceWindows",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:maintenanceWindows"}
+	// +optional
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"`
+
+	// DryRun makes the operator compute the actions a spec change would trigger without applying them.
+	// The plan is written to the <DeviceConfig name>-dry-run-plan ConfigMap and summarized in status.dryRunPlan,
+	// operations already in flight are not interrupted. disabled by default
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="DryRun",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:dryRun"}
+	// +optional
+	DryRun *bool `json:"dryRun,omitempty"`
+}
+
+// MaintenanceWindow describes a recurring time window in which disruptive operations are allowed to start
+type MaintenanceWindow struct {
+	// Schedule is a standard cron expression (minute hour day-of-month month day-of-week) of the window start times,
+	// for example "0 22 * * 5" opens the window every Friday at 22:00
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Schedule",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:schedule"}
+	Schedule string `json:"schedule"`
+
+	// Duration of the window. Accepts duration strings like "30m", "4h", "48h"
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Duration",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:duration"}
+	// +kubebuilder:validation:Pattern=`^([0-9]+(\.[0-9]+)?(s|m|h))+$`
+	Duration string `json:"duration"`
+
+	// TimeZone is the IANA time zone name the schedule is evaluated in, for example "America/Los_Angeles". By default, it is set to UTC
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="TimeZone",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:timeZone"}
+	// +optional
+	// +kubebuilder:default:="UTC"
+	TimeZone string `json:"timeZone,omitempty"`
 }
 
 // RemediationWorkflowSpec defines workflows to run based on node conditions
@@ -251,6 +290,12 @@ type DriverSpec struct {
 	// +optional
 	ImageRegistrySecret *v1.LocalObjectReference `json:"imageRegistrySecret,omitempty"`
 
+	// ConfigMap in the DeviceConfig namespace listing precompiled driver images by OS, kernel and driver version under the catalog.yaml key.
+	// the kernel mapping of a node uses the matching catalog image and falls back to building the image in cluster when there is no match
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="ImageCatalog",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:imageCatalog"}
+	// +optional
+	ImageCatalog *v1.LocalObjectReference `json:"imageCatalog,omitempty"`
+
 	// image signing config to sign the driver image when building driver image on the fly
 	// image signing is required for installing driver on secure boot enabled system
 	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="ImageSign",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:imageSign"}
@@ -322,6 +367,41 @@ const (
 	UpgradeStateRebootInProgress UpgradeState = "Reboot-In-Progress"
 	// Node reboot failed
 	UpgradeStateRebootFailed UpgradeState = "Reboot-Failed"
+	// Node is being reverted to the previous driver version after a failed upgrade
+	UpgradeStateRollbackInProgress UpgradeState = "Rollback-In-Progress"
+	// Node is running the previous driver version after a failed upgrade
+	UpgradeStateRollbackComplete UpgradeState = "Rollback-Complete"
+	// Node could not be reverted to the previous driver version
+	UpgradeStateRollbackFailed UpgradeState = "Rollback-Failed"
+	// Node upgrade is skipped on user request
+	UpgradeStateSkipped UpgradeState = "Upgrade-Skipped"
+	// Node upgrade is paused on user request
+	UpgradeStatePaused UpgradeState = "Upgrade-Paused"
+	// Node is waiting for the GPU workloads to complete before it is drained
+	UpgradeStateDrainWaiting UpgradeState = "Drain-Waiting"
+	// Node pre-upgrade hook is running
+	UpgradeStatePreUpgradeHook UpgradeState = "Pre-Upgrade-Hook"
+	// Node pre-upgrade hook failed
+	UpgradeStatePreUpgradeHookFailed UpgradeState = "Pre-Upgrade-Hook-Failed"
+	// Node post-upgrade hooks are running
+	UpgradeStatePostUpgradeHook UpgradeState = "Post-Upgrade-Hook"
+	// Node post-upgrade hooks failed
+	UpgradeStatePostUpgradeHookFailed UpgradeState = "Post-Upgrade-Hook-Failed"
+)
+
+// NodeUpgradeAction is a per node upgrade action requested by the user through the operator.amd.com/gpu-driver-upgrade-state node label
+// +enum
+type NodeUpgradeAction string
+
+const (
+	// Node is left out of the upgrade while it is labeled
+	NodeUpgradeActionSkip NodeUpgradeAction = "Skip"
+	// Node upgrade is paused at the next safe state while it is labeled
+	NodeUpgradeActionPause NodeUpgradeAction = "Pause"
+	// Node upgrade is retried after a failure
+	NodeUpgradeActionRetry NodeUpgradeAction = "Retry"
+	// Node is upgraded ahead of the other nodes
+	NodeUpgradeActionForce NodeUpgradeAction = "Force"
 )
 
 type DriverUpgradePolicySpec struct {
@@ -356,9 +436,166 @@ type DriverUpgradePolicySpec struct {
 	// +optional
 	// +kubebuilder:default:=true
 	RebootRequired *bool `json:"rebootRequired,omitempty"`
+	// AutoRollback reverts the nodes whose upgrade ended in Upgrade-Failed, Drain-Failed or Upgrade-Timed-Out to the driver version
+	// they were running before the upgrade, disabled by default. The node is rebooted if RebootRequired is set and ends in Rollback-Complete.
+	// Rolled back nodes are upgraded again once the driver version changes or the node is labeled with operator.amd.com/gpu-driver-upgrade-state=upgrade-required
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="AutoRollback",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:autoRollback"}
+	// +optional
+	// +kubebuilder:default:=false
+	AutoRollback *bool `json:"autoRollback,omitempty"`
+	// RolloutStrategy stages the driver upgrade, canary nodes are upgraded first and the remaining nodes in waves.
+	// If not specified, nodes are upgraded in list order within the MaxParallelUpgrades and MaxUnavailableNodes limits
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="RolloutStrategy",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:rolloutStrategy"}
+	// +optional
+	RolloutStrategy *UpgradeRolloutSpec `json:"rolloutStrategy,omitempty"`
+	// Hooks run on every node around its driver upgrade, the node upgrade only proceeds once they succeed
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Hooks",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:hooks"}
+	// +optional
+	Hooks *UpgradeHooksSpec `json:"hooks,omitempty"`
+}
+
+// UpgradeHooksSpec describes the hooks run on every node around its driver upgrade
+type UpgradeHooksSpec struct {
+	// PreUpgrade runs before the node is cordoned, e.g. to checkpoint the workloads or notify a scheduler. A failed hook fails the node upgrade
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="PreUpgrade",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:preUpgrade"}
+	// +optional
+	PreUpgrade *UpgradeHookSpec `json:"preUpgrade,omitempty"`
+	// PostUpgrade runs once the new driver is loaded on the node, the node stays cordoned until it succeeds. A failed hook fails the node upgrade
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="PostUpgrade",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:postUpgrade"}
+	// +optional
+	PostUpgrade *UpgradeHookSpec `json:"postUpgrade,omitempty"`
+	// HealthCheck runs the test runner on the node once the new driver is loaded, after the PostUpgrade hook. A failed test fails the node upgrade
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="HealthCheck",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:healthCheck"}
+	// +optional
+	HealthCheck *UpgradeHealthCheckSpec `json:"healthCheck,omitempty"`
+}
+
+// UpgradeHookSpec describes a hook run for a node, either a Job or an Argo Workflow. Exactly one of JobTemplate and WorkflowTemplate must be set
+type UpgradeHookSpec struct {
+	// JobTemplate is the template of the Job run for the node, the NODE_NAME and DRIVER_VERSION environment variables are set in its containers
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="JobTemplate",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:jobTemplate"}
+	// +optional
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Type=object
+	JobTemplate *batchv1.JobTemplateSpec `json:"jobTemplate,omitempty"`
+	// WorkflowTemplate is the name of an Argo WorkflowTemplate in the DeviceConfig namespace submitted for the node, with the node_name and driver_version parameters
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="WorkflowTemplate",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:workflowTemplate"}
+	// +optional
+	WorkflowTemplate string `json:"workflowTemplate,omitempty"`
+	// TimeoutSeconds is the time the hook has to succeed before it is considered failed
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="TimeoutSeconds",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:timeoutSeconds"}
+	// +optional
+	// +kubebuilder:default:=1800
+	// +kubebuilder:validation:Minimum:=1
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+}
+
+// UpgradeRolloutSpec describes a staged driver upgrade rollout
+type UpgradeRolloutSpec struct {
+	// Canary nodes are upgraded before any other node, the rollout pauses if a canary node fails
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Canary",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:canary"}
+	// +optional
+	Canary *UpgradeCanarySpec `json:"canary,omitempty"`
+	// Waves split the non-canary nodes into batches, a wave starts only once the previous wave is done and has soaked
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Waves",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:waves"}
+	// +optional
+	Waves *UpgradeWavesSpec `json:"waves,omitempty"`
+}
+
+// UpgradeCanarySpec describes the canary stage of a driver upgrade rollout
+type UpgradeCanarySpec struct {
+	// Selector chooses the canary nodes among the nodes selected by the DeviceConfig
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Selector",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:selector"}
+	Selector map[string]string `json:"selector"`
+	// SoakSeconds is the time canary nodes have to stay healthy after their upgrade before the waves start
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="SoakSeconds",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:soakSeconds"}
+	// +optional
+	// +kubebuilder:default:=600
+	// +kubebuilder:validation:Minimum:=0
+	SoakSeconds int `json:"soakSeconds,omitempty"`
+	// HealthCheck runs the test runner on every canary node once its upgrade completes, a failed test pauses the rollout
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="HealthCheck",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:healthCheck"}
+	// +optional
+	HealthCheck *UpgradeHealthCheckSpec `json:"healthCheck,omitempty"`
+}
+
+// UpgradeHealthCheckSpec describes the test runner check executed on a node after its driver upgrade
+type UpgradeHealthCheckSpec struct {
+	// enable the post-upgrade health check, enabled by default
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Enable",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:enable"}
+	// +optional
+	// +kubebuilder:default:=true
+	Enable *bool `json:"enable,omitempty"`
+	// Framework is the test framework used by the test runner, RVS or AGFHC
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Framework",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:framework"}
+	// +optional
+	// +kubebuilder:default:="RVS"
+	// +kubebuilder:validation:Enum=RVS;AGFHC
+	Framework string `json:"framework,omitempty"`
+	// Recipe is the test recipe to run
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Recipe",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:recipe"}
+	// +optional
+	// +kubebuilder:default:="gst_single"
+	Recipe string `json:"recipe,omitempty"`
+	// Iterations is the number of times the recipe is run
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Iterations",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:iterations"}
+	// +optional
+	// +kubebuilder:default:=1
+	// +kubebuilder:validation:Minimum:=1
+	Iterations int `json:"iterations,omitempty"`
+	// TimeoutSeconds is the timeout of a single iteration
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="TimeoutSeconds",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:timeoutSeconds"}
+	// +optional
+	// +kubebuilder:default:=1200
+	// +kubebuilder:validation:Minimum:=1
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+}
+
+// UpgradeWavesSpec describes how the non-canary nodes are batched into waves
+type UpgradeWavesSpec struct {
+	// Size is the number of nodes per wave. Value can be an integer (ex: 10) or a percentage of the non-canary nodes (ex: "20%") rounded up.
+	// Ignored if TopologyLabel is set
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Size",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:size"}
+	// +optional
+	// +kubebuilder:default:="25%"
+	Size intstr.IntOrString `json:"size,omitempty"`
+	// TopologyLabel groups the nodes by the value of the given node label (ex: topology.kubernetes.io/zone), each group being one wave.
+	// Groups are upgraded in the alphabetical order of the label values, nodes without the label are upgraded last
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="TopologyLabel",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:topologyLabel"}
+	// +optional
+	TopologyLabel string `json:"topologyLabel,omitempty"`
+	// SoakSeconds is the time to wait after a wave is done before starting the next wave
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="SoakSeconds",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:soakSeconds"}
+	// +optional
+	// +kubebuilder:default:=0
+	// +kubebuilder:validation:Minimum:=0
+	SoakSeconds int `json:"soakSeconds,omitempty"`
 }
 
+// DrainMode selects how the pods using GPUs are removed from a node
+// +enum
+type DrainMode string
+
+const (
+	// Pods using GPUs are evicted right away
+	DrainModeEvict DrainMode = "Evict"
+	// Pods using GPUs are given time to complete on their own, the remaining ones are evicted afterwards
+	DrainModeWaitForCompletion DrainMode = "WaitForCompletion"
+)
+
 type DrainSpec struct {
+	// Mode selects whether the pods using GPUs are evicted right away or first given time to complete on their own.
+	// WaitForCompletion is only supported for driver upgrades
+	// +optional
+	// +kubebuilder:validation:Enum=Evict;WaitForCompletion
+	// +kubebuilder:default:=Evict
+	Mode DrainMode `json:"mode,omitempty"`
+	// WaitForCompletionTimeoutSeconds specifies the length of time in seconds to wait for the pods using GPUs to complete in WaitForCompletion mode before they are evicted, zero means infinite.
+	// Individual pods can set their own deadline with the operator.amd.com/gpu-drain-deadline annotation
+	// +optional
+	// +kubebuilder:validation:Minimum:=0
+	WaitForCompletionTimeoutSeconds int `json:"waitForCompletionTimeoutSeconds,omitempty"`
 	// Force indicates if force draining is allowed
 	// +optional
 	// +kubebuilder:default:=false
@@ -432,6 +669,11 @@ type DRADriverSpec struct {
 	// +optional
 	UpgradePolicy *DaemonSetUpgradeSpec `json:"upgradePolicy,omitempty"`
 
+	// pod template overrides for the DRA driver DaemonSet
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="PodTemplateOverrides",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:podTemplateOverrides"}
+	// +optional
+	PodTemplateOverrides *PodTemplateOverridesSpec `json:"podTemplateOverrides,omitempty"`
+
 	// arguments is used to pass supported flags and their values while starting DRA driver daemonset
 	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="DRADriverArguments",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:cmdLineArguments"}
 	// +optional
@@ -441,6 +683,114 @@ type DRADriverSpec struct {
 	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Selector",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:selector"}
 	// +optional
 	Selector map[string]string `json:"selector,omitempty"`
+
+	// deviceClasses is the list of DeviceClasses created and kept in sync by the operator,
+	// DeviceClasses removed from the list are deleted
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="DeviceClasses",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:deviceClasses"}
+	// +optional
+	DeviceClasses []DRADeviceClassSpec `json:"deviceClasses,omitempty"`
+
+	// claimTemplates is the list of ResourceClaimTemplates created and kept in sync by the operator,
+	// ResourceClaimTemplates removed from the list are deleted
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="ClaimTemplates",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:claimTemplates"}
+	// +optional
+	ClaimTemplates []DRAClaimTemplateSpec `json:"claimTemplates,omitempty"`
+
+	// migration switches nodes from the device plugin to the DRA driver, one node selector at a time.
+	// While the migration is enabled the device plugin and the DRA driver can be enabled together,
+	// each node being served by only one of them
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Migration",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:migration"}
+	// +optional
+	Migration *DRAMigrationSpec `json:"migration,omitempty"`
+}
+
+// DRAMigrationSpec describes the migration of nodes from the device plugin to the DRA driver
+type DRAMigrationSpec struct {
+	// enable the migration from the device plugin to the DRA driver
+	// +optional
+	// +kubebuilder:default=false
+	Enable *bool `json:"enable,omitempty"`
+
+	// nodeSelector selects the nodes to migrate to the DRA driver, all the nodes are migrated if empty.
+	// Nodes which are already migrated stay on the DRA driver when the selector changes
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// maxParallelNodes is the maximum number of nodes migrated at the same time
+	// +optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=1
+	MaxParallelNodes int `json:"maxParallelNodes,omitempty"`
+
+	// timeoutSeconds is the time given to migrate a node before it is marked as failed
+	// +optional
+	// +kubebuilder:default=1800
+	// +kubebuilder:validation:Minimum=1
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+
+	// nodeDrainPolicy is the policy used to drain the pods requesting amd.com GPU resources from a node before its migration
+	// +optional
+	NodeDrainPolicy *DrainSpec `json:"nodeDrainPolicy,omitempty"`
+}
+
+// DRAClaimAllocationMode defines how many devices are allocated to a claim
+// +kubebuilder:validation:Enum=ExactCount;All
+type DRAClaimAllocationMode string
+
+const (
+	// DRAClaimAllocationModeExactCount allocates the number of devices given by count
+	DRAClaimAllocationModeExactCount DRAClaimAllocationMode = "ExactCount"
+	// DRAClaimAllocationModeAll allocates all the matching devices of a node
+	DRAClaimAllocationModeAll DRAClaimAllocationMode = "All"
+)
+
+// DRAClaimTemplateSpec describes a ResourceClaimTemplate requesting AMD GPUs
+type DRAClaimTemplateSpec struct {
+	// name of the ResourceClaimTemplate
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+	Name string `json:"name"`
+
+	// namespaces to create the ResourceClaimTemplate in, defaults to the DeviceConfig namespace
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// deviceClassName is the DeviceClass the devices are requested from, defaults to gpu.amd.com
+	// +optional
+	DeviceClassName string `json:"deviceClassName,omitempty"`
+
+	// allocationMode is ExactCount to allocate count devices or All to allocate all the matching devices of a node
+	// +optional
+	// +kubebuilder:default=ExactCount
+	AllocationMode DRAClaimAllocationMode `json:"allocationMode,omitempty"`
+
+	// count is the number of devices allocated with the ExactCount allocation mode,
+	// all the devices of a claim are allocated on the same node
+	// +optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=1
+	Count int64 `json:"count,omitempty"`
+
+	// selectors is the list of CEL expressions the requested devices have to match
+	// e.g. device.attributes['gpu.amd.com'].partitionProfile == 'cpx_nps4'
+	// +optional
+	Selectors []string `json:"selectors,omitempty"`
+}
+
+// DRADeviceClassSpec describes a DeviceClass selecting a subset of the GPUs published by the DRA driver
+type DRADeviceClassSpec struct {
+	// name of the DeviceClass
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+	Name string `json:"name"`
+
+	// selectors is the list of CEL expressions a device has to match to belong to the DeviceClass,
+	// devices are always restricted to the ones published by the AMD GPU DRA driver
+	// e.g. device.attributes['gpu.amd.com'].productName == 'AMD_Instinct_MI300X_OAM'
+	// +optional
+	Selectors []string `json:"selectors,omitempty"`
 }
 
 // IsEnabled returns true if the DRA driver is explicitly enabled.
@@ -449,6 +799,11 @@ func (d *DRADriverSpec) IsEnabled() bool {
 	return d.Enable != nil && *d.Enable
 }
 
+// IsMigrationEnabled returns true if the DRA driver is enabled along with the migration from the device plugin.
+func (d *DRADriverSpec) IsMigrationEnabled() bool {
+	return d.IsEnabled() && d.Migration != nil && d.Migration.Enable != nil && *d.Migration.Enable
+}
+
 type DevicePluginSpec struct {
 	// enable Device Plugin, enabled by default
 	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="EnableDevicePlugin",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:enableDevicePlugin"}
@@ -475,10 +830,16 @@ type DevicePluginSpec struct {
 
 	// device plugin arguments is used to pass supported flags and their values while starting device plugin daemonset
 	// supported flag values: {"resource_naming_strategy": {"single", "mixed"}}
+	// Deprecated: use devicePluginConfig instead, its fields take precedence over the same flags set here
 	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="DevicePluginArguments",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:devicePluginArguments"}
 	// +optional
 	DevicePluginArguments map[string]string `json:"devicePluginArguments,omitempty"`
 
+	// device plugin config, rendered as the device plugin command line arguments
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="DevicePluginConfig",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:devicePluginConfig"}
+	// +optional
+	DevicePluginConfig *DevicePluginConfigSpec `json:"devicePluginConfig,omitempty"`
+
 	// node labeller image
 	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="NodeLabellerImage",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:nodeLabellerImage"}
 	// +optional
@@ -504,6 +865,11 @@ type DevicePluginSpec struct {
 	// +optional
 	NodeLabellerArguments []string `json:"nodeLabellerArguments,omitempty"`
 
+	// node labeller config: explicit allowlist / denylist of the labels
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="NodeLabellerConfig",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:nodeLabellerConfig"}
+	// +optional
+	NodeLabellerConfig *NodeLabellerConfigSpec `json:"nodeLabellerConfig,omitempty"`
+
 	// node labeller image registry secret used to pull/push images
 	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="ImageRegistrySecret",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:imageRegistrySecret"}
 	// +optional
@@ -531,6 +897,16 @@ type DevicePluginSpec struct {
 	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="HostNetwork",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:hostNetwork"}
 	// +optional
 	HostNetwork *bool `json:"hostNetwork,omitempty"`
+
+	// pod template overrides for the device plugin DaemonSet
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="DevicePluginPodTemplateOverrides",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:devicePluginPodTemplateOverrides"}
+	// +optional
+	DevicePluginPodTemplateOverrides *PodTemplateOverridesSpec `json:"devicePluginPodTemplateOverrides,omitempty"`
+
+	// pod template overrides for the node labeller DaemonSet
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="NodeLabellerPodTemplateOverrides",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:nodeLabellerPodTemplateOverrides"}
+	// +optional
+	NodeLabellerPodTemplateOverrides *PodTemplateOverridesSpec `json:"nodeLabellerPodTemplateOverrides,omitempty"`
 }
 
 // IsEnabled returns true if the device plugin is explicitly enabled.
@@ -538,6 +914,67 @@ func (d *DevicePluginSpec) IsEnabled() bool {
 	return d.EnableDevicePlugin != nil && *d.EnableDevicePlugin
 }
 
+// NodeLabellerLabel is a kind of label applied by the node labeller
+// +kubebuilder:validation:Enum=firmware;family;driver-version;driver-src-version;device-id;product-name;vram;simd-count;cu-count;compute-memory-partition;compute-partitioning-supported;memory-partitioning-supported
+type NodeLabellerLabel string
+
+// NodeLabellerConfigSpec is the configuration of the node labeller
+type NodeLabellerConfigSpec struct {
+	// labels applied by the node labeller, replaces the default labels of the driver type when specified
+	// the labels of nodeLabellerArguments are still applied
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Allowlist",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:allowlist"}
+	// +optional
+	Allowlist []NodeLabellerLabel `json:"allowlist,omitempty"`
+
+	// labels never applied by the node labeller, even when enabled by default, by the allowlist or by nodeLabellerArguments
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Denylist",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:denylist"}
+	// +optional
+	Denylist []NodeLabellerLabel `json:"denylist,omitempty"`
+}
+
+// NodeFeatureRuleSpec describes the NodeFeatureRule reconciled by the operator
+type NodeFeatureRuleSpec struct {
+	// enable the NodeFeatureRule labelling the nodes with AMD GPUs with feature.node.kubernetes.io/amd-gpu
+	// and the nodes with AMD GPU virtual functions with feature.node.kubernetes.io/amd-vgpu, disabled by default
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Enable",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:enable"}
+	// +optional
+	Enable *bool `json:"enable,omitempty"`
+
+	// PCI device IDs of the GPUs labelled in addition to the ones known by the operator, e.g. 75a3
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="DeviceIDs",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:deviceIDs"}
+	// +optional
+	// +kubebuilder:validation:items:Pattern=`^[0-9a-fA-F]{4}$`
+	DeviceIDs []string `json:"deviceIDs,omitempty"`
+
+	// PCI device IDs of the GPU virtual functions labelled in addition to the ones known by the operator, e.g. 75b3
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="VFDeviceIDs",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:vfDeviceIDs"}
+	// +optional
+	// +kubebuilder:validation:items:Pattern=`^[0-9a-fA-F]{4}$`
+	VFDeviceIDs []string `json:"vfDeviceIDs,omitempty"`
+}
+
+// IsEnabled returns true if the NodeFeatureRule is explicitly enabled.
+func (n *NodeFeatureRuleSpec) IsEnabled() bool {
+	return n.Enable != nil && *n.Enable
+}
+
+// DevicePluginConfigSpec is the configuration of the device plugin
+type DevicePluginConfigSpec struct {
+	// resource naming strategy of the GPUs: single reports every GPU and partition as amd.com/gpu,
+	// mixed reports them under the name of their partition, e.g. amd.com/cpx_nps4.
+	// Defaults to single, or to mixed for the passthrough driver types
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="ResourceNamingStrategy",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:resourceNamingStrategy"}
+	// +optional
+	// +kubebuilder:validation:Enum=single;mixed
+	ResourceNamingStrategy string `json:"resourceNamingStrategy,omitempty"`
+
+	// interval in seconds between two GPU health checks, 30 by default
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="HealthCheckPulseSeconds",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:healthCheckPulseSeconds"}
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	HealthCheckPulseSeconds *int32 `json:"healthCheckPulseSeconds,omitempty"`
+}
+
 type DaemonSetUpgradeSpec struct {
 	// UpgradeStrategy specifies the type of the DaemonSet update. Valid values are "RollingUpdate" (default) or "OnDelete".
 	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="UpgradeStrategy",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:upgradeStrategy"}
@@ -551,6 +988,71 @@ type DaemonSetUpgradeSpec struct {
 	MaxUnavailable int32 `json:"maxUnavailable,omitempty"`
 }
 
+// PodTemplateOverridesSpec customizes the pod template of an operand DaemonSet.
+// Container level overrides apply to the operand container, the first container of the pod.
+// The other pod template fields, e.g. sidecar containers, are set with the patch
+type PodTemplateOverridesSpec struct {
+	// labels added to the pods, labels used by the DaemonSet selector cannot be changed
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Labels",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:labels"}
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// annotations added to the pods
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Annotations",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:annotations"}
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// resource requirements of the operand container
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Resources",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:resources"}
+	// +optional
+	Resources *v1.ResourceRequirements `json:"resources,omitempty"`
+
+	// priority class of the pods, system-node-critical by default
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="PriorityClassName",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:priorityClassName"}
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// affinity of the pods. Node affinity requirements are combined with the ones set by the operator,
+	// pod affinity and anti affinity are set as is. not validated by the CRD schema, to keep the CRD small
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Affinity",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:affinity"}
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	Affinity *v1.Affinity `json:"affinity,omitempty"`
+
+	// security context of the pods. not validated by the CRD schema, to keep the CRD small
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="SecurityContext",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:securityContext"}
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	SecurityContext *v1.PodSecurityContext `json:"securityContext,omitempty"`
+
+	// extra environment variables of the operand container, variables with the same name replace the ones set by the operator
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Env",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:env"}
+	// +optional
+	Env []v1.EnvVar `json:"env,omitempty"`
+
+	// extra volumes of the pods, volumes with the same name replace the ones set by the operator
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Volumes",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:volumes"}
+	// +optional
+	Volumes []v1.Volume `json:"volumes,omitempty"`
+
+	// extra volume mounts of the operand container, mounts with the same mount path replace the ones set by the operator
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="VolumeMounts",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:volumeMounts"}
+	// +optional
+	VolumeMounts []v1.VolumeMount `json:"volumeMounts,omitempty"`
+
+	// strategic merge patch applied to the pod template after all other overrides, e.g. to add sidecar containers
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Patch",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:patch"}
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	Patch *runtime.RawExtension `json:"patch,omitempty"`
+}
+
 type ImageSignSpec struct {
 	// ImageSignKeySecret the private key used to sign kernel modules within image
 	// necessary for secure boot enabled system
@@ -622,6 +1124,48 @@ type ImageBuildSpec struct {
 	// this field will be applied to SourceImageRepo as well
 	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="BaseImageRegistryTLS",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:baseImageRegistryTLS"}
 	BaseImageRegistryTLS RegistryTLS `json:"baseImageRegistryTLS,omitempty"`
+
+	// DockerfileTemplates registers additional Linux distributions for the driver image build,
+	// or replaces the Dockerfile of a built-in one. not applicable to OpenShift
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="DockerfileTemplates",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:dockerfileTemplates"}
+	// +optional
+	DockerfileTemplates *DockerfileTemplatesSpec `json:"dockerfileTemplates,omitempty"`
+
+	// Prebuild makes the operator build the driver images of every OS, kernel and driver version combination of the selected nodes
+	// before starting a driver upgrade, so that upgraded nodes don't wait for the image build. The images are built and pushed
+	// by KMM PreflightValidations, spec.driver.image must point to a registry the built images can be pushed to. disabled by default
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Prebuild",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:prebuild"}
+	// +optional
+	Prebuild *bool `json:"prebuild,omitempty"`
+}
+
+// DockerfileTemplatesSpec maps node OS images to user supplied Dockerfile templates
+type DockerfileTemplatesSpec struct {
+	// ConfigMap in the DeviceConfig namespace holding one Dockerfile template per distribution, keyed by the distribution name.
+	// The templates are rendered with the same placeholders as the built-in ones: $$VERSION is replaced by the OS version
+	// and $$BASEIMG_REGISTRY by spec.driver.imageBuild.baseImageRegistry
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="ConfigMap",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:configMap"}
+	ConfigMap v1.LocalObjectReference `json:"configMap"`
+
+	// Distributions are matched in order against the node OS image, before the built-in distributions
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Distributions",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:distributions"}
+	// +kubebuilder:validation:MinItems=1
+	Distributions []DockerfileTemplateDistribution `json:"distributions"`
+}
+
+// DockerfileTemplateDistribution is the OS image match rule of a Dockerfile template
+type DockerfileTemplateDistribution struct {
+	// Name of the distribution and key of its template in the ConfigMap, e.g. rocky.
+	// The OS name of the matched nodes is <name>-<version>, e.g. rocky-9.4
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Name",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:name"}
+	// +kubebuilder:validation:Pattern=`^[a-z][a-z0-9]*$`
+	Name string `json:"name"`
+
+	// OSImageRegex is matched against the lower cased node OS image (status.nodeInfo.osImage),
+	// its first capture group is the OS version, e.g. rocky linux (\d+\.\d+)
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="OSImageRegex",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:osImageRegex"}
+	// +kubebuilder:validation:MinLength=1
+	OSImageRegex string `json:"osImageRegex"`
 }
 
 // ServiceType string describes ingress methods for a service
@@ -675,10 +1219,94 @@ type ConfigManagerSpec struct {
 	// +optional
 	UpgradePolicy *DaemonSetUpgradeSpec `json:"upgradePolicy,omitempty"`
 
+	// pod template overrides for the config manager DaemonSet
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="PodTemplateOverrides",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:podTemplateOverrides"}
+	// +optional
+	PodTemplateOverrides *PodTemplateOverridesSpec `json:"podTemplateOverrides,omitempty"`
+
 	// tolerations for the device config manager DaemonSet
 	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="ConfigManagerTolerations",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:configManagerTolerations"}
 	// +optional
 	ConfigManagerTolerations []v1.Toleration `json:"configManagerTolerations,omitempty"`
+
+	// PartitionProfiles maps node selectors to the GPU partition applied on the nodes. When set, the operator renders the DCM config.json
+	// into the ConfigMap "<DeviceConfig name>-dcm-partition-config", labels the nodes with their profile and drains them before they are repartitioned.
+	// The first profile whose node selector matches a node is applied on it. Config must not be set together with PartitionProfiles
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="PartitionProfiles",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:partitionProfiles"}
+	// +optional
+	PartitionProfiles []PartitionProfileSpec `json:"partitionProfiles,omitempty"`
+
+	// PartitionPolicy controls how the nodes are repartitioned when PartitionProfiles is set
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="PartitionPolicy",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:partitionPolicy"}
+	// +optional
+	PartitionPolicy *PartitionPolicySpec `json:"partitionPolicy,omitempty"`
+}
+
+// ComputePartitionType is the compute partition mode of a GPU
+// +enum
+type ComputePartitionType string
+
+const (
+	ComputePartitionSPX ComputePartitionType = "SPX"
+	ComputePartitionDPX ComputePartitionType = "DPX"
+	ComputePartitionTPX ComputePartitionType = "TPX"
+	ComputePartitionQPX ComputePartitionType = "QPX"
+	ComputePartitionCPX ComputePartitionType = "CPX"
+)
+
+// MemoryPartitionType is the memory partition mode of a GPU
+// +enum
+type MemoryPartitionType string
+
+const (
+	MemoryPartitionNPS1 MemoryPartitionType = "NPS1"
+	MemoryPartitionNPS2 MemoryPartitionType = "NPS2"
+	MemoryPartitionNPS4 MemoryPartitionType = "NPS4"
+	MemoryPartitionNPS8 MemoryPartitionType = "NPS8"
+)
+
+// PartitionProfileSpec describes the GPU partition applied on a set of nodes
+type PartitionProfileSpec struct {
+	// Name is the name of the profile in the rendered DCM config, it is set as the dcm.amd.com/gpu-config-profile label of the nodes
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Name",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:name"}
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9]([-a-zA-Z0-9_.]*[a-zA-Z0-9])?$`
+	Name string `json:"name"`
+	// NodeSelector selects the nodes the profile is applied on, all the nodes of the config manager are selected when empty
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="NodeSelector",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:nodeSelector"}
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// ComputePartition is the compute partition mode of all the GPUs of the node
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="ComputePartition",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:computePartition"}
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum=SPX;DPX;TPX;QPX;CPX
+	ComputePartition ComputePartitionType `json:"computePartition"`
+	// MemoryPartition is the memory partition mode of all the GPUs of the node
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="MemoryPartition",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:memoryPartition"}
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum=NPS1;NPS2;NPS4;NPS8
+	MemoryPartition MemoryPartitionType `json:"memoryPartition"`
+}
+
+// PartitionPolicySpec controls how the nodes are repartitioned
+type PartitionPolicySpec struct {
+	// MaxParallelNodes is the number of nodes repartitioned at the same time
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="MaxParallelNodes",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:maxParallelNodes"}
+	// +optional
+	// +kubebuilder:default:=1
+	// +kubebuilder:validation:Minimum:=1
+	MaxParallelNodes int `json:"maxParallelNodes,omitempty"`
+	// TimeoutSeconds is the time a node is given to be drained, repartitioned and report its new partition before it is marked as failed
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="TimeoutSeconds",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:timeoutSeconds"}
+	// +optional
+	// +kubebuilder:default:=1800
+	// +kubebuilder:validation:Minimum:=1
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+	// NodeDrainPolicy specifies the drain of the pods using GPUs before the node is repartitioned
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="NodeDrainPolicy",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:nodeDrainPolicy"}
+	// +optional
+	NodeDrainPolicy *DrainSpec `json:"nodeDrainPolicy,omitempty"`
 }
 
 type MetricsExporterSpec struct {
@@ -752,6 +1380,11 @@ type MetricsExporterSpec struct {
 	// +optional
 	UpgradePolicy *DaemonSetUpgradeSpec `json:"upgradePolicy,omitempty"`
 
+	// pod template overrides for the metrics exporter DaemonSet
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="PodTemplateOverrides",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:podTemplateOverrides"}
+	// +optional
+	PodTemplateOverrides *PodTemplateOverridesSpec `json:"podTemplateOverrides,omitempty"`
+
 	// Set the host path for pod-resource kubelet.socket,
 	// vanila kubernetes path is /var/lib/kubelet/pod-resources
 	// microk8s path is /var/snap/microk8s/common/var/lib/kubelet/pod-resources/
@@ -949,6 +1582,11 @@ type TestRunnerSpec struct {
 	// +optional
 	UpgradePolicy *DaemonSetUpgradeSpec `json:"upgradePolicy,omitempty"`
 
+	// pod template overrides for the test runner DaemonSet
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="PodTemplateOverrides",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:podTemplateOverrides"}
+	// +optional
+	PodTemplateOverrides *PodTemplateOverridesSpec `json:"podTemplateOverrides,omitempty"`
+
 	// captures logs location and export config for test runner logs
 	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="LogsLocation",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:logsLocation"}
 	// +optional
@@ -1037,6 +1675,167 @@ type ModuleStatus struct {
 	Status             UpgradeState `json:"status,omitempty"`
 	UpgradeStartTime   string       `json:"upgradeStartTime,omitempty"`
 	BootId             string       `json:"bootId,omitempty"`
+	// UpgradeAction is the last upgrade action requested on the node that the operator acted on
+	UpgradeAction NodeUpgradeAction `json:"upgradeAction,omitempty"`
+}
+
+// UpgradeRolloutPhase is the stage of a staged driver upgrade rollout
+// +enum
+type UpgradeRolloutPhase string
+
+const (
+	// Canary nodes are being upgraded
+	UpgradeRolloutPhaseCanary UpgradeRolloutPhase = "Canary"
+	// Canary nodes are upgraded and running the post-upgrade health check
+	UpgradeRolloutPhaseCanaryHealthCheck UpgradeRolloutPhase = "Canary-Health-Check"
+	// Canary nodes are upgraded and soaking
+	UpgradeRolloutPhaseCanarySoak UpgradeRolloutPhase = "Canary-Soak"
+	// A wave of nodes is being upgraded
+	UpgradeRolloutPhaseWave UpgradeRolloutPhase = "Wave"
+	// A wave is done and soaking before the next one starts
+	UpgradeRolloutPhaseWaveSoak UpgradeRolloutPhase = "Wave-Soak"
+	// Rollout paused after a canary failure
+	UpgradeRolloutPhasePaused UpgradeRolloutPhase = "Paused"
+	// All the nodes are upgraded
+	UpgradeRolloutPhaseComplete UpgradeRolloutPhase = "Complete"
+)
+
+// MaintenanceWindowStatus reports the state of the maintenance windows
+type MaintenanceWindowStatus struct {
+	// Open is true while one of the maintenance windows is open
+	Open bool `json:"open"`
+	// CurrentWindowEnd is the time the open maintenance window closes
+	CurrentWindowEnd string `json:"currentWindowEnd,omitempty"`
+	// NextWindowStart is the time the next maintenance window opens
+	NextWindowStart string `json:"nextWindowStart,omitempty"`
+}
+
+// UpgradeRolloutStatus reports the progress of a staged driver upgrade rollout
+type UpgradeRolloutStatus struct {
+	// Phase is the current stage of the rollout
+	Phase UpgradeRolloutPhase `json:"phase,omitempty"`
+	// CurrentWave is the 1-based index of the wave being upgraded or soaking, 0 during the canary stage
+	CurrentWave int `json:"currentWave,omitempty"`
+	// TotalWaves is the number of waves of the rollout
+	TotalWaves int `json:"totalWaves,omitempty"`
+	// Message gives details about the current phase, e.g. why the rollout is paused
+	Message string `json:"message,omitempty"`
+}
+
+// PartitionState is the state of the repartitioning of a node
+// +enum
+type PartitionState string
+
+const (
+	// Node waits for another node to be repartitioned
+	PartitionStatePending PartitionState = "Pending"
+	// Node is cordoned and the pods using GPUs are being drained
+	PartitionStateDraining PartitionState = "Draining"
+	// Config manager is repartitioning the GPUs of the node
+	PartitionStatePartitioning PartitionState = "Partitioning"
+	// Node waits for the node labeller to report the new partition
+	PartitionStateVerifying PartitionState = "Verifying"
+	// Node has the desired partition
+	PartitionStateApplied PartitionState = "Applied"
+	// Repartitioning failed, the node stays cordoned
+	PartitionStateFailed PartitionState = "Failed"
+)
+
+// NodePartitionStatus reports the desired and actual GPU partition of a node
+type NodePartitionStatus struct {
+	// Profile is the name of the partition profile applied on the node
+	Profile string `json:"profile,omitempty"`
+	// Desired is the partition of the profile, in the format of the amd.com/compute-memory-partition label
+	Desired string `json:"desired,omitempty"`
+	// Actual is the partition reported by the node labeller in the amd.com/compute-memory-partition label
+	Actual string `json:"actual,omitempty"`
+	// State is the state of the repartitioning of the node
+	State PartitionState `json:"state,omitempty"`
+	// Message gives details about the state, e.g. why the repartitioning failed
+	Message string `json:"message,omitempty"`
+}
+
+// DRAMigrationState is the state of the migration of a node from the device plugin to the DRA driver
+// +enum
+type DRAMigrationState string
+
+const (
+	// Node waits for another node to be migrated
+	DRAMigrationStatePending DRAMigrationState = "Pending"
+	// Node is cordoned and the pods requesting amd.com GPU resources are being drained
+	DRAMigrationStateDraining DRAMigrationState = "Draining"
+	// Device plugin is removed from the node and the DRA driver is started
+	DRAMigrationStateSwitching DRAMigrationState = "Switching"
+	// Node is served by the DRA driver
+	DRAMigrationStateMigrated DRAMigrationState = "Migrated"
+	// Migration failed, the node stays cordoned
+	DRAMigrationStateFailed DRAMigrationState = "Failed"
+)
+
+// DRAMigrationStatus reports the progress of the migration from the device plugin to the DRA driver
+type DRAMigrationStatus struct {
+	// Total is the number of nodes selected for the migration
+	Total int `json:"total"`
+	// Migrated is the number of nodes served by the DRA driver
+	Migrated int `json:"migrated"`
+	// Nodes contains the state of every node selected for the migration
+	Nodes map[string]NodeDRAMigrationStatus `json:"nodes,omitempty"`
+}
+
+// NodeDRAMigrationStatus reports the migration state of a node
+type NodeDRAMigrationStatus struct {
+	// State is the state of the migration of the node
+	State DRAMigrationState `json:"state,omitempty"`
+	// Message gives details about the state, e.g. why the migration failed
+	Message string `json:"message,omitempty"`
+}
+
+// DryRunPlanStatus summarizes the actions the operator would take to apply the spec
+type DryRunPlanStatus struct {
+	// ConfigMap is the name of the ConfigMap holding the full plan
+	ConfigMap string `json:"configMap,omitempty"`
+	// ObservedGeneration is the spec generation the plan was computed for
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// NodesToInstall is the number of nodes that would get the driver installed
+	NodesToInstall int `json:"nodesToInstall,omitempty"`
+	// NodesToUpgrade is the number of nodes that would get the driver upgraded
+	NodesToUpgrade int `json:"nodesToUpgrade,omitempty"`
+	// DaemonSetsToRoll is the number of operand DaemonSets whose pods would be replaced
+	DaemonSetsToRoll int `json:"daemonSetsToRoll,omitempty"`
+	// KernelMappingChanges is the number of KMM Module kernel mappings that would be added, changed or removed
+	KernelMappingChanges int `json:"kernelMappingChanges,omitempty"`
+	// BuildImages is the number of driver images KMM would build
+	BuildImages int `json:"buildImages,omitempty"`
+}
+
+// DriverImagePhase is the build phase of a driver image
+type DriverImagePhase string
+
+const (
+	// DriverImagePending the image build has not started yet
+	DriverImagePending DriverImagePhase = "Pending"
+	// DriverImageBuilding the image is being verified, built or signed
+	DriverImageBuilding DriverImagePhase = "Building"
+	// DriverImageReady the image exists in the registry
+	DriverImageReady DriverImagePhase = "Ready"
+	// DriverImageFailed the image build failed
+	DriverImageFailed DriverImagePhase = "Failed"
+)
+
+// DriverImageStatus is the build status of the driver image of an OS, kernel and driver version combination
+type DriverImageStatus struct {
+	// OSName is the OS of the nodes running the kernel, e.g. ubuntu-22.04
+	OSName string `json:"osName"`
+	// KernelVersion is the kernel the driver image is built for
+	KernelVersion string `json:"kernelVersion"`
+	// DriverVersion is the version of the driver in the image
+	DriverVersion string `json:"driverVersion"`
+	// Image is the driver image reference
+	Image string `json:"image"`
+	// Phase is the build phase of the image
+	Phase DriverImagePhase `json:"phase"`
+	// Message gives the reason of the phase
+	Message string `json:"message,omitempty"`
 }
 
 // DeviceConfigStatus defines the observed state of Module.
@@ -1054,6 +1853,24 @@ type DeviceConfigStatus struct {
 	// NodeModuleStatus contains per node status of driver module installation
 	//+operator-sdk:csv:customresourcedefinitions:type=status,displayName="NodeModuleStatus",xDescriptors="urn:alm:descriptor:com.amd.deviceconfigs:nodeModuleStatus"
 	NodeModuleStatus map[string]ModuleStatus `json:"nodeModuleStatus,omitempty"`
+	// UpgradeRollout contains the progress of the staged driver upgrade when spec.driver.upgradePolicy.rolloutStrategy is set
+	//+operator-sdk:csv:customresourcedefinitions:type=status,displayName="UpgradeRollout",xDescriptors="urn:alm:descriptor:com.amd.deviceconfigs:upgradeRollout"
+	UpgradeRollout *UpgradeRolloutStatus `json:"upgradeRollout,omitempty"`
+	// MaintenanceWindow reports the current and next maintenance window when spec.maintenanceWindows is set
+	//+operator-sdk:csv:customresourcedefinitions:type=status,displayName="MaintenanceWindow",xDescriptors="urn:alm:descriptor:com.amd.deviceconfigs:maintenanceWindow"
+	MaintenanceWindow *MaintenanceWindowStatus `json:"maintenanceWindow,omitempty"`
+	// NodePartitionStatus contains per node desired and actual GPU partition when spec.configManager.partitionProfiles is set
+	//+operator-sdk:csv:customresourcedefinitions:type=status,displayName="NodePartitionStatus",xDescriptors="urn:alm:descriptor:com.amd.deviceconfigs:nodePartitionStatus"
+	NodePartitionStatus map[string]NodePartitionStatus `json:"nodePartitionStatus,omitempty"`
+	// DRAMigration reports the progress of the migration from the device plugin to the DRA driver when spec.draDriver.migration is enabled
+	//+operator-sdk:csv:customresourcedefinitions:type=status,displayName="DRAMigration",xDescriptors="urn:alm:descriptor:com.amd.deviceconfigs:draMigration"
+	DRAMigration *DRAMigrationStatus `json:"draMigration,omitempty"`
+	// DriverImages reports the build status of the driver images needed by the selected nodes when spec.driver.imageBuild.prebuild is set
+	//+operator-sdk:csv:customresourcedefinitions:type=status,displayName="DriverImages",xDescriptors="urn:alm:descriptor:com.amd.deviceconfigs:driverImages"
+	DriverImages []DriverImageStatus `json:"driverImages,omitempty"`
+	// DryRunPlan summarizes the plan computed for the current spec when spec.dryRun is set
+	//+operator-sdk:csv:customresourcedefinitions:type=status,displayName="DryRunPlan",xDescriptors="urn:alm:descriptor:com.amd.deviceconfigs:dryRunPlan"
+	DryRunPlan *DryRunPlanStatus `json:"dryRunPlan,omitempty"`
 	// Conditions list the current status of the DeviceConfig object
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 	// ObservedGeneration is the latest spec generation successfully processed by the controller
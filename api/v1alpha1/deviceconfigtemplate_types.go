@@ -0,0 +1,120 @@
+/*
+Copyright (c) Advanced Micro Devices, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the \"License\");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+     Unless required by applicable law or agreed to in writing, software
+     distributed under the License is distributed on an \"AS IS\" BASIS,
+     WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+     See the License for the specific language governing permissions and
+     limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeviceConfigTemplateSpec defines a base DeviceConfig spec and the node group scoped patches applied on top of it
+type DeviceConfigTemplateSpec struct {
+	// namespace of the DeviceConfigs rendered from the template, usually the namespace the operator is installed in
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Namespace",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:namespace"}
+	// +kubebuilder:validation:MinLength=1
+	Namespace string `json:"namespace"`
+
+	// base DeviceConfig spec shared by all node groups, its selector picks all the nodes managed by the template
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Template",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:template"}
+	Template DeviceConfigSpec `json:"template"`
+
+	// node group scoped patches, a node selected by the template belongs to the first override whose selector matches it,
+	// nodes not matched by any override belong to the default node group that uses the base spec as is
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Overrides",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:overrides"}
+	// +optional
+	Overrides []DeviceConfigOverride `json:"overrides,omitempty"`
+}
+
+// DeviceConfigOverride patches the base spec for the nodes matching its selector
+type DeviceConfigOverride struct {
+	// name of the node group, used as suffix of the rendered DeviceConfig name
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Name",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:name"}
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name"`
+
+	// node selector of the node group, matched on top of the template selector
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Selector",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:selector"}
+	// +kubebuilder:validation:MinProperties=1
+	Selector map[string]string `json:"selector"`
+
+	// partial DeviceConfig spec merged into the base spec with strategic merge patch semantics,
+	// e.g. {"driver": {"version": "6.4"}}. lists are replaced as a whole, the selector cannot be patched
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Patch",xDescriptors={"urn:alm:descriptor:com.amd.deviceconfigs:patch"}
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	Patch runtime.RawExtension `json:"patch,omitempty"`
+}
+
+// DeviceConfigTemplateStatus defines the observed state of DeviceConfigTemplate
+type DeviceConfigTemplateStatus struct {
+	// ObservedGeneration is the generation of the template the node groups were rendered from
+	//+operator-sdk:csv:customresourcedefinitions:type=status
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// NodeGroups contains the DeviceConfig rendered for each node group
+	//+operator-sdk:csv:customresourcedefinitions:type=status
+	NodeGroups []DeviceConfigNodeGroupStatus `json:"nodeGroups,omitempty"`
+	// Conditions list the current status of the template
+	//+operator-sdk:csv:customresourcedefinitions:type=status
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// DeviceConfigNodeGroupStatus is the rendered state of a node group
+type DeviceConfigNodeGroupStatus struct {
+	// Name of the node group, default for the nodes not matched by any override
+	Name string `json:"name"`
+	// DeviceConfig is the name of the DeviceConfig rendered for the node group
+	DeviceConfig string `json:"deviceConfig"`
+	// Nodes are the nodes currently in the node group
+	Nodes []string `json:"nodes,omitempty"`
+	// SpecHash is the hash of the spec rendered for the node group, the base spec with the override patch applied,
+	// it changes whenever the spec of the DeviceConfig of the node group changes
+	SpecHash string `json:"specHash"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster,shortName=gpuet
+//+kubebuilder:subresource:status
+
+// DeviceConfigTemplate renders one DeviceConfig per node group from a base spec and node group scoped overrides,
+// so that a heterogeneous cluster can be described by a single resource
+// +operator-sdk:csv:customresourcedefinitions:displayName="DeviceConfigTemplate"
+type DeviceConfigTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DeviceConfigTemplateSpec   `json:"spec,omitempty"`
+	Status DeviceConfigTemplateStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// DeviceConfigTemplateList contains a list of DeviceConfigTemplates
+type DeviceConfigTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []DeviceConfigTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(func(s *runtime.Scheme) error {
+		s.AddKnownTypes(GroupVersion, &DeviceConfigTemplate{}, &DeviceConfigTemplateList{})
+		metav1.AddToGroupVersion(s, GroupVersion)
+		return nil
+	})
+}
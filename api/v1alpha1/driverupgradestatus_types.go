@@ -0,0 +1,76 @@
+/*
+Copyright (c) Advanced Micro Devices, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the \"License\");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+     Unless required by applicable law or agreed to in writing, software
+     distributed under the License is distributed on an \"AS IS\" BASIS,
+     WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+     See the License for the specific language governing permissions and
+     limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Namespaced,shortName=dustatus
+//+kubebuilder:subresource:status
+
+// DriverUpgradeStatus keeps the driver upgrade state machine of every node managed by a DeviceConfig.
+// It has the same name and namespace as the DeviceConfig and is the durable source of truth of the upgrade manager,
+// so that a newly elected operator leader resumes in-flight upgrades where the previous leader left them.
+// +operator-sdk:csv:customresourcedefinitions:displayName="DriverUpgradeStatus"
+type DriverUpgradeStatus struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Status field holds the upgrade state of each node, key is node name
+	Status map[string]NodeUpgradeStatus `json:"status,omitempty"`
+}
+
+// NodeUpgradeStatus is the persisted driver upgrade state of a node
+type NodeUpgradeStatus struct {
+	// State is the current upgrade state of the node
+	State UpgradeState `json:"state,omitempty"`
+	// UpgradeStartTime is the time the node upgrade started, empty when no upgrade is in progress
+	UpgradeStartTime string `json:"upgradeStartTime,omitempty"`
+	// BootID is the boot ID of the node recorded before the upgrade, used to detect node reboots
+	BootID string `json:"bootId,omitempty"`
+	// FailureReason explains why the node upgrade failed
+	FailureReason string `json:"failureReason,omitempty"`
+	// LastTransitionTime is the time the node moved to its current state
+	LastTransitionTime string `json:"lastTransitionTime,omitempty"`
+	// PreviousVersion is the driver version the node was running before the upgrade, used to roll the node back
+	PreviousVersion string `json:"previousVersion,omitempty"`
+	// PreviousImage is the driver image the node was running before the upgrade
+	PreviousImage string `json:"previousImage,omitempty"`
+	// UpgradeAction is the last upgrade action requested on the node that the operator acted on
+	UpgradeAction NodeUpgradeAction `json:"upgradeAction,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// DriverUpgradeStatusList contains a list of DriverUpgradeStatuses
+type DriverUpgradeStatusList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []DriverUpgradeStatus `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(func(s *runtime.Scheme) error {
+		s.AddKnownTypes(GroupVersion, &DriverUpgradeStatus{}, &DriverUpgradeStatusList{})
+		metav1.AddToGroupVersion(s, GroupVersion)
+		return nil
+	})
+}
@@ -38,6 +38,7 @@ package v1alpha1
 
 import (
 	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
@@ -94,6 +95,11 @@ func (in *ConfigManagerSpec) DeepCopyInto(out *ConfigManagerSpec) {
 		*out = new(DaemonSetUpgradeSpec)
 		**out = **in
 	}
+	if in.PodTemplateOverrides != nil {
+		in, out := &in.PodTemplateOverrides, &out.PodTemplateOverrides
+		*out = new(PodTemplateOverridesSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.ConfigManagerTolerations != nil {
 		in, out := &in.ConfigManagerTolerations, &out.ConfigManagerTolerations
 		*out = make([]v1.Toleration, len(*in))
@@ -101,6 +107,18 @@ func (in *ConfigManagerSpec) DeepCopyInto(out *ConfigManagerSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.PartitionProfiles != nil {
+		in, out := &in.PartitionProfiles, &out.PartitionProfiles
+		*out = make([]PartitionProfileSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PartitionPolicy != nil {
+		in, out := &in.PartitionPolicy, &out.PartitionPolicy
+		*out = new(PartitionPolicySpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigManagerSpec.
@@ -113,6 +131,51 @@ func (in *ConfigManagerSpec) DeepCopy() *ConfigManagerSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DRAClaimTemplateSpec) DeepCopyInto(out *DRAClaimTemplateSpec) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Selectors != nil {
+		in, out := &in.Selectors, &out.Selectors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DRAClaimTemplateSpec.
+func (in *DRAClaimTemplateSpec) DeepCopy() *DRAClaimTemplateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DRAClaimTemplateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DRADeviceClassSpec) DeepCopyInto(out *DRADeviceClassSpec) {
+	*out = *in
+	if in.Selectors != nil {
+		in, out := &in.Selectors, &out.Selectors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DRADeviceClassSpec.
+func (in *DRADeviceClassSpec) DeepCopy() *DRADeviceClassSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DRADeviceClassSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DRADriverSpec) DeepCopyInto(out *DRADriverSpec) {
 	*out = *in
@@ -138,6 +201,11 @@ func (in *DRADriverSpec) DeepCopyInto(out *DRADriverSpec) {
 		*out = new(DaemonSetUpgradeSpec)
 		**out = **in
 	}
+	if in.PodTemplateOverrides != nil {
+		in, out := &in.PodTemplateOverrides, &out.PodTemplateOverrides
+		*out = new(PodTemplateOverridesSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.CmdLineArguments != nil {
 		in, out := &in.CmdLineArguments, &out.CmdLineArguments
 		*out = make(map[string]string, len(*in))
@@ -152,6 +220,25 @@ func (in *DRADriverSpec) DeepCopyInto(out *DRADriverSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.DeviceClasses != nil {
+		in, out := &in.DeviceClasses, &out.DeviceClasses
+		*out = make([]DRADeviceClassSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ClaimTemplates != nil {
+		in, out := &in.ClaimTemplates, &out.ClaimTemplates
+		*out = make([]DRAClaimTemplateSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Migration != nil {
+		in, out := &in.Migration, &out.Migration
+		*out = new(DRAMigrationSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DRADriverSpec.
@@ -164,6 +251,60 @@ func (in *DRADriverSpec) DeepCopy() *DRADriverSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DRAMigrationSpec) DeepCopyInto(out *DRAMigrationSpec) {
+	*out = *in
+	if in.Enable != nil {
+		in, out := &in.Enable, &out.Enable
+		*out = new(bool)
+		**out = **in
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.NodeDrainPolicy != nil {
+		in, out := &in.NodeDrainPolicy, &out.NodeDrainPolicy
+		*out = new(DrainSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DRAMigrationSpec.
+func (in *DRAMigrationSpec) DeepCopy() *DRAMigrationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DRAMigrationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DRAMigrationStatus) DeepCopyInto(out *DRAMigrationStatus) {
+	*out = *in
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make(map[string]NodeDRAMigrationStatus, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DRAMigrationStatus.
+func (in *DRAMigrationStatus) DeepCopy() *DRAMigrationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DRAMigrationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DaemonSetUpgradeSpec) DeepCopyInto(out *DaemonSetUpgradeSpec) {
 	*out = *in
@@ -253,6 +394,49 @@ func (in *DeviceConfigList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeviceConfigNodeGroupStatus) DeepCopyInto(out *DeviceConfigNodeGroupStatus) {
+	*out = *in
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceConfigNodeGroupStatus.
+func (in *DeviceConfigNodeGroupStatus) DeepCopy() *DeviceConfigNodeGroupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DeviceConfigNodeGroupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeviceConfigOverride) DeepCopyInto(out *DeviceConfigOverride) {
+	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	in.Patch.DeepCopyInto(&out.Patch)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceConfigOverride.
+func (in *DeviceConfigOverride) DeepCopy() *DeviceConfigOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(DeviceConfigOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DeviceConfigSpec) DeepCopyInto(out *DeviceConfigSpec) {
 	*out = *in
@@ -270,7 +454,18 @@ func (in *DeviceConfigSpec) DeepCopyInto(out *DeviceConfigSpec) {
 			(*out)[key] = val
 		}
 	}
+	in.NodeFeatureRule.DeepCopyInto(&out.NodeFeatureRule)
 	in.RemediationWorkflow.DeepCopyInto(&out.RemediationWorkflow)
+	if in.MaintenanceWindows != nil {
+		in, out := &in.MaintenanceWindows, &out.MaintenanceWindows
+		*out = make([]MaintenanceWindow, len(*in))
+		copy(*out, *in)
+	}
+	if in.DryRun != nil {
+		in, out := &in.DryRun, &out.DryRun
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceConfigSpec.
@@ -298,6 +493,38 @@ func (in *DeviceConfigStatus) DeepCopyInto(out *DeviceConfigStatus) {
 			(*out)[key] = val
 		}
 	}
+	if in.UpgradeRollout != nil {
+		in, out := &in.UpgradeRollout, &out.UpgradeRollout
+		*out = new(UpgradeRolloutStatus)
+		**out = **in
+	}
+	if in.MaintenanceWindow != nil {
+		in, out := &in.MaintenanceWindow, &out.MaintenanceWindow
+		*out = new(MaintenanceWindowStatus)
+		**out = **in
+	}
+	if in.NodePartitionStatus != nil {
+		in, out := &in.NodePartitionStatus, &out.NodePartitionStatus
+		*out = make(map[string]NodePartitionStatus, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.DRAMigration != nil {
+		in, out := &in.DRAMigration, &out.DRAMigration
+		*out = new(DRAMigrationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DriverImages != nil {
+		in, out := &in.DriverImages, &out.DriverImages
+		*out = make([]DriverImageStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.DryRunPlan != nil {
+		in, out := &in.DryRunPlan, &out.DryRunPlan
+		*out = new(DryRunPlanStatus)
+		**out = **in
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]metav1.Condition, len(*in))
@@ -317,6 +544,137 @@ func (in *DeviceConfigStatus) DeepCopy() *DeviceConfigStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeviceConfigTemplate) DeepCopyInto(out *DeviceConfigTemplate) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceConfigTemplate.
+func (in *DeviceConfigTemplate) DeepCopy() *DeviceConfigTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(DeviceConfigTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DeviceConfigTemplate) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeviceConfigTemplateList) DeepCopyInto(out *DeviceConfigTemplateList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DeviceConfigTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceConfigTemplateList.
+func (in *DeviceConfigTemplateList) DeepCopy() *DeviceConfigTemplateList {
+	if in == nil {
+		return nil
+	}
+	out := new(DeviceConfigTemplateList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DeviceConfigTemplateList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeviceConfigTemplateSpec) DeepCopyInto(out *DeviceConfigTemplateSpec) {
+	*out = *in
+	in.Template.DeepCopyInto(&out.Template)
+	if in.Overrides != nil {
+		in, out := &in.Overrides, &out.Overrides
+		*out = make([]DeviceConfigOverride, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceConfigTemplateSpec.
+func (in *DeviceConfigTemplateSpec) DeepCopy() *DeviceConfigTemplateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DeviceConfigTemplateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeviceConfigTemplateStatus) DeepCopyInto(out *DeviceConfigTemplateStatus) {
+	*out = *in
+	if in.NodeGroups != nil {
+		in, out := &in.NodeGroups, &out.NodeGroups
+		*out = make([]DeviceConfigNodeGroupStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceConfigTemplateStatus.
+func (in *DeviceConfigTemplateStatus) DeepCopy() *DeviceConfigTemplateStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DeviceConfigTemplateStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DevicePluginConfigSpec) DeepCopyInto(out *DevicePluginConfigSpec) {
+	*out = *in
+	if in.HealthCheckPulseSeconds != nil {
+		in, out := &in.HealthCheckPulseSeconds, &out.HealthCheckPulseSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DevicePluginConfigSpec.
+func (in *DevicePluginConfigSpec) DeepCopy() *DevicePluginConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DevicePluginConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DevicePluginSpec) DeepCopyInto(out *DevicePluginSpec) {
 	*out = *in
@@ -339,6 +697,11 @@ func (in *DevicePluginSpec) DeepCopyInto(out *DevicePluginSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.DevicePluginConfig != nil {
+		in, out := &in.DevicePluginConfig, &out.DevicePluginConfig
+		*out = new(DevicePluginConfigSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.NodeLabellerTolerations != nil {
 		in, out := &in.NodeLabellerTolerations, &out.NodeLabellerTolerations
 		*out = make([]v1.Toleration, len(*in))
@@ -351,6 +714,11 @@ func (in *DevicePluginSpec) DeepCopyInto(out *DevicePluginSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.NodeLabellerConfig != nil {
+		in, out := &in.NodeLabellerConfig, &out.NodeLabellerConfig
+		*out = new(NodeLabellerConfigSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.ImageRegistrySecret != nil {
 		in, out := &in.ImageRegistrySecret, &out.ImageRegistrySecret
 		*out = new(v1.LocalObjectReference)
@@ -371,6 +739,16 @@ func (in *DevicePluginSpec) DeepCopyInto(out *DevicePluginSpec) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.DevicePluginPodTemplateOverrides != nil {
+		in, out := &in.DevicePluginPodTemplateOverrides, &out.DevicePluginPodTemplateOverrides
+		*out = new(PodTemplateOverridesSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeLabellerPodTemplateOverrides != nil {
+		in, out := &in.NodeLabellerPodTemplateOverrides, &out.NodeLabellerPodTemplateOverrides
+		*out = new(PodTemplateOverridesSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DevicePluginSpec.
@@ -383,6 +761,42 @@ func (in *DevicePluginSpec) DeepCopy() *DevicePluginSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DockerfileTemplateDistribution) DeepCopyInto(out *DockerfileTemplateDistribution) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DockerfileTemplateDistribution.
+func (in *DockerfileTemplateDistribution) DeepCopy() *DockerfileTemplateDistribution {
+	if in == nil {
+		return nil
+	}
+	out := new(DockerfileTemplateDistribution)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DockerfileTemplatesSpec) DeepCopyInto(out *DockerfileTemplatesSpec) {
+	*out = *in
+	out.ConfigMap = in.ConfigMap
+	if in.Distributions != nil {
+		in, out := &in.Distributions, &out.Distributions
+		*out = make([]DockerfileTemplateDistribution, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DockerfileTemplatesSpec.
+func (in *DockerfileTemplatesSpec) DeepCopy() *DockerfileTemplatesSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DockerfileTemplatesSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DrainSpec) DeepCopyInto(out *DrainSpec) {
 	*out = *in
@@ -413,6 +827,21 @@ func (in *DrainSpec) DeepCopy() *DrainSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriverImageStatus) DeepCopyInto(out *DriverImageStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriverImageStatus.
+func (in *DriverImageStatus) DeepCopy() *DriverImageStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DriverImageStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DriverSpec) DeepCopyInto(out *DriverSpec) {
 	*out = *in
@@ -439,6 +868,11 @@ func (in *DriverSpec) DeepCopyInto(out *DriverSpec) {
 		*out = new(v1.LocalObjectReference)
 		**out = **in
 	}
+	if in.ImageCatalog != nil {
+		in, out := &in.ImageCatalog, &out.ImageCatalog
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
 	in.ImageSign.DeepCopyInto(&out.ImageSign)
 	in.ImageBuild.DeepCopyInto(&out.ImageBuild)
 	if in.UpgradePolicy != nil {
@@ -489,6 +923,21 @@ func (in *DriverUpgradePolicySpec) DeepCopyInto(out *DriverUpgradePolicySpec) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.AutoRollback != nil {
+		in, out := &in.AutoRollback, &out.AutoRollback
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RolloutStrategy != nil {
+		in, out := &in.RolloutStrategy, &out.RolloutStrategy
+		*out = new(UpgradeRolloutSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Hooks != nil {
+		in, out := &in.Hooks, &out.Hooks
+		*out = new(UpgradeHooksSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriverUpgradePolicySpec.
@@ -501,10 +950,99 @@ func (in *DriverUpgradePolicySpec) DeepCopy() *DriverUpgradePolicySpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriverUpgradeStatus) DeepCopyInto(out *DriverUpgradeStatus) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	if in.Status != nil {
+		in, out := &in.Status, &out.Status
+		*out = make(map[string]NodeUpgradeStatus, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriverUpgradeStatus.
+func (in *DriverUpgradeStatus) DeepCopy() *DriverUpgradeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DriverUpgradeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DriverUpgradeStatus) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriverUpgradeStatusList) DeepCopyInto(out *DriverUpgradeStatusList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DriverUpgradeStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriverUpgradeStatusList.
+func (in *DriverUpgradeStatusList) DeepCopy() *DriverUpgradeStatusList {
+	if in == nil {
+		return nil
+	}
+	out := new(DriverUpgradeStatusList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DriverUpgradeStatusList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DryRunPlanStatus) DeepCopyInto(out *DryRunPlanStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DryRunPlanStatus.
+func (in *DryRunPlanStatus) DeepCopy() *DryRunPlanStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DryRunPlanStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImageBuildSpec) DeepCopyInto(out *ImageBuildSpec) {
 	*out = *in
 	in.BaseImageRegistryTLS.DeepCopyInto(&out.BaseImageRegistryTLS)
+	if in.DockerfileTemplates != nil {
+		in, out := &in.DockerfileTemplates, &out.DockerfileTemplates
+		*out = new(DockerfileTemplatesSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Prebuild != nil {
+		in, out := &in.Prebuild, &out.Prebuild
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageBuildSpec.
@@ -633,7 +1171,37 @@ func (in *LogsLocationConfig) DeepCopy() *LogsLocationConfig {
 	if in == nil {
 		return nil
 	}
-	out := new(LogsLocationConfig)
+	out := new(LogsLocationConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindowStatus) DeepCopyInto(out *MaintenanceWindowStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindowStatus.
+func (in *MaintenanceWindowStatus) DeepCopy() *MaintenanceWindowStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindowStatus)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -692,6 +1260,11 @@ func (in *MetricsExporterSpec) DeepCopyInto(out *MetricsExporterSpec) {
 		*out = new(DaemonSetUpgradeSpec)
 		**out = **in
 	}
+	if in.PodTemplateOverrides != nil {
+		in, out := &in.PodTemplateOverrides, &out.PodTemplateOverrides
+		*out = new(PodTemplateOverridesSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Resource != nil {
 		in, out := &in.Resource, &out.Resource
 		*out = new(v1.ResourceRequirements)
@@ -743,6 +1316,148 @@ func (in *ModuleStatus) DeepCopy() *ModuleStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeDRAMigrationStatus) DeepCopyInto(out *NodeDRAMigrationStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeDRAMigrationStatus.
+func (in *NodeDRAMigrationStatus) DeepCopy() *NodeDRAMigrationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeDRAMigrationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeFeatureRuleSpec) DeepCopyInto(out *NodeFeatureRuleSpec) {
+	*out = *in
+	if in.Enable != nil {
+		in, out := &in.Enable, &out.Enable
+		*out = new(bool)
+		**out = **in
+	}
+	if in.DeviceIDs != nil {
+		in, out := &in.DeviceIDs, &out.DeviceIDs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.VFDeviceIDs != nil {
+		in, out := &in.VFDeviceIDs, &out.VFDeviceIDs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeFeatureRuleSpec.
+func (in *NodeFeatureRuleSpec) DeepCopy() *NodeFeatureRuleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeFeatureRuleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeLabellerConfigSpec) DeepCopyInto(out *NodeLabellerConfigSpec) {
+	*out = *in
+	if in.Allowlist != nil {
+		in, out := &in.Allowlist, &out.Allowlist
+		*out = make([]NodeLabellerLabel, len(*in))
+		copy(*out, *in)
+	}
+	if in.Denylist != nil {
+		in, out := &in.Denylist, &out.Denylist
+		*out = make([]NodeLabellerLabel, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeLabellerConfigSpec.
+func (in *NodeLabellerConfigSpec) DeepCopy() *NodeLabellerConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeLabellerConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodePartitionStatus) DeepCopyInto(out *NodePartitionStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodePartitionStatus.
+func (in *NodePartitionStatus) DeepCopy() *NodePartitionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodePartitionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeUpgradeStatus) DeepCopyInto(out *NodeUpgradeStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeUpgradeStatus.
+func (in *NodeUpgradeStatus) DeepCopy() *NodeUpgradeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeUpgradeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PartitionPolicySpec) DeepCopyInto(out *PartitionPolicySpec) {
+	*out = *in
+	if in.NodeDrainPolicy != nil {
+		in, out := &in.NodeDrainPolicy, &out.NodeDrainPolicy
+		*out = new(DrainSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PartitionPolicySpec.
+func (in *PartitionPolicySpec) DeepCopy() *PartitionPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PartitionPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PartitionProfileSpec) DeepCopyInto(out *PartitionProfileSpec) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PartitionProfileSpec.
+func (in *PartitionProfileSpec) DeepCopy() *PartitionProfileSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PartitionProfileSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PodDeletionSpec) DeepCopyInto(out *PodDeletionSpec) {
 	*out = *in
@@ -763,6 +1478,76 @@ func (in *PodDeletionSpec) DeepCopy() *PodDeletionSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodTemplateOverridesSpec) DeepCopyInto(out *PodTemplateOverridesSpec) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(v1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SecurityContext != nil {
+		in, out := &in.SecurityContext, &out.SecurityContext
+		*out = new(v1.PodSecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]v1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]v1.Volume, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.VolumeMounts != nil {
+		in, out := &in.VolumeMounts, &out.VolumeMounts
+		*out = make([]v1.VolumeMount, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Patch != nil {
+		in, out := &in.Patch, &out.Patch
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodTemplateOverridesSpec.
+func (in *PodTemplateOverridesSpec) DeepCopy() *PodTemplateOverridesSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PodTemplateOverridesSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PrometheusConfig) DeepCopyInto(out *PrometheusConfig) {
 	*out = *in
@@ -1059,6 +1844,11 @@ func (in *TestRunnerSpec) DeepCopyInto(out *TestRunnerSpec) {
 		*out = new(DaemonSetUpgradeSpec)
 		**out = **in
 	}
+	if in.PodTemplateOverrides != nil {
+		in, out := &in.PodTemplateOverrides, &out.PodTemplateOverrides
+		*out = new(PodTemplateOverridesSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	in.LogsLocation.DeepCopyInto(&out.LogsLocation)
 }
 
@@ -1072,6 +1862,159 @@ func (in *TestRunnerSpec) DeepCopy() *TestRunnerSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeCanarySpec) DeepCopyInto(out *UpgradeCanarySpec) {
+	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.HealthCheck != nil {
+		in, out := &in.HealthCheck, &out.HealthCheck
+		*out = new(UpgradeHealthCheckSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeCanarySpec.
+func (in *UpgradeCanarySpec) DeepCopy() *UpgradeCanarySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeCanarySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeHealthCheckSpec) DeepCopyInto(out *UpgradeHealthCheckSpec) {
+	*out = *in
+	if in.Enable != nil {
+		in, out := &in.Enable, &out.Enable
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeHealthCheckSpec.
+func (in *UpgradeHealthCheckSpec) DeepCopy() *UpgradeHealthCheckSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeHealthCheckSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeHookSpec) DeepCopyInto(out *UpgradeHookSpec) {
+	*out = *in
+	if in.JobTemplate != nil {
+		in, out := &in.JobTemplate, &out.JobTemplate
+		*out = new(batchv1.JobTemplateSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeHookSpec.
+func (in *UpgradeHookSpec) DeepCopy() *UpgradeHookSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeHookSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeHooksSpec) DeepCopyInto(out *UpgradeHooksSpec) {
+	*out = *in
+	if in.PreUpgrade != nil {
+		in, out := &in.PreUpgrade, &out.PreUpgrade
+		*out = new(UpgradeHookSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PostUpgrade != nil {
+		in, out := &in.PostUpgrade, &out.PostUpgrade
+		*out = new(UpgradeHookSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HealthCheck != nil {
+		in, out := &in.HealthCheck, &out.HealthCheck
+		*out = new(UpgradeHealthCheckSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeHooksSpec.
+func (in *UpgradeHooksSpec) DeepCopy() *UpgradeHooksSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeHooksSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeRolloutSpec) DeepCopyInto(out *UpgradeRolloutSpec) {
+	*out = *in
+	if in.Canary != nil {
+		in, out := &in.Canary, &out.Canary
+		*out = new(UpgradeCanarySpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Waves != nil {
+		in, out := &in.Waves, &out.Waves
+		*out = new(UpgradeWavesSpec)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeRolloutSpec.
+func (in *UpgradeRolloutSpec) DeepCopy() *UpgradeRolloutSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeRolloutSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeRolloutStatus) DeepCopyInto(out *UpgradeRolloutStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeRolloutStatus.
+func (in *UpgradeRolloutStatus) DeepCopy() *UpgradeRolloutStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeRolloutStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeWavesSpec) DeepCopyInto(out *UpgradeWavesSpec) {
+	*out = *in
+	out.Size = in.Size
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeWavesSpec.
+func (in *UpgradeWavesSpec) DeepCopy() *UpgradeWavesSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeWavesSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UtilsContainerSpec) DeepCopyInto(out *UtilsContainerSpec) {
 	*out = *in
@@ -39,6 +39,7 @@ import (
 
 	workflowv1alpha1 "github.com/argoproj/argo-workflows/v4/pkg/apis/workflow/v1alpha1"
 	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+	kmmv1beta2 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta2"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -62,6 +63,7 @@ import (
 	"github.com/ROCm/gpu-operator/internal/nodelabeller"
 	"github.com/ROCm/gpu-operator/internal/plugin"
 	"github.com/ROCm/gpu-operator/internal/testrunner"
+	"github.com/ROCm/gpu-operator/internal/webhook"
 	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	//+kubebuilder:scaffold:imports
@@ -78,6 +80,7 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(gpuev1alpha1.AddToScheme(scheme))
 	utilruntime.Must(kmmv1beta1.AddToScheme(scheme))
+	utilruntime.Must(kmmv1beta2.AddToScheme(scheme))
 	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
 	utilruntime.Must(monitoringv1.AddToScheme(scheme))
 	utilruntime.Must(workflowv1alpha1.AddToScheme(scheme))
@@ -154,12 +157,27 @@ func main() {
 		testrunnerHandler,
 		configmanagerHandler,
 		workerMgr,
+		mgr.GetEventRecorderFor(controllers.DeviceConfigReconcilerName),
 		isOpenShift,
 		kmmWatchEnabled)
 	if err = dcr.SetupWithManager(mgr); err != nil {
 		cmd.FatalError(setupLogger, err, "unable to create controller", "name", controllers.DeviceConfigReconcilerName)
 	}
 
+	dctr := controllers.NewDeviceConfigTemplateReconciler(
+		client,
+		scheme,
+		mgr.GetEventRecorderFor(controllers.DeviceConfigTemplateReconcilerName))
+	if err = dctr.SetupWithManager(mgr); err != nil {
+		cmd.FatalError(setupLogger, err, "unable to create controller", "name", controllers.DeviceConfigTemplateReconcilerName)
+	}
+
+	if cfg.Webhook.Enabled {
+		if err = webhook.SetupDeviceConfigWebhookWithManager(mgr); err != nil {
+			cmd.FatalError(setupLogger, err, "unable to create webhook", "kind", utils.KindDeviceConfig)
+		}
+	}
+
 	ctx := ctrl.SetupSignalHandler()
 
 	//+kubebuilder:scaffold:builder
@@ -170,6 +188,12 @@ func main() {
 	if err = mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
 		cmd.FatalError(setupLogger, err, "unable to set up ready check")
 	}
+	if cfg.Webhook.Enabled {
+		// the webhook service only routes the admission requests to the pods serving the webhook
+		if err = mgr.AddReadyzCheck("webhook", mgr.GetWebhookServer().StartedChecker()); err != nil {
+			cmd.FatalError(setupLogger, err, "unable to set up webhook ready check")
+		}
+	}
 
 	setupLogger.Info("starting manager")
 	if err = mgr.Start(ctx); err != nil {
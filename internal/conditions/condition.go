@@ -24,6 +24,8 @@ type ConditionUpdater interface {
 	GetReadyCondition(cr any) *metav1.Condition
 	SetReadyCondition(cr any, status metav1.ConditionStatus, reason string, message string)
 	SetErrorCondition(cr any, status metav1.ConditionStatus, reason string, message string)
+	SetUnmatchedGPUDevicesCondition(cr any, status metav1.ConditionStatus, reason string, message string)
 	DeleteReadyCondition(cr any)
 	DeleteErrorCondition(cr any)
+	DeleteUnmatchedGPUDevicesCondition(cr any)
 }
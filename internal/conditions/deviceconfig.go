@@ -25,6 +25,8 @@ import (
 const (
 	ConditionTypeReady = "Ready"
 	ConditionTypeError = "Error"
+	// ConditionTypeUnmatchedGPUDevices reports the AMD GPUs found by NFD on nodes that are not labelled as GPU nodes
+	ConditionTypeUnmatchedGPUDevices = "UnmatchedGPUDevices"
 )
 
 // Condition Reason
@@ -37,6 +39,10 @@ const (
 	ReadyStatus = "OperatorReady"
 	// NoMatchingNodes indicates no cluster nodes match the DeviceConfig selector
 	NoMatchingNodes = "NoMatchingNodes"
+	// NFDNotInstalled indicates no cluster nodes match the DeviceConfig selector and Node Feature Discovery is not installed
+	NFDNotInstalled = "NFDNotInstalled"
+	// UnmatchedGPUDevices indicates NFD found AMD GPUs on unlabelled nodes
+	UnmatchedGPUDevices = "UnmatchedGPUDevices"
 )
 
 type ConditionManager struct{}
@@ -72,6 +78,17 @@ func (cm *ConditionManager) SetErrorCondition(cr any, status metav1.ConditionSta
 	})
 }
 
+func (cm *ConditionManager) SetUnmatchedGPUDevicesCondition(cr any, status metav1.ConditionStatus, reason string, message string) {
+	devConfig := cr.(*amdv1alpha1.DeviceConfig)
+	setCondition(devConfig, metav1.Condition{
+		Type:               ConditionTypeUnmatchedGPUDevices,
+		Status:             status,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
 func (cm *ConditionManager) DeleteReadyCondition(cr any) {
 	devConfig := cr.(*amdv1alpha1.DeviceConfig)
 	deleteCondition(&devConfig.Status.Conditions, ConditionTypeReady)
@@ -82,6 +99,11 @@ func (cm *ConditionManager) DeleteErrorCondition(cr any) {
 	deleteCondition(&devConfig.Status.Conditions, ConditionTypeError)
 }
 
+func (cm *ConditionManager) DeleteUnmatchedGPUDevicesCondition(cr any) {
+	devConfig := cr.(*amdv1alpha1.DeviceConfig)
+	deleteCondition(&devConfig.Status.Conditions, ConditionTypeUnmatchedGPUDevices)
+}
+
 func setCondition(devConfig *amdv1alpha1.DeviceConfig, newCondition metav1.Condition) {
 	existingCondition := findCondition(devConfig.Status.Conditions, newCondition.Type)
 
@@ -39,6 +39,7 @@ import (
 	"gopkg.in/yaml.v3"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 )
 
 type LeaderElection struct {
@@ -46,10 +47,17 @@ type LeaderElection struct {
 	ResourceID string `yaml:"resourceID"`
 }
 
+type Webhook struct {
+	Enabled bool   `yaml:"enabled"`
+	Port    int    `yaml:"port"`
+	CertDir string `yaml:"certDir"`
+}
+
 type Config struct {
 	HealthProbeBindAddress string         `yaml:"healthProbeBindAddress"`
 	MetricsBindAddress     string         `yaml:"metricsBindAddress"`
 	LeaderElection         LeaderElection `yaml:"leaderElection"`
+	Webhook                Webhook        `yaml:"webhook"`
 }
 
 func ParseFile(path string) (*Config, error) {
@@ -69,7 +77,7 @@ func ParseFile(path string) (*Config, error) {
 }
 
 func (c *Config) ManagerOptions() *manager.Options {
-	return &manager.Options{
+	options := &manager.Options{
 		HealthProbeBindAddress: c.HealthProbeBindAddress,
 		LeaderElection:         c.LeaderElection.Enabled,
 		LeaderElectionID:       c.LeaderElection.ResourceID,
@@ -77,4 +85,11 @@ func (c *Config) ManagerOptions() *manager.Options {
 			BindAddress: c.MetricsBindAddress,
 		},
 	}
+	if c.Webhook.Enabled {
+		options.WebhookServer = webhook.NewServer(webhook.Options{
+			Port:    c.Webhook.Port,
+			CertDir: c.Webhook.CertDir,
+		})
+	}
+	return options
 }
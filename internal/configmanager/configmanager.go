@@ -35,6 +35,7 @@ package configmanager
 import (
 	"context"
 	_ "embed"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
@@ -58,10 +59,10 @@ import (
 
 const (
 	// TODO: determine where to host the config manager image and put the registry URL here
-	defaultConfigManagerImage = "docker.io/rocm/device-config-manager:v0.0.1"
+	defaultConfigManagerImage = utils.DefaultConfigManagerImage
 	ConfigManagerName         = "device-config-manager"
 	defaultSAName             = "amd-gpu-operator-config-manager"
-	defaultInitContainerImage = "busybox:1.36"
+	defaultInitContainerImage = utils.DefaultInitContainerImage
 	// DefaultDCMConfigMapName is the ConfigMap metadata.name mounted when spec.configManager.config
 	// is unset or has an empty name (see api/v1alpha1 ConfigManagerSpec.Config godoc).
 	// The DeviceConfig reconciler creates this ConfigMap in the DeviceConfig namespace when DCM is
@@ -73,6 +74,16 @@ const (
 	ConfigManagerConfigVolumeName = "config-manager-config-volume"
 	// DefaultDCMConfigMountPath is where the DCM container expects ConfigMap data (e.g. config.json).
 	DefaultDCMConfigMountPath = "/etc/config-manager/"
+	// PartitionConfigMapSuffix is appended to the DeviceConfig name to form the name of the DCM ConfigMap
+	// rendered from spec.configManager.partitionProfiles.
+	PartitionConfigMapSuffix = "-dcm-partition-config"
+	// GPUConfigProfileLabel is the node label DCM reads the name of the profile to apply from.
+	GPUConfigProfileLabel = "dcm.amd.com/gpu-config-profile"
+	// DCMTaintKey is the taint DCM tolerates, it evicts the other operands while the GPUs are repartitioned.
+	DCMTaintKey   = "amd-dcm"
+	DCMTaintValue = "up"
+	// PartitionSuccessEventReason is the reason of the event DCM emits once a node is repartitioned.
+	PartitionSuccessEventReason = "SuccessfullyPartitioned"
 )
 
 var (
@@ -83,9 +94,29 @@ var (
 	configManagerLabelPair = []string{"app.kubernetes.io/name", ConfigManagerName}
 )
 
+// dcmConfig is the config.json read by DCM
+type dcmConfig struct {
+	GPUConfigProfiles        map[string]dcmGPUConfigProfile `json:"gpu-config-profiles"`
+	GPUClientSystemdServices *dcmSystemdServices            `json:"gpuClientSystemdServices,omitempty"`
+}
+
+type dcmGPUConfigProfile struct {
+	Profiles []dcmPartitionProfile `json:"profiles"`
+}
+
+type dcmPartitionProfile struct {
+	ComputePartition amdv1alpha1.ComputePartitionType `json:"computePartition"`
+	MemoryPartition  amdv1alpha1.MemoryPartitionType  `json:"memoryPartition"`
+}
+
+type dcmSystemdServices struct {
+	Names []string `json:"names"`
+}
+
 //go:generate mockgen -source=configmanager.go -package=configmanager -destination=mock_configmanager.go ConfigManager
 type ConfigManager interface {
 	SetConfigManagerAsDesired(ds *appsv1.DaemonSet, devConfig *amdv1alpha1.DeviceConfig) error
+	SetPartitionConfigMapAsDesired(cm *v1.ConfigMap, devConfig *amdv1alpha1.DeviceConfig) error
 }
 
 type configManager struct {
@@ -98,6 +129,16 @@ func NewConfigManager(scheme *runtime.Scheme) ConfigManager {
 	}
 }
 
+// PartitionConfigMapName returns the name of the DCM ConfigMap rendered from spec.configManager.partitionProfiles
+func PartitionConfigMapName(devConfig *amdv1alpha1.DeviceConfig) string {
+	return devConfig.Name + PartitionConfigMapSuffix
+}
+
+// PartitionLabelValue returns the amd.com/compute-memory-partition label value the node labeller reports for the profile
+func PartitionLabelValue(profile amdv1alpha1.PartitionProfileSpec) string {
+	return strings.ToLower(fmt.Sprintf("%v_%v", profile.ComputePartition, profile.MemoryPartition))
+}
+
 // EnsureDefaultDCMConfigMap creates ConfigMap DefaultDCMConfigMapName in devConfig.Namespace when DCM is enabled
 // and spec.configManager.config is unset or has an empty name. If the ConfigMap already exists, it is unchanged.
 func EnsureDefaultDCMConfigMap(ctx context.Context, c client.Client, devConfig *amdv1alpha1.DeviceConfig) error {
@@ -111,6 +152,10 @@ func EnsureDefaultDCMConfigMap(ctx context.Context, c client.Client, devConfig *
 	if tr.Config != nil && strings.TrimSpace(tr.Config.Name) != "" {
 		return nil
 	}
+	if len(tr.PartitionProfiles) > 0 {
+		// the operator renders its own ConfigMap from the partition profiles
+		return nil
+	}
 
 	payload := strings.TrimSpace(string(defaultDCMConfigJSON))
 	if payload == "" {
@@ -146,6 +191,47 @@ func EnsureDefaultDCMConfigMap(ctx context.Context, c client.Client, devConfig *
 	return nil
 }
 
+// SetPartitionConfigMapAsDesired renders the DCM config.json from spec.configManager.partitionProfiles. The client
+// systemd services are taken from the default config
+func (nl *configManager) SetPartitionConfigMapAsDesired(cm *v1.ConfigMap, devConfig *amdv1alpha1.DeviceConfig) error {
+	if cm == nil {
+		return fmt.Errorf("config map is not initialized, zero pointer")
+	}
+
+	defaultConfig := dcmConfig{}
+	if err := json.Unmarshal(defaultDCMConfigJSON, &defaultConfig); err != nil {
+		return fmt.Errorf("failed to parse embedded default_dcm_config.json: %v", err)
+	}
+	config := dcmConfig{
+		GPUConfigProfiles:        map[string]dcmGPUConfigProfile{},
+		GPUClientSystemdServices: defaultConfig.GPUClientSystemdServices,
+	}
+	for _, profile := range devConfig.Spec.ConfigManager.PartitionProfiles {
+		config.GPUConfigProfiles[profile.Name] = dcmGPUConfigProfile{
+			Profiles: []dcmPartitionProfile{
+				{
+					ComputePartition: profile.ComputePartition,
+					MemoryPartition:  profile.MemoryPartition,
+				},
+			},
+		}
+	}
+	payload, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to render DCM config: %v", err)
+	}
+
+	if cm.Labels == nil {
+		cm.Labels = map[string]string{}
+	}
+	cm.Labels["app.kubernetes.io/name"] = "gpu-operator"
+	cm.Labels["app.kubernetes.io/component"] = "device-config-manager"
+	cm.Labels["app.kubernetes.io/managed-by"] = "gpu-operator"
+	cm.Data = map[string]string{"config.json": string(payload)}
+
+	return controllerutil.SetControllerReference(devConfig, cm, nl.scheme)
+}
+
 func (nl *configManager) SetConfigManagerAsDesired(ds *appsv1.DaemonSet, devConfig *amdv1alpha1.DeviceConfig) error {
 	if ds == nil {
 		return fmt.Errorf("daemon set is not initialized, zero pointer")
@@ -271,6 +357,8 @@ func (nl *configManager) SetConfigManagerAsDesired(ds *appsv1.DaemonSet, devConf
 	configRef := v1.LocalObjectReference{Name: DefaultDCMConfigMapName}
 	if trSpec.Config != nil && trSpec.Config.Name != "" {
 		configRef.Name = trSpec.Config.Name
+	} else if len(trSpec.PartitionProfiles) > 0 {
+		configRef.Name = PartitionConfigMapName(devConfig)
 	}
 	volumes = append(volumes, v1.Volume{
 		Name: ConfigManagerConfigVolumeName,
@@ -438,5 +526,8 @@ func (nl *configManager) SetConfigManagerAsDesired(ds *appsv1.DaemonSet, devConf
 		ds.Spec.Template.Spec.Tolerations = append(ds.Spec.Template.Spec.Tolerations, devConfig.Spec.ConfigManager.ConfigManagerTolerations...)
 	}
 
+	if err := utils.ApplyPodTemplateOverrides(ds, devConfig.Spec.ConfigManager.PodTemplateOverrides); err != nil {
+		return err
+	}
 	return controllerutil.SetControllerReference(devConfig, ds, nl.scheme)
 }
@@ -0,0 +1,78 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configmanager
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	amdv1alpha1 "github.com/ROCm/gpu-operator/api/v1alpha1"
+)
+
+var _ = Describe("SetPartitionConfigMapAsDesired", func() {
+	var devConfig *amdv1alpha1.DeviceConfig
+
+	BeforeEach(func() {
+		devConfig = &amdv1alpha1.DeviceConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: "test-namespace", UID: "uid"},
+			Spec: amdv1alpha1.DeviceConfigSpec{
+				ConfigManager: amdv1alpha1.ConfigManagerSpec{
+					PartitionProfiles: []amdv1alpha1.PartitionProfileSpec{
+						{Name: "cpx-nps4", ComputePartition: "CPX", MemoryPartition: "NPS4"},
+						{Name: "spx-nps1", ComputePartition: "SPX", MemoryPartition: "NPS1", NodeSelector: map[string]string{"gpu": "mi300"}},
+					},
+				},
+			},
+		}
+	})
+
+	It("renders a DCM profile for every partition profile", func() {
+		cm := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: PartitionConfigMapName(devConfig), Namespace: devConfig.Namespace}}
+		Expect(NewConfigManager(scheme).SetPartitionConfigMapAsDesired(cm, devConfig)).To(Succeed())
+
+		Expect(cm.Name).To(Equal("test-config" + PartitionConfigMapSuffix))
+		Expect(cm.Labels).To(HaveKeyWithValue("app.kubernetes.io/component", "device-config-manager"))
+		Expect(cm.OwnerReferences).To(HaveLen(1))
+		Expect(cm.OwnerReferences[0].Name).To(Equal(devConfig.Name))
+
+		config := dcmConfig{}
+		Expect(json.Unmarshal([]byte(cm.Data["config.json"]), &config)).To(Succeed())
+		Expect(config.GPUConfigProfiles).To(Equal(map[string]dcmGPUConfigProfile{
+			"cpx-nps4": {Profiles: []dcmPartitionProfile{{ComputePartition: "CPX", MemoryPartition: "NPS4"}}},
+			"spx-nps1": {Profiles: []dcmPartitionProfile{{ComputePartition: "SPX", MemoryPartition: "NPS1"}}},
+		}))
+		// the systemd services of the default config are stopped during the repartitioning
+		defaultConfig := dcmConfig{}
+		Expect(json.Unmarshal(defaultDCMConfigJSON, &defaultConfig)).To(Succeed())
+		Expect(config.GPUClientSystemdServices).To(Equal(defaultConfig.GPUClientSystemdServices))
+	})
+
+	It("fails on a nil ConfigMap", func() {
+		Expect(NewConfigManager(scheme).SetPartitionConfigMapAsDesired(nil, devConfig)).NotTo(Succeed())
+	})
+})
+
+var _ = Describe("PartitionLabelValue", func() {
+	It("matches the partition label reported by the node labeller", func() {
+		profile := amdv1alpha1.PartitionProfileSpec{Name: "cpx-nps4", ComputePartition: "CPX", MemoryPartition: "NPS4"}
+		Expect(PartitionLabelValue(profile)).To(Equal("cpx_nps4"))
+	})
+})
@@ -30,6 +30,7 @@ import (
 	v1alpha1 "github.com/ROCm/gpu-operator/api/v1alpha1"
 	gomock "go.uber.org/mock/gomock"
 	v1 "k8s.io/api/apps/v1"
+	v10 "k8s.io/api/core/v1"
 )
 
 // MockConfigManager is a mock of ConfigManager interface.
@@ -68,3 +69,17 @@ func (mr *MockConfigManagerMockRecorder) SetConfigManagerAsDesired(ds, devConfig
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetConfigManagerAsDesired", reflect.TypeOf((*MockConfigManager)(nil).SetConfigManagerAsDesired), ds, devConfig)
 }
+
+// SetPartitionConfigMapAsDesired mocks base method.
+func (m *MockConfigManager) SetPartitionConfigMapAsDesired(cm *v10.ConfigMap, devConfig *v1alpha1.DeviceConfig) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPartitionConfigMapAsDesired", cm, devConfig)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPartitionConfigMapAsDesired indicates an expected call of SetPartitionConfigMapAsDesired.
+func (mr *MockConfigManagerMockRecorder) SetPartitionConfigMapAsDesired(cm, devConfig any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPartitionConfigMapAsDesired", reflect.TypeOf((*MockConfigManager)(nil).SetPartitionConfigMapAsDesired), cm, devConfig)
+}
@@ -37,8 +37,10 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
@@ -54,6 +56,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
@@ -79,10 +82,12 @@ import (
 )
 
 const (
-	DeviceConfigReconcilerName = "DriverAndPluginReconciler"
-	deviceConfigFinalizer      = "amd.node.kubernetes.io/deviceconfig-finalizer"
-	testRunnerNodeLabelPrefix  = "testrunner.amd.com"
-	deviceClassName            = "gpu.amd.com"
+	DeviceConfigReconcilerName        = "DriverAndPluginReconciler"
+	deviceConfigFinalizer             = "amd.node.kubernetes.io/deviceconfig-finalizer"
+	testRunnerNodeLabelPrefix         = "testrunner.amd.com"
+	deviceClassName                   = "gpu.amd.com"
+	deviceClassOwnerNameLabelKey      = "operator.amd.com/deviceconfig-name"
+	deviceClassOwnerNamespaceLabelKey = "operator.amd.com/deviceconfig-namespace"
 )
 
 var draAPIVersionPriority = []string{"v1", "v1beta2", "v1beta1"}
@@ -94,6 +99,7 @@ type DeviceConfigReconciler struct {
 	initErr               error
 	kmmWatchEnabled       bool
 	helper                deviceConfigReconcilerHelperAPI
+	recorder              record.EventRecorder
 	podEventHandler       watchers.PodEventHandlerAPI
 	nodeEventHandler      watchers.NodeEventHandlerAPI
 	daemonsetEventHandler watchers.DaemonsetEventHandlerAPI
@@ -110,11 +116,14 @@ func NewDeviceConfigReconciler(
 	testrunnerHandler testrunner.TestRunner,
 	configmanagerHandler configmanager.ConfigManager,
 	workerMgr workermgr.WorkerMgrAPI,
+	recorder record.EventRecorder,
 	isOpenShift bool,
 	kmmWatchEnabled bool) *DeviceConfigReconciler {
-	upgradeMgrHandler := newUpgradeMgrHandler(client, k8sConfig, isOpenShift)
-	remediationMgrHandler := newRemediationMgrHandler(client, apiReader, k8sConfig, isOpenShift)
-	helper := newDeviceConfigReconcilerHelper(client, kmmHandler, dpHandler, nlHandler, upgradeMgrHandler, remediationMgrHandler, metricsHandler, testrunnerHandler, configmanagerHandler, workerMgr, isOpenShift, kmmWatchEnabled)
+	upgradeMgrHandler := newUpgradeMgrHandler(client, k8sConfig, recorder, isOpenShift)
+	remediationMgrHandler := newRemediationMgrHandler(client, apiReader, k8sConfig, recorder, isOpenShift)
+	partitionMgrHandler := newPartitionMgrHandler(client, k8sConfig, recorder)
+	draMigrationMgrHandler := newDRAMigrationMgrHandler(client, k8sConfig, recorder)
+	helper := newDeviceConfigReconcilerHelper(client, kmmHandler, dpHandler, nlHandler, upgradeMgrHandler, remediationMgrHandler, partitionMgrHandler, draMigrationMgrHandler, metricsHandler, testrunnerHandler, configmanagerHandler, workerMgr, isOpenShift, kmmWatchEnabled)
 	podEventHandler := watchers.NewPodEventHandler(client, workerMgr)
 	nodeEventHandler := watchers.NewNodeEventHandler(client, workerMgr)
 	daemonsetEventHandler := watchers.NewDaemonsetEventHandler(client)
@@ -122,6 +131,7 @@ func NewDeviceConfigReconciler(
 		Client:                client,
 		kmmWatchEnabled:       kmmWatchEnabled,
 		helper:                helper,
+		recorder:              recorder,
 		podEventHandler:       podEventHandler,
 		nodeEventHandler:      nodeEventHandler,
 		daemonsetEventHandler: daemonsetEventHandler,
@@ -157,6 +167,10 @@ func (r *DeviceConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			&v1.Secret{},
 			handler.EnqueueRequestsFromMapFunc(r.helper.findDeviceConfigsForSecret),
 		).
+		Watches( // watch for the driver image build ConfigMaps event, reconcile corresponding DeviceConfig
+			&v1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(r.helper.findDeviceConfigsForConfigMap),
+		).
 		Watches(&v1.Node{}, // watch for Node resource to get latest kernel mapping for KMM CR
 			r.nodeEventHandler,
 			builder.WithPredicates(watchers.NodePredicate{}),
@@ -181,6 +195,9 @@ func (r *DeviceConfigReconciler) init(ctx context.Context) {
 //+kubebuilder:rbac:groups=amd.com,resources=deviceconfigs,verbs=get;list;watch;create;patch;update
 //+kubebuilder:rbac:groups=amd.com,resources=deviceconfigs/status,verbs=get;patch;update
 //+kubebuilder:rbac:groups=amd.com,resources=deviceconfigs/finalizers,verbs=update
+//+kubebuilder:rbac:groups=amd.com,resources=driverupgradestatuses,verbs=get;list;watch;create;patch;update
+//+kubebuilder:rbac:groups=amd.com,resources=driverupgradestatuses/status,verbs=get;patch;update
+//+kubebuilder:rbac:groups=amd.com,resources=driverupgradestatuses/finalizers,verbs=update
 //+kubebuilder:rbac:groups=amd.com,resources=remediationworkflowstatuses,verbs=get;list;watch;create;patch;update
 //+kubebuilder:rbac:groups=amd.com,resources=remediationworkflowstatuses/status,verbs=get;patch;update
 //+kubebuilder:rbac:groups=amd.com,resources=remediationworkflowstatuses/finalizers,verbs=update
@@ -188,11 +205,14 @@ func (r *DeviceConfigReconciler) init(ctx context.Context) {
 //+kubebuilder:rbac:groups=kmm.sigs.x-k8s.io,resources=modules/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=kmm.sigs.x-k8s.io,resources=modules/finalizers,verbs=get;update;watch
 //+kubebuilder:rbac:groups=kmm.sigs.x-k8s.io,resources=nodemodulesconfigs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=kmm.sigs.x-k8s.io,resources=preflightvalidations,verbs=get;list;watch;create;patch;delete
 //+kubebuilder:rbac:groups=kmm.sigs.x-k8s.io,resources=nodemodulesconfigs/status,verbs=get;list;watch
 //+kubebuilder:rbac:groups=kmm.sigs.x-k8s.io,resources=nodemodulesconfigs/finalizers,verbs=get;update;watch
 //+kubebuilder:rbac:groups=nfd.openshift.io,resources=nodefeaturediscoveries,verbs=list;get;delete
 //+kubebuilder:rbac:groups=nfd.openshift.io,resources=nodefeaturediscoveries/status,verbs=get;update
 //+kubebuilder:rbac:groups=nfd.openshift.io,resources=nodefeaturediscoveries/finalizers,verbs=get;update
+//+kubebuilder:rbac:groups=nfd.k8s-sigs.io,resources=nodefeaturerules,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=nfd.k8s-sigs.io,resources=nodefeatures,verbs=get;list;watch
 //+kubebuilder:rbac:groups=core,resources=configmaps,verbs=create;delete;get;list;patch;watch;create
 //+kubebuilder:rbac:groups=core,resources=nodes,verbs=get;patch;list;watch
 //+kubebuilder:rbac:groups=core,resources=nodes/status,verbs=get;update;watch
@@ -201,6 +221,7 @@ func (r *DeviceConfigReconciler) init(ctx context.Context) {
 //+kubebuilder:rbac:groups=apps,resources=daemonsets/status,verbs=create;delete;get;list;patch;watch
 //+kubebuilder:rbac:groups=apps,resources=daemonsets/finalizers,verbs=create;get;update;watch
 //+kubebuilder:rbac:groups=apps,resources=deployments,verbs=create;delete;get;list;patch;watch;update
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=create;delete;get;list;watch
 //+kubebuilder:rbac:groups=core,resources=services,verbs=create;delete;get;list;patch;watch
 //+kubebuilder:rbac:groups=core,resources=services/finalizers,verbs=create;get;update;watch
 //+kubebuilder:rbac:groups=core,resources=pods,verbs=delete;get;list;watch;create
@@ -208,9 +229,12 @@ func (r *DeviceConfigReconciler) init(ctx context.Context) {
 //+kubebuilder:rbac:groups=core,resources=pods/finalizers,verbs=delete;get;list;watch
 //+kubebuilder:rbac:groups=core,resources=secrets,verbs=create;delete;get;list;patch;watch
 //+kubebuilder:rbac:groups=core,resources=pods/eviction,verbs=delete;get;list;create
+//+kubebuilder:rbac:groups=core,resources=events,verbs=get;list;watch
 //+kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;list;watch;delete
 //+kubebuilder:rbac:groups=monitoring.coreos.com,resources=servicemonitors,verbs=get;list;watch;create;update;patch;delete
-//+kubebuilder:rbac:groups=resource.k8s.io,resources=deviceclasses,verbs=create
+//+kubebuilder:rbac:groups=resource.k8s.io,resources=deviceclasses,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=resource.k8s.io,resources=resourceclaimtemplates,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=resource.k8s.io,resources=resourceclaims,verbs=get;list;watch
 //+kubebuilder:rbac:groups=security.openshift.io,resources=securitycontextconstraints,resourceNames=privileged,verbs=use
 
 func (r *DeviceConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -229,7 +253,7 @@ func (r *DeviceConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	if err != nil {
 		if k8serrors.IsNotFound(err) || strings.Contains(err.Error(), "not found") {
 			logger.Info("DeviceConfig CR deleted")
-			r.helper.updateNodeAssignments(req.NamespacedName.String(), nil, true)
+			r.helper.updateNodeAssignments(req.NamespacedName.String(), nil, nil, true)
 			return ctrl.Result{}, nil
 		}
 		return res, fmt.Errorf("failed to get the requested %s CR: %v", req.NamespacedName, err)
@@ -241,6 +265,7 @@ func (r *DeviceConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	}
 
 	if devConfig.GetDeletionTimestamp() != nil {
+		deleteDeviceConfigMetrics(devConfig)
 		// Reset the upgrade states
 		if _, err := r.helper.handleModuleUpgrade(ctx, devConfig, nodes, true); err != nil {
 			logger.Error(err, fmt.Sprintf("upgrade manager delete device config error: %v", err))
@@ -248,6 +273,13 @@ func (r *DeviceConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		if _, err := r.helper.handleRemediationWorkflow(ctx, devConfig, nodes, true); err != nil {
 			logger.Error(err, fmt.Sprintf("remediation manager delete device config error: %v", err))
 		}
+		// Release the nodes held for repartitioning
+		if _, err := r.helper.handlePartitionProfiles(ctx, devConfig, nodes, true); err != nil {
+			logger.Error(err, fmt.Sprintf("partition manager delete device config error: %v", err))
+		}
+		if _, err := r.helper.handleDRAMigration(ctx, devConfig, nodes, true); err != nil {
+			logger.Error(err, fmt.Sprintf("DRA migration delete device config error: %v", err))
+		}
 		// DeviceConfig is being deleted
 		err = r.helper.finalizeDeviceConfig(ctx, devConfig, nodes)
 		if err != nil {
@@ -256,8 +288,8 @@ func (r *DeviceConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return ctrl.Result{}, nil
 	}
 
-	// Verify that the DeviceConfig does not select nodes covered by other DeviceConfigs
-	err = r.helper.validateNodeAssignments(req.NamespacedName.String(), nodes)
+	// Verify that the operands of the DeviceConfig are not owned by other DeviceConfigs on the selected nodes
+	err = r.helper.validateNodeAssignments(req.NamespacedName.String(), utils.GetOwnedOperands(devConfig), nodes)
 	if err != nil {
 		if errSet := r.helper.setCondition(ctx, conditions.ConditionTypeError, devConfig, metav1.ConditionTrue, conditions.ValidationError, fmt.Sprintf("Validation failed: %v", err)); errSet != nil {
 			logger.Error(fmt.Errorf("Failed to set error condition: %v", errSet), "")
@@ -265,6 +297,7 @@ func (r *DeviceConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		if errSet := r.helper.setCondition(ctx, conditions.ConditionTypeReady, devConfig, metav1.ConditionFalse, conditions.ReadyStatus, ""); errSet != nil {
 			logger.Error(fmt.Errorf("Failed to set ready condition: %v", errSet), "")
 		}
+		recordEvent(r.recorder, devConfig, nil, v1.EventTypeWarning, EventReasonValidationFailed, fmt.Sprintf("Validation failed: %v", err))
 		return res, err
 	}
 
@@ -278,20 +311,66 @@ func (r *DeviceConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		if errSet := r.helper.setCondition(ctx, conditions.ConditionTypeReady, devConfig, metav1.ConditionFalse, conditions.ReadyStatus, ""); errSet != nil {
 			logger.Error(fmt.Errorf("Failed to set ready condition: %v", errSet), "")
 		}
+		recordEvent(r.recorder, devConfig, nil, v1.EventTypeWarning, EventReasonValidationFailed, fmt.Sprintf("Validation failed: %v", result))
 		return res, fmt.Errorf("validation failed for DeviceConfig %s: %v", req.NamespacedName, result)
 	}
 
+	// the NodeFeatureRule labels the GPU nodes, so it is reconciled before any node matches the selector
+	if !isDryRun(devConfig) {
+		if devConfig.Spec.NodeFeatureRule.IsEnabled() {
+			if err = r.helper.setFinalizer(ctx, devConfig); err != nil {
+				return res, fmt.Errorf("failed to set finalizer for DeviceConfig %s: %v", req.NamespacedName, err)
+			}
+		}
+		logger.Info("start NodeFeatureRule reconciliation")
+		if err = r.helper.handleNodeFeatureRule(ctx, devConfig); err != nil {
+			return res, fmt.Errorf("failed to handle NodeFeatureRule for DeviceConfig %s: %v", req.NamespacedName, err)
+		}
+	}
+
 	if len(nodes.Items) == 0 {
-		msg := fmt.Sprintf("no nodes found matching selector %s; verify node labels or check that NFD has labeled the GPU nodes", labels.Set(devConfig.Spec.Selector).String())
-		if errSet := r.helper.setCondition(ctx, conditions.ConditionTypeError, devConfig, metav1.ConditionTrue, conditions.NoMatchingNodes, msg); errSet != nil {
+		reason, msg, err := r.helper.diagnoseNoMatchingNodes(ctx, devConfig)
+		if err != nil {
+			logger.Error(err, "failed to diagnose the missing nodes")
+			reason = conditions.NoMatchingNodes
+			msg = fmt.Sprintf("no nodes found matching selector %s; verify node labels or check that NFD has labeled the GPU nodes", labels.Set(devConfig.Spec.Selector).String())
+		}
+		if errSet := r.helper.setCondition(ctx, conditions.ConditionTypeError, devConfig, metav1.ConditionTrue, reason, msg); errSet != nil {
 			logger.Error(fmt.Errorf("Failed to set error condition: %v", errSet), "")
 		}
-		if errSet := r.helper.setCondition(ctx, conditions.ConditionTypeReady, devConfig, metav1.ConditionFalse, conditions.NoMatchingNodes, msg); errSet != nil {
+		if errSet := r.helper.setCondition(ctx, conditions.ConditionTypeReady, devConfig, metav1.ConditionFalse, reason, msg); errSet != nil {
 			logger.Error(fmt.Errorf("Failed to set ready condition: %v", errSet), "")
 		}
 		return ctrl.Result{}, nil
 	}
 
+	// In dry run mode only the plan of the spec is computed. Node upgrades and remediation workflows already in flight
+	// are driven to completion, the upgrade and remediation managers do not start new ones
+	if isDryRun(devConfig) {
+		planned := devConfig.Status.DryRunPlan != nil && devConfig.Status.DryRunPlan.ObservedGeneration == devConfig.Generation
+		if err := r.helper.handleDryRun(ctx, devConfig, nodes); err != nil {
+			return res, fmt.Errorf("failed to handle dry run for DeviceConfig %s: %v", req.NamespacedName, err)
+		}
+		if !planned {
+			recordEvent(r.recorder, devConfig, nil, v1.EventTypeNormal, EventReasonDryRunPlanReady,
+				fmt.Sprintf("Dry run plan of generation %d written to ConfigMap %s", devConfig.Generation, devConfig.Status.DryRunPlan.ConfigMap))
+		}
+
+		logger.Info("start in-flight module upgrade reconciliation")
+		res, err = r.helper.handleModuleUpgrade(ctx, devConfig, nodes, false)
+		if err != nil {
+			return res, fmt.Errorf("failed to handle module upgrade for DeviceConfig %s: %v", req.NamespacedName, err)
+		}
+
+		logger.Info("start in-flight remediation workflow reconciliation")
+		remediationRes, err := r.helper.handleRemediationWorkflow(ctx, devConfig, nodes, false)
+		finalRes := r.helper.shouldReconcile(ctx, res, remediationRes)
+		if err != nil {
+			return finalRes, fmt.Errorf("failed to handle remediation workflow for DeviceConfig %s: %v", req.NamespacedName, err)
+		}
+		return finalRes, nil
+	}
+
 	err = r.helper.setFinalizer(ctx, devConfig)
 	if err != nil {
 		return res, fmt.Errorf("failed to set finalizer for DeviceConfig %s: %v", req.NamespacedName, err)
@@ -303,11 +382,28 @@ func (r *DeviceConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return res, fmt.Errorf("failed to handle build ConfigMap for DeviceConfig %s: %v", req.NamespacedName, err)
 	}
 
+	logger.Info("start driver image prebuild reconciliation")
+	previousImages := devConfig.Status.DriverImages
+	prebuildRes, imagesReady, err := r.helper.handleDriverImagePrebuild(ctx, devConfig, nodes)
+	if err != nil {
+		return res, fmt.Errorf("failed to handle driver image prebuild for DeviceConfig %s: %v", req.NamespacedName, err)
+	}
+	for _, image := range getNewlyFailedDriverImages(previousImages, devConfig.Status.DriverImages) {
+		recordEvent(r.recorder, devConfig, nil, v1.EventTypeWarning, EventReasonDriverImagePrebuildFailed,
+			fmt.Sprintf("Failed to build driver image %s: %s", image.Image, image.Message))
+	}
+
+	// node upgrades in progress go on, the upgrade manager only holds back new node upgrades until the images are ready
+	if !imagesReady {
+		logger.Info("waiting for the driver images to be built before starting new node upgrades")
+	}
+
 	logger.Info("start module install/upgrade reconciliation")
 	res, err = r.helper.handleModuleUpgrade(ctx, devConfig, nodes, false)
 	if err != nil {
 		return res, fmt.Errorf("Failed to fetch nodes for DeviceConfig %s: %v", req.NamespacedName, err)
 	}
+	res = r.helper.shouldReconcile(ctx, res, prebuildRes)
 
 	logger.Info("start KMM reconciliation")
 	if err = r.helper.handleKMMModule(ctx, devConfig, nodes); err != nil {
@@ -324,11 +420,23 @@ func (r *DeviceConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return res, fmt.Errorf("failed to handle DeviceClass for DeviceConfig %s: %v", req.NamespacedName, err)
 	}
 
+	logger.Info("start ResourceClaimTemplate reconciliation")
+	if err = r.helper.handleResourceClaimTemplates(ctx, devConfig); err != nil {
+		return res, fmt.Errorf("failed to handle ResourceClaimTemplates for DeviceConfig %s: %v", req.NamespacedName, err)
+	}
+
 	logger.Info("start dra-driver reconciliation")
 	if err = r.helper.handleDRADriver(ctx, devConfig, nodes); err != nil {
 		return res, fmt.Errorf("failed to handle dra-driver for DeviceConfig %s: %v", req.NamespacedName, err)
 	}
 
+	logger.Info("start DRA migration reconciliation")
+	migrationRes, err := r.helper.handleDRAMigration(ctx, devConfig, nodes, false)
+	if err != nil {
+		return res, fmt.Errorf("failed to handle DRA migration for DeviceConfig %s: %v", req.NamespacedName, err)
+	}
+	res = r.helper.shouldReconcile(ctx, res, migrationRes)
+
 	logger.Info("start kmm mod version label reconciliation")
 	err = r.helper.handleKMMVersionLabel(ctx, devConfig, nodes)
 	if err != nil {
@@ -356,6 +464,13 @@ func (r *DeviceConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return res, fmt.Errorf("failed to handle config manager for DeviceConfig %s: %v", req.NamespacedName, err)
 	}
 
+	logger.Info("start partition profile reconciliation")
+	partitionRes, err := r.helper.handlePartitionProfiles(ctx, devConfig, nodes, false)
+	if err != nil {
+		return res, fmt.Errorf("failed to handle partition profiles for DeviceConfig %s: %v", req.NamespacedName, err)
+	}
+	res = r.helper.shouldReconcile(ctx, res, partitionRes)
+
 	logger.Info("start remediation workflow reconciliation")
 	remediationRes, err := r.helper.handleRemediationWorkflow(ctx, devConfig, nodes, false)
 	// Upgrade manager and Remediation manager both can decide whether a requeue is needed on the overall reconcile loop.
@@ -367,6 +482,12 @@ func (r *DeviceConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return finalRes, fmt.Errorf("failed to handle remediation workflow for DeviceConfig %s: %v", req.NamespacedName, err)
 	}
 
+	// NFD may find GPUs on nodes that are not labelled as GPU nodes while other nodes match the selector
+	logger.Info("start unmatched GPU devices reconciliation")
+	if err := r.helper.handleUnmatchedGPUDevices(ctx, devConfig); err != nil {
+		logger.Error(err, "failed to look for the unmatched GPU devices")
+	}
+
 	err = r.helper.buildDeviceConfigStatus(ctx, devConfig, nodes)
 	if err != nil {
 		return finalRes, fmt.Errorf("failed to build status for DeviceConfig %s: %v", req.NamespacedName, err)
@@ -378,7 +499,7 @@ func (r *DeviceConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	}
 
 	// Update nodeAssignments after DeviceConfig status update
-	r.helper.updateNodeAssignments(req.NamespacedName.String(), nodes, false)
+	r.helper.updateNodeAssignments(req.NamespacedName.String(), utils.GetOwnedOperands(devConfig), nodes, false)
 
 	return finalRes, nil
 }
@@ -388,18 +509,24 @@ type deviceConfigReconcilerHelperAPI interface {
 	getRequestedDeviceConfig(ctx context.Context, namespacedName types.NamespacedName) (*amdv1alpha1.DeviceConfig, error)
 	listDeviceConfigs(ctx context.Context) (*amdv1alpha1.DeviceConfigList, error)
 	buildNodeAssignments(ctx context.Context, deviceConfigList *amdv1alpha1.DeviceConfigList) error
-	validateNodeAssignments(namespacedName string, nodes *v1.NodeList) error
-	updateNodeAssignments(namespacedName string, nodes *v1.NodeList, isFinalizer bool)
+	validateNodeAssignments(namespacedName string, operands []string, nodes *v1.NodeList) error
+	updateNodeAssignments(namespacedName string, operands []string, nodes *v1.NodeList, isFinalizer bool)
 	getDeviceConfigOwnedKMMModule(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig) (*kmmv1beta1.Module, error)
 	buildDeviceConfigStatus(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) error
 	updateDeviceConfigStatus(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig) error
 	finalizeDeviceConfig(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) error
 	findDeviceConfigsForSecret(ctx context.Context, secret client.Object) []reconcile.Request
+	findDeviceConfigsForConfigMap(ctx context.Context, configMap client.Object) []reconcile.Request
 	findDeviceConfigsForNMC(ctx context.Context, nmc client.Object) []reconcile.Request
 	setFinalizer(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig) error
+	handleDryRun(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) error
 	handleKMMModule(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) error
 	handleDevicePlugin(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) error
 	handleDeviceClass(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig) error
+	handleNodeFeatureRule(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig) error
+	diagnoseNoMatchingNodes(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig) (string, string, error)
+	handleUnmatchedGPUDevices(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig) error
+	handleResourceClaimTemplates(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig) error
 	handleDRADriver(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) error
 	handleKMMVersionLabel(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) error
 	handleBuildConfigMap(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) error
@@ -408,9 +535,12 @@ type deviceConfigReconcilerHelperAPI interface {
 	handleTestRunner(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) error
 	handleConfigManager(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig) error
 	handleRemediationWorkflow(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList, delete bool) (ctrl.Result, error)
+	handlePartitionProfiles(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList, delete bool) (ctrl.Result, error)
+	handleDRAMigration(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList, delete bool) (ctrl.Result, error)
 	setCondition(ctx context.Context, condition string, devConfig *amdv1alpha1.DeviceConfig, status metav1.ConditionStatus, reason string, message string) error
 	deleteCondition(ctx context.Context, condition string, devConfig *amdv1alpha1.DeviceConfig) error
 	validateDeviceConfig(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig) []string
+	handleDriverImagePrebuild(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) (ctrl.Result, bool, error)
 	handleModuleUpgrade(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList, delete bool) (ctrl.Result, error)
 	shouldReconcile(ctx context.Context, ugpgradeRes, remediationRes ctrl.Result) ctrl.Result
 }
@@ -426,13 +556,16 @@ type deviceConfigReconcilerHelper struct {
 	testrunnerHandler   testrunner.TestRunner
 
 	configmanagerHandler  configmanager.ConfigManager
-	nodeAssignments       map[string]string
+	nodeAssignments       map[string]map[string]string
 	conditionUpdater      conditions.ConditionUpdater
 	validator             validator.ValidatorAPI
 	kmmPostProcessor      workermgr.WorkerMgrAPI
 	upgradeMgrHandler     upgradeMgrAPI
 	remediationMgrHandler remediationMgrAPI
+	partitionMgrHandler   partitionMgrAPI
+	draMigrationHandler   draMigrationMgrAPI
 	namespace             string
+	unmatchedGPUDevices   unmatchedGPUDevicesCache
 }
 
 func newDeviceConfigReconcilerHelper(client client.Client,
@@ -441,6 +574,8 @@ func newDeviceConfigReconcilerHelper(client client.Client,
 	nlHandler nodelabeller.NodeLabeller,
 	upgradeMgrHandler upgradeMgrAPI,
 	remediationMgrHandler remediationMgrAPI,
+	partitionMgrHandler partitionMgrAPI,
+	draMigrationHandler draMigrationMgrAPI,
 	metricsHandler metricsexporter.MetricsExporter,
 	testrunnerHandler testrunner.TestRunner,
 	configmanagerHandler configmanager.ConfigManager,
@@ -459,12 +594,14 @@ func newDeviceConfigReconcilerHelper(client client.Client,
 		metricsHandler:        metricsHandler,
 		testrunnerHandler:     testrunnerHandler,
 		configmanagerHandler:  configmanagerHandler,
-		nodeAssignments:       make(map[string]string),
+		nodeAssignments:       make(map[string]map[string]string),
 		conditionUpdater:      conditionUpdater,
 		validator:             validator,
 		kmmPostProcessor:      workerMgr,
 		upgradeMgrHandler:     upgradeMgrHandler,
 		remediationMgrHandler: remediationMgrHandler,
+		partitionMgrHandler:   partitionMgrHandler,
+		draMigrationHandler:   draMigrationHandler,
 		namespace:             os.Getenv("OPERATOR_NAMESPACE"),
 	}
 }
@@ -551,6 +688,52 @@ func (drch *deviceConfigReconcilerHelper) findDeviceConfigsForSecret(ctx context
 	return reqs
 }
 
+// findDeviceConfigsForConfigMap when a driver image build ConfigMap changed, only trigger reconcile for related DeviceConfig
+func (drch *deviceConfigReconcilerHelper) findDeviceConfigsForConfigMap(ctx context.Context, configMap client.Object) []reconcile.Request {
+	reqs := []reconcile.Request{}
+	logger := log.FromContext(ctx)
+	cmObj, ok := configMap.(*v1.ConfigMap)
+	if !ok {
+		logger.Error(fmt.Errorf("failed to convert object %+v to ConfigMap", configMap), "")
+		return reqs
+	}
+	if cmObj.Namespace != drch.namespace {
+		return reqs
+	}
+	deviceConfigList, err := drch.listDeviceConfigs(ctx)
+	if err != nil || deviceConfigList == nil {
+		logger.Error(err, "failed to list deviceconfigs")
+		return reqs
+	}
+	for _, dcfg := range deviceConfigList.Items {
+		if dcfg.Namespace == drch.namespace &&
+			drch.hasConfigMapReference(cmObj.Name, dcfg) {
+			reqs = append(reqs, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: dcfg.Namespace,
+					Name:      dcfg.Name,
+				},
+			})
+		}
+	}
+
+	return reqs
+}
+
+// hasConfigMapReference checks whether the DeviceConfig builds or looks up its driver images with the ConfigMap
+func (dcrh *deviceConfigReconcilerHelper) hasConfigMapReference(configMapName string, dcfg amdv1alpha1.DeviceConfig) bool {
+	// the kernel mappings of the KMM module are rendered from these ConfigMaps,
+	// when they change the KMM module needs to be updated
+	if dcfg.Spec.Driver.ImageBuild.DockerfileTemplates != nil &&
+		dcfg.Spec.Driver.ImageBuild.DockerfileTemplates.ConfigMap.Name == configMapName {
+		return true
+	}
+	if dcfg.Spec.Driver.ImageCatalog != nil && dcfg.Spec.Driver.ImageCatalog.Name == configMapName {
+		return true
+	}
+	return false
+}
+
 func (dcrh *deviceConfigReconcilerHelper) hasSecretReference(secretName string, dcfg amdv1alpha1.DeviceConfig) bool {
 	// Check global secrets
 	for _, secret := range dcfg.Spec.CommonConfig.ImageRegistrySecrets {
@@ -610,6 +793,9 @@ func (dcrh *deviceConfigReconcilerHelper) hasSecretReference(secretName string,
 }
 
 func (dcrh *deviceConfigReconcilerHelper) buildDeviceConfigStatus(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) error {
+	// the dry run plan only describes a spec that is not applied yet
+	devConfig.Status.DryRunPlan = nil
+
 	// fetch DeviceConfig-owned custom resource
 	// then retrieve its status and put it to DeviceConfig's status fields
 	if dcrh.shouldUseKMMOperatorLevel(devConfig) {
@@ -632,6 +818,13 @@ func (dcrh *deviceConfigReconcilerHelper) buildDeviceConfigStatus(ctx context.Co
 		return err
 	}
 
+	dsList := &appsv1.DaemonSetList{}
+	if err := dcrh.client.List(ctx, dsList, client.InNamespace(devConfig.Namespace)); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list the operand daemonsets, skipping operand metrics")
+	} else {
+		setOperandPodsMetrics(devConfig, dsList.Items)
+	}
+
 	// Successfully processed the config
 	devConfig.Status.ObservedGeneration = devConfig.Generation
 	dcrh.conditionUpdater.DeleteErrorCondition(devConfig)
@@ -682,6 +875,17 @@ func (dcrh *deviceConfigReconcilerHelper) buildDeviceConfigNodeStatus(ctx contex
 		previousBootIds[nodeName] = moduleStatus.BootId
 	}
 	devConfig.Status.NodeModuleStatus = map[string]amdv1alpha1.ModuleStatus{}
+	devConfig.Status.UpgradeRollout = dcrh.upgradeMgrHandler.GetRolloutStatus(devConfig)
+	if windowStatus, err := utils.GetMaintenanceWindowStatus(devConfig.Spec.MaintenanceWindows, time.Now()); err == nil {
+		devConfig.Status.MaintenanceWindow = windowStatus
+	} else {
+		logger.Error(err, "failed to evaluate maintenance windows")
+		devConfig.Status.MaintenanceWindow = nil
+	}
+	devConfig.Status.NodePartitionStatus = getNodePartitionStatus(devConfig, nodes)
+	devConfig.Status.DRAMigration = getDRAMigrationStatus(devConfig, nodes)
+
+	driverEnabled := devConfig.Spec.Driver.Enable != nil && *devConfig.Spec.Driver.Enable
 
 	// for each node, fetch its status of modules configured by given DeviceConfig
 	for _, node := range nodes.Items {
@@ -689,17 +893,28 @@ func (dcrh *deviceConfigReconcilerHelper) buildDeviceConfigNodeStatus(ctx contex
 		// the info under that node name will have only status and upgrade start time
 		// then it will be clear to see which node didn't get module configured
 
-		upgradeStartTime := dcrh.upgradeMgrHandler.GetNodeUpgradeStartTime(node.Name)
-		//If operator restarted during Upgrade, then fetch previous known upgrade start time since the internal maps would have been cleared
-		if upgradeStartTime == "" {
-			upgradeStartTime = previousUpgradeTimes[node.Name]
-		}
-		bootId := dcrh.upgradeMgrHandler.GetNodeBootId(node.Name)
-		//If operator restarted during Upgrade, then fetch previous known bootId since the internal maps would have been cleared
-		if bootId == "" {
-			bootId = previousBootIds[node.Name]
+		// the upgrade states are tracked per node, only the DeviceConfig owning the driver of the node reports them
+		var (
+			upgradeState     amdv1alpha1.UpgradeState
+			upgradeStartTime string
+			bootId           string
+			upgradeAction    amdv1alpha1.NodeUpgradeAction
+		)
+		if driverEnabled && !dcrh.isNodeOperandOwnedByOther(devConfig, node.Name, utils.OwnedOperandDriver) {
+			upgradeState = dcrh.upgradeMgrHandler.GetNodeStatus(node.Name)
+			upgradeStartTime = dcrh.upgradeMgrHandler.GetNodeUpgradeStartTime(node.Name)
+			//If operator restarted during Upgrade, then fetch previous known upgrade start time since the internal maps would have been cleared
+			if upgradeStartTime == "" {
+				upgradeStartTime = previousUpgradeTimes[node.Name]
+			}
+			bootId = dcrh.upgradeMgrHandler.GetNodeBootId(node.Name)
+			//If operator restarted during Upgrade, then fetch previous known bootId since the internal maps would have been cleared
+			if bootId == "" {
+				bootId = previousBootIds[node.Name]
+			}
+			upgradeAction = dcrh.upgradeMgrHandler.GetNodeUpgradeAction(node.Name)
 		}
-		devConfig.Status.NodeModuleStatus[node.Name] = amdv1alpha1.ModuleStatus{Status: dcrh.upgradeMgrHandler.GetNodeStatus(node.Name), UpgradeStartTime: upgradeStartTime, BootId: bootId}
+		devConfig.Status.NodeModuleStatus[node.Name] = amdv1alpha1.ModuleStatus{Status: upgradeState, UpgradeStartTime: upgradeStartTime, BootId: bootId, UpgradeAction: upgradeAction}
 
 		if !dcrh.kmmWatchEnabled {
 			// Skip NMC lookup if KMM watch is disabled
@@ -726,7 +941,7 @@ func (dcrh *deviceConfigReconcilerHelper) buildDeviceConfigNodeStatus(ctx contex
 					nodeStatus := amdv1alpha1.UpgradeStateEmpty
 					if dcrh.shouldUseKMMOperatorLevel(devConfig) {
 						// only assign node driver status value when DeviceConfig is managing drivers
-						nodeStatus = dcrh.upgradeMgrHandler.GetNodeStatus(node.Name)
+						nodeStatus = upgradeState
 					}
 					devConfig.Status.NodeModuleStatus[node.Name] = amdv1alpha1.ModuleStatus{
 						ContainerImage:     module.Config.ContainerImage,
@@ -735,6 +950,7 @@ func (dcrh *deviceConfigReconcilerHelper) buildDeviceConfigNodeStatus(ctx contex
 						Status:             nodeStatus,
 						UpgradeStartTime:   upgradeStartTime,
 						BootId:             bootId,
+						UpgradeAction:      upgradeAction,
 					}
 				}
 			}
@@ -911,6 +1127,13 @@ func (dcrh *deviceConfigReconcilerHelper) finalizeConfigManager(ctx context.Cont
 func (dcrh *deviceConfigReconcilerHelper) finalizeDeviceConfig(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) error {
 	logger := log.FromContext(ctx)
 
+	// remove the PreflightValidations still building driver images, they are cluster scoped and not garbage collected
+	if len(devConfig.Status.DriverImages) > 0 {
+		if err := dcrh.deletePreflightValidations(ctx, devConfig, nil); err != nil {
+			return err
+		}
+	}
+
 	// finalize config manager before metrics exporter
 	if err := dcrh.finalizeConfigManager(ctx, devConfig); err != nil {
 		return err
@@ -962,6 +1185,24 @@ func (dcrh *deviceConfigReconcilerHelper) finalizeDeviceConfig(ctx context.Conte
 		}
 	}
 
+	if devConfig.Spec.NodeFeatureRule.IsEnabled() {
+		if err := dcrh.deleteNodeFeatureRules(ctx, devConfig); err != nil {
+			return err
+		}
+	}
+
+	// finalize the DeviceClasses and ResourceClaimTemplates, the ones dropped from the spec were already deleted during reconciliation
+	if len(devConfig.Spec.DRADriver.DeviceClasses) > 0 {
+		if err := dcrh.deleteDeviceClasses(ctx, devConfig, nil); err != nil {
+			return err
+		}
+	}
+	if len(devConfig.Spec.DRADriver.ClaimTemplates) > 0 {
+		if err := dcrh.deleteResourceClaimTemplates(ctx, devConfig, nil); err != nil {
+			return err
+		}
+	}
+
 	// finalize node labeller
 	nlDS := appsv1.DaemonSet{}
 	namespacedName = types.NamespacedName{
@@ -1161,7 +1402,7 @@ func (dcrh *deviceConfigReconcilerHelper) handleBuildConfigMap(ctx context.Conte
 		}
 
 		opRes, err := controllerutil.CreateOrPatch(ctx, dcrh.client, buildDockerfileCM, func() error {
-			return dcrh.kmmHandler.SetBuildConfigMapAsDesired(buildDockerfileCM, devConfig)
+			return dcrh.kmmHandler.SetBuildConfigMapAsDesired(ctx, buildDockerfileCM, devConfig)
 		})
 
 		if err == nil {
@@ -1196,8 +1437,27 @@ func (dcrh *deviceConfigReconcilerHelper) handleKMMModule(ctx context.Context, d
 				Name:      devConfig.Name,
 			},
 		}
+		// while nodes are rolled back the Module describes their previous driver, KMM only reconfigures the nodes
+		// whose version label matches the Module version so the other nodes keep their driver meanwhile
+		moduleConfig := devConfig
+		rollbackVersion, rollbackImages := dcrh.upgradeMgrHandler.GetDriverRollback(devConfig)
+		if rollbackVersion != "" {
+			moduleConfig = devConfig.DeepCopy()
+			moduleConfig.Spec.Driver.Version = rollbackVersion
+			for nodeName, moduleStatus := range devConfig.Status.NodeModuleStatus {
+				if _, ok := rollbackImages[nodeName]; !ok && moduleStatus.ContainerImage != "" &&
+					strings.HasSuffix(moduleStatus.ContainerImage, rollbackVersion) {
+					rollbackImages[nodeName] = moduleStatus.ContainerImage
+				}
+			}
+			logger.Info("Rendering KMM Module with the previous driver for rollback", "version", rollbackVersion)
+		}
 		opRes, err := controllerutil.CreateOrPatch(ctx, dcrh.client, kmmMod, func() error {
-			return dcrh.kmmHandler.SetKMMModuleAsDesired(ctx, kmmMod, devConfig, nodes)
+			if err := dcrh.kmmHandler.SetKMMModuleAsDesired(ctx, kmmMod, moduleConfig, nodes); err != nil {
+				return err
+			}
+			kmmmodule.SetKernelMappingImages(kmmMod, nodes, rollbackImages)
+			return nil
 		})
 
 		if err == nil {
@@ -1264,11 +1524,8 @@ func (dcrh *deviceConfigReconcilerHelper) handleDRADriver(ctx context.Context, d
 }
 
 func (dcrh *deviceConfigReconcilerHelper) handleDeviceClass(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig) error {
-	if !dcrh.isOpenShift {
-		return nil
-	}
 	if !devConfig.Spec.DRADriver.IsEnabled() {
-		return nil
+		return dcrh.deleteDeviceClasses(ctx, devConfig, nil)
 	}
 
 	logger := log.FromContext(ctx)
@@ -1276,36 +1533,118 @@ func (dcrh *deviceConfigReconcilerHelper) handleDeviceClass(ctx context.Context,
 	apiVersion := discoverDRAAPIVersion()
 	logger.Info("Discovered DRA API version", "apiVersion", apiVersion)
 
+	// On Kubernetes the default DeviceClass is created by the helm chart
+	if dcrh.isOpenShift {
+		dc := newDeviceClass(apiVersion, deviceClassName)
+		dc.SetLabels(map[string]string{
+			"app.kubernetes.io/component": "amd-gpu",
+			"app.kubernetes.io/part-of":   "amd-gpu",
+		})
+		dc.Object["spec"] = getDeviceClassSpec(nil)
+
+		if err := dcrh.client.Create(ctx, dc); err != nil {
+			if !k8serrors.IsAlreadyExists(err) {
+				return fmt.Errorf("failed to create DeviceClass %s: %v", deviceClassName, err)
+			}
+		} else {
+			logger.Info("Created DeviceClass", "name", deviceClassName, "apiVersion", apiVersion)
+		}
+	}
+
+	desired := map[string]bool{}
+	for _, classSpec := range devConfig.Spec.DRADriver.DeviceClasses {
+		desired[classSpec.Name] = true
+		dc := newDeviceClass(apiVersion, classSpec.Name)
+		opRes, err := controllerutil.CreateOrPatch(ctx, dcrh.client, dc, func() error {
+			dcLabels := dc.GetLabels()
+			if dcLabels == nil {
+				dcLabels = map[string]string{}
+			}
+			// DeviceClasses are cluster scoped and cannot be owned by the DeviceConfig, the labels track the owner instead
+			if dc.GetResourceVersion() != "" && (dcLabels[deviceClassOwnerNameLabelKey] != devConfig.Name || dcLabels[deviceClassOwnerNamespaceLabelKey] != devConfig.Namespace) {
+				return fmt.Errorf("DeviceClass %s already exists and is not managed by DeviceConfig %s/%s", classSpec.Name, devConfig.Namespace, devConfig.Name)
+			}
+			dcLabels["app.kubernetes.io/component"] = "amd-gpu"
+			dcLabels["app.kubernetes.io/part-of"] = "amd-gpu"
+			dcLabels[deviceClassOwnerNameLabelKey] = devConfig.Name
+			dcLabels[deviceClassOwnerNamespaceLabelKey] = devConfig.Namespace
+			dc.SetLabels(dcLabels)
+			dc.Object["spec"] = getDeviceClassSpec(classSpec.Selectors)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to reconcile DeviceClass %s: %v", classSpec.Name, err)
+		}
+		logger.Info("Reconciled DeviceClass", "name", classSpec.Name, "apiVersion", apiVersion, "result", opRes)
+	}
+
+	return dcrh.deleteDeviceClasses(ctx, devConfig, desired)
+}
+
+// deleteDeviceClasses deletes the DeviceClasses created for the DeviceConfig, except the ones to keep
+func (dcrh *deviceConfigReconcilerHelper) deleteDeviceClasses(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, keep map[string]bool) error {
+	logger := log.FromContext(ctx)
+
+	dcList := &unstructured.UnstructuredList{}
+	dcList.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "resource.k8s.io",
+		Version: discoverDRAAPIVersion(),
+		Kind:    "DeviceClassList",
+	})
+	if err := dcrh.client.List(ctx, dcList, client.MatchingLabels{
+		deviceClassOwnerNameLabelKey:      devConfig.Name,
+		deviceClassOwnerNamespaceLabelKey: devConfig.Namespace,
+	}); err != nil {
+		if meta.IsNoMatchError(err) {
+			// DRA is not served by the cluster, nothing to clean up
+			return nil
+		}
+		return fmt.Errorf("failed to list DeviceClasses: %v", err)
+	}
+
+	for i := range dcList.Items {
+		dc := &dcList.Items[i]
+		if keep[dc.GetName()] {
+			continue
+		}
+		logger.Info("deleting DeviceClass", "name", dc.GetName())
+		if err := dcrh.client.Delete(ctx, dc); err != nil && !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete DeviceClass %s: %v", dc.GetName(), err)
+		}
+	}
+	return nil
+}
+
+func newDeviceClass(apiVersion, name string) *unstructured.Unstructured {
 	dc := &unstructured.Unstructured{}
 	dc.SetGroupVersionKind(schema.GroupVersionKind{
 		Group:   "resource.k8s.io",
 		Version: apiVersion,
 		Kind:    "DeviceClass",
 	})
-	dc.SetName(deviceClassName)
-	dc.SetLabels(map[string]string{
-		"app.kubernetes.io/component": "amd-gpu",
-		"app.kubernetes.io/part-of":   "amd-gpu",
-	})
-	dc.Object["spec"] = map[string]interface{}{
-		"selectors": []interface{}{
-			map[string]interface{}{
-				"cel": map[string]interface{}{
-					"expression": "device.driver == '" + deviceClassName + "'",
-				},
+	dc.SetName(name)
+	return dc
+}
+
+// getDeviceClassSpec returns the DeviceClass spec selecting the AMD GPU devices matching all the CEL expressions
+func getDeviceClassSpec(expressions []string) map[string]interface{} {
+	selectors := []interface{}{
+		map[string]interface{}{
+			"cel": map[string]interface{}{
+				"expression": "device.driver == '" + deviceClassName + "'",
 			},
 		},
 	}
-
-	if err := dcrh.client.Create(ctx, dc); err != nil {
-		if k8serrors.IsAlreadyExists(err) {
-			return nil
-		}
-		return fmt.Errorf("failed to create DeviceClass %s: %v", deviceClassName, err)
+	for _, expression := range expressions {
+		selectors = append(selectors, map[string]interface{}{
+			"cel": map[string]interface{}{
+				"expression": expression,
+			},
+		})
+	}
+	return map[string]interface{}{
+		"selectors": selectors,
 	}
-
-	logger.Info("Created DeviceClass", "name", deviceClassName, "apiVersion", apiVersion)
-	return nil
 }
 
 // discoverDRAAPIVersion probes the API server to find the highest available
@@ -1370,7 +1709,9 @@ func (dcrh *deviceConfigReconcilerHelper) handleNodeLabeller(ctx context.Context
 	ds := &appsv1.DaemonSet{
 		ObjectMeta: metav1.ObjectMeta{Namespace: devConfig.Namespace, Name: devConfig.Name + utils.NodeLabellerNameSuffix},
 	}
+	previousKinds := []string{}
 	opRes, err := controllerutil.CreateOrPatch(ctx, dcrh.client, ds, func() error {
+		previousKinds = nodelabeller.GetLabelKinds(ds)
 		return dcrh.nlHandler.SetNodeLabellerAsDesired(ds, devConfig)
 	})
 
@@ -1380,6 +1721,12 @@ func (dcrh *deviceConfigReconcilerHelper) handleNodeLabeller(ctx context.Context
 
 	logger.Info("Reconciled node labeller", "namespace", ds.Namespace, "name", ds.Name, "result", opRes)
 
+	// the node labeller doesn't remove the labels it no longer applies
+	// e.g. after the allowlist or the denylist changed
+	if err := dcrh.removeStaleNodeLabels(ctx, devConfig, nodes, previousKinds); err != nil {
+		logger.Error(err, "failed to remove stale node labeller labels")
+	}
+
 	// todo: temp. cleanup labels set by node-labeller
 	// not required once label cleanup is added in node-labeller
 	labelSelector, err := func() (labels.Selector, error) {
@@ -1430,6 +1777,18 @@ func (dcrh *deviceConfigReconcilerHelper) handleModuleUpgrade(ctx context.Contex
 	if delete {
 		return dcrh.upgradeMgrHandler.HandleDelete(ctx, devConfig, nodes)
 	}
+	// The driver version planned in dry run mode is not applied yet, the node upgrades in flight keep going to the
+	// version of the KMM Module
+	if isDryRun(devConfig) {
+		module, err := dcrh.getDeviceConfigOwnedKMMModule(ctx, devConfig)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if module != nil && module.Spec.ModuleLoader.Container.Version != devConfig.Spec.Driver.Version {
+			devConfig = devConfig.DeepCopy()
+			devConfig.Spec.Driver.Version = module.Spec.ModuleLoader.Container.Version
+		}
+	}
 	return dcrh.upgradeMgrHandler.HandleUpgrade(ctx, devConfig, nodes)
 }
 
@@ -1556,14 +1915,38 @@ func (dcrh *deviceConfigReconcilerHelper) handleRemediationWorkflow(ctx context.
 	return dcrh.remediationMgrHandler.HandleRemediation(ctx, devConfig, nodes)
 }
 
+func (dcrh *deviceConfigReconcilerHelper) handlePartitionProfiles(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList, delete bool) (ctrl.Result, error) {
+	nodes = dcrh.getNodesNotOwnedByOthers(devConfig, utils.OwnedOperandConfigManager, nodes)
+	if delete {
+		return dcrh.partitionMgrHandler.HandleDelete(ctx, devConfig, nodes)
+	}
+	return dcrh.partitionMgrHandler.HandlePartition(ctx, devConfig, nodes)
+}
+
+func (dcrh *deviceConfigReconcilerHelper) handleDRAMigration(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList, delete bool) (ctrl.Result, error) {
+	nodes = dcrh.getNodesNotOwnedByOthers(devConfig, utils.OwnedOperandDRADriver, nodes)
+	if delete {
+		return dcrh.draMigrationHandler.HandleDelete(ctx, devConfig, nodes)
+	}
+	return dcrh.draMigrationHandler.HandleMigration(ctx, devConfig, nodes)
+}
+
 func (dcrh *deviceConfigReconcilerHelper) handleConfigManager(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig) error {
 	logger := log.FromContext(ctx)
 	ds := &appsv1.DaemonSet{
 		ObjectMeta: metav1.ObjectMeta{Namespace: devConfig.Namespace, Name: devConfig.Name + "-" + configmanager.ConfigManagerName},
 	}
 
+	// the partition profile configmap is owned by the DeviceConfig and garbage collected along with it
+	partitionCM := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: devConfig.Namespace, Name: configmanager.PartitionConfigMapName(devConfig)},
+	}
+
 	// delete if disabled
 	if devConfig.Spec.ConfigManager.Enable == nil || !*devConfig.Spec.ConfigManager.Enable {
+		if err := dcrh.client.Delete(ctx, partitionCM); err != nil && !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete partition profile configmap %s: %v", partitionCM.Name, err)
+		}
 		return dcrh.finalizeConfigManager(ctx, devConfig)
 	}
 
@@ -1571,6 +1954,18 @@ func (dcrh *deviceConfigReconcilerHelper) handleConfigManager(ctx context.Contex
 		return fmt.Errorf("ensure default DCM ConfigMap: %w", err)
 	}
 
+	if len(devConfig.Spec.ConfigManager.PartitionProfiles) > 0 {
+		opRes, err := controllerutil.CreateOrPatch(ctx, dcrh.client, partitionCM, func() error {
+			return dcrh.configmanagerHandler.SetPartitionConfigMapAsDesired(partitionCM, devConfig)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to reconcile partition profile configmap %s: %v", partitionCM.Name, err)
+		}
+		logger.Info("Reconciled partition profile configmap", "namespace", partitionCM.Namespace, "name", partitionCM.Name, "result", opRes)
+	} else if err := dcrh.client.Delete(ctx, partitionCM); err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete partition profile configmap %s: %v", partitionCM.Name, err)
+	}
+
 	opRes, err := controllerutil.CreateOrPatch(ctx, dcrh.client, ds, func() error {
 		return dcrh.configmanagerHandler.SetConfigManagerAsDesired(ds, devConfig)
 	})
@@ -1607,7 +2002,9 @@ func (dcrh *deviceConfigReconcilerHelper) updateNodeLabels(ctx context.Context,
 			// search for all existing node labeller labels and remove them
 			// NOTE: don't try to remove all labels with prefix amd.com and beta.amd.com
 			// users may want to self-define labels under amd.com domain like amd.com/gpu:true
-			if utils.RemoveOldNodeLabels(nodeObj) {
+			// the labels set by the node labeller of another DeviceConfig are left untouched
+			if !dcrh.isNodeOperandOwnedByOther(devConfig, node.Name, utils.OwnedOperandNodeLabeller) &&
+				utils.RemoveOldNodeLabels(nodeObj) {
 				updated = true
 			}
 
@@ -1626,18 +2023,106 @@ func (dcrh *deviceConfigReconcilerHelper) updateNodeLabels(ctx context.Context,
 	return nil
 }
 
-func (dcrh *deviceConfigReconcilerHelper) validateNodeAssignments(namespacedName string, nodes *v1.NodeList) error {
-	var err error
+// removeStaleNodeLabels removes the node labeller labels the DeviceConfig no longer applies on its nodes,
+// the nodes whose node labeller is owned by another DeviceConfig are left untouched
+func (dcrh *deviceConfigReconcilerHelper) removeStaleNodeLabels(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList, previousKinds []string) error {
+	if nodes == nil {
+		return nil
+	}
+	var errs error
+	for i := range nodes.Items {
+		if dcrh.isNodeOperandOwnedByOther(devConfig, nodes.Items[i].Name, utils.OwnedOperandNodeLabeller) {
+			continue
+		}
+		// skip the nodes without stale labels without sending any request
+		if !utils.RemoveStaleNodeLabellerLabels(nodes.Items[i].DeepCopy(), devConfig, previousKinds) {
+			continue
+		}
+		nodeName := nodes.Items[i].Name
+		if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			nodeObj := &v1.Node{}
+			if err := dcrh.client.Get(ctx, client.ObjectKey{Name: nodeName}, nodeObj); err != nil {
+				return err
+			}
+			nodeObjCopy := nodeObj.DeepCopy()
+			if !utils.RemoveStaleNodeLabellerLabels(nodeObj, devConfig, previousKinds) {
+				return nil
+			}
+			log.FromContext(ctx).Info(fmt.Sprintf("removing stale node-labeller labels in %v", nodeName))
+			return dcrh.client.Patch(ctx, nodeObj, client.MergeFrom(nodeObjCopy))
+		}); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("failed to remove stale labels from node %s: %v", nodeName, err))
+		}
+	}
+	return errs
+}
+
+// validateNodeAssignments verifies that none of the operands claimed by the DeviceConfig is already
+// owned by another DeviceConfig on the selected nodes, conflicts are reported per operand
+func (dcrh *deviceConfigReconcilerHelper) validateNodeAssignments(namespacedName string, operands []string, nodes *v1.NodeList) error {
+	// operand -> owner -> conflicting nodes
+	conflicts := map[string]map[string][]string{}
 
 	for _, node := range nodes.Items {
-		val, ok := dcrh.nodeAssignments[node.Name]
-		if ok && val != namespacedName {
-			err = fmt.Errorf("node %s already assigned to DeviceConfig %s, cannot re-assign to %s", node.Name, val, namespacedName)
-			break
+		owners, ok := dcrh.nodeAssignments[node.Name]
+		if !ok {
+			continue
+		}
+		for _, operand := range operands {
+			owner, ok := owners[operand]
+			if !ok || owner == namespacedName {
+				continue
+			}
+			if _, ok := conflicts[operand]; !ok {
+				conflicts[operand] = map[string][]string{}
+			}
+			conflicts[operand][owner] = append(conflicts[operand][owner], node.Name)
+		}
+	}
+
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	errs := []string{}
+	for _, operand := range operands {
+		owners, ok := conflicts[operand]
+		if !ok {
+			continue
+		}
+		ownerNames := make([]string, 0, len(owners))
+		for owner := range owners {
+			ownerNames = append(ownerNames, owner)
+		}
+		sort.Strings(ownerNames)
+		for _, owner := range ownerNames {
+			errs = append(errs, fmt.Sprintf("operand %s on node(s) %s already owned by DeviceConfig %s, cannot be owned by %s",
+				operand, strings.Join(owners[owner], ","), owner, namespacedName))
 		}
 	}
 
-	return err
+	return errors.New(strings.Join(errs, "; "))
+}
+
+// isNodeOperandOwnedByOther returns true if another DeviceConfig owns the operand on the node,
+// the DeviceConfig must then not clean up what the operand set on the node
+func (dcrh *deviceConfigReconcilerHelper) isNodeOperandOwnedByOther(devConfig *amdv1alpha1.DeviceConfig, nodeName, operand string) bool {
+	owner, ok := dcrh.nodeAssignments[nodeName][operand]
+	return ok && owner != types.NamespacedName{Namespace: devConfig.Namespace, Name: devConfig.Name}.String()
+}
+
+// getNodesNotOwnedByOthers returns the nodes on which the operand is not owned by another DeviceConfig
+func (dcrh *deviceConfigReconcilerHelper) getNodesNotOwnedByOthers(devConfig *amdv1alpha1.DeviceConfig, operand string, nodes *v1.NodeList) *v1.NodeList {
+	if nodes == nil {
+		return nil
+	}
+	owned := &v1.NodeList{}
+	for _, node := range nodes.Items {
+		if !dcrh.isNodeOperandOwnedByOther(devConfig, node.Name, operand) {
+			owned.Items = append(owned.Items, node)
+		}
+	}
+	return owned
 }
 
 func (dcrh *deviceConfigReconcilerHelper) buildNodeAssignments(ctx context.Context, deviceConfigList *amdv1alpha1.DeviceConfigList) error {
@@ -1666,36 +2151,61 @@ func (dcrh *deviceConfigReconcilerHelper) buildNodeAssignments(ctx context.Conte
 			for node := range devConfig.Status.NodeModuleStatus {
 				nodeItems = append(nodeItems, v1.Node{ObjectMeta: metav1.ObjectMeta{Name: node}})
 			}
-			err := dcrh.validateNodeAssignments(namespacedName.String(), &v1.NodeList{Items: nodeItems})
+			operands := utils.GetOwnedOperands(&devConfig)
+			err := dcrh.validateNodeAssignments(namespacedName.String(), operands, &v1.NodeList{Items: nodeItems})
 			if err != nil {
 				logger.Error(err, "node assignment conflict detected during initialization, skipping DeviceConfig", "DeviceConfig", namespacedName)
 				continue
 			}
-			dcrh.updateNodeAssignments(namespacedName.String(), &v1.NodeList{Items: nodeItems}, false)
+			dcrh.updateNodeAssignments(namespacedName.String(), operands, &v1.NodeList{Items: nodeItems}, false)
 		}
 	}
 
 	return nil
 }
 
-func (dcrh *deviceConfigReconcilerHelper) updateNodeAssignments(namespacedName string, nodes *v1.NodeList, isFinalizer bool) {
+// updateNodeAssignments records the DeviceConfig as the owner of the given operands on the nodes,
+// operands and nodes no longer claimed by the DeviceConfig are released so that another DeviceConfig can own them
+func (dcrh *deviceConfigReconcilerHelper) updateNodeAssignments(namespacedName string, operands []string, nodes *v1.NodeList, isFinalizer bool) {
+	release := func(nodeName string) {
+		for operand, owner := range dcrh.nodeAssignments[nodeName] {
+			if owner == namespacedName {
+				delete(dcrh.nodeAssignments[nodeName], operand)
+			}
+		}
+		if len(dcrh.nodeAssignments[nodeName]) == 0 {
+			delete(dcrh.nodeAssignments, nodeName)
+		}
+	}
+
 	if isFinalizer {
 		if nodes != nil {
 			for _, node := range nodes.Items {
-				delete(dcrh.nodeAssignments, node.Name)
+				release(node.Name)
 			}
 		} else {
-			for k, v := range dcrh.nodeAssignments {
-				if v == namespacedName {
-					delete(dcrh.nodeAssignments, k)
-				}
+			for nodeName := range dcrh.nodeAssignments {
+				release(nodeName)
 			}
 		}
 		return
 	}
 
+	// the nodes that left the selector of the DeviceConfig are released as well, e.g. nodes moved to another node group,
+	// so that the DeviceConfig selecting them now owns their operands and may clean them up
+	for nodeName := range dcrh.nodeAssignments {
+		release(nodeName)
+	}
+	if len(operands) == 0 {
+		return
+	}
 	for _, node := range nodes.Items {
-		dcrh.nodeAssignments[node.Name] = namespacedName
+		if _, ok := dcrh.nodeAssignments[node.Name]; !ok {
+			dcrh.nodeAssignments[node.Name] = map[string]string{}
+		}
+		for _, operand := range operands {
+			dcrh.nodeAssignments[node.Name][operand] = namespacedName
+		}
 	}
 }
 
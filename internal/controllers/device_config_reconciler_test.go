@@ -35,6 +35,7 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/ROCm/gpu-operator/internal/configmanager"
 	"github.com/ROCm/gpu-operator/internal/metricsexporter"
@@ -50,15 +51,22 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+	kmmv1beta2 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta2"
 	"go.uber.org/mock/gomock"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 const (
@@ -197,7 +205,7 @@ var _ = Describe("getLabelsPerModules", func() {
 	BeforeEach(func() {
 		ctrl := gomock.NewController(GinkgoT())
 		kubeClient = mock_client.NewMockClient(ctrl)
-		dcrh = newDeviceConfigReconcilerHelper(kubeClient, nil, nil, nil, nil, nil, nil, nil, nil, nil, false, true)
+		dcrh = newDeviceConfigReconcilerHelper(kubeClient, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, false, true)
 	})
 
 	ctx := context.Background()
@@ -241,7 +249,7 @@ var _ = Describe("deviceConfigReconcilerHelper with KMM watch disabled", func()
 	BeforeEach(func() {
 		ctrl := gomock.NewController(GinkgoT())
 		kubeClient = mock_client.NewMockClient(ctrl)
-		dcrh = newDeviceConfigReconcilerHelper(kubeClient, nil, nil, nil, nil, nil, nil, nil, nil, nil, false, true)
+		dcrh = newDeviceConfigReconcilerHelper(kubeClient, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, false, true)
 	})
 	ctx := context.Background()
 	nn := types.NamespacedName{
@@ -282,7 +290,7 @@ var _ = Describe("setFinalizer", func() {
 	BeforeEach(func() {
 		ctrl := gomock.NewController(GinkgoT())
 		kubeClient = mock_client.NewMockClient(ctrl)
-		dcrh = newDeviceConfigReconcilerHelper(kubeClient, nil, nil, nil, nil, nil, nil, nil, nil, nil, false, true)
+		dcrh = newDeviceConfigReconcilerHelper(kubeClient, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, false, true)
 	})
 
 	ctx := context.Background()
@@ -318,7 +326,7 @@ var _ = Describe("finalizeDeviceConfig", func() {
 	BeforeEach(func() {
 		ctrl := gomock.NewController(GinkgoT())
 		kubeClient = mock_client.NewMockClient(ctrl)
-		dcrh = newDeviceConfigReconcilerHelper(kubeClient, nil, nil, nil, nil, nil, nil, nil, nil, nil, false, true)
+		dcrh = newDeviceConfigReconcilerHelper(kubeClient, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, false, true)
 	})
 
 	ctx := context.Background()
@@ -530,16 +538,18 @@ var _ = Describe("finalizeDeviceConfig", func() {
 
 var _ = Describe("handleKMMModule", func() {
 	var (
-		kubeClient *mock_client.MockClient
-		kmmHelper  *kmmmodule.MockKMMModuleAPI
-		dcrh       deviceConfigReconcilerHelperAPI
+		kubeClient    *mock_client.MockClient
+		kmmHelper     *kmmmodule.MockKMMModuleAPI
+		upgradeHelper *MockupgradeMgrAPI
+		dcrh          deviceConfigReconcilerHelperAPI
 	)
 
 	BeforeEach(func() {
 		ctrl := gomock.NewController(GinkgoT())
 		kubeClient = mock_client.NewMockClient(ctrl)
 		kmmHelper = kmmmodule.NewMockKMMModuleAPI(ctrl)
-		dcrh = newDeviceConfigReconcilerHelper(kubeClient, kmmHelper, nil, nil, nil, nil, nil, nil, nil, nil, false, true)
+		upgradeHelper = NewMockupgradeMgrAPI(ctrl)
+		dcrh = newDeviceConfigReconcilerHelper(kubeClient, kmmHelper, nil, nil, upgradeHelper, nil, nil, nil, nil, nil, nil, nil, false, true)
 	})
 
 	ctx := context.Background()
@@ -565,6 +575,7 @@ var _ = Describe("handleKMMModule", func() {
 		}
 		gomock.InOrder(
 			kubeClient.EXPECT().Patch(ctx, gomock.Any(), gomock.Any()).Return(nil),
+			upgradeHelper.EXPECT().GetDriverRollback(devConfig).Return("", nil),
 			kubeClient.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Return(k8serrors.NewNotFound(schema.GroupResource{}, "whatever")),
 			kmmHelper.EXPECT().SetKMMModuleAsDesired(ctx, newMod, devConfig, testNodeList).Return(nil),
 
@@ -584,6 +595,7 @@ var _ = Describe("handleKMMModule", func() {
 		}
 		gomock.InOrder(
 			kubeClient.EXPECT().Patch(ctx, gomock.Any(), gomock.Any()).Return(nil),
+			upgradeHelper.EXPECT().GetDriverRollback(devConfig).Return("", nil),
 			kubeClient.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Do(
 				func(_ interface{}, _ interface{}, mod *kmmv1beta1.Module, _ ...client.GetOption) {
 					mod.Name = devConfig.Name
@@ -596,6 +608,41 @@ var _ = Describe("handleKMMModule", func() {
 		err := dcrh.handleKMMModule(ctx, devConfig, testNodeList)
 		Expect(err).ToNot(HaveOccurred())
 	})
+
+	It("KMM Module is rendered with the previous driver during rollback", func() {
+		rollbackImage := "test.repo/driver:ubuntu-22.04-6.8.0-40-generic-6.2"
+		var createdMod *kmmv1beta1.Module
+		gomock.InOrder(
+			kubeClient.EXPECT().Patch(ctx, gomock.Any(), gomock.Any()).Return(nil),
+			upgradeHelper.EXPECT().GetDriverRollback(devConfig).Return("6.2", map[string]string{"unit-test-node": rollbackImage}),
+			kubeClient.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Return(k8serrors.NewNotFound(schema.GroupResource{}, "whatever")),
+			kmmHelper.EXPECT().SetKMMModuleAsDesired(ctx, gomock.Any(), gomock.Any(), testNodeList).DoAndReturn(
+				func(_ context.Context, mod *kmmv1beta1.Module, moduleConfig *amdv1alpha1.DeviceConfig, _ *v1.NodeList) error {
+					Expect(moduleConfig.Spec.Driver.Version).To(Equal("6.2"))
+					mod.Spec.ModuleLoader.Container.Version = moduleConfig.Spec.Driver.Version
+					mod.Spec.ModuleLoader.Container.KernelMappings = []kmmv1beta1.KernelMapping{{
+						Literal:        "6.8.0-40-generic",
+						ContainerImage: "test.repo/driver:ubuntu-22.04-${KERNEL_FULL_VERSION}-6.2",
+						Build:          &kmmv1beta1.Build{},
+					}}
+					return nil
+				},
+			),
+			kubeClient.EXPECT().Create(ctx, gomock.Any()).DoAndReturn(
+				func(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
+					createdMod = obj.(*kmmv1beta1.Module)
+					return nil
+				},
+			),
+		)
+
+		err := dcrh.handleKMMModule(ctx, devConfig, testNodeList)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(devConfig.Spec.Driver.Version).To(BeEmpty())
+		Expect(createdMod.Spec.ModuleLoader.Container.Version).To(Equal("6.2"))
+		Expect(createdMod.Spec.ModuleLoader.Container.KernelMappings[0].ContainerImage).To(Equal(rollbackImage))
+		Expect(createdMod.Spec.ModuleLoader.Container.KernelMappings[0].Build).To(BeNil())
+	})
 })
 
 var _ = Describe("handleBuildConfigMap", func() {
@@ -609,7 +656,7 @@ var _ = Describe("handleBuildConfigMap", func() {
 		ctrl := gomock.NewController(GinkgoT())
 		kubeClient = mock_client.NewMockClient(ctrl)
 		kmmHelper = kmmmodule.NewMockKMMModuleAPI(ctrl)
-		dcrh = newDeviceConfigReconcilerHelper(kubeClient, kmmHelper, nil, nil, nil, nil, nil, nil, nil, nil, false, true)
+		dcrh = newDeviceConfigReconcilerHelper(kubeClient, kmmHelper, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, false, true)
 	})
 
 	ctx := context.Background()
@@ -635,7 +682,7 @@ var _ = Describe("handleBuildConfigMap", func() {
 		}
 		gomock.InOrder(
 			kubeClient.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Return(k8serrors.NewNotFound(schema.GroupResource{}, "whatever")),
-			kmmHelper.EXPECT().SetBuildConfigMapAsDesired(newBuildCM, devConfig).Return(nil),
+			kmmHelper.EXPECT().SetBuildConfigMapAsDesired(ctx, newBuildCM, devConfig).Return(nil),
 			kubeClient.EXPECT().Create(ctx, gomock.Any()).Return(nil),
 		)
 
@@ -657,7 +704,7 @@ var _ = Describe("handleBuildConfigMap", func() {
 					buildCM.Namespace = devConfig.Namespace
 				},
 			),
-			kmmHelper.EXPECT().SetBuildConfigMapAsDesired(existingBuildCM, devConfig).Return(nil),
+			kmmHelper.EXPECT().SetBuildConfigMapAsDesired(ctx, existingBuildCM, devConfig).Return(nil),
 		)
 
 		err := dcrh.handleBuildConfigMap(ctx, devConfig, testNodeList)
@@ -676,7 +723,7 @@ var _ = Describe("handleNodeLabeller", func() {
 		ctrl := gomock.NewController(GinkgoT())
 		kubeClient = mock_client.NewMockClient(ctrl)
 		nodeLabellerHelper = nodelabeller.NewMockNodeLabeller(ctrl)
-		dcrh = newDeviceConfigReconcilerHelper(kubeClient, nil, nil, nodeLabellerHelper, nil, nil, nil, nil, nil, nil, false, true)
+		dcrh = newDeviceConfigReconcilerHelper(kubeClient, nil, nil, nodeLabellerHelper, nil, nil, nil, nil, nil, nil, nil, nil, false, true)
 	})
 
 	ctx := context.Background()
@@ -728,6 +775,73 @@ var _ = Describe("handleNodeLabeller", func() {
 		err := dcrh.handleNodeLabeller(ctx, devConfig, testNodeList)
 		Expect(err).ToNot(HaveOccurred())
 	})
+
+	It("removes the labels the node labeller no longer applies", func() {
+		labelledNodes := testNodeList.DeepCopy()
+		labelledNodes.Items[0].Labels = map[string]string{"amd.com/gpu.vram": "192G", "amd.com/gpu.firmware": "1"}
+		deniedDevConfig := devConfig.DeepCopy()
+		deniedDevConfig.Spec.DevicePlugin.NodeLabellerConfig = &amdv1alpha1.NodeLabellerConfigSpec{
+			Denylist: []amdv1alpha1.NodeLabellerLabel{"vram"},
+		}
+
+		gomock.InOrder(
+			// the firmware labels were applied by the previous node labeller arguments
+			kubeClient.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Do(
+				func(_ interface{}, _ interface{}, ds *appsv1.DaemonSet, _ ...client.GetOption) {
+					ds.Name = devConfig.Name + "-node-labeller"
+					ds.Namespace = devConfig.Namespace
+					ds.Spec.Template.Spec.Containers = []v1.Container{{Args: []string{"-c", "./k8s-node-labeller -vram -firmware"}}}
+				},
+			),
+			nodeLabellerHelper.EXPECT().SetNodeLabellerAsDesired(gomock.Any(), deniedDevConfig).Return(nil),
+			kubeClient.EXPECT().Get(ctx, client.ObjectKey{Name: labelledNodes.Items[0].Name}, gomock.Any()).Do(
+				func(_ interface{}, _ interface{}, node *v1.Node, _ ...client.GetOption) {
+					labelledNodes.Items[0].DeepCopyInto(node)
+				},
+			),
+			kubeClient.EXPECT().Patch(ctx, gomock.Any(), gomock.Any()).Do(
+				func(_ interface{}, node *v1.Node, _ client.Patch, _ ...client.PatchOption) {
+					Expect(node.Labels).To(Equal(map[string]string{}))
+				},
+			),
+			kubeClient.EXPECT().List(ctx, gomock.Any(), gomock.Any()).Return(nil),
+		)
+
+		err := dcrh.handleNodeLabeller(ctx, deniedDevConfig, labelledNodes)
+		Expect(err).ToNot(HaveOccurred())
+	})
+	It("leaves the labels of the nodes whose node labeller is owned by another DeviceConfig", func() {
+		labelledNodes := &v1.NodeList{Items: []v1.Node{
+			{ObjectMeta: metav1.ObjectMeta{Name: "owned", Labels: map[string]string{"amd.com/gpu.vram": "192G"}}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "other", Labels: map[string]string{"amd.com/gpu.vram": "192G"}}},
+		}}
+		dcrh.(*deviceConfigReconcilerHelper).nodeAssignments["other"] = map[string]string{utils.OwnedOperandNodeLabeller: devConfigNamespace + "/other"}
+		disabledDevConfig := devConfig.DeepCopy()
+		disabledDevConfig.Spec.DevicePlugin.EnableNodeLabeller = nil
+
+		gomock.InOrder(
+			kubeClient.EXPECT().Get(ctx, gomock.Any(), gomock.AssignableToTypeOf(&appsv1.DaemonSet{})).Return(k8serrors.NewNotFound(schema.GroupResource{}, "whatever")),
+			kubeClient.EXPECT().Get(ctx, client.ObjectKey{Name: "owned"}, gomock.Any()).Do(
+				func(_ interface{}, _ interface{}, node *v1.Node, _ ...client.GetOption) {
+					labelledNodes.Items[0].DeepCopyInto(node)
+				},
+			),
+			kubeClient.EXPECT().Patch(ctx, gomock.Any(), gomock.Any()).Do(
+				func(_ interface{}, node *v1.Node, _ client.Patch, _ ...client.PatchOption) {
+					Expect(node.Name).To(Equal("owned"))
+					Expect(node.Labels).To(BeEmpty())
+				},
+			),
+			kubeClient.EXPECT().Get(ctx, client.ObjectKey{Name: "other"}, gomock.Any()).Do(
+				func(_ interface{}, _ interface{}, node *v1.Node, _ ...client.GetOption) {
+					labelledNodes.Items[1].DeepCopyInto(node)
+				},
+			),
+		)
+
+		err := dcrh.handleNodeLabeller(ctx, disabledDevConfig, labelledNodes)
+		Expect(err).ToNot(HaveOccurred())
+	})
 })
 
 var _ = Describe("buildNodeAssignments", func() {
@@ -747,6 +861,11 @@ var _ = Describe("buildNodeAssignments", func() {
 				Name:      name,
 				Namespace: namespace,
 			},
+			Spec: amdv1alpha1.DeviceConfigSpec{
+				Driver: amdv1alpha1.DriverSpec{
+					Enable: ptr.To(true),
+				},
+			},
 			Status: amdv1alpha1.DeviceConfigStatus{
 				Conditions: []metav1.Condition{
 					{
@@ -762,7 +881,7 @@ var _ = Describe("buildNodeAssignments", func() {
 	BeforeEach(func() {
 		ctrl := gomock.NewController(GinkgoT())
 		kubeClient := mock_client.NewMockClient(ctrl)
-		dcrh = newDeviceConfigReconcilerHelper(kubeClient, nil, nil, nil, nil, nil, nil, nil, nil, nil, false, true)
+		dcrh = newDeviceConfigReconcilerHelper(kubeClient, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, false, true)
 	})
 
 	It("skips non-ready DeviceConfigs", func() {
@@ -822,6 +941,139 @@ var _ = Describe("buildNodeAssignments", func() {
 	})
 })
 
+var _ = Describe("buildDeviceConfigNodeStatus", func() {
+	var (
+		upgradeHandler *MockupgradeMgrAPI
+		dcrh           *deviceConfigReconcilerHelper
+	)
+
+	ctx := context.Background()
+	nodes := &v1.NodeList{Items: []v1.Node{{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}}}
+	newDeviceConfig := func(name string, driver bool) *amdv1alpha1.DeviceConfig {
+		devConfig := &amdv1alpha1.DeviceConfig{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: devConfigNamespace}}
+		devConfig.Spec.Driver.Enable = ptr.To(driver)
+		devConfig.Spec.MetricsExporter.Enable = ptr.To(!driver)
+		return devConfig
+	}
+
+	BeforeEach(func() {
+		ctrl := gomock.NewController(GinkgoT())
+		upgradeHandler = NewMockupgradeMgrAPI(ctrl)
+		dcrh = newDeviceConfigReconcilerHelper(mock_client.NewMockClient(ctrl), nil, nil, nil, upgradeHandler, nil, nil, nil, nil, nil, nil, nil, false, false).(*deviceConfigReconcilerHelper)
+	})
+
+	It("reports the node upgrade state only in the DeviceConfig owning the driver of the node", func() {
+		driverConfig := newDeviceConfig("driver", true)
+		metricsConfig := newDeviceConfig("metrics", false)
+		metricsConfig.Status.NodeModuleStatus = map[string]amdv1alpha1.ModuleStatus{"node-1": {BootId: "stale-boot-id"}}
+		dcrh.updateNodeAssignments(types.NamespacedName{Namespace: devConfigNamespace, Name: "driver"}.String(), utils.GetOwnedOperands(driverConfig), nodes, false)
+		dcrh.updateNodeAssignments(types.NamespacedName{Namespace: devConfigNamespace, Name: "metrics"}.String(), utils.GetOwnedOperands(metricsConfig), nodes, false)
+
+		upgradeHandler.EXPECT().GetRolloutStatus(gomock.Any()).Return(nil).Times(2)
+		upgradeHandler.EXPECT().GetNodeStatus("node-1").Return(amdv1alpha1.UpgradeStateInProgress)
+		upgradeHandler.EXPECT().GetNodeUpgradeStartTime("node-1").Return("2026-01-01 00:00:00 UTC")
+		upgradeHandler.EXPECT().GetNodeBootId("node-1").Return("boot-id")
+		upgradeHandler.EXPECT().GetNodeUpgradeAction("node-1").Return(amdv1alpha1.NodeUpgradeAction(""))
+
+		Expect(dcrh.buildDeviceConfigNodeStatus(ctx, driverConfig, nodes)).To(Succeed())
+		Expect(driverConfig.Status.NodeModuleStatus).To(Equal(map[string]amdv1alpha1.ModuleStatus{
+			"node-1": {Status: amdv1alpha1.UpgradeStateInProgress, UpgradeStartTime: "2026-01-01 00:00:00 UTC", BootId: "boot-id"},
+		}))
+
+		// the node stays listed so that the ownership of the metrics exporter is rebuilt after a restart
+		Expect(dcrh.buildDeviceConfigNodeStatus(ctx, metricsConfig, nodes)).To(Succeed())
+		Expect(metricsConfig.Status.NodeModuleStatus).To(Equal(map[string]amdv1alpha1.ModuleStatus{"node-1": {}}))
+	})
+})
+
+var _ = Describe("validateNodeAssignments", func() {
+	var (
+		dcrh deviceConfigReconcilerHelperAPI
+	)
+
+	makeNodeList := func(names ...string) *v1.NodeList {
+		nodes := &v1.NodeList{}
+		for _, n := range names {
+			nodes.Items = append(nodes.Items, v1.Node{ObjectMeta: metav1.ObjectMeta{Name: n}})
+		}
+		return nodes
+	}
+
+	BeforeEach(func() {
+		ctrl := gomock.NewController(GinkgoT())
+		kubeClient := mock_client.NewMockClient(ctrl)
+		dcrh = newDeviceConfigReconcilerHelper(kubeClient, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, false, true)
+	})
+
+	It("allows DeviceConfigs owning different operands on the same node", func() {
+		nodes := makeNodeList("node-1", "node-2")
+		dcrh.updateNodeAssignments("ns/platform", []string{utils.OwnedOperandDriver, utils.OwnedOperandDevicePlugin}, nodes, false)
+
+		err := dcrh.validateNodeAssignments("ns/observability", []string{utils.OwnedOperandMetricsExporter, utils.OwnedOperandTestRunner}, nodes)
+		Expect(err).ToNot(HaveOccurred())
+		err = dcrh.validateNodeAssignments("ns/platform", []string{utils.OwnedOperandDriver, utils.OwnedOperandDevicePlugin}, nodes)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("reports conflicts per operand", func() {
+		dcrh.updateNodeAssignments("ns/platform", []string{utils.OwnedOperandDriver, utils.OwnedOperandMetricsExporter}, makeNodeList("node-1", "node-2"), false)
+
+		err := dcrh.validateNodeAssignments("ns/observability", []string{utils.OwnedOperandMetricsExporter, utils.OwnedOperandTestRunner}, makeNodeList("node-2", "node-3"))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("operand metricsExporter on node(s) node-2 already owned by DeviceConfig ns/platform, cannot be owned by ns/observability"))
+	})
+
+	It("releases operands no longer claimed by the DeviceConfig", func() {
+		nodes := makeNodeList("node-1")
+		dcrh.updateNodeAssignments("ns/platform", []string{utils.OwnedOperandDriver, utils.OwnedOperandMetricsExporter}, nodes, false)
+		Expect(dcrh.validateNodeAssignments("ns/observability", []string{utils.OwnedOperandMetricsExporter}, nodes)).To(HaveOccurred())
+
+		dcrh.updateNodeAssignments("ns/platform", []string{utils.OwnedOperandDriver}, nodes, false)
+		Expect(dcrh.validateNodeAssignments("ns/observability", []string{utils.OwnedOperandMetricsExporter}, nodes)).ToNot(HaveOccurred())
+		Expect(dcrh.validateNodeAssignments("ns/observability", []string{utils.OwnedOperandDriver}, nodes)).To(HaveOccurred())
+	})
+
+	It("releases the nodes no longer selected by the DeviceConfig", func() {
+		dcrh.updateNodeAssignments("ns/gpu-default", []string{utils.OwnedOperandDriver}, makeNodeList("node-1", "node-2"), false)
+		dcrh.updateNodeAssignments("ns/gpu-default", []string{utils.OwnedOperandDriver}, makeNodeList("node-1"), false)
+
+		Expect(dcrh.validateNodeAssignments("ns/gpu-mi210", []string{utils.OwnedOperandDriver}, makeNodeList("node-2"))).ToNot(HaveOccurred())
+		Expect(dcrh.validateNodeAssignments("ns/gpu-mi210", []string{utils.OwnedOperandDriver}, makeNodeList("node-1"))).To(HaveOccurred())
+	})
+
+	It("lets the DeviceConfig selecting a node that left another selector clean it up", func() {
+		helper := dcrh.(*deviceConfigReconcilerHelper)
+		other := &amdv1alpha1.DeviceConfig{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "gpu-mi210"}}
+		dcrh.updateNodeAssignments("ns/gpu-default", []string{utils.OwnedOperandNodeLabeller}, makeNodeList("node-1", "node-2"), false)
+		Expect(helper.isNodeOperandOwnedByOther(other, "node-2", utils.OwnedOperandNodeLabeller)).To(BeTrue())
+
+		// node-2 left the selector of ns/gpu-default
+		dcrh.updateNodeAssignments("ns/gpu-default", []string{utils.OwnedOperandNodeLabeller}, makeNodeList("node-1"), false)
+		Expect(helper.isNodeOperandOwnedByOther(other, "node-2", utils.OwnedOperandNodeLabeller)).To(BeFalse())
+		Expect(helper.isNodeOperandOwnedByOther(other, "node-1", utils.OwnedOperandNodeLabeller)).To(BeTrue())
+		Expect(helper.getNodesNotOwnedByOthers(other, utils.OwnedOperandNodeLabeller, makeNodeList("node-1", "node-2")).Items).To(HaveLen(1))
+	})
+
+	It("leaves the partitions of the nodes whose config manager is owned by another DeviceConfig", func() {
+		partitionHandler := NewMockpartitionMgrAPI(gomock.NewController(GinkgoT()))
+		helper := newDeviceConfigReconcilerHelper(nil, nil, nil, nil, nil, nil, partitionHandler, nil, nil, nil, nil, nil, false, true)
+		devConfig := &amdv1alpha1.DeviceConfig{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "gpu-default"}}
+		helper.updateNodeAssignments("ns/gpu-mi210", []string{utils.OwnedOperandConfigManager}, makeNodeList("node-2"), false)
+
+		partitionHandler.EXPECT().HandleDelete(gomock.Any(), devConfig, makeNodeList("node-1")).Return(reconcile.Result{}, nil)
+		_, err := helper.handlePartitionProfiles(context.Background(), devConfig, makeNodeList("node-1", "node-2"), true)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("releases all operands when the DeviceConfig is deleted", func() {
+		nodes := makeNodeList("node-1")
+		dcrh.updateNodeAssignments("ns/platform", []string{utils.OwnedOperandDriver}, nodes, false)
+
+		dcrh.updateNodeAssignments("ns/platform", nil, nil, true)
+		Expect(dcrh.validateNodeAssignments("ns/other", []string{utils.OwnedOperandDriver}, nodes)).ToNot(HaveOccurred())
+	})
+})
+
 var _ = Describe("handleDeviceClass", func() {
 	var (
 		kubeClient *mock_client.MockClient
@@ -846,19 +1098,23 @@ var _ = Describe("handleDeviceClass", func() {
 		},
 	}
 
-	It("should skip when not on OpenShift", func() {
+	It("should not create the default DeviceClass when not on OpenShift", func() {
 		ctrl := gomock.NewController(GinkgoT())
 		kubeClient = mock_client.NewMockClient(ctrl)
-		dcrh = newDeviceConfigReconcilerHelper(kubeClient, nil, nil, nil, nil, nil, nil, nil, nil, nil, false, true)
+		dcrh = newDeviceConfigReconcilerHelper(kubeClient, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, false, true)
+
+		kubeClient.EXPECT().List(ctx, gomock.Any(), gomock.Any()).Return(nil)
 
 		err := dcrh.handleDeviceClass(ctx, draEnabledConfig)
 		Expect(err).ToNot(HaveOccurred())
 	})
 
-	It("should skip when DRA driver is not enabled", func() {
+	It("should only clean up DeviceClasses when DRA driver is not enabled", func() {
 		ctrl := gomock.NewController(GinkgoT())
 		kubeClient = mock_client.NewMockClient(ctrl)
-		dcrh = newDeviceConfigReconcilerHelper(kubeClient, nil, nil, nil, nil, nil, nil, nil, nil, nil, true, true)
+		dcrh = newDeviceConfigReconcilerHelper(kubeClient, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, true, true)
+
+		kubeClient.EXPECT().List(ctx, gomock.Any(), gomock.Any()).Return(nil)
 
 		err := dcrh.handleDeviceClass(ctx, draDisabledConfig)
 		Expect(err).ToNot(HaveOccurred())
@@ -867,9 +1123,10 @@ var _ = Describe("handleDeviceClass", func() {
 	It("should create DeviceClass when it does not exist", func() {
 		ctrl := gomock.NewController(GinkgoT())
 		kubeClient = mock_client.NewMockClient(ctrl)
-		dcrh = newDeviceConfigReconcilerHelper(kubeClient, nil, nil, nil, nil, nil, nil, nil, nil, nil, true, true)
+		dcrh = newDeviceConfigReconcilerHelper(kubeClient, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, true, true)
 
 		kubeClient.EXPECT().Create(ctx, gomock.Any()).Return(nil)
+		kubeClient.EXPECT().List(ctx, gomock.Any(), gomock.Any()).Return(nil)
 
 		err := dcrh.handleDeviceClass(ctx, draEnabledConfig)
 		Expect(err).ToNot(HaveOccurred())
@@ -878,20 +1135,96 @@ var _ = Describe("handleDeviceClass", func() {
 	It("should succeed when DeviceClass already exists", func() {
 		ctrl := gomock.NewController(GinkgoT())
 		kubeClient = mock_client.NewMockClient(ctrl)
-		dcrh = newDeviceConfigReconcilerHelper(kubeClient, nil, nil, nil, nil, nil, nil, nil, nil, nil, true, true)
+		dcrh = newDeviceConfigReconcilerHelper(kubeClient, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, true, true)
 
 		kubeClient.EXPECT().Create(ctx, gomock.Any()).Return(
 			k8serrors.NewAlreadyExists(schema.GroupResource{Group: "resource.k8s.io", Resource: "deviceclasses"}, "gpu.amd.com"),
 		)
+		kubeClient.EXPECT().List(ctx, gomock.Any(), gomock.Any()).Return(nil)
+
+		err := dcrh.handleDeviceClass(ctx, draEnabledConfig)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should create the DeviceClasses of the spec on Kubernetes", func() {
+		ctrl := gomock.NewController(GinkgoT())
+		kubeClient = mock_client.NewMockClient(ctrl)
+		dcrh = newDeviceConfigReconcilerHelper(kubeClient, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, false, true)
+
+		devConfig := draEnabledConfig.DeepCopy()
+		devConfig.Spec.DRADriver.DeviceClasses = []amdv1alpha1.DRADeviceClassSpec{
+			{Name: "mi300x.gpu.amd.com", Selectors: []string{"device.attributes['gpu.amd.com'].productName == 'AMD_Instinct_MI300X_OAM'"}},
+		}
+
+		gomock.InOrder(
+			kubeClient.EXPECT().Get(ctx, client.ObjectKey{Name: "mi300x.gpu.amd.com"}, gomock.Any()).Return(
+				k8serrors.NewNotFound(schema.GroupResource{Group: "resource.k8s.io", Resource: "deviceclasses"}, "mi300x.gpu.amd.com"),
+			),
+			kubeClient.EXPECT().Create(ctx, gomock.Any()).DoAndReturn(
+				func(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
+					dc := obj.(*unstructured.Unstructured)
+					Expect(dc.GetLabels()).To(HaveKeyWithValue(deviceClassOwnerNameLabelKey, devConfigName))
+					Expect(dc.GetLabels()).To(HaveKeyWithValue(deviceClassOwnerNamespaceLabelKey, devConfigNamespace))
+					selectors, _, _ := unstructured.NestedSlice(dc.Object, "spec", "selectors")
+					Expect(selectors).To(HaveLen(2))
+					return nil
+				},
+			),
+			kubeClient.EXPECT().List(ctx, gomock.Any(), gomock.Any()).Return(nil),
+		)
+
+		err := dcrh.handleDeviceClass(ctx, devConfig)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should delete the DeviceClasses removed from the spec", func() {
+		ctrl := gomock.NewController(GinkgoT())
+		kubeClient = mock_client.NewMockClient(ctrl)
+		dcrh = newDeviceConfigReconcilerHelper(kubeClient, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, false, true)
+
+		stale := unstructured.Unstructured{}
+		stale.SetName("stale.gpu.amd.com")
+
+		kubeClient.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, list client.ObjectList, _ ...client.ListOption) error {
+				list.(*unstructured.UnstructuredList).Items = []unstructured.Unstructured{stale}
+				return nil
+			},
+		)
+		kubeClient.EXPECT().Delete(ctx, gomock.Any()).DoAndReturn(
+			func(_ context.Context, obj client.Object, _ ...client.DeleteOption) error {
+				Expect(obj.GetName()).To(Equal("stale.gpu.amd.com"))
+				return nil
+			},
+		)
 
 		err := dcrh.handleDeviceClass(ctx, draEnabledConfig)
 		Expect(err).ToNot(HaveOccurred())
 	})
 
+	It("should not take over a DeviceClass it does not manage", func() {
+		ctrl := gomock.NewController(GinkgoT())
+		kubeClient = mock_client.NewMockClient(ctrl)
+		dcrh = newDeviceConfigReconcilerHelper(kubeClient, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, false, true)
+
+		devConfig := draEnabledConfig.DeepCopy()
+		devConfig.Spec.DRADriver.DeviceClasses = []amdv1alpha1.DRADeviceClassSpec{{Name: "foreign.gpu.amd.com"}}
+
+		kubeClient.EXPECT().Get(ctx, client.ObjectKey{Name: "foreign.gpu.amd.com"}, gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+				obj.SetResourceVersion("1")
+				return nil
+			},
+		)
+
+		err := dcrh.handleDeviceClass(ctx, devConfig)
+		Expect(err).To(HaveOccurred())
+	})
+
 	It("should return error when Create fails", func() {
 		ctrl := gomock.NewController(GinkgoT())
 		kubeClient = mock_client.NewMockClient(ctrl)
-		dcrh = newDeviceConfigReconcilerHelper(kubeClient, nil, nil, nil, nil, nil, nil, nil, nil, nil, true, true)
+		dcrh = newDeviceConfigReconcilerHelper(kubeClient, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, true, true)
 
 		kubeClient.EXPECT().Create(ctx, gomock.Any()).Return(fmt.Errorf("server error"))
 
@@ -899,3 +1232,613 @@ var _ = Describe("handleDeviceClass", func() {
 		Expect(err).To(HaveOccurred())
 	})
 })
+
+var _ = Describe("getResourceClaimTemplateSpec", func() {
+	templateSpec := amdv1alpha1.DRAClaimTemplateSpec{
+		Name:      "cpx-partition",
+		Count:     2,
+		Selectors: []string{"device.attributes['gpu.amd.com'].partitionProfile == 'cpx_nps4'"},
+	}
+
+	It("should inline the request fields for v1beta1", func() {
+		spec := getResourceClaimTemplateSpec("v1beta1", templateSpec)
+		requests, _, _ := unstructured.NestedSlice(spec, "spec", "devices", "requests")
+		Expect(requests).To(HaveLen(1))
+		request := requests[0].(map[string]interface{})
+		Expect(request).To(HaveKeyWithValue("name", claimTemplateRequestName))
+		Expect(request).To(HaveKeyWithValue("deviceClassName", deviceClassName))
+		Expect(request).To(HaveKeyWithValue("allocationMode", "ExactCount"))
+		Expect(request).To(HaveKeyWithValue("count", int64(2)))
+		Expect(request).To(HaveKey("selectors"))
+		Expect(request).ToNot(HaveKey("exactly"))
+	})
+
+	It("should nest the request fields under exactly for v1", func() {
+		spec := getResourceClaimTemplateSpec("v1", templateSpec)
+		requests, _, _ := unstructured.NestedSlice(spec, "spec", "devices", "requests")
+		Expect(requests).To(HaveLen(1))
+		request := requests[0].(map[string]interface{})
+		Expect(request).To(HaveKeyWithValue("name", claimTemplateRequestName))
+		Expect(request).To(HaveKey("exactly"))
+		exactly := request["exactly"].(map[string]interface{})
+		Expect(exactly).To(HaveKeyWithValue("deviceClassName", deviceClassName))
+		Expect(exactly).To(HaveKeyWithValue("count", int64(2)))
+	})
+
+	It("should not set a count when all devices are requested", func() {
+		allSpec := amdv1alpha1.DRAClaimTemplateSpec{Name: "all-gpus", AllocationMode: amdv1alpha1.DRAClaimAllocationModeAll, DeviceClassName: "mi300x.gpu.amd.com"}
+		spec := getResourceClaimTemplateSpec("v1", allSpec)
+		requests, _, _ := unstructured.NestedSlice(spec, "spec", "devices", "requests")
+		exactly := requests[0].(map[string]interface{})["exactly"].(map[string]interface{})
+		Expect(exactly).To(HaveKeyWithValue("allocationMode", "All"))
+		Expect(exactly).To(HaveKeyWithValue("deviceClassName", "mi300x.gpu.amd.com"))
+		Expect(exactly).ToNot(HaveKey("count"))
+	})
+})
+
+var _ = Describe("DeviceConfigTemplate", func() {
+	newTemplate := func(overrides ...amdv1alpha1.DeviceConfigOverride) *amdv1alpha1.DeviceConfigTemplate {
+		return &amdv1alpha1.DeviceConfigTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "gpu"},
+			Spec: amdv1alpha1.DeviceConfigTemplateSpec{
+				Namespace: devConfigNamespace,
+				Template: amdv1alpha1.DeviceConfigSpec{
+					Driver: amdv1alpha1.DriverSpec{
+						Enable:  ptr.To(true),
+						Version: "6.3",
+					},
+					DevicePlugin: amdv1alpha1.DevicePluginSpec{
+						DevicePluginArguments: map[string]string{"resource_naming_strategy": "single"},
+					},
+					Selector: map[string]string{"feature.node.kubernetes.io/amd-gpu": "true"},
+				},
+				Overrides: overrides,
+			},
+		}
+	}
+
+	It("renders the base spec for the default node group", func() {
+		tmpl := newTemplate()
+		spec, err := getNodeGroupSpec(tmpl, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(spec.Driver.Version).To(Equal("6.3"))
+		Expect(spec.Selector).To(Equal(map[string]string{
+			"feature.node.kubernetes.io/amd-gpu": "true",
+			"nodegroup.operator.amd.com/gpu":     defaultNodeGroup,
+		}))
+		// the template itself is left untouched
+		Expect(tmpl.Spec.Template.Selector).To(HaveLen(1))
+	})
+
+	It("applies the override patch on top of the base spec", func() {
+		override := amdv1alpha1.DeviceConfigOverride{
+			Name:     "mi210",
+			Selector: map[string]string{"amd.com/gpu.product-name": "MI210"},
+			Patch:    runtime.RawExtension{Raw: []byte(`{"driver":{"version":"6.4"},"devicePlugin":{"devicePluginArguments":{"resource_naming_strategy":"mixed"}}}`)},
+		}
+		tmpl := newTemplate(override)
+		spec, err := getNodeGroupSpec(tmpl, &tmpl.Spec.Overrides[0])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(spec.Driver.Version).To(Equal("6.4"))
+		Expect(*spec.Driver.Enable).To(BeTrue())
+		Expect(spec.DevicePlugin.DevicePluginArguments).To(Equal(map[string]string{"resource_naming_strategy": "mixed"}))
+		Expect(spec.Selector["nodegroup.operator.amd.com/gpu"]).To(Equal("mi210"))
+	})
+
+	It("hashes the spec rendered for the node group", func() {
+		override := amdv1alpha1.DeviceConfigOverride{
+			Name:     "mi210",
+			Selector: map[string]string{"amd.com/gpu.product-name": "MI210"},
+			Patch:    runtime.RawExtension{Raw: []byte(`{"driver":{"version":"6.4"}}`)},
+		}
+		tmpl := newTemplate(override)
+		defaultSpec, err := getNodeGroupSpec(tmpl, nil)
+		Expect(err).ToNot(HaveOccurred())
+		overrideSpec, err := getNodeGroupSpec(tmpl, &tmpl.Spec.Overrides[0])
+		Expect(err).ToNot(HaveOccurred())
+
+		defaultHash, err := getDeviceConfigSpecHash(defaultSpec)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(defaultHash).To(HaveLen(16))
+		Expect(getDeviceConfigSpecHash(defaultSpec.DeepCopy())).To(Equal(defaultHash))
+		Expect(getDeviceConfigSpecHash(overrideSpec)).ToNot(Equal(defaultHash))
+	})
+
+	It("assigns a node to the first matching override", func() {
+		tmpl := newTemplate(
+			amdv1alpha1.DeviceConfigOverride{Name: "mi210", Selector: map[string]string{"gpu": "mi210"}},
+			amdv1alpha1.DeviceConfigOverride{Name: "partitioned", Selector: map[string]string{"partitioned": "true"}},
+		)
+		Expect(getNodeGroup(tmpl, map[string]string{"gpu": "mi210", "partitioned": "true"})).To(Equal("mi210"))
+		Expect(getNodeGroup(tmpl, map[string]string{"gpu": "mi300x", "partitioned": "true"})).To(Equal("partitioned"))
+		Expect(getNodeGroup(tmpl, map[string]string{"gpu": "mi300x"})).To(Equal(defaultNodeGroup))
+	})
+
+	It("validates the overrides", func() {
+		Expect(validateDeviceConfigTemplate(newTemplate(
+			amdv1alpha1.DeviceConfigOverride{Name: "mi210", Selector: map[string]string{"gpu": "mi210"}},
+		))).To(Succeed())
+		Expect(validateDeviceConfigTemplate(newTemplate(
+			amdv1alpha1.DeviceConfigOverride{Name: defaultNodeGroup, Selector: map[string]string{"gpu": "mi210"}},
+		))).ToNot(Succeed())
+		Expect(validateDeviceConfigTemplate(newTemplate(
+			amdv1alpha1.DeviceConfigOverride{Name: "mi210", Selector: map[string]string{"gpu": "mi210"}},
+			amdv1alpha1.DeviceConfigOverride{Name: "mi210", Selector: map[string]string{"gpu": "mi250"}},
+		))).ToNot(Succeed())
+		Expect(validateDeviceConfigTemplate(newTemplate(
+			amdv1alpha1.DeviceConfigOverride{Name: "mi210"},
+		))).ToNot(Succeed())
+		Expect(validateDeviceConfigTemplate(newTemplate(
+			amdv1alpha1.DeviceConfigOverride{
+				Name:     "mi210",
+				Selector: map[string]string{"gpu": "mi210"},
+				Patch:    runtime.RawExtension{Raw: []byte(`{"selector":{"gpu":"mi210"}}`)},
+			},
+		))).ToNot(Succeed())
+	})
+})
+
+var _ = Describe("dry run", func() {
+	newDeviceConfig := func() *amdv1alpha1.DeviceConfig {
+		return &amdv1alpha1.DeviceConfig{
+			ObjectMeta: metav1.ObjectMeta{Namespace: devConfigNamespace, Name: devConfigName},
+			Spec: amdv1alpha1.DeviceConfigSpec{
+				Driver: amdv1alpha1.DriverSpec{
+					Enable:  ptr.To(true),
+					Version: "6.4",
+					UpgradePolicy: &amdv1alpha1.DriverUpgradePolicySpec{
+						MaxParallelUpgrades: 2,
+						MaxUnavailableNodes: intstr.FromString("50%"),
+					},
+				},
+			},
+		}
+	}
+	newNodes := func(devConfig *amdv1alpha1.DeviceConfig, versions map[string]string) *v1.NodeList {
+		versionLabel, _ := kmmmodule.GetVersionLabelKV(devConfig)
+		nodes := &v1.NodeList{}
+		for name, version := range versions {
+			node := v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{}}}
+			if version != "" {
+				node.Labels[versionLabel] = version
+			}
+			nodes.Items = append(nodes.Items, node)
+		}
+		return nodes
+	}
+
+	It("plans the installs and the upgrade batches", func() {
+		devConfig := newDeviceConfig()
+		nodes := newNodes(devConfig, map[string]string{
+			"node-a": "6.3", "node-b": "6.3", "node-c": "6.3", "node-d": "6.4", "node-e": "", "node-f": "6.3",
+		})
+		plan := getDryRunDriverUpgradePlan(devConfig, nodes)
+		Expect(plan.Install).To(Equal([]string{"node-e"}))
+		Expect(plan.MaxParallelUpgrades).To(Equal(2))
+		Expect(plan.Batches).To(Equal([]dryRunUpgradeBatch{
+			{Stage: "upgrade", Nodes: []string{"node-a", "node-b"}},
+			{Stage: "upgrade", Nodes: []string{"node-c", "node-f"}},
+		}))
+	})
+
+	It("upgrades the canary nodes first", func() {
+		devConfig := newDeviceConfig()
+		devConfig.Spec.Driver.UpgradePolicy.RolloutStrategy = &amdv1alpha1.UpgradeRolloutSpec{
+			Canary: &amdv1alpha1.UpgradeCanarySpec{Selector: map[string]string{"canary": "true"}},
+		}
+		nodes := newNodes(devConfig, map[string]string{"node-a": "6.3", "node-b": "6.3", "node-c": "6.3"})
+		nodes.Items[0].Labels["canary"] = "true"
+		canary := nodes.Items[0].Name
+		plan := getDryRunDriverUpgradePlan(devConfig, nodes)
+		Expect(plan.Batches).ToNot(BeEmpty())
+		Expect(plan.Batches[0]).To(Equal(dryRunUpgradeBatch{Stage: "canary", Nodes: []string{canary}}))
+		Expect(plan.Batches[1].Stage).To(Equal("wave 1"))
+	})
+
+	It("reports no batches when the upgrade policy does not allow any unavailable node", func() {
+		devConfig := newDeviceConfig()
+		devConfig.Spec.Driver.UpgradePolicy.MaxUnavailableNodes = intstr.FromInt32(0)
+		nodes := newNodes(devConfig, map[string]string{"node-a": "6.3"})
+		plan := getDryRunDriverUpgradePlan(devConfig, nodes)
+		Expect(plan.MaxParallelUpgrades).To(BeZero())
+		Expect(plan.Batches).To(BeEmpty())
+	})
+
+	It("diffs the kernel mappings", func() {
+		existing := []kmmv1beta1.KernelMapping{
+			{Literal: "5.15.0-100-generic", ContainerImage: "registry/driver:6.3-5.15.0-100-generic"},
+			{Literal: "5.15.0-101-generic", ContainerImage: "registry/driver:6.3-5.15.0-101-generic"},
+		}
+		desired := []kmmv1beta1.KernelMapping{
+			{Literal: "5.15.0-101-generic", ContainerImage: "registry/driver:6.4-5.15.0-101-generic", Build: &kmmv1beta1.Build{}},
+			{Literal: "6.8.0-40-generic", ContainerImage: "registry/driver:6.4-6.8.0-40-generic"},
+		}
+		Expect(getKernelMappingChanges(existing, desired)).To(Equal([]dryRunKernelMappingChange{
+			{Kernel: "5.15.0-100-generic", Action: dryRunActionDelete, PreviousImage: "registry/driver:6.3-5.15.0-100-generic"},
+			{Kernel: "5.15.0-101-generic", Action: dryRunActionUpdate, Image: "registry/driver:6.4-5.15.0-101-generic",
+				PreviousImage: "registry/driver:6.3-5.15.0-101-generic", Build: true},
+			{Kernel: "6.8.0-40-generic", Action: dryRunActionCreate, Image: "registry/driver:6.4-6.8.0-40-generic"},
+		}))
+		Expect(getKernelMappingChanges(existing, existing)).To(BeEmpty())
+	})
+
+	It("drives the node upgrades in flight to the driver version of the KMM Module", func() {
+		mockCtrl := gomock.NewController(GinkgoT())
+		kubeClient := mock_client.NewMockClient(mockCtrl)
+		upgradeHandler := NewMockupgradeMgrAPI(mockCtrl)
+		dcrh := newDeviceConfigReconcilerHelper(kubeClient, nil, nil, nil, upgradeHandler, nil, nil, nil, nil, nil, nil, nil, false, true).(*deviceConfigReconcilerHelper)
+
+		devConfig := newDeviceConfig()
+		devConfig.Spec.DryRun = ptr.To(true)
+		nodes := newNodes(devConfig, map[string]string{"node-a": "6.3"})
+
+		kubeClient.EXPECT().Get(gomock.Any(), types.NamespacedName{Namespace: devConfigNamespace, Name: devConfigName}, gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ types.NamespacedName, obj client.Object, _ ...client.GetOption) error {
+				obj.(*kmmv1beta1.Module).Spec.ModuleLoader.Container.Version = "6.3"
+				return nil
+			})
+		upgradeHandler.EXPECT().HandleUpgrade(gomock.Any(), gomock.Any(), nodes).DoAndReturn(
+			func(_ context.Context, appliedConfig *amdv1alpha1.DeviceConfig, _ *v1.NodeList) (reconcile.Result, error) {
+				Expect(appliedConfig.Spec.Driver.Version).To(Equal("6.3"))
+				return reconcile.Result{}, nil
+			})
+
+		_, err := dcrh.handleModuleUpgrade(context.Background(), devConfig, nodes, false)
+		Expect(err).ToNot(HaveOccurred())
+		// the planned spec itself is left as is
+		Expect(devConfig.Spec.Driver.Version).To(Equal("6.4"))
+	})
+
+	It("summarizes the plan in the status", func() {
+		plan := &dryRunPlan{
+			Generation:    3,
+			DriverUpgrade: &dryRunDriverUpgradePlan{Install: []string{"node-e"}, Batches: []dryRunUpgradeBatch{{Nodes: []string{"node-a", "node-b"}}}},
+			KMMModule:     &dryRunKMMModulePlan{KernelMappings: make([]dryRunKernelMappingChange, 2), BuildImages: []string{"registry/driver:6.4"}},
+			DaemonSets:    []dryRunDaemonSetPlan{{Name: "a", Action: dryRunActionRoll}, {Name: "b", Action: dryRunActionCreate}},
+		}
+		Expect(getDryRunPlanStatus(plan, "dc-dry-run-plan")).To(Equal(&amdv1alpha1.DryRunPlanStatus{
+			ConfigMap:            "dc-dry-run-plan",
+			ObservedGeneration:   3,
+			NodesToInstall:       1,
+			NodesToUpgrade:       2,
+			DaemonSetsToRoll:     1,
+			KernelMappingChanges: 2,
+			BuildImages:          1,
+		}))
+	})
+})
+
+var _ = Describe("driver image prebuild", func() {
+	devConfig := &amdv1alpha1.DeviceConfig{
+		ObjectMeta: metav1.ObjectMeta{Namespace: devConfigNamespace, Name: devConfigName},
+	}
+	image := kmmmodule.DriverImage{
+		OSName:         "ubuntu-22.04",
+		KernelVersion:  "5.15.0-40-generic",
+		DriverVersion:  "6.4",
+		Image:          "registry/driver:ubuntu-22.04-5.15.0-40-generic-6.4",
+		ContainerImage: "registry/driver:ubuntu-22.04-${KERNEL_FULL_VERSION}-6.4",
+	}
+	newPreflightValidation := func(stage, status string) *kmmv1beta2.PreflightValidation {
+		return &kmmv1beta2.PreflightValidation{
+			Status: kmmv1beta2.PreflightValidationStatus{
+				Modules: []kmmv1beta2.PreflightValidationModuleStatus{
+					{
+						Name:      "other",
+						Namespace: devConfigNamespace,
+						CRBaseStatus: kmmv1beta2.CRBaseStatus{
+							VerificationStatus: kmmv1beta2.VerificationTrue,
+							VerificationStage:  kmmv1beta2.VerificationStageDone,
+						},
+					},
+					{
+						Name:      devConfigName,
+						Namespace: devConfigNamespace,
+						CRBaseStatus: kmmv1beta2.CRBaseStatus{
+							VerificationStatus: status,
+							VerificationStage:  stage,
+							StatusReason:       "reason",
+						},
+					},
+				},
+			},
+		}
+	}
+
+	It("maps the PreflightValidation status of the DeviceConfig module", func() {
+		phase, _ := getDriverImagePhase(&kmmv1beta2.PreflightValidation{}, devConfig)
+		Expect(phase).To(Equal(amdv1alpha1.DriverImagePending))
+		phase, _ = getDriverImagePhase(newPreflightValidation(kmmv1beta2.VerificationStageBuild, kmmv1beta2.VerificationFalse), devConfig)
+		Expect(phase).To(Equal(amdv1alpha1.DriverImageBuilding))
+		phase, _ = getDriverImagePhase(newPreflightValidation(kmmv1beta2.VerificationStageDone, kmmv1beta2.VerificationTrue), devConfig)
+		Expect(phase).To(Equal(amdv1alpha1.DriverImageReady))
+		phase, msg := getDriverImagePhase(newPreflightValidation(kmmv1beta2.VerificationStageDone, kmmv1beta2.VerificationFalse), devConfig)
+		Expect(phase).To(Equal(amdv1alpha1.DriverImageFailed))
+		Expect(msg).To(Equal("reason"))
+	})
+
+	It("waits for the KMM Module to refer to the image", func() {
+		Expect(moduleHasDriverImage(nil, image)).To(BeFalse())
+		mod := &kmmv1beta1.Module{}
+		mod.Spec.ModuleLoader.Container.KernelMappings = []kmmv1beta1.KernelMapping{
+			{Literal: image.KernelVersion, ContainerImage: "registry/driver:ubuntu-22.04-${KERNEL_FULL_VERSION}-6.3"},
+		}
+		Expect(moduleHasDriverImage(mod, image)).To(BeFalse())
+		mod.Spec.ModuleLoader.Container.KernelMappings[0].ContainerImage = image.ContainerImage
+		Expect(moduleHasDriverImage(mod, image)).To(BeTrue())
+	})
+
+	It("reports the images whose build newly failed", func() {
+		previous := []amdv1alpha1.DriverImageStatus{
+			{Image: "a", Phase: amdv1alpha1.DriverImageFailed},
+			{Image: "b", Phase: amdv1alpha1.DriverImageBuilding},
+		}
+		current := []amdv1alpha1.DriverImageStatus{
+			{Image: "a", Phase: amdv1alpha1.DriverImageFailed},
+			{Image: "b", Phase: amdv1alpha1.DriverImageFailed},
+		}
+		Expect(getNewlyFailedDriverImages(previous, current)).To(Equal(current[1:]))
+	})
+
+	It("shares the PreflightValidation of a kernel between the DeviceConfigs", func() {
+		name := getPreflightValidationName(image)
+		other := image
+		other.Image = "registry/driver:ubuntu-22.04-5.15.0-40-generic-6.3"
+		Expect(getPreflightValidationName(other)).To(Equal(name))
+		other.KernelVersion = "6.8.0-40-generic"
+		Expect(getPreflightValidationName(other)).ToNot(Equal(name))
+
+		ownerKey := getPreflightValidationOwnerLabel(devConfig)
+		otherConfig := &amdv1alpha1.DeviceConfig{ObjectMeta: metav1.ObjectMeta{Namespace: devConfigNamespace, Name: "other"}}
+		otherKey := getPreflightValidationOwnerLabel(otherConfig)
+		Expect(otherKey).ToNot(Equal(ownerKey))
+
+		pfv := &kmmv1beta2.PreflightValidation{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{
+			preflightValidationLabelKey: "true",
+			ownerKey:                    getImageHash(image),
+		}}}
+		Expect(isPreflightValidationShared(pfv, ownerKey)).To(BeFalse())
+		pfv.Labels[otherKey] = getImageHash(other)
+		Expect(isPreflightValidationShared(pfv, ownerKey)).To(BeTrue())
+	})
+
+	It("reports the driver images ready once all of them are built", func() {
+		config := devConfig.DeepCopy()
+		Expect(areDriverImagesReady(config)).To(BeTrue())
+		config.Status.DriverImages = []amdv1alpha1.DriverImageStatus{
+			{Image: "a", Phase: amdv1alpha1.DriverImageReady},
+			{Image: "b", Phase: amdv1alpha1.DriverImageFailed},
+		}
+		Expect(areDriverImagesReady(config)).To(BeFalse())
+		config.Status.DriverImages[1].Phase = amdv1alpha1.DriverImageReady
+		Expect(areDriverImagesReady(config)).To(BeTrue())
+	})
+})
+
+var _ = Describe("removeStaleNodeLabels", func() {
+	var (
+		kubeClient *mock_client.MockClient
+		dcrh       *deviceConfigReconcilerHelper
+	)
+
+	BeforeEach(func() {
+		ctrl := gomock.NewController(GinkgoT())
+		kubeClient = mock_client.NewMockClient(ctrl)
+		dcrh = newDeviceConfigReconcilerHelper(kubeClient, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, false, true).(*deviceConfigReconcilerHelper)
+	})
+
+	ctx := context.Background()
+	devConfig := &amdv1alpha1.DeviceConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      devConfigName,
+			Namespace: devConfigNamespace,
+		},
+	}
+	newNodes := func() *v1.NodeList {
+		return &v1.NodeList{
+			Items: []v1.Node{
+				{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{
+					"amd.com/gpu.firmware":             "123",
+					"amd.com/gpu.driver-src-version":   "6.12.12",
+					"amd.com/compute-memory-partition": "spx_nps1",
+				}}},
+			},
+		}
+	}
+
+	It("keeps the labels never applied by the node labeller", func() {
+		Expect(dcrh.removeStaleNodeLabels(ctx, devConfig, newNodes(), nil)).To(Succeed())
+	})
+
+	It("removes the labels of the kinds previously applied", func() {
+		nodes := newNodes()
+		gomock.InOrder(
+			kubeClient.EXPECT().Get(ctx, client.ObjectKey{Name: "node1"}, gomock.Any()).Do(
+				func(_ interface{}, _ interface{}, node *v1.Node, _ ...client.GetOption) {
+					nodes.Items[0].DeepCopyInto(node)
+				},
+			),
+			kubeClient.EXPECT().Patch(ctx, gomock.Any(), gomock.Any()).Do(
+				func(_ interface{}, node *v1.Node, _ client.Patch, _ ...client.PatchOption) {
+					Expect(node.Labels).ToNot(HaveKey("amd.com/gpu.firmware"))
+					Expect(node.Labels).To(HaveKey("amd.com/compute-memory-partition"))
+				},
+			),
+		)
+		Expect(dcrh.removeStaleNodeLabels(ctx, devConfig, nodes, []string{"firmware", "compute-memory-partition"})).To(Succeed())
+	})
+
+	It("leaves the nodes whose node labeller is owned by another DeviceConfig", func() {
+		dcrh.nodeAssignments["node1"] = map[string]string{utils.OwnedOperandNodeLabeller: devConfigNamespace + "/other"}
+		Expect(dcrh.removeStaleNodeLabels(ctx, devConfig, newNodes(), []string{"firmware"})).To(Succeed())
+	})
+})
+
+var _ = Describe("NodeFeatureRule", func() {
+	var (
+		kubeClient *mock_client.MockClient
+		dcrh       deviceConfigReconcilerHelperAPI
+	)
+
+	BeforeEach(func() {
+		ctrl := gomock.NewController(GinkgoT())
+		kubeClient = mock_client.NewMockClient(ctrl)
+		dcrh = newDeviceConfigReconcilerHelper(kubeClient, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, false, true)
+	})
+
+	ctx := context.Background()
+	enable := true
+	devConfig := &amdv1alpha1.DeviceConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      devConfigName,
+			Namespace: devConfigNamespace,
+		},
+		Spec: amdv1alpha1.DeviceConfigSpec{
+			Selector: map[string]string{utils.NodeFeatureLabelAmdGpu: "true"},
+			NodeFeatureRule: amdv1alpha1.NodeFeatureRuleSpec{
+				Enable:    &enable,
+				DeviceIDs: []string{"75A3", utils.DefaultPFDeviceIDs[0]},
+			},
+		},
+	}
+	newNodeFeature := func(nodeName string, devices ...map[string]interface{}) unstructured.Unstructured {
+		elements := []interface{}{}
+		for _, device := range devices {
+			elements = append(elements, map[string]interface{}{"attributes": device})
+		}
+		feature := unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"features": map[string]interface{}{
+					"instances": map[string]interface{}{
+						"pci.device": map[string]interface{}{"elements": elements},
+					},
+				},
+			},
+		}}
+		feature.SetLabels(map[string]string{nfdNodeNameLabelKey: nodeName})
+		return feature
+	}
+
+	It("adds the device IDs of the spec to the ones known by the operator", func() {
+		rules, _, _ := unstructured.NestedSlice(getNodeFeatureRuleSpec(devConfig), "rules")
+		Expect(rules).To(HaveLen(2))
+		values, _, _ := unstructured.NestedSlice(rules[0].(map[string]interface{}), "matchFeatures")
+		deviceIDs, _, _ := unstructured.NestedStringSlice(values[0].(map[string]interface{}), "matchExpressions", "device", "value")
+		Expect(deviceIDs).To(HaveLen(len(utils.DefaultPFDeviceIDs) + 1))
+		Expect(deviceIDs[len(deviceIDs)-1]).To(Equal("75a3"))
+	})
+
+	It("does not take over a NodeFeatureRule it does not manage", func() {
+		kubeClient.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Do(
+			func(_ interface{}, _ types.NamespacedName, obj *unstructured.Unstructured, _ ...client.GetOption) {
+				obj.SetResourceVersion("1")
+				obj.SetLabels(map[string]string{deviceClassOwnerNameLabelKey: "other", deviceClassOwnerNamespaceLabelKey: devConfigNamespace})
+			},
+		).Return(nil)
+
+		Expect(dcrh.handleNodeFeatureRule(ctx, devConfig)).ToNot(Succeed())
+	})
+
+	It("reports the AMD GPUs of the nodes that are not labelled", func() {
+		features := &unstructured.UnstructuredList{Items: []unstructured.Unstructured{
+			newNodeFeature("labelled", map[string]interface{}{"vendor": amdPCIVendorID, "class": "0380", "device": "74a1"}),
+			newNodeFeature("unlabelled",
+				map[string]interface{}{"vendor": amdPCIVendorID, "class": "1200", "device": "75a3"},
+				map[string]interface{}{"vendor": amdPCIVendorID, "class": "0403", "device": "ab20"},
+				map[string]interface{}{"vendor": "8086", "class": "0300", "device": "56a0"}),
+		}}
+		nodes := &v1.NodeList{Items: []v1.Node{
+			{ObjectMeta: metav1.ObjectMeta{Name: "labelled", Labels: map[string]string{utils.NodeFeatureLabelAmdGpu: "true"}}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "unlabelled"}},
+		}}
+
+		gomock.InOrder(
+			kubeClient.EXPECT().List(ctx, gomock.Any(), gomock.Any()).Do(
+				func(_ interface{}, list *unstructured.UnstructuredList, _ ...client.ListOption) {
+					list.Items = features.Items
+				},
+			).Return(nil),
+			kubeClient.EXPECT().List(ctx, gomock.Any()).Do(
+				func(_ interface{}, list *v1.NodeList, _ ...client.ListOption) {
+					list.Items = nodes.Items
+				},
+			).Return(nil),
+		)
+
+		reason, msg, err := dcrh.diagnoseNoMatchingNodes(ctx, devConfig)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reason).To(Equal(conditions.UnmatchedGPUDevices))
+		Expect(msg).To(ContainSubstring("unlabelled (1002:75a3)"))
+		Expect(msg).ToNot(ContainSubstring("labelled (1002:74a1)"))
+	})
+
+	It("reports the AMD GPUs of the nodes that are not labelled while other nodes match the selector", func() {
+		reportedConfig := devConfig.DeepCopy()
+		features := []unstructured.Unstructured{
+			newNodeFeature("unlabelled", map[string]interface{}{"vendor": amdPCIVendorID, "class": "1200", "device": "75a3"}),
+		}
+		nodes := []v1.Node{{ObjectMeta: metav1.ObjectMeta{Name: "unlabelled"}}}
+
+		gomock.InOrder(
+			kubeClient.EXPECT().List(ctx, gomock.Any(), gomock.Any()).Do(
+				func(_ interface{}, list *unstructured.UnstructuredList, _ ...client.ListOption) {
+					list.Items = features
+				},
+			).Return(nil),
+			kubeClient.EXPECT().List(ctx, gomock.Any()).Do(
+				func(_ interface{}, list *v1.NodeList, _ ...client.ListOption) {
+					list.Items = nodes
+				},
+			).Return(nil),
+			// NFD is uninstalled
+			kubeClient.EXPECT().List(ctx, gomock.Any(), gomock.Any()).Return(&meta.NoKindMatchError{}),
+		)
+
+		Expect(dcrh.handleUnmatchedGPUDevices(ctx, reportedConfig)).To(Succeed())
+		condition := meta.FindStatusCondition(reportedConfig.Status.Conditions, conditions.ConditionTypeUnmatchedGPUDevices)
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.Message).To(ContainSubstring("unlabelled (1002:75a3)"))
+
+		// the last lookup is reused until the check interval elapses
+		Expect(dcrh.handleUnmatchedGPUDevices(ctx, reportedConfig)).To(Succeed())
+		Expect(meta.FindStatusCondition(reportedConfig.Status.Conditions, conditions.ConditionTypeUnmatchedGPUDevices)).ToNot(BeNil())
+
+		dcrh.(*deviceConfigReconcilerHelper).unmatchedGPUDevices.checkedAt = time.Now().Add(-unmatchedGPUDevicesCheckInterval)
+		Expect(dcrh.handleUnmatchedGPUDevices(ctx, reportedConfig)).To(Succeed())
+		Expect(meta.FindStatusCondition(reportedConfig.Status.Conditions, conditions.ConditionTypeUnmatchedGPUDevices)).To(BeNil())
+	})
+})
+
+var _ = Describe("findDeviceConfigsForConfigMap", func() {
+	It("only reconciles the DeviceConfigs using the ConfigMap to build or look up driver images", func() {
+		ctrl := gomock.NewController(GinkgoT())
+		kubeClient := mock_client.NewMockClient(ctrl)
+		dcrh := &deviceConfigReconcilerHelper{client: kubeClient, namespace: devConfigNamespace}
+
+		templates := amdv1alpha1.DeviceConfig{ObjectMeta: metav1.ObjectMeta{Namespace: devConfigNamespace, Name: "templates"}}
+		templates.Spec.Driver.ImageBuild.DockerfileTemplates = &amdv1alpha1.DockerfileTemplatesSpec{
+			ConfigMap: v1.LocalObjectReference{Name: "shared"},
+		}
+		catalog := amdv1alpha1.DeviceConfig{ObjectMeta: metav1.ObjectMeta{Namespace: devConfigNamespace, Name: "catalog"}}
+		catalog.Spec.Driver.ImageCatalog = &v1.LocalObjectReference{Name: "shared"}
+		other := amdv1alpha1.DeviceConfig{ObjectMeta: metav1.ObjectMeta{Namespace: devConfigNamespace, Name: "other"}}
+		other.Spec.Driver.ImageCatalog = &v1.LocalObjectReference{Name: "other-catalog"}
+
+		kubeClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, list client.ObjectList, _ ...client.ListOption) error {
+				list.(*amdv1alpha1.DeviceConfigList).Items = []amdv1alpha1.DeviceConfig{templates, catalog, other}
+				return nil
+			})
+
+		cm := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: devConfigNamespace, Name: "shared"}}
+		reqs := dcrh.findDeviceConfigsForConfigMap(context.TODO(), cm)
+		Expect(reqs).To(ConsistOf(
+			reconcile.Request{NamespacedName: types.NamespacedName{Namespace: devConfigNamespace, Name: "templates"}},
+			reconcile.Request{NamespacedName: types.NamespacedName{Namespace: devConfigNamespace, Name: "catalog"}},
+		))
+
+		// ConfigMaps of other namespaces are ignored
+		cm.Namespace = "other"
+		Expect(dcrh.findDeviceConfigsForConfigMap(context.TODO(), cm)).To(BeEmpty())
+	})
+})
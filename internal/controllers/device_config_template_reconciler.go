@@ -0,0 +1,427 @@
+/*
+Copyright (c) Advanced Micro Devices, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the \"License\");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an \"AS IS\" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	amdv1alpha1 "github.com/ROCm/gpu-operator/api/v1alpha1"
+	"github.com/ROCm/gpu-operator/internal/conditions"
+	"github.com/ROCm/gpu-operator/internal/controllers/watchers"
+)
+
+const (
+	DeviceConfigTemplateReconcilerName = "DeviceConfigTemplateReconciler"
+	deviceConfigTemplateFinalizer      = "amd.node.kubernetes.io/deviceconfigtemplate-finalizer"
+	// label set on the DeviceConfigs rendered from a template, value is the template name
+	deviceConfigTemplateLabelKey = "operator.amd.com/deviceconfig-template"
+	// annotation set on the DeviceConfigs rendered from a template, value is the hash of the rendered spec
+	deviceConfigTemplateSpecHashAnnotation = "operator.amd.com/deviceconfig-template-spec-hash"
+	// node label recording the node group of a node, the template name is appended to the prefix
+	nodeGroupLabelPrefix = "nodegroup.operator.amd.com/"
+	// node group of the nodes not matched by any override
+	defaultNodeGroup = "default"
+)
+
+// DeviceConfigTemplateReconciler renders the DeviceConfigs of the node groups defined by a DeviceConfigTemplate
+type DeviceConfigTemplateReconciler struct {
+	client.Client
+	scheme   *runtime.Scheme
+	recorder record.EventRecorder
+}
+
+func NewDeviceConfigTemplateReconciler(client client.Client, scheme *runtime.Scheme, recorder record.EventRecorder) *DeviceConfigTemplateReconciler {
+	return &DeviceConfigTemplateReconciler{
+		Client:   client,
+		scheme:   scheme,
+		recorder: recorder,
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *DeviceConfigTemplateReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(DeviceConfigTemplateReconcilerName).
+		For(&amdv1alpha1.DeviceConfigTemplate{}, builder.WithPredicates(watchers.SpecChangedOrDeletionPredicate{})).
+		// revert manual changes of the rendered DeviceConfigs
+		Owns(&amdv1alpha1.DeviceConfig{}, builder.WithPredicates(watchers.SpecChangedOrDeletionPredicate{})).
+		Watches( // nodes moving between node groups
+			&v1.Node{},
+			handler.EnqueueRequestsFromMapFunc(r.findTemplatesForNode),
+			builder.WithPredicates(predicate.LabelChangedPredicate{}),
+		).Complete(r)
+}
+
+func (r *DeviceConfigTemplateReconciler) findTemplatesForNode(ctx context.Context, obj client.Object) []reconcile.Request {
+	templates := &amdv1alpha1.DeviceConfigTemplateList{}
+	if err := r.List(ctx, templates); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list DeviceConfigTemplates")
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, tmpl := range templates.Items {
+		selector := labels.SelectorFromSet(labels.Set(tmpl.Spec.Template.Selector))
+		if _, ok := obj.GetLabels()[getNodeGroupLabelKey(&tmpl)]; ok || selector.Matches(labels.Set(obj.GetLabels())) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&tmpl)})
+		}
+	}
+	return requests
+}
+
+//+kubebuilder:rbac:groups=amd.com,resources=deviceconfigtemplates,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=amd.com,resources=deviceconfigtemplates/status,verbs=get;patch;update
+//+kubebuilder:rbac:groups=amd.com,resources=deviceconfigtemplates/finalizers,verbs=update
+//+kubebuilder:rbac:groups=amd.com,resources=deviceconfigs,verbs=delete
+
+func (r *DeviceConfigTemplateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	tmpl := &amdv1alpha1.DeviceConfigTemplate{}
+	if err := r.Get(ctx, req.NamespacedName, tmpl); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to get DeviceConfigTemplate %s: %v", req.Name, err)
+	}
+
+	if tmpl.GetDeletionTimestamp() != nil {
+		// the rendered DeviceConfigs are garbage collected through their owner reference
+		if err := r.updateNodeGroupLabels(ctx, tmpl, nil); err != nil {
+			return ctrl.Result{}, err
+		}
+		if controllerutil.RemoveFinalizer(tmpl, deviceConfigTemplateFinalizer) {
+			if err := r.Update(ctx, tmpl); err != nil {
+				return ctrl.Result{}, fmt.Errorf("failed to remove finalizer from DeviceConfigTemplate %s: %v", tmpl.Name, err)
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if controllerutil.AddFinalizer(tmpl, deviceConfigTemplateFinalizer) {
+		if err := r.Update(ctx, tmpl); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to add finalizer to DeviceConfigTemplate %s: %v", tmpl.Name, err)
+		}
+	}
+
+	if err := validateDeviceConfigTemplate(tmpl); err != nil {
+		r.recorder.Event(tmpl, v1.EventTypeWarning, EventReasonValidationFailed, fmt.Sprintf("Validation failed: %v", err))
+		return ctrl.Result{}, r.updateTemplateStatus(ctx, tmpl, nil, conditions.ValidationError, fmt.Sprintf("Validation failed: %v", err))
+	}
+
+	nodeGroups, err := r.getNodeGroups(ctx, tmpl)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.updateNodeGroupLabels(ctx, tmpl, nodeGroups); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	groupStatus, err := r.renderDeviceConfigs(ctx, tmpl, nodeGroups)
+	if err != nil {
+		logger.Error(err, "failed to render DeviceConfigs", "DeviceConfigTemplate", tmpl.Name)
+		r.recorder.Event(tmpl, v1.EventTypeWarning, EventReasonDeviceConfigTemplateFailed, err.Error())
+		if errStatus := r.updateTemplateStatus(ctx, tmpl, groupStatus, conditions.ErrorStatus, err.Error()); errStatus != nil {
+			logger.Error(errStatus, "failed to update DeviceConfigTemplate status")
+		}
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, r.updateTemplateStatus(ctx, tmpl, groupStatus, "", "")
+}
+
+// getNodeGroups returns the node group of every node selected by the template, a node belongs to the first override
+// whose selector matches it, or to the default node group
+func (r *DeviceConfigTemplateReconciler) getNodeGroups(ctx context.Context, tmpl *amdv1alpha1.DeviceConfigTemplate) (map[string]string, error) {
+	nodes := &v1.NodeList{}
+	if err := r.List(ctx, nodes, client.MatchingLabels(tmpl.Spec.Template.Selector)); err != nil {
+		return nil, fmt.Errorf("failed to list nodes for DeviceConfigTemplate %s: %v", tmpl.Name, err)
+	}
+
+	nodeGroups := map[string]string{}
+	for _, node := range nodes.Items {
+		nodeGroups[node.Name] = getNodeGroup(tmpl, node.Labels)
+	}
+	return nodeGroups, nil
+}
+
+func getNodeGroup(tmpl *amdv1alpha1.DeviceConfigTemplate, nodeLabels map[string]string) string {
+	for _, override := range tmpl.Spec.Overrides {
+		if labels.SelectorFromSet(labels.Set(override.Selector)).Matches(labels.Set(nodeLabels)) {
+			return override.Name
+		}
+	}
+	return defaultNodeGroup
+}
+
+// updateNodeGroupLabels labels the nodes with their node group and removes the label from the nodes left the template
+func (r *DeviceConfigTemplateReconciler) updateNodeGroupLabels(ctx context.Context, tmpl *amdv1alpha1.DeviceConfigTemplate, nodeGroups map[string]string) error {
+	labelKey := getNodeGroupLabelKey(tmpl)
+
+	labelledNodes := &v1.NodeList{}
+	if err := r.List(ctx, labelledNodes, client.HasLabels{labelKey}); err != nil {
+		return fmt.Errorf("failed to list nodes labelled by DeviceConfigTemplate %s: %v", tmpl.Name, err)
+	}
+	currentGroups := map[string]string{}
+	for _, node := range labelledNodes.Items {
+		currentGroups[node.Name] = node.Labels[labelKey]
+		if _, ok := nodeGroups[node.Name]; ok {
+			continue
+		}
+		if err := patchNode(ctx, r.Client, node.Name, func(node *v1.Node) {
+			delete(node.Labels, labelKey)
+		}); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to remove node group label from node %s: %v", node.Name, err)
+		}
+	}
+
+	for nodeName, group := range nodeGroups {
+		if currentGroups[nodeName] == group {
+			continue
+		}
+		if err := patchNode(ctx, r.Client, nodeName, func(node *v1.Node) {
+			node.Labels[labelKey] = group
+		}); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to set node group label on node %s: %v", nodeName, err)
+		}
+	}
+	return nil
+}
+
+// renderDeviceConfigs creates or updates the DeviceConfig of every node group and deletes the stale ones
+func (r *DeviceConfigTemplateReconciler) renderDeviceConfigs(ctx context.Context, tmpl *amdv1alpha1.DeviceConfigTemplate,
+	nodeGroups map[string]string) ([]amdv1alpha1.DeviceConfigNodeGroupStatus, error) {
+	groupNodes := map[string][]string{}
+	for nodeName, group := range nodeGroups {
+		groupNodes[group] = append(groupNodes[group], nodeName)
+	}
+
+	groups := []*amdv1alpha1.DeviceConfigOverride{}
+	for i := range tmpl.Spec.Overrides {
+		groups = append(groups, &tmpl.Spec.Overrides[i])
+	}
+	// default node group, rendered from the base spec
+	groups = append(groups, nil)
+
+	groupStatus := []amdv1alpha1.DeviceConfigNodeGroupStatus{}
+	desired := sets.New[string]()
+	for _, override := range groups {
+		group := defaultNodeGroup
+		if override != nil {
+			group = override.Name
+		}
+		spec, err := getNodeGroupSpec(tmpl, override)
+		if err != nil {
+			return groupStatus, fmt.Errorf("failed to render node group %s: %v", group, err)
+		}
+		specHash, err := getDeviceConfigSpecHash(spec)
+		if err != nil {
+			return groupStatus, fmt.Errorf("failed to hash the spec of node group %s: %v", group, err)
+		}
+		devConfigName := getNodeGroupDeviceConfigName(tmpl, group)
+		desired.Insert(devConfigName)
+
+		devConfig := &amdv1alpha1.DeviceConfig{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      devConfigName,
+				Namespace: tmpl.Spec.Namespace,
+			},
+		}
+		if _, err := controllerutil.CreateOrPatch(ctx, r.Client, devConfig, func() error {
+			if devConfig.GetResourceVersion() != "" && devConfig.Labels[deviceConfigTemplateLabelKey] != tmpl.Name {
+				return fmt.Errorf("DeviceConfig %s/%s already exists and is not managed by the template", devConfig.Namespace, devConfig.Name)
+			}
+			if devConfig.Labels == nil {
+				devConfig.Labels = map[string]string{}
+			}
+			devConfig.Labels[deviceConfigTemplateLabelKey] = tmpl.Name
+			if devConfig.Annotations == nil {
+				devConfig.Annotations = map[string]string{}
+			}
+			devConfig.Annotations[deviceConfigTemplateSpecHashAnnotation] = specHash
+			devConfig.Spec = *spec
+			return controllerutil.SetControllerReference(tmpl, devConfig, r.scheme)
+		}); err != nil {
+			return groupStatus, fmt.Errorf("failed to apply DeviceConfig of node group %s: %v", group, err)
+		}
+
+		nodes := groupNodes[group]
+		sort.Strings(nodes)
+		groupStatus = append(groupStatus, amdv1alpha1.DeviceConfigNodeGroupStatus{
+			Name:         group,
+			DeviceConfig: devConfigName,
+			Nodes:        nodes,
+			SpecHash:     specHash,
+		})
+	}
+
+	devConfigs := &amdv1alpha1.DeviceConfigList{}
+	if err := r.List(ctx, devConfigs, client.MatchingLabels{deviceConfigTemplateLabelKey: tmpl.Name}); err != nil {
+		return groupStatus, fmt.Errorf("failed to list DeviceConfigs of DeviceConfigTemplate %s: %v", tmpl.Name, err)
+	}
+	for i := range devConfigs.Items {
+		devConfig := &devConfigs.Items[i]
+		if desired.Has(devConfig.Name) && devConfig.Namespace == tmpl.Spec.Namespace {
+			continue
+		}
+		if err := r.Delete(ctx, devConfig); client.IgnoreNotFound(err) != nil {
+			return groupStatus, fmt.Errorf("failed to delete stale DeviceConfig %s/%s: %v", devConfig.Namespace, devConfig.Name, err)
+		}
+	}
+
+	return groupStatus, nil
+}
+
+// getDeviceConfigSpecHash returns the hash of the DeviceConfig spec
+func getDeviceConfigSpecHash(spec *amdv1alpha1.DeviceConfigSpec) (string, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+	hash := fnv.New64a()
+	hash.Write(data)
+	return fmt.Sprintf("%016x", hash.Sum64()), nil
+}
+
+// getNodeGroupSpec returns the effective spec of a node group, the base spec patched by the override.
+// The selector always picks the nodes labelled with the node group, so that node groups never overlap
+func getNodeGroupSpec(tmpl *amdv1alpha1.DeviceConfigTemplate, override *amdv1alpha1.DeviceConfigOverride) (*amdv1alpha1.DeviceConfigSpec, error) {
+	spec := tmpl.Spec.Template.DeepCopy()
+	group := defaultNodeGroup
+	if override != nil {
+		group = override.Name
+		if len(override.Patch.Raw) > 0 {
+			base, err := json.Marshal(spec)
+			if err != nil {
+				return nil, err
+			}
+			patched, err := strategicpatch.StrategicMergePatch(base, override.Patch.Raw, amdv1alpha1.DeviceConfigSpec{})
+			if err != nil {
+				return nil, fmt.Errorf("failed to apply patch: %v", err)
+			}
+			spec = &amdv1alpha1.DeviceConfigSpec{}
+			if err := json.Unmarshal(patched, spec); err != nil {
+				return nil, fmt.Errorf("failed to decode patched spec: %v", err)
+			}
+		}
+	}
+
+	spec.Selector = map[string]string{}
+	maps.Copy(spec.Selector, tmpl.Spec.Template.Selector)
+	spec.Selector[getNodeGroupLabelKey(tmpl)] = group
+	return spec, nil
+}
+
+func validateDeviceConfigTemplate(tmpl *amdv1alpha1.DeviceConfigTemplate) error {
+	if errs := validation.IsQualifiedName(getNodeGroupLabelKey(tmpl)); len(errs) > 0 {
+		return fmt.Errorf("template name %s cannot be used in the node group label: %v", tmpl.Name, errs)
+	}
+	if tmpl.Spec.Namespace == "" {
+		return fmt.Errorf("namespace of the rendered DeviceConfigs is required")
+	}
+
+	names := sets.New(defaultNodeGroup)
+	for _, override := range tmpl.Spec.Overrides {
+		if names.Has(override.Name) {
+			return fmt.Errorf("override name %s is reserved or duplicated", override.Name)
+		}
+		names.Insert(override.Name)
+		if errs := validation.IsDNS1123Label(override.Name); len(errs) > 0 {
+			return fmt.Errorf("invalid override name %s: %v", override.Name, errs)
+		}
+		if len(override.Selector) == 0 {
+			return fmt.Errorf("override %s requires a node selector", override.Name)
+		}
+		if len(override.Patch.Raw) == 0 {
+			continue
+		}
+		patch := map[string]any{}
+		if err := json.Unmarshal(override.Patch.Raw, &patch); err != nil {
+			return fmt.Errorf("override %s patch is not a JSON object: %v", override.Name, err)
+		}
+		if _, ok := patch["selector"]; ok {
+			return fmt.Errorf("override %s patch cannot change the selector, use the override selector instead", override.Name)
+		}
+	}
+	return nil
+}
+
+func (r *DeviceConfigTemplateReconciler) updateTemplateStatus(ctx context.Context, tmpl *amdv1alpha1.DeviceConfigTemplate,
+	groupStatus []amdv1alpha1.DeviceConfigNodeGroupStatus, errReason, errMessage string) error {
+	if groupStatus != nil {
+		tmpl.Status.NodeGroups = groupStatus
+	}
+	tmpl.Status.ObservedGeneration = tmpl.Generation
+
+	if errReason != "" {
+		meta.SetStatusCondition(&tmpl.Status.Conditions, metav1.Condition{
+			Type:    conditions.ConditionTypeError,
+			Status:  metav1.ConditionTrue,
+			Reason:  errReason,
+			Message: errMessage,
+		})
+		meta.SetStatusCondition(&tmpl.Status.Conditions, metav1.Condition{
+			Type:   conditions.ConditionTypeReady,
+			Status: metav1.ConditionFalse,
+			Reason: conditions.ReadyStatus,
+		})
+	} else {
+		meta.RemoveStatusCondition(&tmpl.Status.Conditions, conditions.ConditionTypeError)
+		meta.SetStatusCondition(&tmpl.Status.Conditions, metav1.Condition{
+			Type:   conditions.ConditionTypeReady,
+			Status: metav1.ConditionTrue,
+			Reason: conditions.ReadyStatus,
+		})
+	}
+
+	if err := r.Status().Update(ctx, tmpl); err != nil {
+		return fmt.Errorf("failed to update status of DeviceConfigTemplate %s: %v", tmpl.Name, err)
+	}
+	return nil
+}
+
+func getNodeGroupLabelKey(tmpl *amdv1alpha1.DeviceConfigTemplate) string {
+	return nodeGroupLabelPrefix + tmpl.Name
+}
+
+func getNodeGroupDeviceConfigName(tmpl *amdv1alpha1.DeviceConfigTemplate, group string) string {
+	return fmt.Sprintf("%s-%s", tmpl.Name, group)
+}
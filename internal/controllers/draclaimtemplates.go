@@ -0,0 +1,188 @@
+/*
+Copyright (c) Advanced Micro Devices, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the \"License\");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an \"AS IS\" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	amdv1alpha1 "github.com/ROCm/gpu-operator/api/v1alpha1"
+)
+
+// claimTemplateRequestName is the name of the device request of the generated ResourceClaimTemplates
+const claimTemplateRequestName = "gpu"
+
+func (dcrh *deviceConfigReconcilerHelper) handleResourceClaimTemplates(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig) error {
+	if !devConfig.Spec.DRADriver.IsEnabled() {
+		return dcrh.deleteResourceClaimTemplates(ctx, devConfig, nil)
+	}
+
+	logger := log.FromContext(ctx)
+	apiVersion := discoverDRAAPIVersion()
+
+	desired := map[types.NamespacedName]bool{}
+	for _, templateSpec := range devConfig.Spec.DRADriver.ClaimTemplates {
+		namespaces := templateSpec.Namespaces
+		if len(namespaces) == 0 {
+			namespaces = []string{devConfig.Namespace}
+		}
+		for _, namespace := range namespaces {
+			key := types.NamespacedName{Namespace: namespace, Name: templateSpec.Name}
+			desired[key] = true
+
+			rct := newResourceClaimTemplate(apiVersion, key)
+			opRes, err := controllerutil.CreateOrPatch(ctx, dcrh.client, rct, func() error {
+				rctLabels := rct.GetLabels()
+				if rctLabels == nil {
+					rctLabels = map[string]string{}
+				}
+				// templates may be created out of the DeviceConfig namespace, the labels track the owner instead of an owner reference
+				if rct.GetResourceVersion() != "" && (rctLabels[deviceClassOwnerNameLabelKey] != devConfig.Name || rctLabels[deviceClassOwnerNamespaceLabelKey] != devConfig.Namespace) {
+					return fmt.Errorf("ResourceClaimTemplate %s already exists and is not managed by DeviceConfig %s/%s", key, devConfig.Namespace, devConfig.Name)
+				}
+				rctLabels["app.kubernetes.io/component"] = "amd-gpu"
+				rctLabels["app.kubernetes.io/part-of"] = "amd-gpu"
+				rctLabels[deviceClassOwnerNameLabelKey] = devConfig.Name
+				rctLabels[deviceClassOwnerNamespaceLabelKey] = devConfig.Namespace
+				rct.SetLabels(rctLabels)
+				rct.Object["spec"] = getResourceClaimTemplateSpec(apiVersion, templateSpec)
+				return nil
+			})
+			if err != nil {
+				if k8serrors.IsNotFound(err) {
+					logger.Info(fmt.Sprintf("skipping ResourceClaimTemplate %s, namespace %s not found", key, namespace))
+					continue
+				}
+				return fmt.Errorf("failed to reconcile ResourceClaimTemplate %s: %v", key, err)
+			}
+			logger.Info("Reconciled ResourceClaimTemplate", "namespace", namespace, "name", templateSpec.Name, "apiVersion", apiVersion, "result", opRes)
+		}
+	}
+
+	return dcrh.deleteResourceClaimTemplates(ctx, devConfig, desired)
+}
+
+// deleteResourceClaimTemplates deletes the ResourceClaimTemplates created for the DeviceConfig, except the ones to keep
+func (dcrh *deviceConfigReconcilerHelper) deleteResourceClaimTemplates(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, keep map[types.NamespacedName]bool) error {
+	logger := log.FromContext(ctx)
+
+	rctList := &unstructured.UnstructuredList{}
+	rctList.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "resource.k8s.io",
+		Version: discoverDRAAPIVersion(),
+		Kind:    "ResourceClaimTemplateList",
+	})
+	if err := dcrh.client.List(ctx, rctList, client.InNamespace(metav1.NamespaceAll), client.MatchingLabels{
+		deviceClassOwnerNameLabelKey:      devConfig.Name,
+		deviceClassOwnerNamespaceLabelKey: devConfig.Namespace,
+	}); err != nil {
+		if meta.IsNoMatchError(err) {
+			// DRA is not served by the cluster, nothing to clean up
+			return nil
+		}
+		return fmt.Errorf("failed to list ResourceClaimTemplates: %v", err)
+	}
+
+	for i := range rctList.Items {
+		rct := &rctList.Items[i]
+		if keep[types.NamespacedName{Namespace: rct.GetNamespace(), Name: rct.GetName()}] {
+			continue
+		}
+		logger.Info("deleting ResourceClaimTemplate", "namespace", rct.GetNamespace(), "name", rct.GetName())
+		if err := dcrh.client.Delete(ctx, rct); err != nil && !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete ResourceClaimTemplate %s/%s: %v", rct.GetNamespace(), rct.GetName(), err)
+		}
+	}
+	return nil
+}
+
+func newResourceClaimTemplate(apiVersion string, key types.NamespacedName) *unstructured.Unstructured {
+	rct := &unstructured.Unstructured{}
+	rct.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "resource.k8s.io",
+		Version: apiVersion,
+		Kind:    "ResourceClaimTemplate",
+	})
+	rct.SetNamespace(key.Namespace)
+	rct.SetName(key.Name)
+	return rct
+}
+
+// getResourceClaimTemplateSpec returns the ResourceClaimTemplate spec of the given DRA API version,
+// v1beta1 inlines the request fields while later versions nest them under exactly
+func getResourceClaimTemplateSpec(apiVersion string, templateSpec amdv1alpha1.DRAClaimTemplateSpec) map[string]interface{} {
+	deviceClass := templateSpec.DeviceClassName
+	if deviceClass == "" {
+		deviceClass = deviceClassName
+	}
+	allocationMode := templateSpec.AllocationMode
+	if allocationMode == "" {
+		allocationMode = amdv1alpha1.DRAClaimAllocationModeExactCount
+	}
+
+	deviceRequest := map[string]interface{}{
+		"deviceClassName": deviceClass,
+		"allocationMode":  string(allocationMode),
+	}
+	if allocationMode == amdv1alpha1.DRAClaimAllocationModeExactCount {
+		count := templateSpec.Count
+		if count < 1 {
+			count = 1
+		}
+		deviceRequest["count"] = count
+	}
+	if len(templateSpec.Selectors) > 0 {
+		selectors := []interface{}{}
+		for _, expression := range templateSpec.Selectors {
+			selectors = append(selectors, map[string]interface{}{
+				"cel": map[string]interface{}{
+					"expression": expression,
+				},
+			})
+		}
+		deviceRequest["selectors"] = selectors
+	}
+
+	request := map[string]interface{}{
+		"name": claimTemplateRequestName,
+	}
+	if apiVersion == "v1beta1" {
+		for k, v := range deviceRequest {
+			request[k] = v
+		}
+	} else {
+		request["exactly"] = deviceRequest
+	}
+
+	return map[string]interface{}{
+		"spec": map[string]interface{}{
+			"devices": map[string]interface{}{
+				"requests": []interface{}{request},
+			},
+		},
+	}
+}
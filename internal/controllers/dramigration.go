@@ -0,0 +1,312 @@
+/*
+Copyright (c) Advanced Micro Devices, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the \"License\");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an \"AS IS\" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	amdv1alpha1 "github.com/ROCm/gpu-operator/api/v1alpha1"
+	utils "github.com/ROCm/gpu-operator/internal"
+)
+
+const (
+	// draMigrationTaintKey keeps new pods off the node while it is switched to the DRA driver
+	draMigrationTaintKey = "amd-gpu-dra-migration"
+
+	draMigrationStateAnnotation     = "operator.amd.com/gpu-dra-migration-state"
+	draMigrationStartTimeAnnotation = "operator.amd.com/gpu-dra-migration-start-time"
+	draMigrationMessageAnnotation   = "operator.amd.com/gpu-dra-migration-message"
+
+	defaultDRAMigrationTimeoutSeconds = 1800
+	draMigrationPollInterval          = 10 * time.Second
+	draMigrationRequeueInterval       = 30 * time.Second
+)
+
+var draMigrationTaint = v1.Taint{
+	Key:    draMigrationTaintKey,
+	Value:  "true",
+	Effect: v1.TaintEffectNoSchedule,
+}
+
+type draMigrationMgr struct {
+	client       client.Client
+	k8sInterface kubernetes.Interface
+	recorder     record.EventRecorder
+	nodeOp       *nodeOperation
+}
+
+//go:generate mockgen -source=dramigration.go -package=controllers -destination=mock_dramigration.go draMigrationMgrAPI
+type draMigrationMgrAPI interface {
+	HandleMigration(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) (ctrl.Result, error)
+	HandleDelete(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) (ctrl.Result, error)
+}
+
+func newDRAMigrationMgrHandler(client client.Client, k8sConfig *rest.Config, recorder record.EventRecorder) draMigrationMgrAPI {
+	k8sIntf, err := kubernetes.NewForConfig(k8sConfig)
+	if err != nil {
+		return nil
+	}
+	return newDRAMigrationMgr(client, k8sIntf, recorder)
+}
+
+func newDRAMigrationMgr(client client.Client, k8sIntf kubernetes.Interface, recorder record.EventRecorder) *draMigrationMgr {
+	return &draMigrationMgr{
+		client:       client,
+		k8sInterface: k8sIntf,
+		recorder:     recorder,
+		nodeOp: &nodeOperation{
+			client:              client,
+			k8sInterface:        k8sIntf,
+			nodeInProgress:      new(sync.Map),
+			name:                "DRA migration",
+			taint:               draMigrationTaint,
+			stateAnnotation:     draMigrationStateAnnotation,
+			startTimeAnnotation: draMigrationStartTimeAnnotation,
+			messageAnnotation:   draMigrationMessageAnnotation,
+			pollInterval:        draMigrationPollInterval,
+		},
+	}
+}
+
+// HandleMigration switches the nodes selected by the migration from the device plugin to the DRA driver. A node is
+// cordoned and the pods requesting amd.com GPU resources are drained, then the node is labelled to move it from the
+// device plugin DaemonSet to the DRA driver DaemonSet, and the node is released once the DRA driver runs on it
+func (d *draMigrationMgr) HandleMigration(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) (ctrl.Result, error) {
+	if !deviceConfig.Spec.DRADriver.IsMigrationEnabled() {
+		return d.HandleDelete(ctx, deviceConfig, nodes)
+	}
+
+	res := ctrl.Result{}
+	migrationSpec := deviceConfig.Spec.DRADriver.Migration
+	maxParallel := 1
+	if migrationSpec.MaxParallelNodes > 0 {
+		maxParallel = migrationSpec.MaxParallelNodes
+	}
+
+	sortedNodes := append([]v1.Node{}, nodes.Items...)
+	sort.Slice(sortedNodes, func(i, j int) bool { return sortedNodes[i].Name < sortedNodes[j].Name })
+
+	running := 0
+	for i := range sortedNodes {
+		if isDRAMigrationInProgress(getNodeDRAMigrationState(&sortedNodes[i])) {
+			running++
+		}
+	}
+
+	for i := range sortedNodes {
+		node := &sortedNodes[i]
+		state := getNodeDRAMigrationState(node)
+		if d.nodeOp.isRunning(node.Name) {
+			res = ctrl.Result{Requeue: true, RequeueAfter: draMigrationRequeueInterval}
+			continue
+		}
+
+		switch {
+		case isDRAMigrationInProgress(state):
+			// Operator restarted while the node was migrated, resume where it stopped
+			d.startNodeMigration(ctx, deviceConfig, node)
+			res = ctrl.Result{Requeue: true, RequeueAfter: draMigrationRequeueInterval}
+		case state == amdv1alpha1.DRAMigrationStateFailed:
+			// Failed nodes are retried once the state annotation is removed
+			continue
+		case isNodeMigratedToDRA(node):
+			continue
+		case !isNodeSelectedForDRAMigration(deviceConfig, node) && !hasTaint(node, draMigrationTaint):
+			continue
+		case running < maxParallel:
+			running++
+			d.startNodeMigration(ctx, deviceConfig, node)
+			res = ctrl.Result{Requeue: true, RequeueAfter: draMigrationRequeueInterval}
+		default:
+			// Pending until another node is done
+			res = ctrl.Result{Requeue: true, RequeueAfter: draMigrationRequeueInterval}
+		}
+	}
+
+	return res, nil
+}
+
+// HandleDelete removes the migration labels, annotations and taints once the migration is disabled
+func (d *draMigrationMgr) HandleDelete(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) (ctrl.Result, error) {
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		if getNodeDRAMigrationState(node) == "" && !hasTaint(node, draMigrationTaint) {
+			if _, ok := node.Labels[utils.GPUResourceDriverLabel]; !ok {
+				continue
+			}
+		}
+		if d.nodeOp.isRunning(node.Name) {
+			// the running migration stops at its next poll and the node is cleaned up on the next reconcile
+			return ctrl.Result{Requeue: true, RequeueAfter: draMigrationRequeueInterval}, nil
+		}
+		if err := d.nodeOp.release(ctx, node.Name, func(nodeObj *v1.Node) {
+			delete(nodeObj.Labels, utils.GPUResourceDriverLabel)
+		}); err != nil {
+			log.FromContext(ctx).Error(err, fmt.Sprintf("Node: %v failed to clean up DRA migration", node.Name))
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+func (d *draMigrationMgr) startNodeMigration(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, node *v1.Node) {
+	deviceConfigCopy, nodeCopy := *deviceConfig, *node
+	d.nodeOp.run(node.Name, func() {
+		d.handleNodeMigration(ctx, deviceConfigCopy, nodeCopy)
+	})
+}
+
+func (d *draMigrationMgr) handleNodeMigration(ctx context.Context, deviceConfig amdv1alpha1.DeviceConfig, node v1.Node) {
+	logger := log.FromContext(ctx)
+
+	state := getNodeDRAMigrationState(&node)
+	startTime, err := d.nodeOp.getStartTime(&node)
+	if !isDRAMigrationInProgress(state) || err != nil {
+		logger.Info(fmt.Sprintf("Node: %v migrating from device plugin to DRA driver", node.Name))
+		state = amdv1alpha1.DRAMigrationStateDraining
+		if startTime, err = d.nodeOp.begin(ctx, node.Name, string(state), nil); err != nil {
+			logger.Error(err, fmt.Sprintf("Node: %v failed to cordon node for DRA migration", node.Name))
+			return
+		}
+		recordEvent(d.recorder, &deviceConfig, &node, v1.EventTypeNormal, EventReasonDRAMigrationStarted,
+			fmt.Sprintf("Migration of node %v from device plugin to DRA driver started", node.Name))
+	}
+	deadline := startTime.Add(time.Duration(getDRAMigrationTimeoutSeconds(&deviceConfig)) * time.Second)
+
+	if state == amdv1alpha1.DRAMigrationStateDraining {
+		// only the pods of the device plugin resources are evicted, the pods holding DRA claims keep their GPUs
+		if err := d.nodeOp.drain(ctx, node.Name, deviceConfig.Spec.DRADriver.Migration.NodeDrainPolicy, deadline, requestsGPUResource); err != nil {
+			d.failNodeMigration(ctx, &deviceConfig, &node, fmt.Sprintf("failed to drain node: %v", err))
+			return
+		}
+		// the device plugin DaemonSet does not select the node anymore once labelled, the DRA driver DaemonSet does
+		state = amdv1alpha1.DRAMigrationStateSwitching
+		if err := d.nodeOp.setState(ctx, node.Name, string(state), func(nodeObj *v1.Node) {
+			nodeObj.Labels[utils.GPUResourceDriverLabel] = utils.GPUResourceDriverDRA
+		}); err != nil {
+			d.failNodeMigration(ctx, &deviceConfig, &node, fmt.Sprintf("failed to label node for DRA driver: %v", err))
+			return
+		}
+	}
+
+	if !d.nodeOp.poll(ctx, node.Name, deadline, func() (bool, error) {
+		return d.isNodeServedByDRA(ctx, &deviceConfig, &node)
+	}) {
+		d.failNodeMigration(ctx, &deviceConfig, &node, "DRA driver did not become ready on the node")
+		return
+	}
+
+	if err := d.nodeOp.release(ctx, node.Name, nil); err != nil {
+		logger.Error(err, fmt.Sprintf("Node: %v failed to release node after DRA migration", node.Name))
+		return
+	}
+	logger.Info(fmt.Sprintf("Node: %v migrated to DRA driver", node.Name))
+	recordEvent(d.recorder, &deviceConfig, &node, v1.EventTypeNormal, EventReasonDRAMigrationComplete,
+		fmt.Sprintf("Node %v migrated from device plugin to DRA driver", node.Name))
+}
+
+// isNodeServedByDRA returns true once the device plugin pod is gone from the node and the DRA driver pod is ready
+func (d *draMigrationMgr) isNodeServedByDRA(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, node *v1.Node) (bool, error) {
+	pods, err := d.k8sInterface.CoreV1().Pods(deviceConfig.Namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.SelectorFromSet(fields.Set{"spec.nodeName": node.Name}).String(),
+	})
+	if err != nil {
+		return false, err
+	}
+	draReady := false
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		switch {
+		case isPodOwnedByDaemonSet(pod, deviceConfig.Name+utils.DevicePluginNameSuffix):
+			return false, nil
+		case isPodOwnedByDaemonSet(pod, deviceConfig.Name+utils.DRADriverNameSuffix):
+			draReady = isPodReady(pod)
+		}
+	}
+	return draReady, nil
+}
+
+func (d *draMigrationMgr) failNodeMigration(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, node *v1.Node, reason string) {
+	d.nodeOp.fail(ctx, node.Name, string(amdv1alpha1.DRAMigrationStateFailed), reason, nil)
+	recordEvent(d.recorder, deviceConfig, node, v1.EventTypeWarning, EventReasonDRAMigrationFailed,
+		fmt.Sprintf("Migration of node %v from device plugin to DRA driver failed: %v", node.Name, reason))
+}
+
+// getDRAMigrationStatus returns the migration state of the nodes selected by the migration or already migrated
+func getDRAMigrationStatus(deviceConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) *amdv1alpha1.DRAMigrationStatus {
+	if !deviceConfig.Spec.DRADriver.IsMigrationEnabled() {
+		return nil
+	}
+	status := &amdv1alpha1.DRAMigrationStatus{Nodes: map[string]amdv1alpha1.NodeDRAMigrationStatus{}}
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		state := getNodeDRAMigrationState(node)
+		nodeStatus := amdv1alpha1.NodeDRAMigrationStatus{State: state, Message: node.Annotations[draMigrationMessageAnnotation]}
+		switch {
+		case state != "":
+		case isNodeMigratedToDRA(node):
+			nodeStatus.State = amdv1alpha1.DRAMigrationStateMigrated
+			status.Migrated++
+		case isNodeSelectedForDRAMigration(deviceConfig, node) || hasTaint(node, draMigrationTaint):
+			nodeStatus.State = amdv1alpha1.DRAMigrationStatePending
+		default:
+			continue
+		}
+		status.Nodes[node.Name] = nodeStatus
+	}
+	status.Total = len(status.Nodes)
+	return status
+}
+
+func isNodeSelectedForDRAMigration(deviceConfig *amdv1alpha1.DeviceConfig, node *v1.Node) bool {
+	return labels.SelectorFromSet(deviceConfig.Spec.DRADriver.Migration.NodeSelector).Matches(labels.Set(node.Labels))
+}
+
+// isNodeMigratedToDRA returns true if the node is switched to the DRA driver and released
+func isNodeMigratedToDRA(node *v1.Node) bool {
+	return node.Labels[utils.GPUResourceDriverLabel] == utils.GPUResourceDriverDRA &&
+		getNodeDRAMigrationState(node) == "" && !hasTaint(node, draMigrationTaint)
+}
+
+func getNodeDRAMigrationState(node *v1.Node) amdv1alpha1.DRAMigrationState {
+	return amdv1alpha1.DRAMigrationState(node.Annotations[draMigrationStateAnnotation])
+}
+
+func isDRAMigrationInProgress(state amdv1alpha1.DRAMigrationState) bool {
+	return state == amdv1alpha1.DRAMigrationStateDraining || state == amdv1alpha1.DRAMigrationStateSwitching
+}
+
+func getDRAMigrationTimeoutSeconds(deviceConfig *amdv1alpha1.DeviceConfig) int {
+	if migration := deviceConfig.Spec.DRADriver.Migration; migration != nil && migration.TimeoutSeconds > 0 {
+		return migration.TimeoutSeconds
+	}
+	return defaultDRAMigrationTimeoutSeconds
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	amdv1alpha1 "github.com/ROCm/gpu-operator/api/v1alpha1"
+	utils "github.com/ROCm/gpu-operator/internal"
+	mock_client "github.com/ROCm/gpu-operator/internal/client"
+)
+
+var _ = Describe("draMigrationMgr", func() {
+	var (
+		ctx        context.Context
+		kubeClient *mock_client.MockClient
+		clientset  *fake.Clientset
+		mgr        *draMigrationMgr
+		devConfig  *amdv1alpha1.DeviceConfig
+		nodes      map[string]*v1.Node
+		patched    map[string][]*v1.Node
+	)
+
+	newNode := func(name string, state amdv1alpha1.DRAMigrationState, nodeLabels map[string]string) *v1.Node {
+		node := &v1.Node{ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Labels:      map[string]string{"gpu": "mi300"},
+			Annotations: map[string]string{},
+		}}
+		for key, value := range nodeLabels {
+			node.Labels[key] = value
+		}
+		if state != "" {
+			node.Annotations[draMigrationStateAnnotation] = string(state)
+			node.Spec.Taints = []v1.Taint{draMigrationTaint}
+		}
+		nodes[name] = node
+		return node
+	}
+	nodeList := func(names ...string) *v1.NodeList {
+		list := &v1.NodeList{}
+		for _, name := range names {
+			list.Items = append(list.Items, *nodes[name])
+		}
+		return list
+	}
+	daemonSetPod := func(dsName string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            dsName + "-pod",
+				Namespace:       devConfigNamespace,
+				OwnerReferences: []metav1.OwnerReference{{Kind: "DaemonSet", Name: dsName}},
+			},
+			Spec:   v1.PodSpec{NodeName: "node1"},
+			Status: v1.PodStatus{Conditions: []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}}},
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		kubeClient = mock_client.NewMockClient(gomock.NewController(GinkgoT()))
+		clientset = fake.NewSimpleClientset()
+		mgr = newDRAMigrationMgr(kubeClient, clientset, record.NewFakeRecorder(10))
+		enable := true
+		devConfig = &amdv1alpha1.DeviceConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: devConfigName, Namespace: devConfigNamespace},
+			Spec: amdv1alpha1.DeviceConfigSpec{
+				DRADriver: amdv1alpha1.DRADriverSpec{
+					Enable: &enable,
+					Migration: &amdv1alpha1.DRAMigrationSpec{
+						Enable:       &enable,
+						NodeSelector: map[string]string{"gpu": "mi300"},
+					},
+				},
+			},
+		}
+		nodes = map[string]*v1.Node{}
+		patched = map[string][]*v1.Node{}
+
+		kubeClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&v1.Node{})).DoAndReturn(
+			func(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+				nodes[key.Name].DeepCopyInto(obj.(*v1.Node))
+				return nil
+			}).AnyTimes()
+		kubeClient.EXPECT().Patch(gomock.Any(), gomock.AssignableToTypeOf(&v1.Node{}), gomock.Any()).DoAndReturn(
+			func(_ context.Context, obj client.Object, _ client.Patch, _ ...client.PatchOption) error {
+				node := obj.(*v1.Node).DeepCopy()
+				nodes[node.Name] = node
+				patched[node.Name] = append(patched[node.Name], node)
+				return nil
+			}).AnyTimes()
+	})
+
+	It("skips the failed, migrated and unselected nodes", func() {
+		newNode("node1", amdv1alpha1.DRAMigrationStateFailed, nil)
+		newNode("node2", "", map[string]string{utils.GPUResourceDriverLabel: utils.GPUResourceDriverDRA})
+		newNode("node3", "", nil)
+		delete(nodes["node3"].Labels, "gpu")
+
+		res, err := mgr.HandleMigration(ctx, devConfig, nodeList("node1", "node2", "node3"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res).To(Equal(ctrl.Result{}))
+		Expect(patched).To(BeEmpty())
+	})
+
+	It("keeps the nodes pending while the parallel migrations are running", func() {
+		newNode("node1", amdv1alpha1.DRAMigrationStateSwitching, nil)
+		newNode("node2", "", nil)
+		mgr.nodeOp.nodeInProgress.Store("node1", true)
+
+		res, err := mgr.HandleMigration(ctx, devConfig, nodeList("node1", "node2"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.RequeueAfter).To(Equal(draMigrationRequeueInterval))
+		Expect(patched).To(BeEmpty())
+		Expect(mgr.nodeOp.isRunning("node2")).To(BeFalse())
+	})
+
+	It("cleans up the nodes once the migration is disabled", func() {
+		newNode("node1", amdv1alpha1.DRAMigrationStateFailed, map[string]string{utils.GPUResourceDriverLabel: utils.GPUResourceDriverDRA})
+		newNode("node2", "", nil)
+		devConfig.Spec.DRADriver.Migration = nil
+
+		res, err := mgr.HandleMigration(ctx, devConfig, nodeList("node1", "node2"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res).To(Equal(ctrl.Result{}))
+		Expect(patched).To(HaveLen(1))
+		Expect(nodes["node1"].Annotations).To(BeEmpty())
+		Expect(nodes["node1"].Labels).NotTo(HaveKey(utils.GPUResourceDriverLabel))
+		Expect(nodes["node1"].Spec.Taints).To(BeEmpty())
+	})
+
+	It("switches the node to the DRA driver and releases it once the DRA driver is ready", func() {
+		newNode("node1", "", nil)
+		_, err := clientset.CoreV1().Pods(devConfigNamespace).Create(ctx, daemonSetPod(devConfigName+utils.DRADriverNameSuffix), metav1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		mgr.handleNodeMigration(ctx, *devConfig, *nodes["node1"])
+		Expect(patched["node1"]).To(HaveLen(3))
+		Expect(patched["node1"][0].Annotations).To(HaveKeyWithValue(draMigrationStateAnnotation, string(amdv1alpha1.DRAMigrationStateDraining)))
+		Expect(patched["node1"][0].Spec.Taints).To(ContainElement(draMigrationTaint))
+		Expect(patched["node1"][1].Annotations).To(HaveKeyWithValue(draMigrationStateAnnotation, string(amdv1alpha1.DRAMigrationStateSwitching)))
+		Expect(isNodeMigratedToDRA(nodes["node1"])).To(BeTrue())
+	})
+
+	It("marks the node as failed when it cannot be drained", func() {
+		newNode("node1", "", nil)
+		clientset.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, errors.New("list failed")
+		})
+
+		mgr.handleNodeMigration(ctx, *devConfig, *nodes["node1"])
+		node := nodes["node1"]
+		Expect(getNodeDRAMigrationState(node)).To(Equal(amdv1alpha1.DRAMigrationStateFailed))
+		Expect(node.Annotations[draMigrationMessageAnnotation]).To(ContainSubstring("list failed"))
+		Expect(node.Labels).NotTo(HaveKey(utils.GPUResourceDriverLabel))
+		// the node stays cordoned to be inspected
+		Expect(node.Spec.Taints).To(ConsistOf(draMigrationTaint))
+	})
+})
@@ -0,0 +1,496 @@
+/*
+Copyright (c) Advanced Micro Devices, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the \"License\");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an \"AS IS\" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+
+	amdv1alpha1 "github.com/ROCm/gpu-operator/api/v1alpha1"
+	utils "github.com/ROCm/gpu-operator/internal"
+	"github.com/ROCm/gpu-operator/internal/configmanager"
+	"github.com/ROCm/gpu-operator/internal/kmmmodule"
+)
+
+const (
+	dryRunPlanConfigMapSuffix = "-dry-run-plan"
+	dryRunPlanConfigMapKey    = "plan.yaml"
+
+	dryRunActionCreate = "create"
+	dryRunActionUpdate = "update"
+	dryRunActionRoll   = "roll"
+	dryRunActionDelete = "delete"
+)
+
+// dryRunPlan lists the actions the reconciler would take to apply the DeviceConfig spec
+type dryRunPlan struct {
+	DeviceConfig  string                               `json:"deviceConfig"`
+	Generation    int64                                `json:"generation"`
+	GeneratedAt   string                               `json:"generatedAt"`
+	Nodes         dryRunNodesPlan                      `json:"nodes"`
+	DriverUpgrade *dryRunDriverUpgradePlan             `json:"driverUpgrade,omitempty"`
+	KMMModule     *dryRunKMMModulePlan                 `json:"kmmModule,omitempty"`
+	DaemonSets    []dryRunDaemonSetPlan                `json:"daemonSets,omitempty"`
+	Errors        []string                             `json:"errors,omitempty"`
+	Maintenance   *amdv1alpha1.MaintenanceWindowStatus `json:"maintenanceWindow,omitempty"`
+}
+
+// dryRunNodesPlan lists the nodes selected by the DeviceConfig and the changes of the selection
+type dryRunNodesPlan struct {
+	Selected int      `json:"selected"`
+	Added    []string `json:"added,omitempty"`
+	Removed  []string `json:"removed,omitempty"`
+}
+
+// dryRunDriverUpgradePlan lists the nodes getting a driver installed or upgraded, in upgrade order
+type dryRunDriverUpgradePlan struct {
+	Version             string               `json:"version"`
+	MaxParallelUpgrades int                  `json:"maxParallelUpgrades"`
+	Install             []string             `json:"install,omitempty"`
+	Batches             []dryRunUpgradeBatch `json:"batches,omitempty"`
+}
+
+// dryRunUpgradeBatch is a set of nodes upgraded in parallel, batches are upgraded one after the other
+type dryRunUpgradeBatch struct {
+	Stage string   `json:"stage"`
+	Nodes []string `json:"nodes"`
+}
+
+// dryRunKMMModulePlan lists the changes of the KMM Module kernel mappings and the driver images KMM would build
+type dryRunKMMModulePlan struct {
+	Action         string                      `json:"action,omitempty"`
+	KernelMappings []dryRunKernelMappingChange `json:"kernelMappings,omitempty"`
+	BuildImages    []string                    `json:"buildImages,omitempty"`
+}
+
+// dryRunKernelMappingChange is a kernel mapping added, changed or removed from the KMM Module
+type dryRunKernelMappingChange struct {
+	Kernel        string `json:"kernel"`
+	Action        string `json:"action"`
+	Image         string `json:"image,omitempty"`
+	PreviousImage string `json:"previousImage,omitempty"`
+	Build         bool   `json:"build,omitempty"`
+}
+
+// dryRunDaemonSetPlan is an operand DaemonSet that would be created, updated, rolled or deleted
+type dryRunDaemonSetPlan struct {
+	Name    string   `json:"name"`
+	Action  string   `json:"action"`
+	Changes []string `json:"changes,omitempty"`
+}
+
+// isDryRun returns true if the DeviceConfig spec is only planned, not applied
+func isDryRun(devConfig *amdv1alpha1.DeviceConfig) bool {
+	return devConfig.Spec.DryRun != nil && *devConfig.Spec.DryRun
+}
+
+// handleDryRun computes the plan of the DeviceConfig without applying it. Objects are only evaluated with
+// server-side dry-run requests, so that the plan accounts for API server defaulting and admission
+func (dcrh *deviceConfigReconcilerHelper) handleDryRun(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) error {
+	logger := log.FromContext(ctx)
+
+	plan := &dryRunPlan{
+		DeviceConfig: fmt.Sprintf("%s/%s", devConfig.Namespace, devConfig.Name),
+		Generation:   devConfig.Generation,
+		GeneratedAt:  time.Now().UTC().Format(time.RFC3339),
+		Nodes:        getDryRunNodesPlan(devConfig, nodes),
+	}
+	addError := func(err error) {
+		logger.Error(err, "dry run")
+		plan.Errors = append(plan.Errors, err.Error())
+	}
+
+	if len(devConfig.Spec.MaintenanceWindows) > 0 {
+		if status, err := utils.GetMaintenanceWindowStatus(devConfig.Spec.MaintenanceWindows, time.Now()); err == nil {
+			plan.Maintenance = status
+		}
+	}
+
+	if dcrh.shouldUseKMMOperatorLevel(devConfig) {
+		plan.DriverUpgrade = getDryRunDriverUpgradePlan(devConfig, nodes)
+		kmmPlan, err := dcrh.getDryRunKMMModulePlan(ctx, devConfig, nodes)
+		if err != nil {
+			addError(err)
+		}
+		plan.KMMModule = kmmPlan
+	}
+
+	for _, operand := range dcrh.getDryRunOperands(devConfig) {
+		dsPlan, err := dcrh.getDryRunDaemonSetPlan(ctx, devConfig.Namespace, operand)
+		if err != nil {
+			addError(err)
+			continue
+		}
+		if dsPlan != nil {
+			plan.DaemonSets = append(plan.DaemonSets, *dsPlan)
+		}
+	}
+
+	data, err := yaml.Marshal(plan)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dry run plan: %v", err)
+	}
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: devConfig.Namespace, Name: devConfig.Name + dryRunPlanConfigMapSuffix},
+	}
+	opRes, err := controllerutil.CreateOrPatch(ctx, dcrh.client, cm, func() error {
+		cm.Data = map[string]string{dryRunPlanConfigMapKey: string(data)}
+		return controllerutil.SetControllerReference(devConfig, cm, dcrh.client.Scheme())
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write dry run plan configmap %s: %v", cm.Name, err)
+	}
+	logger.Info("Reconciled dry run plan", "namespace", cm.Namespace, "name", cm.Name, "result", opRes)
+
+	devConfig.Status.DryRunPlan = getDryRunPlanStatus(plan, cm.Name)
+	return dcrh.updateDeviceConfigStatus(ctx, devConfig)
+}
+
+func getDryRunPlanStatus(plan *dryRunPlan, cmName string) *amdv1alpha1.DryRunPlanStatus {
+	status := &amdv1alpha1.DryRunPlanStatus{
+		ConfigMap:          cmName,
+		ObservedGeneration: plan.Generation,
+	}
+	if plan.DriverUpgrade != nil {
+		status.NodesToInstall = len(plan.DriverUpgrade.Install)
+		for _, batch := range plan.DriverUpgrade.Batches {
+			status.NodesToUpgrade += len(batch.Nodes)
+		}
+	}
+	if plan.KMMModule != nil {
+		status.KernelMappingChanges = len(plan.KMMModule.KernelMappings)
+		status.BuildImages = len(plan.KMMModule.BuildImages)
+	}
+	for _, ds := range plan.DaemonSets {
+		if ds.Action == dryRunActionRoll {
+			status.DaemonSetsToRoll++
+		}
+	}
+	return status
+}
+
+// getDryRunNodesPlan compares the selected nodes with the nodes currently managed by the DeviceConfig
+func getDryRunNodesPlan(devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) dryRunNodesPlan {
+	plan := dryRunNodesPlan{Selected: len(nodes.Items)}
+	selected := map[string]bool{}
+	for _, node := range nodes.Items {
+		selected[node.Name] = true
+		if _, ok := devConfig.Status.NodeModuleStatus[node.Name]; !ok {
+			plan.Added = append(plan.Added, node.Name)
+		}
+	}
+	for nodeName := range devConfig.Status.NodeModuleStatus {
+		if !selected[nodeName] {
+			plan.Removed = append(plan.Removed, nodeName)
+		}
+	}
+	sort.Strings(plan.Added)
+	sort.Strings(plan.Removed)
+	return plan
+}
+
+// getDryRunDriverUpgradePlan returns the nodes getting the driver installed and the upgrade batches, following the
+// rollout strategy and the parallelism allowed by the upgrade policy
+func getDryRunDriverUpgradePlan(devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) *dryRunDriverUpgradePlan {
+	versionLabel, _ := kmmmodule.GetVersionLabelKV(devConfig)
+	plan := &dryRunDriverUpgradePlan{Version: devConfig.Spec.Driver.Version}
+
+	var upgradeNodes []v1.Node
+	for _, node := range nodes.Items {
+		currentVersion, ok := node.Labels[versionLabel]
+		if !ok {
+			plan.Install = append(plan.Install, node.Name)
+			continue
+		}
+		desiredVersion, _ := utils.GetDriverVersion(node, *devConfig)
+		if currentVersion != desiredVersion {
+			upgradeNodes = append(upgradeNodes, node)
+		}
+	}
+	sort.Strings(plan.Install)
+
+	if devConfig.Spec.Driver.UpgradePolicy == nil {
+		devConfig = devConfig.DeepCopy()
+		devConfig.Spec.Driver.UpgradePolicy = &amdv1alpha1.DriverUpgradePolicySpec{}
+	}
+	upgradePolicy := devConfig.Spec.Driver.UpgradePolicy
+	// the parallelism the upgrade manager allows while no node upgrade is running nor failed
+	if maxParallelUpgrades, violated := new(upgradeMgrHelper).isUpgradePolicyViolated(0, 0, len(nodes.Items), devConfig); !violated {
+		plan.MaxParallelUpgrades = maxParallelUpgrades
+	}
+	if len(upgradeNodes) == 0 || plan.MaxParallelUpgrades <= 0 {
+		return plan
+	}
+
+	type stage struct {
+		name  string
+		nodes []v1.Node
+	}
+	var stages []stage
+	if rollout := upgradePolicy.RolloutStrategy; rollout != nil {
+		var canaryNodes, otherNodes []v1.Node
+		for _, node := range upgradeNodes {
+			if rollout.Canary != nil && len(rollout.Canary.Selector) > 0 &&
+				labels.SelectorFromSet(labels.Set(rollout.Canary.Selector)).Matches(labels.Set(node.Labels)) {
+				canaryNodes = append(canaryNodes, node)
+			} else {
+				otherNodes = append(otherNodes, node)
+			}
+		}
+		if len(canaryNodes) > 0 {
+			stages = append(stages, stage{name: "canary", nodes: buildUpgradeWaves(nil, canaryNodes)[0]})
+		}
+		for i, wave := range buildUpgradeWaves(rollout.Waves, otherNodes) {
+			stages = append(stages, stage{name: fmt.Sprintf("wave %d", i+1), nodes: wave})
+		}
+	} else {
+		stages = append(stages, stage{name: "upgrade", nodes: buildUpgradeWaves(nil, upgradeNodes)[0]})
+	}
+
+	for _, s := range stages {
+		for start := 0; start < len(s.nodes); start += plan.MaxParallelUpgrades {
+			batch := dryRunUpgradeBatch{Stage: s.name}
+			for _, node := range s.nodes[start:min(start+plan.MaxParallelUpgrades, len(s.nodes))] {
+				batch.Nodes = append(batch.Nodes, node.Name)
+			}
+			plan.Batches = append(plan.Batches, batch)
+		}
+	}
+	return plan
+}
+
+// getDryRunKMMModulePlan evaluates the desired KMM Module against the existing one
+func (dcrh *deviceConfigReconcilerHelper) getDryRunKMMModulePlan(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) (*dryRunKMMModulePlan, error) {
+	plan := &dryRunKMMModulePlan{}
+
+	existing := &kmmv1beta1.Module{}
+	err := dcrh.client.Get(ctx, client.ObjectKey{Namespace: devConfig.Namespace, Name: devConfig.Name}, existing)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return plan, fmt.Errorf("failed to get KMM Module: %v", err)
+	}
+	exists := err == nil
+
+	desired := existing.DeepCopy()
+	if !exists {
+		desired = &kmmv1beta1.Module{ObjectMeta: metav1.ObjectMeta{Namespace: devConfig.Namespace, Name: devConfig.Name}}
+	}
+	if err := dcrh.kmmHandler.SetKMMModuleAsDesired(ctx, desired, devConfig, nodes); err != nil {
+		return plan, fmt.Errorf("failed to compute KMM Module: %v", err)
+	}
+	if exists {
+		err = dcrh.client.Patch(ctx, desired, client.MergeFrom(existing), client.DryRunAll)
+	} else {
+		err = dcrh.client.Create(ctx, desired, client.DryRunAll)
+	}
+	if err != nil {
+		return plan, fmt.Errorf("KMM Module would be rejected: %v", err)
+	}
+
+	switch {
+	case !exists:
+		plan.Action = dryRunActionCreate
+	case !equality.Semantic.DeepEqual(existing.Spec, desired.Spec):
+		plan.Action = dryRunActionUpdate
+	}
+	plan.KernelMappings = getKernelMappingChanges(existing.Spec.ModuleLoader.Container.KernelMappings, desired.Spec.ModuleLoader.Container.KernelMappings)
+
+	images := map[string]bool{}
+	for _, km := range desired.Spec.ModuleLoader.Container.KernelMappings {
+		if km.Build != nil && !images[km.ContainerImage] {
+			images[km.ContainerImage] = true
+			plan.BuildImages = append(plan.BuildImages, km.ContainerImage)
+		}
+	}
+	sort.Strings(plan.BuildImages)
+	return plan, nil
+}
+
+// getKernelMappingChanges compares the kernel mappings by kernel literal or regexp
+func getKernelMappingChanges(existing, desired []kmmv1beta1.KernelMapping) []dryRunKernelMappingChange {
+	kernel := func(km kmmv1beta1.KernelMapping) string {
+		if km.Literal != "" {
+			return km.Literal
+		}
+		return km.Regexp
+	}
+
+	existingByKernel := map[string]kmmv1beta1.KernelMapping{}
+	for _, km := range existing {
+		existingByKernel[kernel(km)] = km
+	}
+
+	changes := []dryRunKernelMappingChange{}
+	for _, km := range desired {
+		old, ok := existingByKernel[kernel(km)]
+		delete(existingByKernel, kernel(km))
+		change := dryRunKernelMappingChange{Kernel: kernel(km), Image: km.ContainerImage, Build: km.Build != nil}
+		switch {
+		case !ok:
+			change.Action = dryRunActionCreate
+		case !equality.Semantic.DeepEqual(old, km):
+			change.Action = dryRunActionUpdate
+			if old.ContainerImage != km.ContainerImage {
+				change.PreviousImage = old.ContainerImage
+			}
+		default:
+			continue
+		}
+		changes = append(changes, change)
+	}
+	for k, km := range existingByKernel {
+		changes = append(changes, dryRunKernelMappingChange{Kernel: k, Action: dryRunActionDelete, PreviousImage: km.ContainerImage})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Kernel < changes[j].Kernel })
+	return changes
+}
+
+type dryRunOperand struct {
+	name       string
+	enabled    bool
+	setDesired func(ds *appsv1.DaemonSet) error
+}
+
+// getDryRunOperands returns the operand DaemonSets with the same enablement rules as their handlers
+func (dcrh *deviceConfigReconcilerHelper) getDryRunOperands(devConfig *amdv1alpha1.DeviceConfig) []dryRunOperand {
+	isTrue := func(b *bool) bool {
+		return b != nil && *b
+	}
+
+	return []dryRunOperand{
+		{
+			name:    devConfig.Name + utils.DevicePluginNameSuffix,
+			enabled: devConfig.Spec.DevicePlugin.IsEnabled(),
+			setDesired: func(ds *appsv1.DaemonSet) error {
+				return dcrh.devicePluginHandler.SetDevicePluginAsDesired(ds, devConfig)
+			},
+		},
+		{
+			name:    devConfig.Name + utils.DRADriverNameSuffix,
+			enabled: devConfig.Spec.DRADriver.IsEnabled(),
+			setDesired: func(ds *appsv1.DaemonSet) error {
+				return dcrh.devicePluginHandler.SetDRADriverAsDesired(ds, devConfig)
+			},
+		},
+		{
+			name:    devConfig.Name + utils.NodeLabellerNameSuffix,
+			enabled: isTrue(devConfig.Spec.DevicePlugin.EnableNodeLabeller),
+			setDesired: func(ds *appsv1.DaemonSet) error {
+				return dcrh.nlHandler.SetNodeLabellerAsDesired(ds, devConfig)
+			},
+		},
+		{
+			name:    devConfig.Name + utils.MetricsExporterNameSuffix,
+			enabled: isTrue(devConfig.Spec.MetricsExporter.Enable),
+			setDesired: func(ds *appsv1.DaemonSet) error {
+				return dcrh.metricsHandler.SetMetricsExporterAsDesired(ds, devConfig)
+			},
+		},
+		{
+			name:    devConfig.Name + utils.TestRunnerNameSuffix,
+			enabled: isTrue(devConfig.Spec.TestRunner.Enable) && isTrue(devConfig.Spec.MetricsExporter.Enable),
+			setDesired: func(ds *appsv1.DaemonSet) error {
+				return dcrh.testrunnerHandler.SetTestRunnerAsDesired(ds, devConfig)
+			},
+		},
+		{
+			name:    devConfig.Name + "-" + configmanager.ConfigManagerName,
+			enabled: isTrue(devConfig.Spec.ConfigManager.Enable),
+			setDesired: func(ds *appsv1.DaemonSet) error {
+				return dcrh.configmanagerHandler.SetConfigManagerAsDesired(ds, devConfig)
+			},
+		},
+	}
+}
+
+// getDryRunDaemonSetPlan evaluates the desired DaemonSet against the existing one, nil is returned if nothing would change
+func (dcrh *deviceConfigReconcilerHelper) getDryRunDaemonSetPlan(ctx context.Context, namespace string, operand dryRunOperand) (*dryRunDaemonSetPlan, error) {
+	existing := &appsv1.DaemonSet{}
+	err := dcrh.client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: operand.name}, existing)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get daemonset %s: %v", operand.name, err)
+	}
+	exists := err == nil
+
+	if !operand.enabled {
+		if exists {
+			return &dryRunDaemonSetPlan{Name: operand.name, Action: dryRunActionDelete}, nil
+		}
+		return nil, nil
+	}
+
+	desired := existing.DeepCopy()
+	if !exists {
+		desired = &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: operand.name}}
+	}
+	if err := operand.setDesired(desired); err != nil {
+		return nil, fmt.Errorf("failed to compute daemonset %s: %v", operand.name, err)
+	}
+	if exists {
+		err = dcrh.client.Patch(ctx, desired, client.MergeFrom(existing), client.DryRunAll)
+	} else {
+		err = dcrh.client.Create(ctx, desired, client.DryRunAll)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("daemonset %s would be rejected: %v", operand.name, err)
+	}
+
+	if !exists {
+		return &dryRunDaemonSetPlan{Name: operand.name, Action: dryRunActionCreate, Changes: getContainerImageChanges(nil, desired)}, nil
+	}
+	if !equality.Semantic.DeepEqual(existing.Spec.Template, desired.Spec.Template) {
+		return &dryRunDaemonSetPlan{Name: operand.name, Action: dryRunActionRoll, Changes: getContainerImageChanges(existing, desired)}, nil
+	}
+	if !equality.Semantic.DeepEqual(existing.Spec, desired.Spec) {
+		return &dryRunDaemonSetPlan{Name: operand.name, Action: dryRunActionUpdate}, nil
+	}
+	return nil, nil
+}
+
+// getContainerImageChanges lists the container images added or changed in the desired DaemonSet
+func getContainerImageChanges(existing, desired *appsv1.DaemonSet) []string {
+	existingImages := map[string]string{}
+	if existing != nil {
+		for _, c := range append(existing.Spec.Template.Spec.InitContainers, existing.Spec.Template.Spec.Containers...) {
+			existingImages[c.Name] = c.Image
+		}
+	}
+	var changes []string
+	for _, c := range append(desired.Spec.Template.Spec.InitContainers, desired.Spec.Template.Spec.Containers...) {
+		old, ok := existingImages[c.Name]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("container %s: image %s", c.Name, c.Image))
+		case old != c.Image:
+			changes = append(changes, fmt.Sprintf("container %s: image %s -> %s", c.Name, old, c.Image))
+		}
+	}
+	return changes
+}
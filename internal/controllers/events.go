@@ -0,0 +1,67 @@
+/*
+Copyright (c) Advanced Micro Devices, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the \"License\");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an \"AS IS\" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	amdv1alpha1 "github.com/ROCm/gpu-operator/api/v1alpha1"
+)
+
+// Kubernetes event reasons emitted by the operator
+const (
+	EventReasonValidationFailed           = "ValidationFailed"
+	EventReasonDriverUpgradeStarted       = "DriverUpgradeStarted"
+	EventReasonNodeCordonFailed           = "NodeCordonFailed"
+	EventReasonNodeDrainFailed            = "NodeDrainFailed"
+	EventReasonNodeDrainWaiting           = "NodeDrainWaiting"
+	EventReasonNodeRebootIssued           = "NodeRebootIssued"
+	EventReasonDriverRollbackStarted      = "DriverRollbackStarted"
+	EventReasonDriverRollbackComplete     = "DriverRollbackComplete"
+	EventReasonDriverRollbackFailed       = "DriverRollbackFailed"
+	EventReasonUpgradeRolloutPaused       = "UpgradeRolloutPaused"
+	EventReasonDriverUpgradeSkipped       = "DriverUpgradeSkipped"
+	EventReasonDriverUpgradePaused        = "DriverUpgradePaused"
+	EventReasonDriverUpgradeRetried       = "DriverUpgradeRetried"
+	EventReasonDriverUpgradeForced        = "DriverUpgradeForced"
+	EventReasonUpgradeHookFailed          = "UpgradeHookFailed"
+	EventReasonGPUPartitionStarted        = "GPUPartitionStarted"
+	EventReasonGPUPartitionComplete       = "GPUPartitionComplete"
+	EventReasonGPUPartitionFailed         = "GPUPartitionFailed"
+	EventReasonDRAMigrationStarted        = "DRAMigrationStarted"
+	EventReasonDRAMigrationComplete       = "DRAMigrationComplete"
+	EventReasonDRAMigrationFailed         = "DRAMigrationFailed"
+	EventReasonRemediationWorkflowCreated = "RemediationWorkflowCreated"
+	EventReasonRemediationWorkflowAborted = "RemediationWorkflowAborted"
+	EventReasonDeviceConfigTemplateFailed = "DeviceConfigTemplateFailed"
+	EventReasonDryRunPlanReady            = "DryRunPlanReady"
+	EventReasonDriverImagePrebuildFailed  = "DriverImagePrebuildFailed"
+)
+
+// recordEvent records the event on the DeviceConfig and, if given, on the Node it refers to
+func recordEvent(recorder record.EventRecorder, devConfig *amdv1alpha1.DeviceConfig, node *v1.Node, eventType, reason, message string) {
+	if recorder == nil {
+		return
+	}
+	if devConfig != nil {
+		recorder.Event(devConfig, eventType, reason, message)
+	}
+	if node != nil {
+		recorder.Event(node, eventType, reason, message)
+	}
+}
@@ -0,0 +1,297 @@
+/*
+Copyright (c) Advanced Micro Devices, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the \"License\");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an \"AS IS\" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+	kmmv1beta2 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta2"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	amdv1alpha1 "github.com/ROCm/gpu-operator/api/v1alpha1"
+	"github.com/ROCm/gpu-operator/internal/kmmmodule"
+)
+
+const (
+	driverImagePrebuildRequeueInterval = 30 * time.Second
+	// preflightValidationLabelKey marks the PreflightValidations created to prebuild driver images
+	preflightValidationLabelKey = "operator.amd.com/driver-prebuild"
+	// preflightValidationOwnerLabelPrefix prefixes the label of every DeviceConfig sharing a PreflightValidation,
+	// its value is the hash of the image the DeviceConfig waits for
+	preflightValidationOwnerLabelPrefix = "operator.amd.com/prebuild-owner-"
+)
+
+// handleDriverImagePrebuild builds the driver images of the selected nodes ahead of the driver upgrade.
+// Every image that is not known to exist yet needs a KMM PreflightValidation for its kernel, which builds
+// and pushes the image of the KMM Module. A PreflightValidation verifies all the KMM Modules of the cluster,
+// so the DeviceConfigs share one per kernel. It returns true once every image is ready, so that the upgrade can start
+func (dcrh *deviceConfigReconcilerHelper) handleDriverImagePrebuild(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) (ctrl.Result, bool, error) {
+	logger := log.FromContext(ctx)
+
+	prebuild := devConfig.Spec.Driver.ImageBuild.Prebuild
+	if prebuild == nil || !*prebuild || !dcrh.shouldUseKMMOperatorLevel(devConfig) {
+		if len(devConfig.Status.DriverImages) > 0 {
+			devConfig.Status.DriverImages = nil
+			return ctrl.Result{}, true, dcrh.deletePreflightValidations(ctx, devConfig, nil)
+		}
+		return ctrl.Result{}, true, nil
+	}
+
+	images, err := dcrh.kmmHandler.GetDriverImages(ctx, devConfig, nodes)
+	if err != nil {
+		return ctrl.Result{}, false, fmt.Errorf("failed to get driver images: %v", err)
+	}
+
+	mod := &kmmv1beta1.Module{}
+	if err := dcrh.client.Get(ctx, client.ObjectKey{Namespace: devConfig.Namespace, Name: devConfig.Name}, mod); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return ctrl.Result{}, false, fmt.Errorf("failed to get KMM Module: %v", err)
+		}
+		mod = nil
+	}
+
+	previous := map[string]amdv1alpha1.DriverImageStatus{}
+	for _, status := range devConfig.Status.DriverImages {
+		previous[status.KernelVersion] = status
+	}
+
+	ready := true
+	inUse := map[string]bool{}
+	statuses := make([]amdv1alpha1.DriverImageStatus, 0, len(images))
+	for _, image := range images {
+		status := amdv1alpha1.DriverImageStatus{
+			OSName:        image.OSName,
+			KernelVersion: image.KernelVersion,
+			DriverVersion: image.DriverVersion,
+			Image:         image.Image,
+			Phase:         amdv1alpha1.DriverImagePending,
+		}
+		prev, ok := previous[image.KernelVersion]
+		if ok && prev.Image == image.Image && prev.Phase == amdv1alpha1.DriverImageReady {
+			statuses = append(statuses, prev)
+			continue
+		}
+		ready = false
+
+		// the PreflightValidation builds the images of the KMM Module as it is,
+		// so it can only be created once the Module refers to the new image
+		if !moduleHasDriverImage(mod, image) {
+			status.Message = "waiting for the KMM Module to refer to the image"
+			statuses = append(statuses, status)
+			continue
+		}
+
+		pfv := &kmmv1beta2.PreflightValidation{}
+		pfvName := getPreflightValidationName(image)
+		inUse[pfvName] = true
+		ownerKey, imageHash := getPreflightValidationOwnerLabel(devConfig), getImageHash(image)
+		err := dcrh.client.Get(ctx, client.ObjectKey{Name: pfvName}, pfv)
+		switch {
+		case k8serrors.IsNotFound(err):
+			pfv = &kmmv1beta2.PreflightValidation{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: pfvName,
+					Labels: map[string]string{
+						preflightValidationLabelKey: "true",
+						ownerKey:                    imageHash,
+					},
+				},
+				Spec: kmmv1beta2.PreflightValidationSpec{
+					KernelVersion:  image.KernelVersion,
+					PushBuiltImage: true,
+				},
+			}
+			if err := dcrh.client.Create(ctx, pfv); err != nil {
+				return ctrl.Result{}, false, fmt.Errorf("failed to create PreflightValidation %s: %v", pfvName, err)
+			}
+			logger.Info("created PreflightValidation to prebuild driver image", "name", pfvName, "image", image.Image)
+		case err != nil:
+			return ctrl.Result{}, false, fmt.Errorf("failed to get PreflightValidation %s: %v", pfvName, err)
+		case pfv.Labels[ownerKey] != imageHash:
+			status.Message = "waiting for the PreflightValidation to verify the KMM Module"
+			phase, _ := getDriverImagePhase(pfv, devConfig)
+			if phase == amdv1alpha1.DriverImageReady || phase == amdv1alpha1.DriverImageFailed {
+				// the KMM Module was verified before it referred to the image, the PreflightValidation
+				// is created again on the next reconcile to verify the image
+				logger.Info("deleting PreflightValidation to verify the new driver image", "name", pfvName, "image", image.Image)
+				if err := dcrh.client.Delete(ctx, pfv); err != nil && !k8serrors.IsNotFound(err) {
+					return ctrl.Result{}, false, fmt.Errorf("failed to delete PreflightValidation %s: %v", pfvName, err)
+				}
+				break
+			}
+			patch := client.MergeFrom(pfv.DeepCopy())
+			if pfv.Labels == nil {
+				pfv.Labels = map[string]string{}
+			}
+			pfv.Labels[ownerKey] = imageHash
+			if err := dcrh.client.Patch(ctx, pfv, patch); err != nil {
+				return ctrl.Result{}, false, fmt.Errorf("failed to patch PreflightValidation %s: %v", pfvName, err)
+			}
+			status.Phase, status.Message = getDriverImagePhase(pfv, devConfig)
+		default:
+			status.Phase, status.Message = getDriverImagePhase(pfv, devConfig)
+		}
+
+		statuses = append(statuses, status)
+	}
+	devConfig.Status.DriverImages = statuses
+
+	// PreflightValidations of the images that are ready or no longer needed are removed
+	if err := dcrh.deletePreflightValidations(ctx, devConfig, inUse); err != nil {
+		return ctrl.Result{}, false, err
+	}
+	if !ready {
+		return ctrl.Result{Requeue: true, RequeueAfter: driverImagePrebuildRequeueInterval}, false, nil
+	}
+	return ctrl.Result{}, true, nil
+}
+
+// getNewlyFailedDriverImages returns the images whose build failed since the previous status
+func getNewlyFailedDriverImages(previous, current []amdv1alpha1.DriverImageStatus) []amdv1alpha1.DriverImageStatus {
+	failed := map[string]bool{}
+	for _, status := range previous {
+		if status.Phase == amdv1alpha1.DriverImageFailed {
+			failed[status.Image] = true
+		}
+	}
+	var newlyFailed []amdv1alpha1.DriverImageStatus
+	for _, status := range current {
+		if status.Phase == amdv1alpha1.DriverImageFailed && !failed[status.Image] {
+			newlyFailed = append(newlyFailed, status)
+		}
+	}
+	return newlyFailed
+}
+
+// moduleHasDriverImage checks whether the KMM Module kernel mapping of the image kernel refers to the image
+func moduleHasDriverImage(mod *kmmv1beta1.Module, image kmmmodule.DriverImage) bool {
+	if mod == nil {
+		return false
+	}
+	for _, km := range mod.Spec.ModuleLoader.Container.KernelMappings {
+		if km.Literal == image.KernelVersion {
+			return km.ContainerImage == image.ContainerImage
+		}
+	}
+	return false
+}
+
+// getPreflightValidationName returns the name of the cluster scoped PreflightValidation of the image kernel
+func getPreflightValidationName(image kmmmodule.DriverImage) string {
+	h := fnv.New32a()
+	h.Write([]byte(image.KernelVersion))
+	return fmt.Sprintf("amd-gpu-driver-prebuild-%08x", h.Sum32())
+}
+
+// getPreflightValidationOwnerLabel returns the key of the label marking the PreflightValidations the DeviceConfig uses
+func getPreflightValidationOwnerLabel(devConfig *amdv1alpha1.DeviceConfig) string {
+	h := fnv.New32a()
+	h.Write([]byte(devConfig.Namespace + "/" + devConfig.Name))
+	return fmt.Sprintf("%s%08x", preflightValidationOwnerLabelPrefix, h.Sum32())
+}
+
+// getImageHash returns the hash of the image, used as the value of the PreflightValidation owner label
+func getImageHash(image kmmmodule.DriverImage) string {
+	h := fnv.New32a()
+	h.Write([]byte(image.Image))
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+// getDriverImagePhase maps the verification status of the DeviceConfig KMM Module to the image build phase
+func getDriverImagePhase(pfv *kmmv1beta2.PreflightValidation, devConfig *amdv1alpha1.DeviceConfig) (amdv1alpha1.DriverImagePhase, string) {
+	for _, mod := range pfv.Status.Modules {
+		if mod.Namespace != devConfig.Namespace || mod.Name != devConfig.Name {
+			continue
+		}
+		switch {
+		case mod.VerificationStatus == kmmv1beta2.VerificationTrue:
+			return amdv1alpha1.DriverImageReady, mod.StatusReason
+		case mod.VerificationStage == kmmv1beta2.VerificationStageDone:
+			return amdv1alpha1.DriverImageFailed, mod.StatusReason
+		default:
+			return amdv1alpha1.DriverImageBuilding, fmt.Sprintf("%s: %s", mod.VerificationStage, mod.StatusReason)
+		}
+	}
+	return amdv1alpha1.DriverImagePending, "waiting for the PreflightValidation to verify the KMM Module"
+}
+
+// deletePreflightValidations releases the PreflightValidations used by the DeviceConfig, except the ones to keep.
+// A PreflightValidation is deleted once no DeviceConfig uses it anymore
+func (dcrh *deviceConfigReconcilerHelper) deletePreflightValidations(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, keep map[string]bool) error {
+	logger := log.FromContext(ctx)
+
+	ownerKey := getPreflightValidationOwnerLabel(devConfig)
+	pfvList := &kmmv1beta2.PreflightValidationList{}
+	if err := dcrh.client.List(ctx, pfvList, client.HasLabels{preflightValidationLabelKey, ownerKey}); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to list PreflightValidations: %v", err)
+	}
+
+	for i := range pfvList.Items {
+		pfv := &pfvList.Items[i]
+		if keep[pfv.Name] {
+			continue
+		}
+		if isPreflightValidationShared(pfv, ownerKey) {
+			patch := client.MergeFrom(pfv.DeepCopy())
+			delete(pfv.Labels, ownerKey)
+			if err := dcrh.client.Patch(ctx, pfv, patch); err != nil && !k8serrors.IsNotFound(err) {
+				return fmt.Errorf("failed to patch PreflightValidation %s: %v", pfv.Name, err)
+			}
+			continue
+		}
+		logger.Info("deleting PreflightValidation", "name", pfv.Name)
+		if err := dcrh.client.Delete(ctx, pfv); err != nil && !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete PreflightValidation %s: %v", pfv.Name, err)
+		}
+	}
+	return nil
+}
+
+// isPreflightValidationShared checks whether another DeviceConfig than the owner still uses the PreflightValidation
+func isPreflightValidationShared(pfv *kmmv1beta2.PreflightValidation, ownerKey string) bool {
+	for key := range pfv.Labels {
+		if key != ownerKey && strings.HasPrefix(key, preflightValidationOwnerLabelPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// areDriverImagesReady checks whether all the prebuilt driver images of the DeviceConfig exist,
+// it is always true when the images are not prebuilt
+func areDriverImagesReady(devConfig *amdv1alpha1.DeviceConfig) bool {
+	for _, status := range devConfig.Status.DriverImages {
+		if status.Phase != amdv1alpha1.DriverImageReady {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,45 @@
+/*
+Copyright (c) Advanced Micro Devices, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the \"License\");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an \"AS IS\" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	amdv1alpha1 "github.com/ROCm/gpu-operator/api/v1alpha1"
+	utils "github.com/ROCm/gpu-operator/internal"
+)
+
+// maxMaintenanceWindowRequeue bounds the requeue while waiting for a maintenance window that opens far in the future
+const maxMaintenanceWindowRequeue = time.Hour
+
+// checkMaintenanceWindow returns true if disruptive operations of the DeviceConfig may start now,
+// otherwise it also returns how long to wait before checking again
+func checkMaintenanceWindow(devConfig *amdv1alpha1.DeviceConfig) (bool, time.Duration) {
+	now := time.Now()
+	status, err := utils.GetMaintenanceWindowStatus(devConfig.Spec.MaintenanceWindows, now)
+	if err != nil {
+		return false, maxMaintenanceWindowRequeue
+	}
+	if status == nil || status.Open {
+		return true, 0
+	}
+	nextStart, err := time.Parse(DefaultTimeFormatLayout, status.NextWindowStart)
+	if err != nil {
+		return false, maxMaintenanceWindowRequeue
+	}
+	return false, min(max(nextStart.Sub(now), time.Second*20), maxMaintenanceWindowRequeue)
+}
@@ -0,0 +1,246 @@
+/*
+Copyright (c) Advanced Micro Devices, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the \"License\");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an \"AS IS\" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strings"
+	"time"
+
+	workflowv1alpha1 "github.com/argoproj/argo-workflows/v4/pkg/apis/workflow/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	appsv1 "k8s.io/api/apps/v1"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	amdv1alpha1 "github.com/ROCm/gpu-operator/api/v1alpha1"
+	utils "github.com/ROCm/gpu-operator/internal"
+	"github.com/ROCm/gpu-operator/internal/configmanager"
+	"github.com/ROCm/gpu-operator/internal/metricsexporter"
+	"github.com/ROCm/gpu-operator/internal/testrunner"
+)
+
+const (
+	metricsNamespace = "amd_gpu_operator"
+
+	// operand names used as metric label values
+	operandDriver          = "driver"
+	operandDevicePlugin    = "device-plugin"
+	operandMetricsExporter = "metrics-exporter"
+	operandConfigManager   = "config-manager"
+	operandNodeLabeller    = "node-labeller"
+	operandTestRunner      = "test-runner"
+	operandDRADriver       = "dra-driver"
+
+	// upgrade and remediation workflow results used as metric label values
+	upgradeResultSucceeded = "succeeded"
+	upgradeResultFailed    = "failed"
+	workflowResultStarted  = "started"
+)
+
+var (
+	upgradeNodesGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "driver_upgrade_nodes",
+			Help:      "Number of nodes per driver upgrade state",
+		},
+		[]string{"namespace", "deviceconfig", "state"},
+	)
+	upgradeDurationHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "driver_upgrade_duration_seconds",
+			Help:      "Duration of driver upgrades on a node, from upgrade start until completion or failure",
+			Buckets:   []float64{60, 120, 300, 600, 900, 1200, 1800, 2700, 3600, 5400, 7200},
+		},
+		[]string{"namespace", "deviceconfig", "result"},
+	)
+	drainFailuresCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "driver_upgrade_drain_failures_total",
+			Help:      "Number of node drain failures during driver upgrade",
+		},
+		[]string{"namespace", "deviceconfig"},
+	)
+	remediationWorkflowsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "remediation_workflows_total",
+			Help:      "Number of remediation workflows started, succeeded and failed per node condition",
+		},
+		[]string{"namespace", "deviceconfig", "condition", "result"},
+	)
+	recoveryPolicyViolationsGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "remediation_recovery_policy_violated_nodes",
+			Help:      "Number of nodes that reached the recovery policy limit of a node condition and are not remediated anymore",
+		},
+		[]string{"namespace", "deviceconfig", "condition"},
+	)
+	operandDesiredPodsGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "operand_desired_pods",
+			Help:      "Number of pods that should be running for an operand",
+		},
+		[]string{"namespace", "deviceconfig", "operand"},
+	)
+	operandAvailablePodsGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "operand_available_pods",
+			Help:      "Number of pods that are available for an operand",
+		},
+		[]string{"namespace", "deviceconfig", "operand"},
+	)
+
+	// all the upgrade states reported by the node count gauge
+	upgradeStates = []amdv1alpha1.UpgradeState{
+		amdv1alpha1.UpgradeStateNotStarted,
+		amdv1alpha1.UpgradeStateStarted,
+		amdv1alpha1.UpgradeStateInstallInProgress,
+		amdv1alpha1.UpgradeStateInstallComplete,
+		amdv1alpha1.UpgradeStateInProgress,
+		amdv1alpha1.UpgradeStateComplete,
+		amdv1alpha1.UpgradeStateFailed,
+		amdv1alpha1.UpgradeStateTimedOut,
+		amdv1alpha1.UpgradeStateCordonFailed,
+		amdv1alpha1.UpgradeStateUncordonFailed,
+		amdv1alpha1.UpgradeStateDrainFailed,
+		amdv1alpha1.UpgradeStateRebootInProgress,
+		amdv1alpha1.UpgradeStateRebootFailed,
+		amdv1alpha1.UpgradeStateRollbackInProgress,
+		amdv1alpha1.UpgradeStateRollbackComplete,
+		amdv1alpha1.UpgradeStateRollbackFailed,
+		amdv1alpha1.UpgradeStateSkipped,
+		amdv1alpha1.UpgradeStatePaused,
+		amdv1alpha1.UpgradeStateDrainWaiting,
+		amdv1alpha1.UpgradeStatePreUpgradeHook,
+		amdv1alpha1.UpgradeStatePreUpgradeHookFailed,
+		amdv1alpha1.UpgradeStatePostUpgradeHook,
+		amdv1alpha1.UpgradeStatePostUpgradeHookFailed,
+	}
+)
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(
+		upgradeNodesGauge,
+		upgradeDurationHistogram,
+		drainFailuresCounter,
+		remediationWorkflowsCounter,
+		recoveryPolicyViolationsGauge,
+		operandDesiredPodsGauge,
+		operandAvailablePodsGauge,
+	)
+}
+
+// setUpgradeNodesMetric publishes the number of nodes per upgrade state for the DeviceConfig
+func setUpgradeNodesMetric(devConfig *amdv1alpha1.DeviceConfig, nodeStates map[amdv1alpha1.UpgradeState]int) {
+	for _, state := range upgradeStates {
+		upgradeNodesGauge.WithLabelValues(devConfig.Namespace, devConfig.Name, string(state)).Set(float64(nodeStates[state]))
+	}
+}
+
+// observeUpgradeDuration records the upgrade duration of a node given its upgrade start time
+func observeUpgradeDuration(devConfig *amdv1alpha1.DeviceConfig, startTime string, result string) {
+	if startTime == "" {
+		return
+	}
+	start, err := time.Parse(DefaultTimeFormatLayout, startTime)
+	if err != nil {
+		return
+	}
+	upgradeDurationHistogram.WithLabelValues(devConfig.Namespace, devConfig.Name, result).Observe(time.Since(start).Seconds())
+}
+
+func incDrainFailuresMetric(devConfig *amdv1alpha1.DeviceConfig) {
+	drainFailuresCounter.WithLabelValues(devConfig.Namespace, devConfig.Name).Inc()
+}
+
+func incRemediationWorkflowsMetric(devConfig *amdv1alpha1.DeviceConfig, condition string, result string) {
+	remediationWorkflowsCounter.WithLabelValues(devConfig.Namespace, devConfig.Name, condition, result).Inc()
+}
+
+// getWorkflowResult returns the result label of a remediation workflow in a terminal phase
+func getWorkflowResult(wf *workflowv1alpha1.Workflow) (string, bool) {
+	switch wf.Status.Phase {
+	case workflowv1alpha1.WorkflowSucceeded:
+		return upgradeResultSucceeded, true
+	case workflowv1alpha1.WorkflowFailed, workflowv1alpha1.WorkflowError:
+		return upgradeResultFailed, true
+	}
+	return "", false
+}
+
+// setRecoveryPolicyViolationsMetric publishes the number of nodes over the recovery policy limit per node condition
+func setRecoveryPolicyViolationsMetric(devConfig *amdv1alpha1.DeviceConfig, violatedNodes map[string]int) {
+	recoveryPolicyViolationsGauge.DeletePartialMatch(prometheus.Labels{"namespace": devConfig.Namespace, "deviceconfig": devConfig.Name})
+	for condition, count := range violatedNodes {
+		recoveryPolicyViolationsGauge.WithLabelValues(devConfig.Namespace, devConfig.Name, condition).Set(float64(count))
+	}
+}
+
+// setOperandPodsMetrics publishes desired vs available pods of every operand, the driver from the DeviceConfig status
+// and the other operands from their DaemonSets. The series of an operand without DaemonSet are removed
+func setOperandPodsMetrics(devConfig *amdv1alpha1.DeviceConfig, daemonSets []appsv1.DaemonSet) {
+	operands := map[string]amdv1alpha1.DeploymentStatus{
+		operandDriver: devConfig.Status.Drivers,
+	}
+	dsOperands := map[string]string{
+		devConfig.Name + utils.DevicePluginNameSuffix:          operandDevicePlugin,
+		devConfig.Name + "-" + metricsexporter.ExporterName:    operandMetricsExporter,
+		devConfig.Name + "-" + configmanager.ConfigManagerName: operandConfigManager,
+		devConfig.Name + utils.NodeLabellerNameSuffix:          operandNodeLabeller,
+		devConfig.Name + "-" + testrunner.TestRunnerName:       operandTestRunner,
+		devConfig.Name + utils.DRADriverNameSuffix:             operandDRADriver,
+	}
+	for _, ds := range daemonSets {
+		if ds.Namespace != devConfig.Namespace || !strings.HasPrefix(ds.Name, devConfig.Name) {
+			continue
+		}
+		if operand, ok := dsOperands[ds.Name]; ok {
+			operands[operand] = amdv1alpha1.DeploymentStatus{
+				DesiredNumber:   ds.Status.DesiredNumberScheduled,
+				AvailableNumber: ds.Status.NumberAvailable,
+			}
+		}
+	}
+	for _, operand := range dsOperands {
+		if _, ok := operands[operand]; !ok {
+			labels := prometheus.Labels{"namespace": devConfig.Namespace, "deviceconfig": devConfig.Name, "operand": operand}
+			operandDesiredPodsGauge.Delete(labels)
+			operandAvailablePodsGauge.Delete(labels)
+		}
+	}
+	for operand, status := range operands {
+		operandDesiredPodsGauge.WithLabelValues(devConfig.Namespace, devConfig.Name, operand).Set(float64(status.DesiredNumber))
+		operandAvailablePodsGauge.WithLabelValues(devConfig.Namespace, devConfig.Name, operand).Set(float64(status.AvailableNumber))
+	}
+}
+
+// deleteDeviceConfigMetrics removes all the series of a deleted DeviceConfig
+func deleteDeviceConfigMetrics(devConfig *amdv1alpha1.DeviceConfig) {
+	labels := prometheus.Labels{"namespace": devConfig.Namespace, "deviceconfig": devConfig.Name}
+	upgradeNodesGauge.DeletePartialMatch(labels)
+	upgradeDurationHistogram.DeletePartialMatch(labels)
+	drainFailuresCounter.DeletePartialMatch(labels)
+	remediationWorkflowsCounter.DeletePartialMatch(labels)
+	recoveryPolicyViolationsGauge.DeletePartialMatch(labels)
+	operandDesiredPodsGauge.DeletePartialMatch(labels)
+	operandAvailablePodsGauge.DeletePartialMatch(labels)
+}
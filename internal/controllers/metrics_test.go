@@ -0,0 +1,175 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	workflowv1alpha1 "github.com/argoproj/argo-workflows/v4/pkg/apis/workflow/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.uber.org/mock/gomock"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	amdv1alpha1 "github.com/ROCm/gpu-operator/api/v1alpha1"
+	mock_client "github.com/ROCm/gpu-operator/internal/client"
+)
+
+// collectSeries returns the value of every series of the collector by its label values
+func collectSeries(collector prometheus.Collector) map[string]float64 {
+	ch := make(chan prometheus.Metric, 100)
+	collector.Collect(ch)
+	close(ch)
+	series := make(map[string]float64)
+	for metric := range ch {
+		m := &dto.Metric{}
+		Expect(metric.Write(m)).To(Succeed())
+		key := ""
+		for _, label := range m.GetLabel() {
+			key += label.GetValue() + "/"
+		}
+		if m.Counter != nil {
+			series[key] = m.GetCounter().GetValue()
+		} else {
+			series[key] = m.GetGauge().GetValue()
+		}
+	}
+	return series
+}
+
+var _ = Describe("metrics", func() {
+	devConfig := &amdv1alpha1.DeviceConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: devConfigName, Namespace: devConfigNamespace},
+		Status: amdv1alpha1.DeviceConfigStatus{
+			Drivers: amdv1alpha1.DeploymentStatus{DesiredNumber: 2, AvailableNumber: 1},
+		},
+	}
+	daemonSet := func(name string, desired, available int32) appsv1.DaemonSet {
+		return appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: devConfigName + name, Namespace: devConfigNamespace},
+			Status:     appsv1.DaemonSetStatus{DesiredNumberScheduled: desired, NumberAvailable: available},
+		}
+	}
+
+	AfterEach(func() {
+		deleteDeviceConfigMetrics(devConfig)
+	})
+
+	It("publishes the operand pods from the operand daemonsets", func() {
+		setOperandPodsMetrics(devConfig, []appsv1.DaemonSet{
+			daemonSet("-node-labeller", 2, 2),
+			daemonSet("-test-runner", 2, 1),
+			daemonSet("-dra-driver", 2, 0),
+		})
+		Expect(collectSeries(operandDesiredPodsGauge)).To(Equal(map[string]float64{
+			devConfigName + "/" + devConfigNamespace + "/driver/":        2,
+			devConfigName + "/" + devConfigNamespace + "/node-labeller/": 2,
+			devConfigName + "/" + devConfigNamespace + "/test-runner/":   2,
+			devConfigName + "/" + devConfigNamespace + "/dra-driver/":    2,
+		}))
+		Expect(collectSeries(operandAvailablePodsGauge)).To(HaveKeyWithValue(devConfigName+"/"+devConfigNamespace+"/test-runner/", 1.0))
+
+		// the test runner got disabled
+		setOperandPodsMetrics(devConfig, []appsv1.DaemonSet{
+			daemonSet("-node-labeller", 2, 2),
+			daemonSet("-dra-driver", 2, 2),
+		})
+		Expect(collectSeries(operandDesiredPodsGauge)).NotTo(HaveKey(devConfigName + "/" + devConfigNamespace + "/test-runner/"))
+		Expect(collectSeries(operandAvailablePodsGauge)).To(HaveKeyWithValue(devConfigName+"/"+devConfigNamespace+"/dra-driver/", 2.0))
+	})
+
+	It("counts the remediation workflows once when they finish", func() {
+		mockCtrl := gomock.NewController(GinkgoT())
+		helper := newRemediationMgrHelperHandler(mock_client.NewMockClient(mockCtrl), nil, nil, nil, false).(*remediationMgrHelper)
+		workflow := func(uid, condition string, phase workflowv1alpha1.WorkflowPhase) workflowv1alpha1.Workflow {
+			return workflowv1alpha1.Workflow{
+				ObjectMeta: metav1.ObjectMeta{UID: types.UID(uid), Labels: map[string]string{RemediationConditionLabelKey: condition}},
+				Status:     workflowv1alpha1.WorkflowStatus{Phase: phase},
+			}
+		}
+
+		// the workflows finished before the operator started are not counted
+		helper.countFinishedWorkflows(devConfig, []workflowv1alpha1.Workflow{
+			workflow("wf1", "AMDGPUUnhealthy", workflowv1alpha1.WorkflowFailed),
+			workflow("wf2", "AMDGPUUnhealthy", workflowv1alpha1.WorkflowRunning),
+			workflow("wf3", "AMDGPUHang", workflowv1alpha1.WorkflowRunning),
+		})
+		incRemediationWorkflowsMetric(devConfig, "AMDGPUHang", workflowResultStarted)
+		Expect(collectSeries(remediationWorkflowsCounter)).To(Equal(map[string]float64{
+			"AMDGPUHang/" + devConfigName + "/" + devConfigNamespace + "/started/": 1,
+		}))
+
+		helper.countFinishedWorkflows(devConfig, []workflowv1alpha1.Workflow{
+			workflow("wf1", "AMDGPUUnhealthy", workflowv1alpha1.WorkflowFailed),
+			workflow("wf2", "AMDGPUUnhealthy", workflowv1alpha1.WorkflowSucceeded),
+			workflow("wf3", "AMDGPUHang", workflowv1alpha1.WorkflowError),
+			{ObjectMeta: metav1.ObjectMeta{UID: "not-a-remediation-workflow"}, Status: workflowv1alpha1.WorkflowStatus{Phase: workflowv1alpha1.WorkflowFailed}},
+		})
+		// the counters are kept when the workflows get garbage collected
+		helper.countFinishedWorkflows(devConfig, []workflowv1alpha1.Workflow{
+			workflow("wf3", "AMDGPUHang", workflowv1alpha1.WorkflowError),
+		})
+		helper.countFinishedWorkflows(devConfig, nil)
+		Expect(collectSeries(remediationWorkflowsCounter)).To(Equal(map[string]float64{
+			"AMDGPUHang/" + devConfigName + "/" + devConfigNamespace + "/started/":        1,
+			"AMDGPUHang/" + devConfigName + "/" + devConfigNamespace + "/failed/":         1,
+			"AMDGPUUnhealthy/" + devConfigName + "/" + devConfigNamespace + "/succeeded/": 1,
+		}))
+	})
+
+	It("counts the nodes over the recovery policy limit", func() {
+		mockCtrl := gomock.NewController(GinkgoT())
+		kubeClient := mock_client.NewMockClient(mockCtrl)
+		helper := newRemediationMgrHelperHandler(kubeClient, nil, nil, nil, false).(*remediationMgrHelper)
+		kubeClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+
+		mappings := map[string]ConditionWorkflowMapping{
+			"AMDGPUUnhealthy": {NodeCondition: "AMDGPUUnhealthy", RecoveryPolicy: RecoveryPolicyConfig{MaxAllowedRunsPerWindow: 2}},
+			"AMDGPUHang":      {NodeCondition: "AMDGPUHang", RecoveryPolicy: RecoveryPolicyConfig{MaxAllowedRunsPerWindow: 2}},
+		}
+		helper.recoveryTracker.Store(helper.getRecoveryTrackerKey("node1", "AMDGPUUnhealthy"), []time.Time{time.Now(), time.Now()})
+		helper.recoveryTracker.Store(helper.getRecoveryTrackerKey("node2", "AMDGPUUnhealthy"), []time.Time{time.Now()})
+		nodes := &v1.NodeList{Items: []v1.Node{
+			{ObjectMeta: metav1.ObjectMeta{Name: "node1"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "node2"}},
+		}}
+
+		helper.updateRemediationMetrics(context.Background(), devConfig, nodes, mappings)
+		Expect(collectSeries(recoveryPolicyViolationsGauge)).To(Equal(map[string]float64{
+			"AMDGPUUnhealthy/" + devConfigName + "/" + devConfigNamespace + "/": 1,
+			"AMDGPUHang/" + devConfigName + "/" + devConfigNamespace + "/":      0,
+		}))
+	})
+
+	It("deletes all the series of the DeviceConfig", func() {
+		setOperandPodsMetrics(devConfig, nil)
+		setUpgradeNodesMetric(devConfig, map[amdv1alpha1.UpgradeState]int{amdv1alpha1.UpgradeStateStarted: 1})
+		setRecoveryPolicyViolationsMetric(devConfig, map[string]int{"AMDGPUUnhealthy": 1})
+
+		deleteDeviceConfigMetrics(devConfig)
+		Expect(collectSeries(operandDesiredPodsGauge)).To(BeEmpty())
+		Expect(collectSeries(upgradeNodesGauge)).To(BeEmpty())
+		Expect(collectSeries(recoveryPolicyViolationsGauge)).To(BeEmpty())
+	})
+})
@@ -104,6 +104,22 @@ func (mr *MockdeviceConfigReconcilerHelperAPIMockRecorder) deleteCondition(ctx,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "deleteCondition", reflect.TypeOf((*MockdeviceConfigReconcilerHelperAPI)(nil).deleteCondition), ctx, condition, devConfig)
 }
 
+// diagnoseNoMatchingNodes mocks base method.
+func (m *MockdeviceConfigReconcilerHelperAPI) diagnoseNoMatchingNodes(ctx context.Context, devConfig *v1alpha1.DeviceConfig) (string, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "diagnoseNoMatchingNodes", ctx, devConfig)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// diagnoseNoMatchingNodes indicates an expected call of diagnoseNoMatchingNodes.
+func (mr *MockdeviceConfigReconcilerHelperAPIMockRecorder) diagnoseNoMatchingNodes(ctx, devConfig any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "diagnoseNoMatchingNodes", reflect.TypeOf((*MockdeviceConfigReconcilerHelperAPI)(nil).diagnoseNoMatchingNodes), ctx, devConfig)
+}
+
 // finalizeDeviceConfig mocks base method.
 func (m *MockdeviceConfigReconcilerHelperAPI) finalizeDeviceConfig(ctx context.Context, devConfig *v1alpha1.DeviceConfig, nodes *v1.NodeList) error {
 	m.ctrl.T.Helper()
@@ -118,6 +134,20 @@ func (mr *MockdeviceConfigReconcilerHelperAPIMockRecorder) finalizeDeviceConfig(
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "finalizeDeviceConfig", reflect.TypeOf((*MockdeviceConfigReconcilerHelperAPI)(nil).finalizeDeviceConfig), ctx, devConfig, nodes)
 }
 
+// findDeviceConfigsForConfigMap mocks base method.
+func (m *MockdeviceConfigReconcilerHelperAPI) findDeviceConfigsForConfigMap(ctx context.Context, configMap client.Object) []reconcile.Request {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "findDeviceConfigsForConfigMap", ctx, configMap)
+	ret0, _ := ret[0].([]reconcile.Request)
+	return ret0
+}
+
+// findDeviceConfigsForConfigMap indicates an expected call of findDeviceConfigsForConfigMap.
+func (mr *MockdeviceConfigReconcilerHelperAPIMockRecorder) findDeviceConfigsForConfigMap(ctx, configMap any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "findDeviceConfigsForConfigMap", reflect.TypeOf((*MockdeviceConfigReconcilerHelperAPI)(nil).findDeviceConfigsForConfigMap), ctx, configMap)
+}
+
 // findDeviceConfigsForNMC mocks base method.
 func (m *MockdeviceConfigReconcilerHelperAPI) findDeviceConfigsForNMC(ctx context.Context, nmc client.Object) []reconcile.Request {
 	m.ctrl.T.Helper()
@@ -218,6 +248,21 @@ func (mr *MockdeviceConfigReconcilerHelperAPIMockRecorder) handleDRADriver(ctx,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleDRADriver", reflect.TypeOf((*MockdeviceConfigReconcilerHelperAPI)(nil).handleDRADriver), ctx, devConfig, nodes)
 }
 
+// handleDRAMigration mocks base method.
+func (m *MockdeviceConfigReconcilerHelperAPI) handleDRAMigration(ctx context.Context, devConfig *v1alpha1.DeviceConfig, nodes *v1.NodeList, delete bool) (controllerruntime.Result, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "handleDRAMigration", ctx, devConfig, nodes, delete)
+	ret0, _ := ret[0].(controllerruntime.Result)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// handleDRAMigration indicates an expected call of handleDRAMigration.
+func (mr *MockdeviceConfigReconcilerHelperAPIMockRecorder) handleDRAMigration(ctx, devConfig, nodes, delete any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleDRAMigration", reflect.TypeOf((*MockdeviceConfigReconcilerHelperAPI)(nil).handleDRAMigration), ctx, devConfig, nodes, delete)
+}
+
 // handleDeviceClass mocks base method.
 func (m *MockdeviceConfigReconcilerHelperAPI) handleDeviceClass(ctx context.Context, devConfig *v1alpha1.DeviceConfig) error {
 	m.ctrl.T.Helper()
@@ -246,6 +291,36 @@ func (mr *MockdeviceConfigReconcilerHelperAPIMockRecorder) handleDevicePlugin(ct
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleDevicePlugin", reflect.TypeOf((*MockdeviceConfigReconcilerHelperAPI)(nil).handleDevicePlugin), ctx, devConfig, nodes)
 }
 
+// handleDriverImagePrebuild mocks base method.
+func (m *MockdeviceConfigReconcilerHelperAPI) handleDriverImagePrebuild(ctx context.Context, devConfig *v1alpha1.DeviceConfig, nodes *v1.NodeList) (controllerruntime.Result, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "handleDriverImagePrebuild", ctx, devConfig, nodes)
+	ret0, _ := ret[0].(controllerruntime.Result)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// handleDriverImagePrebuild indicates an expected call of handleDriverImagePrebuild.
+func (mr *MockdeviceConfigReconcilerHelperAPIMockRecorder) handleDriverImagePrebuild(ctx, devConfig, nodes any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleDriverImagePrebuild", reflect.TypeOf((*MockdeviceConfigReconcilerHelperAPI)(nil).handleDriverImagePrebuild), ctx, devConfig, nodes)
+}
+
+// handleDryRun mocks base method.
+func (m *MockdeviceConfigReconcilerHelperAPI) handleDryRun(ctx context.Context, devConfig *v1alpha1.DeviceConfig, nodes *v1.NodeList) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "handleDryRun", ctx, devConfig, nodes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// handleDryRun indicates an expected call of handleDryRun.
+func (mr *MockdeviceConfigReconcilerHelperAPIMockRecorder) handleDryRun(ctx, devConfig, nodes any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleDryRun", reflect.TypeOf((*MockdeviceConfigReconcilerHelperAPI)(nil).handleDryRun), ctx, devConfig, nodes)
+}
+
 // handleKMMModule mocks base method.
 func (m *MockdeviceConfigReconcilerHelperAPI) handleKMMModule(ctx context.Context, devConfig *v1alpha1.DeviceConfig, nodes *v1.NodeList) error {
 	m.ctrl.T.Helper()
@@ -303,6 +378,20 @@ func (mr *MockdeviceConfigReconcilerHelperAPIMockRecorder) handleModuleUpgrade(c
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleModuleUpgrade", reflect.TypeOf((*MockdeviceConfigReconcilerHelperAPI)(nil).handleModuleUpgrade), ctx, devConfig, nodes, delete)
 }
 
+// handleNodeFeatureRule mocks base method.
+func (m *MockdeviceConfigReconcilerHelperAPI) handleNodeFeatureRule(ctx context.Context, devConfig *v1alpha1.DeviceConfig) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "handleNodeFeatureRule", ctx, devConfig)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// handleNodeFeatureRule indicates an expected call of handleNodeFeatureRule.
+func (mr *MockdeviceConfigReconcilerHelperAPIMockRecorder) handleNodeFeatureRule(ctx, devConfig any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleNodeFeatureRule", reflect.TypeOf((*MockdeviceConfigReconcilerHelperAPI)(nil).handleNodeFeatureRule), ctx, devConfig)
+}
+
 // handleNodeLabeller mocks base method.
 func (m *MockdeviceConfigReconcilerHelperAPI) handleNodeLabeller(ctx context.Context, devConfig *v1alpha1.DeviceConfig, nodes *v1.NodeList) error {
 	m.ctrl.T.Helper()
@@ -317,6 +406,21 @@ func (mr *MockdeviceConfigReconcilerHelperAPIMockRecorder) handleNodeLabeller(ct
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleNodeLabeller", reflect.TypeOf((*MockdeviceConfigReconcilerHelperAPI)(nil).handleNodeLabeller), ctx, devConfig, nodes)
 }
 
+// handlePartitionProfiles mocks base method.
+func (m *MockdeviceConfigReconcilerHelperAPI) handlePartitionProfiles(ctx context.Context, devConfig *v1alpha1.DeviceConfig, nodes *v1.NodeList, delete bool) (controllerruntime.Result, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "handlePartitionProfiles", ctx, devConfig, nodes, delete)
+	ret0, _ := ret[0].(controllerruntime.Result)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// handlePartitionProfiles indicates an expected call of handlePartitionProfiles.
+func (mr *MockdeviceConfigReconcilerHelperAPIMockRecorder) handlePartitionProfiles(ctx, devConfig, nodes, delete any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handlePartitionProfiles", reflect.TypeOf((*MockdeviceConfigReconcilerHelperAPI)(nil).handlePartitionProfiles), ctx, devConfig, nodes, delete)
+}
+
 // handleRemediationWorkflow mocks base method.
 func (m *MockdeviceConfigReconcilerHelperAPI) handleRemediationWorkflow(ctx context.Context, devConfig *v1alpha1.DeviceConfig, nodes *v1.NodeList, delete bool) (controllerruntime.Result, error) {
 	m.ctrl.T.Helper()
@@ -332,6 +436,20 @@ func (mr *MockdeviceConfigReconcilerHelperAPIMockRecorder) handleRemediationWork
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleRemediationWorkflow", reflect.TypeOf((*MockdeviceConfigReconcilerHelperAPI)(nil).handleRemediationWorkflow), ctx, devConfig, nodes, delete)
 }
 
+// handleResourceClaimTemplates mocks base method.
+func (m *MockdeviceConfigReconcilerHelperAPI) handleResourceClaimTemplates(ctx context.Context, devConfig *v1alpha1.DeviceConfig) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "handleResourceClaimTemplates", ctx, devConfig)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// handleResourceClaimTemplates indicates an expected call of handleResourceClaimTemplates.
+func (mr *MockdeviceConfigReconcilerHelperAPIMockRecorder) handleResourceClaimTemplates(ctx, devConfig any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleResourceClaimTemplates", reflect.TypeOf((*MockdeviceConfigReconcilerHelperAPI)(nil).handleResourceClaimTemplates), ctx, devConfig)
+}
+
 // handleTestRunner mocks base method.
 func (m *MockdeviceConfigReconcilerHelperAPI) handleTestRunner(ctx context.Context, devConfig *v1alpha1.DeviceConfig, nodes *v1.NodeList) error {
 	m.ctrl.T.Helper()
@@ -346,6 +464,20 @@ func (mr *MockdeviceConfigReconcilerHelperAPIMockRecorder) handleTestRunner(ctx,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleTestRunner", reflect.TypeOf((*MockdeviceConfigReconcilerHelperAPI)(nil).handleTestRunner), ctx, devConfig, nodes)
 }
 
+// handleUnmatchedGPUDevices mocks base method.
+func (m *MockdeviceConfigReconcilerHelperAPI) handleUnmatchedGPUDevices(ctx context.Context, devConfig *v1alpha1.DeviceConfig) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "handleUnmatchedGPUDevices", ctx, devConfig)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// handleUnmatchedGPUDevices indicates an expected call of handleUnmatchedGPUDevices.
+func (mr *MockdeviceConfigReconcilerHelperAPIMockRecorder) handleUnmatchedGPUDevices(ctx, devConfig any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleUnmatchedGPUDevices", reflect.TypeOf((*MockdeviceConfigReconcilerHelperAPI)(nil).handleUnmatchedGPUDevices), ctx, devConfig)
+}
+
 // listDeviceConfigs mocks base method.
 func (m *MockdeviceConfigReconcilerHelperAPI) listDeviceConfigs(ctx context.Context) (*v1alpha1.DeviceConfigList, error) {
 	m.ctrl.T.Helper()
@@ -418,15 +550,15 @@ func (mr *MockdeviceConfigReconcilerHelperAPIMockRecorder) updateDeviceConfigSta
 }
 
 // updateNodeAssignments mocks base method.
-func (m *MockdeviceConfigReconcilerHelperAPI) updateNodeAssignments(namespacedName string, nodes *v1.NodeList, isFinalizer bool) {
+func (m *MockdeviceConfigReconcilerHelperAPI) updateNodeAssignments(namespacedName string, operands []string, nodes *v1.NodeList, isFinalizer bool) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "updateNodeAssignments", namespacedName, nodes, isFinalizer)
+	m.ctrl.Call(m, "updateNodeAssignments", namespacedName, operands, nodes, isFinalizer)
 }
 
 // updateNodeAssignments indicates an expected call of updateNodeAssignments.
-func (mr *MockdeviceConfigReconcilerHelperAPIMockRecorder) updateNodeAssignments(namespacedName, nodes, isFinalizer any) *gomock.Call {
+func (mr *MockdeviceConfigReconcilerHelperAPIMockRecorder) updateNodeAssignments(namespacedName, operands, nodes, isFinalizer any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "updateNodeAssignments", reflect.TypeOf((*MockdeviceConfigReconcilerHelperAPI)(nil).updateNodeAssignments), namespacedName, nodes, isFinalizer)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "updateNodeAssignments", reflect.TypeOf((*MockdeviceConfigReconcilerHelperAPI)(nil).updateNodeAssignments), namespacedName, operands, nodes, isFinalizer)
 }
 
 // validateDeviceConfig mocks base method.
@@ -444,15 +576,15 @@ func (mr *MockdeviceConfigReconcilerHelperAPIMockRecorder) validateDeviceConfig(
 }
 
 // validateNodeAssignments mocks base method.
-func (m *MockdeviceConfigReconcilerHelperAPI) validateNodeAssignments(namespacedName string, nodes *v1.NodeList) error {
+func (m *MockdeviceConfigReconcilerHelperAPI) validateNodeAssignments(namespacedName string, operands []string, nodes *v1.NodeList) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "validateNodeAssignments", namespacedName, nodes)
+	ret := m.ctrl.Call(m, "validateNodeAssignments", namespacedName, operands, nodes)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // validateNodeAssignments indicates an expected call of validateNodeAssignments.
-func (mr *MockdeviceConfigReconcilerHelperAPIMockRecorder) validateNodeAssignments(namespacedName, nodes any) *gomock.Call {
+func (mr *MockdeviceConfigReconcilerHelperAPIMockRecorder) validateNodeAssignments(namespacedName, operands, nodes any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "validateNodeAssignments", reflect.TypeOf((*MockdeviceConfigReconcilerHelperAPI)(nil).validateNodeAssignments), namespacedName, nodes)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "validateNodeAssignments", reflect.TypeOf((*MockdeviceConfigReconcilerHelperAPI)(nil).validateNodeAssignments), namespacedName, operands, nodes)
 }
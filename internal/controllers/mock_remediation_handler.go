@@ -140,18 +140,18 @@ func (mr *MockremediationMgrHelperAPIMockRecorder) applyTolerationsToWorkflow(wf
 }
 
 // attemptAbortWorkflowOnNode mocks base method.
-func (m *MockremediationMgrHelperAPI) attemptAbortWorkflowOnNode(ctx context.Context, node *v1.Node, wf *v1alpha10.Workflow) (bool, error) {
+func (m *MockremediationMgrHelperAPI) attemptAbortWorkflowOnNode(ctx context.Context, devConfig *v1alpha1.DeviceConfig, node *v1.Node, wf *v1alpha10.Workflow) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "attemptAbortWorkflowOnNode", ctx, node, wf)
+	ret := m.ctrl.Call(m, "attemptAbortWorkflowOnNode", ctx, devConfig, node, wf)
 	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // attemptAbortWorkflowOnNode indicates an expected call of attemptAbortWorkflowOnNode.
-func (mr *MockremediationMgrHelperAPIMockRecorder) attemptAbortWorkflowOnNode(ctx, node, wf any) *gomock.Call {
+func (mr *MockremediationMgrHelperAPIMockRecorder) attemptAbortWorkflowOnNode(ctx, devConfig, node, wf any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "attemptAbortWorkflowOnNode", reflect.TypeOf((*MockremediationMgrHelperAPI)(nil).attemptAbortWorkflowOnNode), ctx, node, wf)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "attemptAbortWorkflowOnNode", reflect.TypeOf((*MockremediationMgrHelperAPI)(nil).attemptAbortWorkflowOnNode), ctx, devConfig, node, wf)
 }
 
 // attemptResumeWorkflowOnNode mocks base method.
@@ -858,6 +858,18 @@ func (mr *MockremediationMgrHelperAPIMockRecorder) updateMaxParallelWorkflows(ct
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "updateMaxParallelWorkflows", reflect.TypeOf((*MockremediationMgrHelperAPI)(nil).updateMaxParallelWorkflows), ctx, devConfig)
 }
 
+// updateRemediationMetrics mocks base method.
+func (m *MockremediationMgrHelperAPI) updateRemediationMetrics(ctx context.Context, devConfig *v1alpha1.DeviceConfig, nodes *v1.NodeList, mappings map[string]ConditionWorkflowMapping) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "updateRemediationMetrics", ctx, devConfig, nodes, mappings)
+}
+
+// updateRemediationMetrics indicates an expected call of updateRemediationMetrics.
+func (mr *MockremediationMgrHelperAPIMockRecorder) updateRemediationMetrics(ctx, devConfig, nodes, mappings any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "updateRemediationMetrics", reflect.TypeOf((*MockremediationMgrHelperAPI)(nil).updateRemediationMetrics), ctx, devConfig, nodes, mappings)
+}
+
 // validateNodeConditions mocks base method.
 func (m *MockremediationMgrHelperAPI) validateNodeConditions(ctx context.Context, devConfig *v1alpha1.DeviceConfig, node *v1.Node, mappings map[string]ConditionWorkflowMapping) (ConditionWorkflowMapping, error) {
 	m.ctrl.T.Helper()
@@ -19,7 +19,7 @@ limitations under the License.
 //
 // Generated by this command:
 //
-//	mockgen -source=upgrademgr.go -package=controllers -destination=mock_upgrademgr.go upgradeMgrHelperAPI
+//	mockgen -source=upgrademgr.go -package=controllers -destination=mock_upgrademgr.go upgradeMgrAPI
 //
 // Package controllers is a generated GoMock package.
 package controllers
@@ -27,6 +27,7 @@ package controllers
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	v1alpha1 "github.com/ROCm/gpu-operator/api/v1alpha1"
 	gomock "go.uber.org/mock/gomock"
@@ -57,6 +58,21 @@ func (m *MockupgradeMgrAPI) EXPECT() *MockupgradeMgrAPIMockRecorder {
 	return m.recorder
 }
 
+// GetDriverRollback mocks base method.
+func (m *MockupgradeMgrAPI) GetDriverRollback(deviceConfig *v1alpha1.DeviceConfig) (string, map[string]string) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDriverRollback", deviceConfig)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(map[string]string)
+	return ret0, ret1
+}
+
+// GetDriverRollback indicates an expected call of GetDriverRollback.
+func (mr *MockupgradeMgrAPIMockRecorder) GetDriverRollback(deviceConfig any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDriverRollback", reflect.TypeOf((*MockupgradeMgrAPI)(nil).GetDriverRollback), deviceConfig)
+}
+
 // GetNodeBootId mocks base method.
 func (m *MockupgradeMgrAPI) GetNodeBootId(nodeName string) string {
 	m.ctrl.T.Helper()
@@ -85,6 +101,20 @@ func (mr *MockupgradeMgrAPIMockRecorder) GetNodeStatus(nodeName any) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNodeStatus", reflect.TypeOf((*MockupgradeMgrAPI)(nil).GetNodeStatus), nodeName)
 }
 
+// GetNodeUpgradeAction mocks base method.
+func (m *MockupgradeMgrAPI) GetNodeUpgradeAction(nodeName string) v1alpha1.NodeUpgradeAction {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNodeUpgradeAction", nodeName)
+	ret0, _ := ret[0].(v1alpha1.NodeUpgradeAction)
+	return ret0
+}
+
+// GetNodeUpgradeAction indicates an expected call of GetNodeUpgradeAction.
+func (mr *MockupgradeMgrAPIMockRecorder) GetNodeUpgradeAction(nodeName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNodeUpgradeAction", reflect.TypeOf((*MockupgradeMgrAPI)(nil).GetNodeUpgradeAction), nodeName)
+}
+
 // GetNodeUpgradeStartTime mocks base method.
 func (m *MockupgradeMgrAPI) GetNodeUpgradeStartTime(nodeName string) string {
 	m.ctrl.T.Helper()
@@ -99,6 +129,20 @@ func (mr *MockupgradeMgrAPIMockRecorder) GetNodeUpgradeStartTime(nodeName any) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNodeUpgradeStartTime", reflect.TypeOf((*MockupgradeMgrAPI)(nil).GetNodeUpgradeStartTime), nodeName)
 }
 
+// GetRolloutStatus mocks base method.
+func (m *MockupgradeMgrAPI) GetRolloutStatus(deviceConfig *v1alpha1.DeviceConfig) *v1alpha1.UpgradeRolloutStatus {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRolloutStatus", deviceConfig)
+	ret0, _ := ret[0].(*v1alpha1.UpgradeRolloutStatus)
+	return ret0
+}
+
+// GetRolloutStatus indicates an expected call of GetRolloutStatus.
+func (mr *MockupgradeMgrAPIMockRecorder) GetRolloutStatus(deviceConfig any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRolloutStatus", reflect.TypeOf((*MockupgradeMgrAPI)(nil).GetRolloutStatus), deviceConfig)
+}
+
 // HandleDelete mocks base method.
 func (m *MockupgradeMgrAPI) HandleDelete(ctx context.Context, deviceConfig *v1alpha1.DeviceConfig, nodes *v1.NodeList) (controllerruntime.Result, error) {
 	m.ctrl.T.Helper()
@@ -166,6 +210,20 @@ func (mr *MockupgradeMgrHelperAPIMockRecorder) cleanupDanglingKMMPods(ctx, node,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "cleanupDanglingKMMPods", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).cleanupDanglingKMMPods), ctx, node, deviceConfig)
 }
 
+// cleanupStaleUpgradeHooks mocks base method.
+func (m *MockupgradeMgrHelperAPI) cleanupStaleUpgradeHooks(ctx context.Context, deviceConfig *v1alpha1.DeviceConfig) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "cleanupStaleUpgradeHooks", ctx, deviceConfig)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// cleanupStaleUpgradeHooks indicates an expected call of cleanupStaleUpgradeHooks.
+func (mr *MockupgradeMgrHelperAPIMockRecorder) cleanupStaleUpgradeHooks(ctx, deviceConfig any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "cleanupStaleUpgradeHooks", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).cleanupStaleUpgradeHooks), ctx, deviceConfig)
+}
+
 // clearNodeStatus mocks base method.
 func (m *MockupgradeMgrHelperAPI) clearNodeStatus() {
 	m.ctrl.T.Helper()
@@ -178,6 +236,18 @@ func (mr *MockupgradeMgrHelperAPIMockRecorder) clearNodeStatus() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "clearNodeStatus", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).clearNodeStatus))
 }
 
+// clearRolloutStatus mocks base method.
+func (m *MockupgradeMgrHelperAPI) clearRolloutStatus(deviceConfig *v1alpha1.DeviceConfig) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "clearRolloutStatus", deviceConfig)
+}
+
+// clearRolloutStatus indicates an expected call of clearRolloutStatus.
+func (mr *MockupgradeMgrHelperAPIMockRecorder) clearRolloutStatus(deviceConfig any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "clearRolloutStatus", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).clearRolloutStatus), deviceConfig)
+}
+
 // clearUpgradeStartTime mocks base method.
 func (m *MockupgradeMgrHelperAPI) clearUpgradeStartTime(nodeName string) {
 	m.ctrl.T.Helper()
@@ -244,6 +314,21 @@ func (mr *MockupgradeMgrHelperAPIMockRecorder) getBootID(nodeName any) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getBootID", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).getBootID), nodeName)
 }
 
+// getDriverRollback mocks base method.
+func (m *MockupgradeMgrHelperAPI) getDriverRollback(deviceConfig *v1alpha1.DeviceConfig) (string, map[string]string) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "getDriverRollback", deviceConfig)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(map[string]string)
+	return ret0, ret1
+}
+
+// getDriverRollback indicates an expected call of getDriverRollback.
+func (mr *MockupgradeMgrHelperAPIMockRecorder) getDriverRollback(deviceConfig any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getDriverRollback", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).getDriverRollback), deviceConfig)
+}
+
 // getNode mocks base method.
 func (m *MockupgradeMgrHelperAPI) getNode(ctx context.Context, nodeName string) (*v1.Node, error) {
 	m.ctrl.T.Helper()
@@ -259,6 +344,20 @@ func (mr *MockupgradeMgrHelperAPIMockRecorder) getNode(ctx, nodeName any) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getNode", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).getNode), ctx, nodeName)
 }
 
+// getNodeFailureReason mocks base method.
+func (m *MockupgradeMgrHelperAPI) getNodeFailureReason(nodeName string) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "getNodeFailureReason", nodeName)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// getNodeFailureReason indicates an expected call of getNodeFailureReason.
+func (mr *MockupgradeMgrHelperAPIMockRecorder) getNodeFailureReason(nodeName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getNodeFailureReason", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).getNodeFailureReason), nodeName)
+}
+
 // getNodeStatus mocks base method.
 func (m *MockupgradeMgrHelperAPI) getNodeStatus(nodeName string) v1alpha1.UpgradeState {
 	m.ctrl.T.Helper()
@@ -273,6 +372,35 @@ func (mr *MockupgradeMgrHelperAPIMockRecorder) getNodeStatus(nodeName any) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getNodeStatus", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).getNodeStatus), nodeName)
 }
 
+// getNodeUpgradeAction mocks base method.
+func (m *MockupgradeMgrHelperAPI) getNodeUpgradeAction(nodeName string) v1alpha1.NodeUpgradeAction {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "getNodeUpgradeAction", nodeName)
+	ret0, _ := ret[0].(v1alpha1.NodeUpgradeAction)
+	return ret0
+}
+
+// getNodeUpgradeAction indicates an expected call of getNodeUpgradeAction.
+func (mr *MockupgradeMgrHelperAPIMockRecorder) getNodeUpgradeAction(nodeName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getNodeUpgradeAction", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).getNodeUpgradeAction), nodeName)
+}
+
+// getOrCreateUpgradeStatus mocks base method.
+func (m *MockupgradeMgrHelperAPI) getOrCreateUpgradeStatus(ctx context.Context, deviceConfig *v1alpha1.DeviceConfig) (*v1alpha1.DriverUpgradeStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "getOrCreateUpgradeStatus", ctx, deviceConfig)
+	ret0, _ := ret[0].(*v1alpha1.DriverUpgradeStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// getOrCreateUpgradeStatus indicates an expected call of getOrCreateUpgradeStatus.
+func (mr *MockupgradeMgrHelperAPIMockRecorder) getOrCreateUpgradeStatus(ctx, deviceConfig any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getOrCreateUpgradeStatus", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).getOrCreateUpgradeStatus), ctx, deviceConfig)
+}
+
 // getPod mocks base method.
 func (m *MockupgradeMgrHelperAPI) getPod(ctx context.Context, podName, namespace string) (*v1.Pod, error) {
 	m.ctrl.T.Helper()
@@ -317,6 +445,34 @@ func (mr *MockupgradeMgrHelperAPIMockRecorder) getRebootPod(nodeName, dc any) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getRebootPod", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).getRebootPod), nodeName, dc)
 }
 
+// getRequestedUpgradeAction mocks base method.
+func (m *MockupgradeMgrHelperAPI) getRequestedUpgradeAction(node *v1.Node) v1alpha1.NodeUpgradeAction {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "getRequestedUpgradeAction", node)
+	ret0, _ := ret[0].(v1alpha1.NodeUpgradeAction)
+	return ret0
+}
+
+// getRequestedUpgradeAction indicates an expected call of getRequestedUpgradeAction.
+func (mr *MockupgradeMgrHelperAPIMockRecorder) getRequestedUpgradeAction(node any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getRequestedUpgradeAction", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).getRequestedUpgradeAction), node)
+}
+
+// getRolloutStatus mocks base method.
+func (m *MockupgradeMgrHelperAPI) getRolloutStatus(deviceConfig *v1alpha1.DeviceConfig) *v1alpha1.UpgradeRolloutStatus {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "getRolloutStatus", deviceConfig)
+	ret0, _ := ret[0].(*v1alpha1.UpgradeRolloutStatus)
+	return ret0
+}
+
+// getRolloutStatus indicates an expected call of getRolloutStatus.
+func (mr *MockupgradeMgrHelperAPIMockRecorder) getRolloutStatus(deviceConfig any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getRolloutStatus", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).getRolloutStatus), deviceConfig)
+}
+
 // getUpgradeStartTime mocks base method.
 func (m *MockupgradeMgrHelperAPI) getUpgradeStartTime(nodeName string) string {
 	m.ctrl.T.Helper()
@@ -331,6 +487,20 @@ func (mr *MockupgradeMgrHelperAPIMockRecorder) getUpgradeStartTime(nodeName any)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getUpgradeStartTime", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).getUpgradeStartTime), nodeName)
 }
 
+// handleForcedNodeUpgrade mocks base method.
+func (m *MockupgradeMgrHelperAPI) handleForcedNodeUpgrade(ctx context.Context, deviceConfig *v1alpha1.DeviceConfig, node *v1.Node) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "handleForcedNodeUpgrade", ctx, deviceConfig, node)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// handleForcedNodeUpgrade indicates an expected call of handleForcedNodeUpgrade.
+func (mr *MockupgradeMgrHelperAPIMockRecorder) handleForcedNodeUpgrade(ctx, deviceConfig, node any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleForcedNodeUpgrade", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).handleForcedNodeUpgrade), ctx, deviceConfig, node)
+}
+
 // handleInitStatus mocks base method.
 func (m *MockupgradeMgrHelperAPI) handleInitStatus(ctx context.Context, node *v1.Node, deviceConfig *v1alpha1.DeviceConfig) {
 	m.ctrl.T.Helper()
@@ -355,6 +525,18 @@ func (mr *MockupgradeMgrHelperAPIMockRecorder) handleNodeReboot(ctx, node, dc an
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleNodeReboot", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).handleNodeReboot), ctx, node, dc)
 }
 
+// handleNodeRollback mocks base method.
+func (m *MockupgradeMgrHelperAPI) handleNodeRollback(ctx context.Context, deviceConfig v1alpha1.DeviceConfig, node v1.Node) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "handleNodeRollback", ctx, deviceConfig, node)
+}
+
+// handleNodeRollback indicates an expected call of handleNodeRollback.
+func (mr *MockupgradeMgrHelperAPIMockRecorder) handleNodeRollback(ctx, deviceConfig, node any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleNodeRollback", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).handleNodeRollback), ctx, deviceConfig, node)
+}
+
 // handleNodeUpgrade mocks base method.
 func (m *MockupgradeMgrHelperAPI) handleNodeUpgrade(ctx context.Context, deviceConfig v1alpha1.DeviceConfig, node v1.Node) {
 	m.ctrl.T.Helper()
@@ -393,6 +575,18 @@ func (mr *MockupgradeMgrHelperAPIMockRecorder) hasUpgradeTimeExceeded(ctx, nodeN
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "hasUpgradeTimeExceeded", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).hasUpgradeTimeExceeded), ctx, nodeName, deviceConfig)
 }
 
+// holdNodeUpgrade mocks base method.
+func (m *MockupgradeMgrHelperAPI) holdNodeUpgrade(ctx context.Context, deviceConfig *v1alpha1.DeviceConfig, node *v1.Node, action v1alpha1.NodeUpgradeAction) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "holdNodeUpgrade", ctx, deviceConfig, node, action)
+}
+
+// holdNodeUpgrade indicates an expected call of holdNodeUpgrade.
+func (mr *MockupgradeMgrHelperAPIMockRecorder) holdNodeUpgrade(ctx, deviceConfig, node, action any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "holdNodeUpgrade", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).holdNodeUpgrade), ctx, deviceConfig, node, action)
+}
+
 // isDeviceConfigValid mocks base method.
 func (m *MockupgradeMgrHelperAPI) isDeviceConfigValid(ctx context.Context, deviceConfig *v1alpha1.DeviceConfig) bool {
 	m.ctrl.T.Helper()
@@ -435,6 +629,20 @@ func (mr *MockupgradeMgrHelperAPIMockRecorder) isLabelUpgradeRequiredOnNode(ctx,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "isLabelUpgradeRequiredOnNode", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).isLabelUpgradeRequiredOnNode), ctx, deviceConfig, node)
 }
 
+// isMaintenanceWindowOverridden mocks base method.
+func (m *MockupgradeMgrHelperAPI) isMaintenanceWindowOverridden(node *v1.Node) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "isMaintenanceWindowOverridden", node)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// isMaintenanceWindowOverridden indicates an expected call of isMaintenanceWindowOverridden.
+func (mr *MockupgradeMgrHelperAPIMockRecorder) isMaintenanceWindowOverridden(node any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "isMaintenanceWindowOverridden", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).isMaintenanceWindowOverridden), node)
+}
+
 // isNodeInFailedUpgradeStates mocks base method.
 func (m *MockupgradeMgrHelperAPI) isNodeInFailedUpgradeStates(state v1alpha1.UpgradeState) bool {
 	m.ctrl.T.Helper()
@@ -505,6 +713,35 @@ func (mr *MockupgradeMgrHelperAPIMockRecorder) isNodeReadyForUpgrade(ctx, node a
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "isNodeReadyForUpgrade", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).isNodeReadyForUpgrade), ctx, node)
 }
 
+// isNodeRollbackDeferred mocks base method.
+func (m *MockupgradeMgrHelperAPI) isNodeRollbackDeferred(ctx context.Context, node *v1.Node, deviceConfig *v1alpha1.DeviceConfig) (bool, time.Duration) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "isNodeRollbackDeferred", ctx, node, deviceConfig)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(time.Duration)
+	return ret0, ret1
+}
+
+// isNodeRollbackDeferred indicates an expected call of isNodeRollbackDeferred.
+func (mr *MockupgradeMgrHelperAPIMockRecorder) isNodeRollbackDeferred(ctx, node, deviceConfig any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "isNodeRollbackDeferred", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).isNodeRollbackDeferred), ctx, node, deviceConfig)
+}
+
+// isNodeRollbackRequired mocks base method.
+func (m *MockupgradeMgrHelperAPI) isNodeRollbackRequired(node *v1.Node, deviceConfig *v1alpha1.DeviceConfig) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "isNodeRollbackRequired", node, deviceConfig)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// isNodeRollbackRequired indicates an expected call of isNodeRollbackRequired.
+func (mr *MockupgradeMgrHelperAPIMockRecorder) isNodeRollbackRequired(node, deviceConfig any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "isNodeRollbackRequired", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).isNodeRollbackRequired), node, deviceConfig)
+}
+
 // isNodeStateInstallInProgress mocks base method.
 func (m *MockupgradeMgrHelperAPI) isNodeStateInstallInProgress(ctx context.Context, node *v1.Node, deviceConfig *v1alpha1.DeviceConfig) bool {
 	m.ctrl.T.Helper()
@@ -519,6 +756,20 @@ func (mr *MockupgradeMgrHelperAPIMockRecorder) isNodeStateInstallInProgress(ctx,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "isNodeStateInstallInProgress", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).isNodeStateInstallInProgress), ctx, node, deviceConfig)
 }
 
+// isNodeStatePostUpgradeHook mocks base method.
+func (m *MockupgradeMgrHelperAPI) isNodeStatePostUpgradeHook(ctx context.Context, node *v1.Node, deviceConfig *v1alpha1.DeviceConfig) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "isNodeStatePostUpgradeHook", ctx, node, deviceConfig)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// isNodeStatePostUpgradeHook indicates an expected call of isNodeStatePostUpgradeHook.
+func (mr *MockupgradeMgrHelperAPIMockRecorder) isNodeStatePostUpgradeHook(ctx, node, deviceConfig any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "isNodeStatePostUpgradeHook", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).isNodeStatePostUpgradeHook), ctx, node, deviceConfig)
+}
+
 // isNodeStateUpgradeFailed mocks base method.
 func (m *MockupgradeMgrHelperAPI) isNodeStateUpgradeFailed(ctx context.Context, node *v1.Node) bool {
 	m.ctrl.T.Helper()
@@ -561,6 +812,34 @@ func (mr *MockupgradeMgrHelperAPIMockRecorder) isNodeStateUpgradeStarted(node an
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "isNodeStateUpgradeStarted", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).isNodeStateUpgradeStarted), node)
 }
 
+// isNodeUpgradeHeld mocks base method.
+func (m *MockupgradeMgrHelperAPI) isNodeUpgradeHeld(ctx context.Context, node *v1.Node) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "isNodeUpgradeHeld", ctx, node)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// isNodeUpgradeHeld indicates an expected call of isNodeUpgradeHeld.
+func (mr *MockupgradeMgrHelperAPIMockRecorder) isNodeUpgradeHeld(ctx, node any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "isNodeUpgradeHeld", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).isNodeUpgradeHeld), ctx, node)
+}
+
+// isNodeUpgradePausing mocks base method.
+func (m *MockupgradeMgrHelperAPI) isNodeUpgradePausing(node *v1.Node) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "isNodeUpgradePausing", node)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// isNodeUpgradePausing indicates an expected call of isNodeUpgradePausing.
+func (mr *MockupgradeMgrHelperAPIMockRecorder) isNodeUpgradePausing(node any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "isNodeUpgradePausing", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).isNodeUpgradePausing), node)
+}
+
 // isUpgradePolicyViolated mocks base method.
 func (m *MockupgradeMgrHelperAPI) isUpgradePolicyViolated(upgradeInProgress, upgradeFailedState, totalNodes int, deviceConfig *v1alpha1.DeviceConfig) (int, bool) {
 	m.ctrl.T.Helper()
@@ -576,6 +855,73 @@ func (mr *MockupgradeMgrHelperAPIMockRecorder) isUpgradePolicyViolated(upgradeIn
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "isUpgradePolicyViolated", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).isUpgradePolicyViolated), upgradeInProgress, upgradeFailedState, totalNodes, deviceConfig)
 }
 
+// pauseNodeUpgradeIfRequested mocks base method.
+func (m *MockupgradeMgrHelperAPI) pauseNodeUpgradeIfRequested(ctx context.Context, deviceConfig *v1alpha1.DeviceConfig, node *v1.Node) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "pauseNodeUpgradeIfRequested", ctx, deviceConfig, node)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// pauseNodeUpgradeIfRequested indicates an expected call of pauseNodeUpgradeIfRequested.
+func (mr *MockupgradeMgrHelperAPIMockRecorder) pauseNodeUpgradeIfRequested(ctx, deviceConfig, node any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "pauseNodeUpgradeIfRequested", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).pauseNodeUpgradeIfRequested), ctx, deviceConfig, node)
+}
+
+// persistNodeState mocks base method.
+func (m *MockupgradeMgrHelperAPI) persistNodeState(ctx context.Context, nodeName string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "persistNodeState", ctx, nodeName)
+}
+
+// persistNodeState indicates an expected call of persistNodeState.
+func (mr *MockupgradeMgrHelperAPIMockRecorder) persistNodeState(ctx, nodeName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "persistNodeState", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).persistNodeState), ctx, nodeName)
+}
+
+// planUpgradeRollout mocks base method.
+func (m *MockupgradeMgrHelperAPI) planUpgradeRollout(ctx context.Context, deviceConfig *v1alpha1.DeviceConfig, nodes, candidateNodes []v1.Node) (*v1alpha1.UpgradeRolloutStatus, map[string]bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "planUpgradeRollout", ctx, deviceConfig, nodes, candidateNodes)
+	ret0, _ := ret[0].(*v1alpha1.UpgradeRolloutStatus)
+	ret1, _ := ret[1].(map[string]bool)
+	return ret0, ret1
+}
+
+// planUpgradeRollout indicates an expected call of planUpgradeRollout.
+func (mr *MockupgradeMgrHelperAPIMockRecorder) planUpgradeRollout(ctx, deviceConfig, nodes, candidateNodes any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "planUpgradeRollout", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).planUpgradeRollout), ctx, deviceConfig, nodes, candidateNodes)
+}
+
+// pruneUpgradeStatus mocks base method.
+func (m *MockupgradeMgrHelperAPI) pruneUpgradeStatus(ctx context.Context, upgradeStatus *v1alpha1.DriverUpgradeStatus, nodeList *v1.NodeList) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "pruneUpgradeStatus", ctx, upgradeStatus, nodeList)
+}
+
+// pruneUpgradeStatus indicates an expected call of pruneUpgradeStatus.
+func (mr *MockupgradeMgrHelperAPIMockRecorder) pruneUpgradeStatus(ctx, upgradeStatus, nodeList any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "pruneUpgradeStatus", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).pruneUpgradeStatus), ctx, upgradeStatus, nodeList)
+}
+
+// refuseConflictingNodeRollback mocks base method.
+func (m *MockupgradeMgrHelperAPI) refuseConflictingNodeRollback(ctx context.Context, node *v1.Node, deviceConfig *v1alpha1.DeviceConfig, nodes []v1.Node) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "refuseConflictingNodeRollback", ctx, node, deviceConfig, nodes)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// refuseConflictingNodeRollback indicates an expected call of refuseConflictingNodeRollback.
+func (mr *MockupgradeMgrHelperAPIMockRecorder) refuseConflictingNodeRollback(ctx, node, deviceConfig, nodes any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "refuseConflictingNodeRollback", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).refuseConflictingNodeRollback), ctx, node, deviceConfig, nodes)
+}
+
 // removeLabelUpgradeRequiredOnNode mocks base method.
 func (m *MockupgradeMgrHelperAPI) removeLabelUpgradeRequiredOnNode(ctx context.Context, node *v1.Node) error {
 	m.ctrl.T.Helper()
@@ -590,6 +936,20 @@ func (mr *MockupgradeMgrHelperAPIMockRecorder) removeLabelUpgradeRequiredOnNode(
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "removeLabelUpgradeRequiredOnNode", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).removeLabelUpgradeRequiredOnNode), ctx, node)
 }
 
+// removeUpgradeActionLabelOnNode mocks base method.
+func (m *MockupgradeMgrHelperAPI) removeUpgradeActionLabelOnNode(ctx context.Context, node *v1.Node, labelValue string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "removeUpgradeActionLabelOnNode", ctx, node, labelValue)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// removeUpgradeActionLabelOnNode indicates an expected call of removeUpgradeActionLabelOnNode.
+func (mr *MockupgradeMgrHelperAPIMockRecorder) removeUpgradeActionLabelOnNode(ctx, node, labelValue any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "removeUpgradeActionLabelOnNode", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).removeUpgradeActionLabelOnNode), ctx, node, labelValue)
+}
+
 // resetModuleVersionOnNode mocks base method.
 func (m *MockupgradeMgrHelperAPI) resetModuleVersionOnNode(ctx context.Context, deviceConfig *v1alpha1.DeviceConfig, node *v1.Node) error {
 	m.ctrl.T.Helper()
@@ -604,6 +964,32 @@ func (mr *MockupgradeMgrHelperAPIMockRecorder) resetModuleVersionOnNode(ctx, dev
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "resetModuleVersionOnNode", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).resetModuleVersionOnNode), ctx, deviceConfig, node)
 }
 
+// restoreNodeState mocks base method.
+func (m *MockupgradeMgrHelperAPI) restoreNodeState(nodeName string, nodeState v1alpha1.NodeUpgradeStatus) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "restoreNodeState", nodeName, nodeState)
+}
+
+// restoreNodeState indicates an expected call of restoreNodeState.
+func (mr *MockupgradeMgrHelperAPIMockRecorder) restoreNodeState(nodeName, nodeState any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "restoreNodeState", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).restoreNodeState), nodeName, nodeState)
+}
+
+// runPreUpgradeHook mocks base method.
+func (m *MockupgradeMgrHelperAPI) runPreUpgradeHook(ctx context.Context, deviceConfig *v1alpha1.DeviceConfig, node *v1.Node) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "runPreUpgradeHook", ctx, deviceConfig, node)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// runPreUpgradeHook indicates an expected call of runPreUpgradeHook.
+func (mr *MockupgradeMgrHelperAPIMockRecorder) runPreUpgradeHook(ctx, deviceConfig, node any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "runPreUpgradeHook", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).runPreUpgradeHook), ctx, deviceConfig, node)
+}
+
 // setBootID mocks base method.
 func (m *MockupgradeMgrHelperAPI) setBootID(nodeName, bootID string) {
 	m.ctrl.T.Helper()
@@ -616,6 +1002,30 @@ func (mr *MockupgradeMgrHelperAPIMockRecorder) setBootID(nodeName, bootID any) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "setBootID", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).setBootID), nodeName, bootID)
 }
 
+// setNodeDeviceConfig mocks base method.
+func (m *MockupgradeMgrHelperAPI) setNodeDeviceConfig(nodeName string, deviceConfig *v1alpha1.DeviceConfig) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "setNodeDeviceConfig", nodeName, deviceConfig)
+}
+
+// setNodeDeviceConfig indicates an expected call of setNodeDeviceConfig.
+func (mr *MockupgradeMgrHelperAPIMockRecorder) setNodeDeviceConfig(nodeName, deviceConfig any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "setNodeDeviceConfig", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).setNodeDeviceConfig), nodeName, deviceConfig)
+}
+
+// setNodeFailureReason mocks base method.
+func (m *MockupgradeMgrHelperAPI) setNodeFailureReason(nodeName, reason string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "setNodeFailureReason", nodeName, reason)
+}
+
+// setNodeFailureReason indicates an expected call of setNodeFailureReason.
+func (mr *MockupgradeMgrHelperAPIMockRecorder) setNodeFailureReason(nodeName, reason any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "setNodeFailureReason", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).setNodeFailureReason), nodeName, reason)
+}
+
 // setNodeStatus mocks base method.
 func (m *MockupgradeMgrHelperAPI) setNodeStatus(ctx context.Context, nodeName string, status v1alpha1.UpgradeState) {
 	m.ctrl.T.Helper()
@@ -628,6 +1038,30 @@ func (mr *MockupgradeMgrHelperAPIMockRecorder) setNodeStatus(ctx, nodeName, stat
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "setNodeStatus", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).setNodeStatus), ctx, nodeName, status)
 }
 
+// setNodeUpgradeAction mocks base method.
+func (m *MockupgradeMgrHelperAPI) setNodeUpgradeAction(nodeName string, action v1alpha1.NodeUpgradeAction) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "setNodeUpgradeAction", nodeName, action)
+}
+
+// setNodeUpgradeAction indicates an expected call of setNodeUpgradeAction.
+func (mr *MockupgradeMgrHelperAPIMockRecorder) setNodeUpgradeAction(nodeName, action any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "setNodeUpgradeAction", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).setNodeUpgradeAction), nodeName, action)
+}
+
+// setPreviousDriver mocks base method.
+func (m *MockupgradeMgrHelperAPI) setPreviousDriver(node *v1.Node, deviceConfig *v1alpha1.DeviceConfig) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "setPreviousDriver", node, deviceConfig)
+}
+
+// setPreviousDriver indicates an expected call of setPreviousDriver.
+func (mr *MockupgradeMgrHelperAPIMockRecorder) setPreviousDriver(node, deviceConfig any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "setPreviousDriver", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).setPreviousDriver), node, deviceConfig)
+}
+
 // setUpgradeStartTime mocks base method.
 func (m *MockupgradeMgrHelperAPI) setUpgradeStartTime(nodeName string) {
 	m.ctrl.T.Helper()
@@ -679,3 +1113,17 @@ func (mr *MockupgradeMgrHelperAPIMockRecorder) updateModuleVersionOnNode(ctx, de
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "updateModuleVersionOnNode", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).updateModuleVersionOnNode), ctx, deviceConfig, node)
 }
+
+// waitForGPUPodsCompletion mocks base method.
+func (m *MockupgradeMgrHelperAPI) waitForGPUPodsCompletion(ctx context.Context, deviceConfig *v1alpha1.DeviceConfig, node *v1.Node) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "waitForGPUPodsCompletion", ctx, deviceConfig, node)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// waitForGPUPodsCompletion indicates an expected call of waitForGPUPodsCompletion.
+func (mr *MockupgradeMgrHelperAPIMockRecorder) waitForGPUPodsCompletion(ctx, deviceConfig, node any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "waitForGPUPodsCompletion", reflect.TypeOf((*MockupgradeMgrHelperAPI)(nil).waitForGPUPodsCompletion), ctx, deviceConfig, node)
+}
@@ -0,0 +1,341 @@
+/*
+Copyright (c) Advanced Micro Devices, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the \"License\");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an \"AS IS\" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	amdv1alpha1 "github.com/ROCm/gpu-operator/api/v1alpha1"
+	utils "github.com/ROCm/gpu-operator/internal"
+	"github.com/ROCm/gpu-operator/internal/conditions"
+)
+
+const (
+	nfdGroup   = "nfd.k8s-sigs.io"
+	nfdVersion = "v1alpha1"
+	// NodeFeature objects are labelled with the name of the node they describe
+	nfdNodeNameLabelKey = "nfd.node.kubernetes.io/node-name"
+	amdPCIVendorID      = "1002"
+	// at most this number of nodes are named in the condition message
+	maxReportedUnmatchedNodes = 10
+	// the NodeFeatures are not cached by the manager, so they are listed at most once per interval to look for unmatched GPU devices
+	unmatchedGPUDevicesCheckInterval = 5 * time.Minute
+)
+
+// unmatchedGPUDevicesCache keeps the last lookup of the unmatched GPU devices,
+// the lookup is cluster wide so it is shared by all the DeviceConfigs
+type unmatchedGPUDevicesCache struct {
+	mu        sync.Mutex
+	checkedAt time.Time
+	devices   map[string][]string
+	err       error
+}
+
+// PCI classes of the GPUs: display controllers (03xx) and processing accelerators (12xx)
+var gpuPCIClassPrefixes = []string{"03", "12"}
+
+func newNodeFeatureRule(name string) *unstructured.Unstructured {
+	rule := &unstructured.Unstructured{}
+	rule.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   nfdGroup,
+		Version: nfdVersion,
+		Kind:    "NodeFeatureRule",
+	})
+	rule.SetName(name)
+	return rule
+}
+
+// getNodeFeatureRuleName returns the name of the NodeFeatureRule of the DeviceConfig, NodeFeatureRules are cluster scoped
+func getNodeFeatureRuleName(devConfig *amdv1alpha1.DeviceConfig) string {
+	return fmt.Sprintf("%s-%s-amd-gpu-nfd-rule", devConfig.Namespace, devConfig.Name)
+}
+
+// getNodeFeatureRuleDeviceIDs returns the lower case device IDs known by the operator followed by the ones of the user, without duplicates
+func getNodeFeatureRuleDeviceIDs(defaults, extra []string) []string {
+	seen := map[string]bool{}
+	ids := []string{}
+	for _, id := range append(append([]string{}, defaults...), extra...) {
+		id = strings.ToLower(id)
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// getNodeFeatureRuleSpec returns the NodeFeatureRule spec labelling the nodes with AMD GPUs and AMD GPU virtual functions
+func getNodeFeatureRuleSpec(devConfig *amdv1alpha1.DeviceConfig) map[string]interface{} {
+	newRule := func(name, label string, deviceIDs []string) map[string]interface{} {
+		values := []interface{}{}
+		for _, id := range deviceIDs {
+			values = append(values, id)
+		}
+		return map[string]interface{}{
+			"name": name,
+			"labels": map[string]interface{}{
+				label: "true",
+			},
+			"matchFeatures": []interface{}{
+				map[string]interface{}{
+					"feature": "pci.device",
+					"matchExpressions": map[string]interface{}{
+						"vendor": map[string]interface{}{"op": "In", "value": []interface{}{amdPCIVendorID}},
+						"device": map[string]interface{}{"op": "In", "value": values},
+					},
+				},
+			},
+		}
+	}
+	spec := devConfig.Spec.NodeFeatureRule
+	return map[string]interface{}{
+		"rules": []interface{}{
+			newRule("amd-gpu", utils.NodeFeatureLabelAmdGpu, getNodeFeatureRuleDeviceIDs(utils.DefaultPFDeviceIDs, spec.DeviceIDs)),
+			newRule("amd-vgpu", utils.NodeFeatureLabelAmdVGpu, getNodeFeatureRuleDeviceIDs(utils.DefaultVFDeviceIDs, spec.VFDeviceIDs)),
+		},
+	}
+}
+
+// handleNodeFeatureRule reconciles the NodeFeatureRule labelling the GPU nodes,
+// adding a device ID to the rule doesn't require to upgrade the helm chart
+func (dcrh *deviceConfigReconcilerHelper) handleNodeFeatureRule(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig) error {
+	logger := log.FromContext(ctx)
+	if !devConfig.Spec.NodeFeatureRule.IsEnabled() {
+		return dcrh.deleteNodeFeatureRules(ctx, devConfig)
+	}
+
+	name := getNodeFeatureRuleName(devConfig)
+	rule := newNodeFeatureRule(name)
+	opRes, err := controllerutil.CreateOrPatch(ctx, dcrh.client, rule, func() error {
+		ruleLabels := rule.GetLabels()
+		if ruleLabels == nil {
+			ruleLabels = map[string]string{}
+		}
+		// NodeFeatureRules are cluster scoped and cannot be owned by the DeviceConfig, the labels track the owner instead
+		if rule.GetResourceVersion() != "" && (ruleLabels[deviceClassOwnerNameLabelKey] != devConfig.Name || ruleLabels[deviceClassOwnerNamespaceLabelKey] != devConfig.Namespace) {
+			return fmt.Errorf("NodeFeatureRule %s already exists and is not managed by DeviceConfig %s/%s", name, devConfig.Namespace, devConfig.Name)
+		}
+		ruleLabels["app.kubernetes.io/component"] = "amd-gpu"
+		ruleLabels["app.kubernetes.io/part-of"] = "amd-gpu"
+		ruleLabels[deviceClassOwnerNameLabelKey] = devConfig.Name
+		ruleLabels[deviceClassOwnerNamespaceLabelKey] = devConfig.Namespace
+		rule.SetLabels(ruleLabels)
+		rule.Object["spec"] = getNodeFeatureRuleSpec(devConfig)
+		return nil
+	})
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			// the missing NFD is reported when no node matches the selector
+			logger.Info("NodeFeatureRule is not served by the cluster, skip NodeFeatureRule reconciliation", "name", name)
+			return nil
+		}
+		return fmt.Errorf("failed to reconcile NodeFeatureRule %s: %v", name, err)
+	}
+	logger.Info("Reconciled NodeFeatureRule", "name", name, "result", opRes)
+	return nil
+}
+
+// deleteNodeFeatureRules deletes the NodeFeatureRules created for the DeviceConfig
+func (dcrh *deviceConfigReconcilerHelper) deleteNodeFeatureRules(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig) error {
+	logger := log.FromContext(ctx)
+
+	ruleList := &unstructured.UnstructuredList{}
+	ruleList.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   nfdGroup,
+		Version: nfdVersion,
+		Kind:    "NodeFeatureRuleList",
+	})
+	if err := dcrh.client.List(ctx, ruleList, client.MatchingLabels{
+		deviceClassOwnerNameLabelKey:      devConfig.Name,
+		deviceClassOwnerNamespaceLabelKey: devConfig.Namespace,
+	}); err != nil {
+		if meta.IsNoMatchError(err) {
+			// NFD is not installed, nothing to clean up
+			return nil
+		}
+		return fmt.Errorf("failed to list NodeFeatureRules: %v", err)
+	}
+
+	for i := range ruleList.Items {
+		rule := &ruleList.Items[i]
+		logger.Info("deleting NodeFeatureRule", "name", rule.GetName())
+		if err := dcrh.client.Delete(ctx, rule); err != nil && !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete NodeFeatureRule %s: %v", rule.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// diagnoseNoMatchingNodes explains why no node matches the selector of the DeviceConfig:
+// NFD is not installed, or NFD found AMD GPU PCI devices on nodes that are not labelled as GPU nodes
+func (dcrh *deviceConfigReconcilerHelper) diagnoseNoMatchingNodes(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig) (string, string, error) {
+	msg := fmt.Sprintf("no nodes found matching selector %s", labels.Set(devConfig.Spec.Selector).String())
+
+	unmatched, err := dcrh.findUnmatchedGPUDevices(ctx)
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			return conditions.NFDNotInstalled, msg + "; Node Feature Discovery is not installed, install it or label the GPU nodes", nil
+		}
+		return "", "", err
+	}
+	if len(unmatched) == 0 {
+		return conditions.NoMatchingNodes, msg + "; verify node labels or check that NFD has labeled the GPU nodes", nil
+	}
+	return conditions.UnmatchedGPUDevices, msg + "; " + getUnmatchedGPUDevicesMessage(unmatched), nil
+}
+
+// handleUnmatchedGPUDevices reports the AMD GPU PCI devices found by NFD on nodes that are not labelled as GPU nodes,
+// the nodes matching the selector of the DeviceConfig may not be all the GPU nodes, e.g. after a new GPU model was added
+func (dcrh *deviceConfigReconcilerHelper) handleUnmatchedGPUDevices(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig) error {
+	unmatched, err := dcrh.findUnmatchedGPUDevices(ctx)
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			// without NFD the GPU nodes are labelled by the user
+			dcrh.conditionUpdater.DeleteUnmatchedGPUDevicesCondition(devConfig)
+			return nil
+		}
+		return err
+	}
+	if len(unmatched) == 0 {
+		dcrh.conditionUpdater.DeleteUnmatchedGPUDevicesCondition(devConfig)
+		return nil
+	}
+	dcrh.conditionUpdater.SetUnmatchedGPUDevicesCondition(devConfig, metav1.ConditionTrue, conditions.UnmatchedGPUDevices, getUnmatchedGPUDevicesMessage(unmatched))
+	return nil
+}
+
+// findUnmatchedGPUDevices returns the AMD GPUs of the nodes that are not labelled as GPU nodes, looked up at most once
+// per unmatchedGPUDevicesCheckInterval, a NoMatch error is returned when NFD is not installed
+func (dcrh *deviceConfigReconcilerHelper) findUnmatchedGPUDevices(ctx context.Context) (map[string][]string, error) {
+	cache := &dcrh.unmatchedGPUDevices
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if !cache.checkedAt.IsZero() && time.Since(cache.checkedAt) < unmatchedGPUDevicesCheckInterval {
+		return cache.devices, cache.err
+	}
+
+	devices, err := dcrh.lookupUnmatchedGPUDevices(ctx)
+	if err != nil && !meta.IsNoMatchError(err) {
+		// transient errors are retried by the next reconciliation
+		return nil, err
+	}
+	cache.checkedAt, cache.devices, cache.err = time.Now(), devices, err
+	return devices, err
+}
+
+// lookupUnmatchedGPUDevices lists the NodeFeatures and the nodes to find the AMD GPUs of the nodes that are not labelled as GPU nodes
+func (dcrh *deviceConfigReconcilerHelper) lookupUnmatchedGPUDevices(ctx context.Context) (map[string][]string, error) {
+	featureList := &unstructured.UnstructuredList{}
+	featureList.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   nfdGroup,
+		Version: nfdVersion,
+		Kind:    "NodeFeatureList",
+	})
+	if err := dcrh.client.List(ctx, featureList, client.InNamespace(metav1.NamespaceAll)); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to list NodeFeatures: %v", err)
+	}
+
+	nodeList := &v1.NodeList{}
+	if err := dcrh.client.List(ctx, nodeList); err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %v", err)
+	}
+	return getUnmatchedGPUDevices(featureList, nodeList), nil
+}
+
+// getUnmatchedGPUDevicesMessage names the unmatched AMD GPU PCI devices of the first nodes
+func getUnmatchedGPUDevicesMessage(unmatched map[string][]string) string {
+	nodeNames := []string{}
+	for nodeName := range unmatched {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+	reported := []string{}
+	for i, nodeName := range nodeNames {
+		if i == maxReportedUnmatchedNodes {
+			reported = append(reported, fmt.Sprintf("and %d more nodes", len(nodeNames)-i))
+			break
+		}
+		reported = append(reported, fmt.Sprintf("%s (%s)", nodeName, strings.Join(unmatched[nodeName], ", ")))
+	}
+	return fmt.Sprintf("AMD GPU PCI devices found on nodes without the %s or %s label: %s; add their device IDs to spec.nodeFeatureRule",
+		utils.NodeFeatureLabelAmdGpu, utils.NodeFeatureLabelAmdVGpu, strings.Join(reported, "; "))
+}
+
+// getUnmatchedGPUDevices returns the vendor:device IDs of the AMD GPU PCI devices reported by NFD per node,
+// for the nodes labelled neither as GPU nodes nor as virtual GPU nodes
+func getUnmatchedGPUDevices(featureList *unstructured.UnstructuredList, nodeList *v1.NodeList) map[string][]string {
+	labelled := map[string]bool{}
+	for _, node := range nodeList.Items {
+		if utils.HasNodeLabelKey(node, utils.NodeFeatureLabelAmdGpu) || utils.HasNodeLabelKey(node, utils.NodeFeatureLabelAmdVGpu) {
+			labelled[node.Name] = true
+		}
+	}
+
+	unmatched := map[string][]string{}
+	for _, feature := range featureList.Items {
+		nodeName := feature.GetLabels()[nfdNodeNameLabelKey]
+		if nodeName == "" || labelled[nodeName] {
+			continue
+		}
+		elements, _, _ := unstructured.NestedSlice(feature.Object, "spec", "features", "instances", "pci.device", "elements")
+		for _, element := range elements {
+			elementMap, ok := element.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			attributes, _, _ := unstructured.NestedStringMap(elementMap, "attributes")
+			if attributes["vendor"] != amdPCIVendorID || !isGPUPCIClass(attributes["class"]) {
+				continue
+			}
+			device := amdPCIVendorID + ":" + attributes["device"]
+			if !slices.Contains(unmatched[nodeName], device) {
+				unmatched[nodeName] = append(unmatched[nodeName], device)
+			}
+		}
+	}
+	return unmatched
+}
+
+func isGPUPCIClass(class string) bool {
+	for _, prefix := range gpuPCIClassPrefixes {
+		if strings.HasPrefix(class, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+/*
+Copyright (c) Advanced Micro Devices, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the \"License\");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an \"AS IS\" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	amdv1alpha1 "github.com/ROCm/gpu-operator/api/v1alpha1"
+)
+
+// nodeOperation runs an operation on a node in the background, like a repartitioning or a DRA migration. The node
+// is tainted while the operation runs and its progress is kept in the node annotations, so that the operation
+// resumes where it stopped after an operator restart
+type nodeOperation struct {
+	client         client.Client
+	k8sInterface   kubernetes.Interface
+	nodeInProgress *sync.Map
+
+	// name describes the operation in the logs
+	name                string
+	taint               v1.Taint
+	stateAnnotation     string
+	startTimeAnnotation string
+	messageAnnotation   string
+	// annotations are the other annotations of the operation, they are removed along with the state once the node is released
+	annotations  []string
+	pollInterval time.Duration
+}
+
+// isRunning returns true while the operation of the node runs in the background
+func (o *nodeOperation) isRunning(nodeName string) bool {
+	_, ok := o.nodeInProgress.Load(nodeName)
+	return ok
+}
+
+// run runs the operation of the node in the background
+func (o *nodeOperation) run(nodeName string, operation func()) {
+	o.nodeInProgress.Store(nodeName, true)
+	go func() {
+		defer o.nodeInProgress.Delete(nodeName)
+		operation()
+	}()
+}
+
+func (o *nodeOperation) getState(node *v1.Node) string {
+	return node.Annotations[o.stateAnnotation]
+}
+
+// getStartTime returns the time the operation started on the node
+func (o *nodeOperation) getStartTime(node *v1.Node) (time.Time, error) {
+	return time.Parse(time.RFC3339, node.Annotations[o.startTimeAnnotation])
+}
+
+// begin taints the node and annotates it with the first state of the operation, it returns the start time of the operation
+func (o *nodeOperation) begin(ctx context.Context, nodeName, state string, mutate func(nodeObj *v1.Node)) (time.Time, error) {
+	startTime := time.Now().UTC()
+	err := patchNode(ctx, o.client, nodeName, func(nodeObj *v1.Node) {
+		nodeObj.Annotations[o.stateAnnotation] = state
+		nodeObj.Annotations[o.startTimeAnnotation] = startTime.Format(time.RFC3339)
+		delete(nodeObj.Annotations, o.messageAnnotation)
+		nodeObj.Spec.Taints = addTaint(nodeObj.Spec.Taints, o.taint)
+		if mutate != nil {
+			mutate(nodeObj)
+		}
+	})
+	return startTime, err
+}
+
+// setState annotates the node with the next state of the operation
+func (o *nodeOperation) setState(ctx context.Context, nodeName, state string, mutate func(nodeObj *v1.Node)) error {
+	return patchNode(ctx, o.client, nodeName, func(nodeObj *v1.Node) {
+		nodeObj.Annotations[o.stateAnnotation] = state
+		if mutate != nil {
+			mutate(nodeObj)
+		}
+	})
+}
+
+// drain evicts the pods of the node accepted by selectPod following the drain policy
+func (o *nodeOperation) drain(ctx context.Context, nodeName string, drainPolicy *amdv1alpha1.DrainSpec, deadline time.Time,
+	selectPod func(pod *v1.Pod) bool) error {
+	evicted, err := evictNodePods(ctx, o.k8sInterface, nodeName, drainPolicy, deadline, selectPod)
+	if evicted > 0 {
+		log.FromContext(ctx).Info(fmt.Sprintf("Node: %v drained %v pods before %v", nodeName, evicted, o.name))
+	}
+	return err
+}
+
+// poll polls the condition until it is met, the deadline passes or the operation got canceled
+func (o *nodeOperation) poll(ctx context.Context, nodeName string, deadline time.Time, condition func() (bool, error)) bool {
+	ticker := time.NewTicker(o.pollInterval)
+	defer ticker.Stop()
+	for {
+		done, err := condition()
+		if err != nil {
+			log.FromContext(ctx).Error(err, fmt.Sprintf("Node: %v failed to check %v", nodeName, o.name))
+		} else if done {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// fail annotates the node with the failed state and the reason, the node stays tainted to be inspected
+func (o *nodeOperation) fail(ctx context.Context, nodeName, state, reason string, mutate func(nodeObj *v1.Node)) {
+	log.FromContext(ctx).Info(fmt.Sprintf("Node: %v %v failed: %v", nodeName, o.name, reason))
+	if err := o.setState(ctx, nodeName, state, func(nodeObj *v1.Node) {
+		nodeObj.Annotations[o.messageAnnotation] = reason
+		if mutate != nil {
+			mutate(nodeObj)
+		}
+	}); err != nil {
+		log.FromContext(ctx).Error(err, fmt.Sprintf("Node: %v failed to mark %v as failed", nodeName, o.name))
+	}
+}
+
+// release removes the taint and the annotations of the operation from the node
+func (o *nodeOperation) release(ctx context.Context, nodeName string, mutate func(nodeObj *v1.Node)) error {
+	return patchNode(ctx, o.client, nodeName, func(nodeObj *v1.Node) {
+		for _, key := range append([]string{o.stateAnnotation, o.startTimeAnnotation, o.messageAnnotation}, o.annotations...) {
+			delete(nodeObj.Annotations, key)
+		}
+		nodeObj.Spec.Taints = removeTaint(nodeObj.Spec.Taints, o.taint)
+		if mutate != nil {
+			mutate(nodeObj)
+		}
+	})
+}
@@ -0,0 +1,242 @@
+/*
+Copyright (c) Advanced Micro Devices, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the \"License\");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an \"AS IS\" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/kubectl/pkg/drain"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	amdv1alpha1 "github.com/ROCm/gpu-operator/api/v1alpha1"
+)
+
+// patchNode applies the mutation on the latest version of the node with a merge patch
+func patchNode(ctx context.Context, c client.Client, nodeName string, mutate func(nodeObj *v1.Node)) error {
+	nodeObj := &v1.Node{}
+	if err := c.Get(ctx, client.ObjectKey{Name: nodeName}, nodeObj); err != nil {
+		return err
+	}
+	original := nodeObj.DeepCopy()
+	if nodeObj.Labels == nil {
+		nodeObj.Labels = map[string]string{}
+	}
+	if nodeObj.Annotations == nil {
+		nodeObj.Annotations = map[string]string{}
+	}
+	mutate(nodeObj)
+	return c.Patch(ctx, nodeObj, client.MergeFrom(original))
+}
+
+// evictNodePods evicts the pods of the node accepted by selectPod following the drain policy, DaemonSet pods are never evicted.
+// It returns the number of pods to evict
+func evictNodePods(ctx context.Context, k8sInterface kubernetes.Interface, nodeName string, drainPolicy *amdv1alpha1.DrainSpec,
+	deadline time.Time, selectPod func(pod *v1.Pod) bool) (int, error) {
+	pods, err := k8sInterface.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.SelectorFromSet(fields.Set{"spec.nodeName": nodeName}).String(),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	drainHelper := &drain.Helper{
+		Ctx:                 ctx,
+		Client:              k8sInterface,
+		Out:                 logWriter{logger: log.FromContext(ctx)},
+		ErrOut:              logWriter{logger: log.FromContext(ctx), isErr: true},
+		IgnoreAllDaemonSets: true,
+		GracePeriodSeconds:  -1,
+		DeleteEmptyDirData:  true,
+		Timeout:             time.Until(deadline),
+	}
+	var ignoreNamespaces []string
+	if drainPolicy != nil {
+		drainHelper.Force = drainPolicy.Force != nil && *drainPolicy.Force
+		drainHelper.GracePeriodSeconds = drainPolicy.GracePeriodSeconds
+		if drainPolicy.TimeoutSeconds > 0 {
+			drainHelper.Timeout = min(drainHelper.Timeout, time.Duration(drainPolicy.TimeoutSeconds)*time.Second)
+		}
+		ignoreNamespaces = drainPolicy.IgnoreNamespaces
+	}
+
+	var selected []v1.Pod
+	for _, pod := range pods.Items {
+		if isDaemonSetPod(&pod) || slices.Contains(ignoreNamespaces, pod.Namespace) {
+			continue
+		}
+		if selectPod(&pod) {
+			selected = append(selected, pod)
+		}
+	}
+	if len(selected) == 0 {
+		return 0, nil
+	}
+	return len(selected), drainHelper.DeleteOrEvictPods(selected)
+}
+
+// logWriter writes the output of the drain helper to the logger
+type logWriter struct {
+	logger logr.Logger
+	isErr  bool
+}
+
+func (w logWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimSpace(string(p)), "\n") {
+		if line == "" {
+			continue
+		}
+		if w.isErr {
+			w.logger.Error(nil, line)
+		} else {
+			w.logger.Info(line)
+		}
+	}
+	return len(p), nil
+}
+
+// isGPUPod returns true if the pod requests GPU resources, mounts the GPU devices
+// or holds a ResourceClaim allocated by the AMD GPU DRA driver
+func isGPUPod(ctx context.Context, c client.Client, pod *v1.Pod) bool {
+	if requestsGPUResource(pod) {
+		return true
+	}
+	for _, container := range slices.Concat(pod.Spec.InitContainers, pod.Spec.Containers) {
+		for _, volumeMount := range container.VolumeMounts {
+			if strings.HasPrefix(volumeMount.MountPath, "/dev/dri") {
+				return true
+			}
+		}
+	}
+	return hasGPUResourceClaim(ctx, c, pod)
+}
+
+// hasGPUResourceClaim returns true if one of the ResourceClaims of the pod got devices allocated by the AMD GPU DRA driver,
+// a claim that cannot be read is assumed to hold GPUs so that the pod is not left running on the node
+func hasGPUResourceClaim(ctx context.Context, c client.Client, pod *v1.Pod) bool {
+	claimNames := map[string]bool{}
+	for _, podClaim := range pod.Spec.ResourceClaims {
+		if podClaim.ResourceClaimName != nil {
+			claimNames[*podClaim.ResourceClaimName] = true
+		}
+	}
+	for _, claimStatus := range pod.Status.ResourceClaimStatuses {
+		if claimStatus.ResourceClaimName != nil {
+			claimNames[*claimStatus.ResourceClaimName] = true
+		}
+	}
+	if len(claimNames) == 0 {
+		return false
+	}
+
+	apiVersion := discoverDRAAPIVersion()
+	for claimName := range claimNames {
+		claim := &unstructured.Unstructured{}
+		claim.SetGroupVersionKind(schema.GroupVersionKind{
+			Group:   "resource.k8s.io",
+			Version: apiVersion,
+			Kind:    "ResourceClaim",
+		})
+		if err := c.Get(ctx, types.NamespacedName{Namespace: pod.Namespace, Name: claimName}, claim); err != nil {
+			if k8serrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+				continue
+			}
+			log.FromContext(ctx).Error(err, fmt.Sprintf("failed to get ResourceClaim %v/%v of pod %v", pod.Namespace, claimName, pod.Name))
+			return true
+		}
+		results, _, _ := unstructured.NestedSlice(claim.Object, "status", "allocation", "devices", "results")
+		for _, result := range results {
+			if resultMap, ok := result.(map[string]interface{}); ok && resultMap["driver"] == deviceClassName {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func addTaint(taints []v1.Taint, taint v1.Taint) []v1.Taint {
+	for _, t := range taints {
+		if t.Key == taint.Key && t.Effect == taint.Effect {
+			return taints
+		}
+	}
+	return append(taints, taint)
+}
+
+func removeTaint(taints []v1.Taint, taint v1.Taint) []v1.Taint {
+	var result []v1.Taint
+	for _, t := range taints {
+		if t.Key == taint.Key && t.Effect == taint.Effect {
+			continue
+		}
+		result = append(result, t)
+	}
+	return result
+}
+
+// requestsGPUResource returns true if the pod requests GPU resources advertised by the device plugin
+func requestsGPUResource(pod *v1.Pod) bool {
+	for _, container := range slices.Concat(pod.Spec.InitContainers, pod.Spec.Containers) {
+		for resourceName := range container.Resources.Requests {
+			if _, ok := validResources[string(resourceName)]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasTaint(node *v1.Node, taint v1.Taint) bool {
+	for _, t := range node.Spec.Taints {
+		if t.Key == taint.Key && t.Effect == taint.Effect {
+			return true
+		}
+	}
+	return false
+}
+
+func isPodOwnedByDaemonSet(pod *v1.Pod, dsName string) bool {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind == "DaemonSet" && owner.Name == dsName {
+			return true
+		}
+	}
+	return false
+}
+
+func isPodReady(pod *v1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,405 @@
+/*
+Copyright (c) Advanced Micro Devices, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the \"License\");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an \"AS IS\" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	amdv1alpha1 "github.com/ROCm/gpu-operator/api/v1alpha1"
+	utils "github.com/ROCm/gpu-operator/internal"
+	"github.com/ROCm/gpu-operator/internal/configmanager"
+)
+
+const (
+	// partitionTaintKey keeps new pods off the node while it is repartitioned
+	partitionTaintKey = "amd-gpu-partition"
+
+	partitionProfileAnnotation   = "operator.amd.com/gpu-partition-profile"
+	partitionStateAnnotation     = "operator.amd.com/gpu-partition-state"
+	partitionStartTimeAnnotation = "operator.amd.com/gpu-partition-start-time"
+	partitionMessageAnnotation   = "operator.amd.com/gpu-partition-message"
+
+	defaultPartitionTimeoutSeconds = 1800
+	partitionPollInterval          = 10 * time.Second
+	partitionRequeueInterval       = 30 * time.Second
+)
+
+var (
+	partitionTaint = v1.Taint{
+		Key:    partitionTaintKey,
+		Value:  "true",
+		Effect: v1.TaintEffectNoSchedule,
+	}
+	dcmTaint = v1.Taint{
+		Key:    configmanager.DCMTaintKey,
+		Value:  configmanager.DCMTaintValue,
+		Effect: v1.TaintEffectNoExecute,
+	}
+)
+
+type partitionMgr struct {
+	client       client.Client
+	k8sInterface kubernetes.Interface
+	recorder     record.EventRecorder
+	nodeOp       *nodeOperation
+}
+
+//go:generate mockgen -source=partitionmgr.go -package=controllers -destination=mock_partitionmgr.go partitionMgrAPI
+type partitionMgrAPI interface {
+	HandlePartition(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) (ctrl.Result, error)
+	HandleDelete(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) (ctrl.Result, error)
+}
+
+func newPartitionMgrHandler(client client.Client, k8sConfig *rest.Config, recorder record.EventRecorder) partitionMgrAPI {
+	k8sIntf, err := kubernetes.NewForConfig(k8sConfig)
+	if err != nil {
+		return nil
+	}
+	return newPartitionMgr(client, k8sIntf, recorder)
+}
+
+func newPartitionMgr(client client.Client, k8sIntf kubernetes.Interface, recorder record.EventRecorder) *partitionMgr {
+	return &partitionMgr{
+		client:       client,
+		k8sInterface: k8sIntf,
+		recorder:     recorder,
+		nodeOp: &nodeOperation{
+			client:              client,
+			k8sInterface:        k8sIntf,
+			nodeInProgress:      new(sync.Map),
+			name:                "repartitioning",
+			taint:               partitionTaint,
+			stateAnnotation:     partitionStateAnnotation,
+			startTimeAnnotation: partitionStartTimeAnnotation,
+			messageAnnotation:   partitionMessageAnnotation,
+			annotations:         []string{partitionProfileAnnotation},
+			pollInterval:        partitionPollInterval,
+		},
+	}
+}
+
+// HandlePartition repartitions the nodes whose GPU partition differs from their partition profile. A node is
+// cordoned and drained, then the config manager applies the profile while the other operands are evicted by its
+// taint, and the node is released once the node labeller reports the new partition
+func (p *partitionMgr) HandlePartition(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) (ctrl.Result, error) {
+	cmSpec := deviceConfig.Spec.ConfigManager
+	if cmSpec.Enable == nil || !*cmSpec.Enable || len(cmSpec.PartitionProfiles) == 0 {
+		return p.HandleDelete(ctx, deviceConfig, nodes)
+	}
+
+	res := ctrl.Result{}
+	maxParallel := 1
+	if cmSpec.PartitionPolicy != nil && cmSpec.PartitionPolicy.MaxParallelNodes > 0 {
+		maxParallel = cmSpec.PartitionPolicy.MaxParallelNodes
+	}
+
+	sortedNodes := append([]v1.Node{}, nodes.Items...)
+	sort.Slice(sortedNodes, func(i, j int) bool { return sortedNodes[i].Name < sortedNodes[j].Name })
+
+	running := 0
+	for i := range sortedNodes {
+		if isPartitionInProgress(getNodePartitionState(&sortedNodes[i])) {
+			running++
+		}
+	}
+
+	for i := range sortedNodes {
+		node := &sortedNodes[i]
+		state := getNodePartitionState(node)
+		profile := getNodePartitionProfile(deviceConfig, node)
+		if profile == nil {
+			if state != "" {
+				if !p.nodeOp.isRunning(node.Name) {
+					p.releaseNode(ctx, node.Name)
+				}
+			}
+			continue
+		}
+		if p.nodeOp.isRunning(node.Name) {
+			res = ctrl.Result{Requeue: true, RequeueAfter: partitionRequeueInterval}
+			continue
+		}
+
+		switch {
+		case isPartitionInProgress(state):
+			// Operator restarted while the node was repartitioned, resume where it stopped
+			p.startNodePartition(ctx, deviceConfig, node, *profile)
+			res = ctrl.Result{Requeue: true, RequeueAfter: partitionRequeueInterval}
+		case state == amdv1alpha1.PartitionStateFailed && node.Annotations[partitionProfileAnnotation] == profile.Name:
+			// Failed nodes are retried once the state annotation is removed or the profile changes
+			continue
+		case strings.EqualFold(node.Labels[utils.PartitionTypeLabel], configmanager.PartitionLabelValue(*profile)):
+			if state != "" {
+				p.releaseNode(ctx, node.Name)
+			}
+			// Keep the profile label in line so that the config manager applies the same profile after a reboot
+			if node.Labels[configmanager.GPUConfigProfileLabel] != profile.Name {
+				if err := patchNode(ctx, p.client, node.Name, func(nodeObj *v1.Node) {
+					nodeObj.Labels[configmanager.GPUConfigProfileLabel] = profile.Name
+				}); err != nil {
+					log.FromContext(ctx).Error(err, fmt.Sprintf("Node: %v failed to set partition profile label", node.Name))
+				}
+			}
+		case running < maxParallel:
+			running++
+			p.startNodePartition(ctx, deviceConfig, node, *profile)
+			res = ctrl.Result{Requeue: true, RequeueAfter: partitionRequeueInterval}
+		default:
+			// Pending until another node is done
+			res = ctrl.Result{Requeue: true, RequeueAfter: partitionRequeueInterval}
+		}
+	}
+
+	return res, nil
+}
+
+// HandleDelete releases the nodes held by the repartitioning when the partition profiles are removed
+func (p *partitionMgr) HandleDelete(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) (ctrl.Result, error) {
+	for i := range nodes.Items {
+		if getNodePartitionState(&nodes.Items[i]) == "" {
+			continue
+		}
+		if p.nodeOp.isRunning(nodes.Items[i].Name) {
+			// the running repartitioning stops at its next poll and releases the node on the next reconcile
+			return ctrl.Result{Requeue: true, RequeueAfter: partitionRequeueInterval}, nil
+		}
+		p.releaseNode(ctx, nodes.Items[i].Name)
+	}
+	return ctrl.Result{}, nil
+}
+
+func (p *partitionMgr) startNodePartition(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, node *v1.Node, profile amdv1alpha1.PartitionProfileSpec) {
+	deviceConfigCopy, nodeCopy := *deviceConfig, *node
+	p.nodeOp.run(node.Name, func() {
+		p.handleNodePartition(ctx, deviceConfigCopy, nodeCopy, profile)
+	})
+}
+
+func (p *partitionMgr) handleNodePartition(ctx context.Context, deviceConfig amdv1alpha1.DeviceConfig, node v1.Node, profile amdv1alpha1.PartitionProfileSpec) {
+	logger := log.FromContext(ctx)
+
+	state := getNodePartitionState(&node)
+	startTime, err := p.nodeOp.getStartTime(&node)
+	if !isPartitionInProgress(state) || err != nil || node.Annotations[partitionProfileAnnotation] != profile.Name {
+		logger.Info(fmt.Sprintf("Node: %v repartitioning to profile %v", node.Name, profile.Name))
+		state = amdv1alpha1.PartitionStateDraining
+		if startTime, err = p.nodeOp.begin(ctx, node.Name, string(state), func(nodeObj *v1.Node) {
+			nodeObj.Annotations[partitionProfileAnnotation] = profile.Name
+		}); err != nil {
+			logger.Error(err, fmt.Sprintf("Node: %v failed to cordon node for repartitioning", node.Name))
+			return
+		}
+		recordEvent(p.recorder, &deviceConfig, &node, v1.EventTypeNormal, EventReasonGPUPartitionStarted,
+			fmt.Sprintf("Repartitioning of node %v to profile %v started", node.Name, profile.Name))
+	}
+	deadline := startTime.Add(time.Duration(getPartitionTimeoutSeconds(&deviceConfig)) * time.Second)
+
+	if state == amdv1alpha1.PartitionStateDraining {
+		if err := p.drainNode(ctx, &deviceConfig, &node, deadline); err != nil {
+			p.failNodePartition(ctx, &deviceConfig, &node, fmt.Sprintf("failed to drain node: %v", err))
+			return
+		}
+		// The config manager taint evicts the remaining operands, they come back once the node is repartitioned
+		state = amdv1alpha1.PartitionStatePartitioning
+		if err := p.nodeOp.setState(ctx, node.Name, string(state), func(nodeObj *v1.Node) {
+			nodeObj.Spec.Taints = addTaint(nodeObj.Spec.Taints, dcmTaint)
+			nodeObj.Labels[configmanager.GPUConfigProfileLabel] = profile.Name
+		}); err != nil {
+			p.failNodePartition(ctx, &deviceConfig, &node, fmt.Sprintf("failed to label node with partition profile: %v", err))
+			return
+		}
+	}
+
+	if state == amdv1alpha1.PartitionStatePartitioning {
+		if !p.nodeOp.poll(ctx, node.Name, deadline, func() (bool, error) {
+			return p.isNodePartitioned(ctx, &deviceConfig, &node, startTime)
+		}) {
+			p.failNodePartition(ctx, &deviceConfig, &node, "config manager did not report the node as partitioned")
+			return
+		}
+		state = amdv1alpha1.PartitionStateVerifying
+		if err := p.nodeOp.setState(ctx, node.Name, string(state), func(nodeObj *v1.Node) {
+			nodeObj.Spec.Taints = removeTaint(nodeObj.Spec.Taints, dcmTaint)
+		}); err != nil {
+			logger.Error(err, fmt.Sprintf("Node: %v failed to remove %v taint", node.Name, dcmTaint.Key))
+			return
+		}
+	}
+
+	desired := configmanager.PartitionLabelValue(profile)
+	if !p.nodeOp.poll(ctx, node.Name, deadline, func() (bool, error) {
+		nodeObj := &v1.Node{}
+		if err := p.client.Get(ctx, client.ObjectKey{Name: node.Name}, nodeObj); err != nil {
+			return false, err
+		}
+		return strings.EqualFold(nodeObj.Labels[utils.PartitionTypeLabel], desired), nil
+	}) {
+		p.failNodePartition(ctx, &deviceConfig, &node, fmt.Sprintf("node labeller did not report partition %v", desired))
+		return
+	}
+
+	p.releaseNode(ctx, node.Name)
+	logger.Info(fmt.Sprintf("Node: %v repartitioned to profile %v", node.Name, profile.Name))
+	recordEvent(p.recorder, &deviceConfig, &node, v1.EventTypeNormal, EventReasonGPUPartitionComplete,
+		fmt.Sprintf("Node %v repartitioned to profile %v", node.Name, profile.Name))
+}
+
+func (p *partitionMgr) failNodePartition(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, node *v1.Node, reason string) {
+	// The node stays cordoned, the other operands are allowed back to let the node be inspected
+	p.nodeOp.fail(ctx, node.Name, string(amdv1alpha1.PartitionStateFailed), reason, func(nodeObj *v1.Node) {
+		nodeObj.Spec.Taints = removeTaint(nodeObj.Spec.Taints, dcmTaint)
+	})
+	recordEvent(p.recorder, deviceConfig, node, v1.EventTypeWarning, EventReasonGPUPartitionFailed,
+		fmt.Sprintf("Repartitioning of node %v failed: %v", node.Name, reason))
+}
+
+// releaseNode removes the taints and annotations set on the node for its repartitioning
+func (p *partitionMgr) releaseNode(ctx context.Context, nodeName string) {
+	if err := p.nodeOp.release(ctx, nodeName, func(nodeObj *v1.Node) {
+		nodeObj.Spec.Taints = removeTaint(nodeObj.Spec.Taints, dcmTaint)
+	}); err != nil {
+		log.FromContext(ctx).Error(err, fmt.Sprintf("Node: %v failed to release node after repartitioning", nodeName))
+	}
+}
+
+// drainNode evicts the pods using GPUs from the node, following the partition drain policy
+func (p *partitionMgr) drainNode(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, node *v1.Node, deadline time.Time) error {
+	var drainPolicy *amdv1alpha1.DrainSpec
+	if policy := deviceConfig.Spec.ConfigManager.PartitionPolicy; policy != nil {
+		drainPolicy = policy.NodeDrainPolicy
+	}
+	return p.nodeOp.drain(ctx, node.Name, drainPolicy, deadline, func(pod *v1.Pod) bool {
+		return isGPUPod(ctx, p.client, pod)
+	})
+}
+
+// isNodePartitioned returns true once the config manager pod of the node reported a successful repartitioning
+func (p *partitionMgr) isNodePartitioned(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, node *v1.Node, since time.Time) (bool, error) {
+	dcmPods, err := p.k8sInterface.CoreV1().Pods(deviceConfig.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{
+			"app.kubernetes.io/name": configmanager.ConfigManagerName,
+			"daemonset-name":         deviceConfig.Name,
+		}).String(),
+		FieldSelector: fields.SelectorFromSet(fields.Set{"spec.nodeName": node.Name}).String(),
+	})
+	if err != nil {
+		return false, err
+	}
+	podNames := map[string]bool{}
+	for _, pod := range dcmPods.Items {
+		podNames[pod.Name] = true
+	}
+
+	events, err := p.k8sInterface.CoreV1().Events(deviceConfig.Namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.SelectorFromSet(fields.Set{
+			"involvedObject.kind": "Pod",
+			"reason":              configmanager.PartitionSuccessEventReason,
+		}).String(),
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, event := range events.Items {
+		eventTime := event.LastTimestamp.Time
+		if eventTime.IsZero() {
+			eventTime = event.EventTime.Time
+		}
+		if podNames[event.InvolvedObject.Name] && !eventTime.Before(since) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// getNodePartitionStatus returns the desired and actual partition of every node selected by a partition profile
+func getNodePartitionStatus(deviceConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) map[string]amdv1alpha1.NodePartitionStatus {
+	if len(deviceConfig.Spec.ConfigManager.PartitionProfiles) == 0 {
+		return nil
+	}
+	status := map[string]amdv1alpha1.NodePartitionStatus{}
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		profile := getNodePartitionProfile(deviceConfig, node)
+		if profile == nil {
+			continue
+		}
+		nodeStatus := amdv1alpha1.NodePartitionStatus{
+			Profile: profile.Name,
+			Desired: configmanager.PartitionLabelValue(*profile),
+			Actual:  node.Labels[utils.PartitionTypeLabel],
+			State:   amdv1alpha1.PartitionStatePending,
+		}
+		state := getNodePartitionState(node)
+		switch {
+		case state != "" && node.Annotations[partitionProfileAnnotation] == profile.Name:
+			nodeStatus.State = state
+			nodeStatus.Message = node.Annotations[partitionMessageAnnotation]
+		case strings.EqualFold(nodeStatus.Actual, nodeStatus.Desired):
+			nodeStatus.State = amdv1alpha1.PartitionStateApplied
+		}
+		status[node.Name] = nodeStatus
+	}
+	return status
+}
+
+// getNodePartitionProfile returns the first partition profile selecting the node, nil if none does
+func getNodePartitionProfile(deviceConfig *amdv1alpha1.DeviceConfig, node *v1.Node) *amdv1alpha1.PartitionProfileSpec {
+	cmSpec := deviceConfig.Spec.ConfigManager
+	// the config manager only runs on the nodes of its own selector
+	if len(cmSpec.Selector) > 0 && !labels.SelectorFromSet(cmSpec.Selector).Matches(labels.Set(node.Labels)) {
+		return nil
+	}
+	for i := range cmSpec.PartitionProfiles {
+		if labels.SelectorFromSet(cmSpec.PartitionProfiles[i].NodeSelector).Matches(labels.Set(node.Labels)) {
+			return &cmSpec.PartitionProfiles[i]
+		}
+	}
+	return nil
+}
+
+func getNodePartitionState(node *v1.Node) amdv1alpha1.PartitionState {
+	return amdv1alpha1.PartitionState(node.Annotations[partitionStateAnnotation])
+}
+
+func isPartitionInProgress(state amdv1alpha1.PartitionState) bool {
+	return state == amdv1alpha1.PartitionStateDraining ||
+		state == amdv1alpha1.PartitionStatePartitioning ||
+		state == amdv1alpha1.PartitionStateVerifying
+}
+
+func getPartitionTimeoutSeconds(deviceConfig *amdv1alpha1.DeviceConfig) int {
+	if policy := deviceConfig.Spec.ConfigManager.PartitionPolicy; policy != nil && policy.TimeoutSeconds > 0 {
+		return policy.TimeoutSeconds
+	}
+	return defaultPartitionTimeoutSeconds
+}
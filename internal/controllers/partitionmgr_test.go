@@ -0,0 +1,239 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	amdv1alpha1 "github.com/ROCm/gpu-operator/api/v1alpha1"
+	mock_client "github.com/ROCm/gpu-operator/internal/client"
+	"github.com/ROCm/gpu-operator/internal/configmanager"
+)
+
+var _ = Describe("partitionMgr", func() {
+	var (
+		ctx        context.Context
+		kubeClient *mock_client.MockClient
+		clientset  *fake.Clientset
+		mgr        *partitionMgr
+		devConfig  *amdv1alpha1.DeviceConfig
+		nodes      map[string]*v1.Node
+		patched    map[string][]*v1.Node
+	)
+
+	profile := amdv1alpha1.PartitionProfileSpec{
+		Name:             "cpx-nps4",
+		ComputePartition: "CPX",
+		MemoryPartition:  "NPS4",
+	}
+
+	newNode := func(name string, state amdv1alpha1.PartitionState, partition string) *v1.Node {
+		node := &v1.Node{ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Labels:      map[string]string{},
+			Annotations: map[string]string{},
+		}}
+		if state != "" {
+			node.Annotations[partitionStateAnnotation] = string(state)
+			node.Annotations[partitionProfileAnnotation] = profile.Name
+			node.Spec.Taints = []v1.Taint{partitionTaint}
+		}
+		if partition != "" {
+			node.Labels["amd.com/compute-memory-partition"] = partition
+		}
+		nodes[name] = node
+		return node
+	}
+	nodeList := func(names ...string) *v1.NodeList {
+		list := &v1.NodeList{}
+		for _, name := range names {
+			list.Items = append(list.Items, *nodes[name])
+		}
+		return list
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		kubeClient = mock_client.NewMockClient(gomock.NewController(GinkgoT()))
+		clientset = fake.NewSimpleClientset()
+		mgr = newPartitionMgr(kubeClient, clientset, record.NewFakeRecorder(10))
+		enable := true
+		devConfig = &amdv1alpha1.DeviceConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: devConfigName, Namespace: devConfigNamespace},
+			Spec: amdv1alpha1.DeviceConfigSpec{
+				ConfigManager: amdv1alpha1.ConfigManagerSpec{
+					Enable:            &enable,
+					PartitionProfiles: []amdv1alpha1.PartitionProfileSpec{profile},
+				},
+			},
+		}
+		nodes = map[string]*v1.Node{}
+		patched = map[string][]*v1.Node{}
+
+		kubeClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&v1.Node{})).DoAndReturn(
+			func(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+				nodes[key.Name].DeepCopyInto(obj.(*v1.Node))
+				return nil
+			}).AnyTimes()
+		kubeClient.EXPECT().Patch(gomock.Any(), gomock.AssignableToTypeOf(&v1.Node{}), gomock.Any()).DoAndReturn(
+			func(_ context.Context, obj client.Object, _ client.Patch, _ ...client.PatchOption) error {
+				node := obj.(*v1.Node).DeepCopy()
+				nodes[node.Name] = node
+				patched[node.Name] = append(patched[node.Name], node)
+				return nil
+			}).AnyTimes()
+	})
+
+	It("releases the node and sets the profile label once the node reports the partition", func() {
+		newNode("node2", amdv1alpha1.PartitionStateFailed, "cpx_nps4")
+		// the node failed a previous profile
+		nodes["node2"].Annotations[partitionProfileAnnotation] = "previous"
+
+		res, err := mgr.HandlePartition(ctx, devConfig, nodeList("node2"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res).To(Equal(ctrl.Result{}))
+		Expect(patched["node2"]).To(HaveLen(2))
+		node := nodes["node2"]
+		Expect(node.Annotations).NotTo(HaveKey(partitionStateAnnotation))
+		Expect(node.Annotations).NotTo(HaveKey(partitionProfileAnnotation))
+		Expect(node.Spec.Taints).To(BeEmpty())
+		Expect(node.Labels).To(HaveKeyWithValue(configmanager.GPUConfigProfileLabel, profile.Name))
+	})
+
+	It("keeps a failed node as it is until its profile changes", func() {
+		newNode("node1", amdv1alpha1.PartitionStateFailed, "spx_nps1")
+
+		res, err := mgr.HandlePartition(ctx, devConfig, nodeList("node1"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res).To(Equal(ctrl.Result{}))
+		Expect(patched).To(BeEmpty())
+	})
+
+	It("keeps the nodes pending while the parallel repartitionings are running", func() {
+		newNode("node1", amdv1alpha1.PartitionStateDraining, "spx_nps1")
+		newNode("node2", "", "spx_nps1")
+		mgr.nodeOp.nodeInProgress.Store("node1", true)
+
+		res, err := mgr.HandlePartition(ctx, devConfig, nodeList("node1", "node2"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.RequeueAfter).To(Equal(partitionRequeueInterval))
+		Expect(patched).To(BeEmpty())
+		_, started := mgr.nodeOp.nodeInProgress.Load("node2")
+		Expect(started).To(BeFalse())
+	})
+
+	It("releases the nodes no partition profile selects anymore", func() {
+		newNode("node1", amdv1alpha1.PartitionStateFailed, "spx_nps1")
+		devConfig.Spec.ConfigManager.PartitionProfiles[0].NodeSelector = map[string]string{"gpu": "mi300"}
+
+		_, err := mgr.HandlePartition(ctx, devConfig, nodeList("node1"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(patched["node1"]).To(HaveLen(1))
+		Expect(nodes["node1"].Annotations).NotTo(HaveKey(partitionStateAnnotation))
+		Expect(nodes["node1"].Spec.Taints).To(BeEmpty())
+	})
+
+	It("waits for the running repartitioning before releasing the nodes on delete", func() {
+		newNode("node1", amdv1alpha1.PartitionStatePartitioning, "spx_nps1")
+		mgr.nodeOp.nodeInProgress.Store("node1", true)
+
+		res, err := mgr.HandleDelete(ctx, devConfig, nodeList("node1"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.RequeueAfter).To(Equal(partitionRequeueInterval))
+		Expect(patched).To(BeEmpty())
+
+		mgr.nodeOp.nodeInProgress.Delete("node1")
+		res, err = mgr.HandleDelete(ctx, devConfig, nodeList("node1"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res).To(Equal(ctrl.Result{}))
+		Expect(nodes["node1"].Annotations).NotTo(HaveKey(partitionStateAnnotation))
+	})
+
+	It("marks the node as failed when it cannot be drained", func() {
+		newNode("node1", "", "spx_nps1")
+		clientset.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, errors.New("list failed")
+		})
+
+		mgr.handleNodePartition(ctx, *devConfig, *nodes["node1"], profile)
+		Expect(patched["node1"]).To(HaveLen(2))
+		draining := patched["node1"][0]
+		Expect(draining.Annotations).To(HaveKeyWithValue(partitionStateAnnotation, string(amdv1alpha1.PartitionStateDraining)))
+		Expect(draining.Annotations).To(HaveKeyWithValue(partitionProfileAnnotation, profile.Name))
+		Expect(draining.Spec.Taints).To(ContainElement(partitionTaint))
+		failed := nodes["node1"]
+		Expect(failed.Annotations).To(HaveKeyWithValue(partitionStateAnnotation, string(amdv1alpha1.PartitionStateFailed)))
+		Expect(failed.Annotations[partitionMessageAnnotation]).To(ContainSubstring("list failed"))
+		// the node stays cordoned to be inspected
+		Expect(failed.Spec.Taints).To(ConsistOf(partitionTaint))
+	})
+})
+
+var _ = Describe("isGPUPod", func() {
+	It("selects the pods using GPUs from any container, mount or ResourceClaim", func() {
+		ctx := context.Background()
+		kubeClient := mock_client.NewMockClient(gomock.NewController(GinkgoT()))
+
+		initContainer := &v1.Pod{Spec: v1.PodSpec{InitContainers: []v1.Container{{
+			Resources: v1.ResourceRequirements{Requests: v1.ResourceList{"amd.com/gpu": resource.MustParse("1")}},
+		}}}}
+		Expect(isGPUPod(ctx, kubeClient, initContainer)).To(BeTrue())
+
+		deviceMount := &v1.Pod{Spec: v1.PodSpec{InitContainers: []v1.Container{{
+			VolumeMounts: []v1.VolumeMount{{Name: "dri", MountPath: "/dev/dri"}},
+		}}}}
+		Expect(isGPUPod(ctx, kubeClient, deviceMount)).To(BeTrue())
+
+		claimName := "gpu-claim"
+		otherClaimName := "nic-claim"
+		claimPod := func(name *string) *v1.Pod {
+			return &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"},
+				Spec:       v1.PodSpec{ResourceClaims: []v1.PodResourceClaim{{Name: "claim"}}},
+				Status:     v1.PodStatus{ResourceClaimStatuses: []v1.PodResourceClaimStatus{{Name: "claim", ResourceClaimName: name}}},
+			}
+		}
+		drivers := map[string]string{claimName: deviceClassName, otherClaimName: "nic.example.com"}
+		kubeClient.EXPECT().Get(ctx, gomock.Any(), gomock.AssignableToTypeOf(&unstructured.Unstructured{})).DoAndReturn(
+			func(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+				claim := obj.(*unstructured.Unstructured)
+				Expect(claim.GetKind()).To(Equal("ResourceClaim"))
+				return unstructured.SetNestedSlice(claim.Object, []interface{}{
+					map[string]interface{}{"driver": drivers[key.Name], "device": "gpu-0"},
+				}, "status", "allocation", "devices", "results")
+			}).Times(2)
+		Expect(isGPUPod(ctx, kubeClient, claimPod(&claimName))).To(BeTrue())
+		Expect(isGPUPod(ctx, kubeClient, claimPod(&otherClaimName))).To(BeFalse())
+		// the claim is not created yet
+		Expect(isGPUPod(ctx, kubeClient, claimPod(nil))).To(BeFalse())
+	})
+})
@@ -47,16 +47,20 @@ import (
 	"gopkg.in/yaml.v3"
 
 	amdv1alpha1 "github.com/ROCm/gpu-operator/api/v1alpha1"
+	utils "github.com/ROCm/gpu-operator/internal"
 
 	workflowv1alpha1 "github.com/argoproj/argo-workflows/v4/pkg/apis/workflow/v1alpha1"
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -79,7 +83,7 @@ const (
 	// DefaultRecoveryPolicyMaxRunsPerWindow - defines the max allowed runs per window for recovery policy
 	// If a specific node condition is hit more than this number of times within the window size, no new remediation workflows will be scheduled
 	DefaultRecoveryPolicyMaxRunsPerWindow = 3
-	DefaultTimeFormatLayout               = "2006-01-02 15:04:05 UTC"
+	DefaultTimeFormatLayout               = utils.DefaultTimeFormatLayout
 	DefaultStatusCRCleanupWindowSize      = "72h"
 	// DefaultRebootTimeout - default total time the waitfornodeready
 	// step waits for a node to reboot and remain Ready before timing out.
@@ -97,6 +101,8 @@ const (
 	ArgoWorkflowControllerConfigMap = "amd-gpu-operator-workflow-controller-config"
 	ArgoWorkflowInstaceIDLabelKey   = "workflows.argoproj.io/controller-instanceid"
 	ArgoWorkflowInstaceIDLabelValue = "amd-gpu-operator-remediation-workflow"
+	// Below is the label set on remediation workflows to identify the node condition it remediates
+	RemediationConditionLabelKey = "operator.amd.com/gpu-remediation-condition"
 )
 
 type RecoveryPolicyConfig struct {
@@ -126,7 +132,8 @@ type ValidationTestsProfile struct {
 }
 
 type remediationMgr struct {
-	helper remediationMgrHelperAPI
+	helper   remediationMgrHelperAPI
+	recorder record.EventRecorder
 }
 
 //go:generate mockgen -source=remediation_handler.go -package=controllers -destination=mock_remediation_handler.go remediationMgr
@@ -135,13 +142,14 @@ type remediationMgrAPI interface {
 	HandleDelete(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) (ctrl.Result, error)
 }
 
-func newRemediationMgrHandler(client client.Client, apiReader client.Reader, k8sConfig *rest.Config, isOpenShift bool) remediationMgrAPI {
+func newRemediationMgrHandler(client client.Client, apiReader client.Reader, k8sConfig *rest.Config, recorder record.EventRecorder, isOpenShift bool) remediationMgrAPI {
 	k8sIntf, err := kubernetes.NewForConfig(k8sConfig)
 	if err != nil {
 		return nil
 	}
 	return &remediationMgr{
-		helper: newRemediationMgrHelperHandler(client, apiReader, k8sIntf, isOpenShift),
+		helper:   newRemediationMgrHelperHandler(client, apiReader, k8sIntf, recorder, isOpenShift),
+		recorder: recorder,
 	}
 }
 
@@ -162,23 +170,28 @@ func (n *remediationMgr) HandleRemediation(ctx context.Context, devConfig *amdv1
 		return ctrl.Result{}, nil
 	}
 
+	// Dry run mode only plans the spec, the workflows in flight are driven to completion but no new one starts
+	dryRun := isDryRun(devConfig)
+
 	//if user provided a custom configmap, validate it
 	if err := n.helper.validateUserConfigMap(ctx, devConfig); err != nil {
 		logger.Error(err, "User provided configmap validation failed, skipping remediation")
 		return res, err
 	}
 
-	//if user did not provide a custom configmap, create a default one from the config image
-	if devConfig.Spec.RemediationWorkflow.Config == nil || devConfig.Spec.RemediationWorkflow.Config.Name == "" {
-		if result, err := n.helper.createConfigMapFromImage(ctx, devConfig); err != nil {
-			return res, err
-		} else if result.RequeueAfter > 0 {
-			return result, nil
+	if !dryRun {
+		//if user did not provide a custom configmap, create a default one from the config image
+		if devConfig.Spec.RemediationWorkflow.Config == nil || devConfig.Spec.RemediationWorkflow.Config.Name == "" {
+			if result, err := n.helper.createConfigMapFromImage(ctx, devConfig); err != nil {
+				return res, err
+			} else if result.RequeueAfter > 0 {
+				return result, nil
+			}
 		}
-	}
 
-	if err := n.helper.createDefaultObjects(ctx, devConfig); err != nil {
-		return res, err
+		if err := n.helper.createDefaultObjects(ctx, devConfig); err != nil {
+			return res, err
+		}
 	}
 
 	var cfgMapName string
@@ -189,13 +202,19 @@ func (n *remediationMgr) HandleRemediation(ctx context.Context, devConfig *amdv1
 	}
 	configMap, err := n.helper.getConfigMap(ctx, cfgMapName, devConfig.Namespace)
 	if err != nil {
+		if dryRun && k8serrors.IsNotFound(err) {
+			// remediation was never applied, there is no workflow in flight
+			return ctrl.Result{}, nil
+		}
 		logger.Error(err, "Failed to get remediation ConfigMap", "name", cfgMapName)
 		return res, err
 	}
 
 	// Update max parallel workflows based on DeviceConfig
-	if err := n.helper.updateMaxParallelWorkflows(ctx, devConfig); err != nil {
-		logger.Error(err, "Failed to update max parallel workflows, continuing with remediation")
+	if !dryRun {
+		if err := n.helper.updateMaxParallelWorkflows(ctx, devConfig); err != nil {
+			logger.Error(err, "Failed to update max parallel workflows, continuing with remediation")
+		}
 	}
 
 	// Clear any older recovery attempts from the status CR
@@ -220,6 +239,9 @@ func (n *remediationMgr) HandleRemediation(ctx context.Context, devConfig *amdv1
 	}
 	logger.Info("Internal map synced from status CR successfully")
 
+	// Publish the remediation metrics from the current workflows and recovery attempts
+	n.helper.updateRemediationMetrics(ctx, devConfig, nodes, mappings)
+
 	var errs error
 	for _, node := range nodes.Items {
 		// Validate node conditions
@@ -231,10 +253,19 @@ func (n *remediationMgr) HandleRemediation(ctx context.Context, devConfig *amdv1
 		if !createNewWorkflow {
 			continue
 		}
+		if dryRun {
+			logger.Info(fmt.Sprintf("GPU Condition: %s observed on node: %s in dry run mode, not starting Remediation Workflow", mapping.NodeCondition, node.Name))
+			continue
+		}
 		canSchedule := n.helper.isWorkflowSchedulableOnNode(ctx, devConfig, &node, mapping)
 		if !canSchedule {
 			continue
 		}
+		// The workflow taints and drains the node right away, hold it until a maintenance window opens
+		if !utils.IsMaintenanceWindowOpen(devConfig, time.Now()) {
+			logger.Info(fmt.Sprintf("GPU Condition: %s observed on node: %s outside of maintenance windows, deferring Remediation Workflow", mapping.NodeCondition, node.Name))
+			continue
+		}
 		logger.Info(fmt.Sprintf("GPU Condition: %s observed and node: %s is unhealthy. Starting Remediation Workflow: %s", mapping.NodeCondition, node.Name, mapping.WorkflowTemplate))
 
 		// Fetch WorkflowTemplate
@@ -259,6 +290,9 @@ func (n *remediationMgr) HandleRemediation(ctx context.Context, devConfig *amdv1
 			continue
 		}
 		logger.Info(fmt.Sprintf("Remediation Workflow for the condition is created successfully on node %s using template %s", node.Name, mapping.WorkflowTemplate))
+		incRemediationWorkflowsMetric(devConfig, mapping.NodeCondition, workflowResultStarted)
+		recordEvent(n.recorder, devConfig, &node, v1.EventTypeNormal, EventReasonRemediationWorkflowCreated,
+			fmt.Sprintf("Remediation workflow %s created on node %s for condition %s", wf.Name, node.Name, mapping.NodeCondition))
 
 		// Drop older recovery attempts from internal map based on the window size
 		windowSize := n.helper.getWindowSize(&mapping.RecoveryPolicy)
@@ -360,7 +394,7 @@ type remediationMgrHelperAPI interface {
 	isNodeLabelledForAbortWorkflow(node *v1.Node) bool
 	removeAbortWorkflowLabelFromNode(ctx context.Context, node *v1.Node) error
 	abortWorkflow(ctx context.Context, workflow *workflowv1alpha1.Workflow) error
-	attemptAbortWorkflowOnNode(ctx context.Context, node *v1.Node, wf *workflowv1alpha1.Workflow) (bool, error)
+	attemptAbortWorkflowOnNode(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, node *v1.Node, wf *workflowv1alpha1.Workflow) (bool, error)
 	attemptResumeWorkflowOnNode(ctx context.Context, node *v1.Node, mapping ConditionWorkflowMapping, wf *workflowv1alpha1.Workflow, stageName string)
 	handleSuspendedWorkflowsOnNode(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, node *v1.Node, mapping ConditionWorkflowMapping, wf *workflowv1alpha1.Workflow) bool
 	getWorkflowTaskScriptSource(scriptFileName string) (string, error)
@@ -377,28 +411,33 @@ type remediationMgrHelperAPI interface {
 	updateCustomTolerationsOnDaemonset(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, daemonsetLabelSelector labels.Selector, tolerations []v1.Toleration) error
 	customTaintsChanged(devConfig *amdv1alpha1.DeviceConfig) bool
 	createConfigMapFromImage(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig) (ctrl.Result, error)
+	updateRemediationMetrics(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList, mappings map[string]ConditionWorkflowMapping)
 }
 
 type remediationMgrHelper struct {
 	client               client.Client
 	apiReader            client.Reader
 	k8sInterface         kubernetes.Interface
+	recorder             record.EventRecorder
 	recoveryTracker      *sync.Map
 	serviceAccountName   string
 	maxParallelWorkflows int32
 	tolerationsCache     *sync.Map
+	finishedWorkflows    *sync.Map
 	isOpenShift          bool
 }
 
 // Initialize remediation manager helper interface
-func newRemediationMgrHelperHandler(client client.Client, apiReader client.Reader, k8sInterface kubernetes.Interface, isOpenShift bool) remediationMgrHelperAPI {
+func newRemediationMgrHelperHandler(client client.Client, apiReader client.Reader, k8sInterface kubernetes.Interface, recorder record.EventRecorder, isOpenShift bool) remediationMgrHelperAPI {
 	return &remediationMgrHelper{
-		client:           client,
-		apiReader:        apiReader,
-		k8sInterface:     k8sInterface,
-		recoveryTracker:  new(sync.Map),
-		tolerationsCache: new(sync.Map),
-		isOpenShift:      isOpenShift,
+		client:            client,
+		apiReader:         apiReader,
+		k8sInterface:      k8sInterface,
+		recorder:          recorder,
+		recoveryTracker:   new(sync.Map),
+		tolerationsCache:  new(sync.Map),
+		finishedWorkflows: new(sync.Map),
+		isOpenShift:       isOpenShift,
 	}
 }
 
@@ -538,11 +577,15 @@ func (h *remediationMgrHelper) resumeSuspendedWorkflow(ctx context.Context, wfNa
 func (h *remediationMgrHelper) isDriverUpgradeInProgress(devCfg *amdv1alpha1.DeviceConfig, node *v1.Node) bool {
 	// Define the blocked states that indicate an upgrade is in progress
 	blockedStates := map[amdv1alpha1.UpgradeState]bool{
-		amdv1alpha1.UpgradeStateNotStarted:        true,
-		amdv1alpha1.UpgradeStateStarted:           true,
-		amdv1alpha1.UpgradeStateInstallInProgress: true,
-		amdv1alpha1.UpgradeStateInProgress:        true,
-		amdv1alpha1.UpgradeStateRebootInProgress:  true,
+		amdv1alpha1.UpgradeStateNotStarted:         true,
+		amdv1alpha1.UpgradeStateStarted:            true,
+		amdv1alpha1.UpgradeStateInstallInProgress:  true,
+		amdv1alpha1.UpgradeStateInProgress:         true,
+		amdv1alpha1.UpgradeStateRebootInProgress:   true,
+		amdv1alpha1.UpgradeStateDrainWaiting:       true,
+		amdv1alpha1.UpgradeStatePreUpgradeHook:     true,
+		amdv1alpha1.UpgradeStatePostUpgradeHook:    true,
+		amdv1alpha1.UpgradeStateRollbackInProgress: true,
 	}
 
 	for nodeName, moduleStatus := range devCfg.Status.NodeModuleStatus {
@@ -1166,6 +1209,7 @@ func (h *remediationMgrHelper) populateWorkflow(ctx context.Context, wfTemplate
 			Namespace:    devConfig.Namespace,
 			Labels: map[string]string{
 				ArgoWorkflowInstaceIDLabelKey: ArgoWorkflowInstaceIDLabelValue,
+				RemediationConditionLabelKey:  mapping.NodeCondition,
 			},
 		},
 		Spec: *wfTemplate.Spec.DeepCopy(),
@@ -1402,6 +1446,55 @@ func (h *remediationMgrHelper) deleteWorkflow(ctx context.Context, workflow *wor
 	return nil
 }
 
+// updateRemediationMetrics counts the remediation workflows of the DeviceConfig namespace that finished since the
+// previous reconcile and computes the recovery policy violations from the recovery attempts tracked for its nodes
+func (h *remediationMgrHelper) updateRemediationMetrics(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList, mappings map[string]ConditionWorkflowMapping) {
+	if wfList, err := h.getWorkflowList(ctx, devConfig.Namespace); err != nil {
+		log.FromContext(ctx).Error(err, "Get workflow list failed, skipping workflow metrics")
+	} else {
+		h.countFinishedWorkflows(devConfig, wfList.Items)
+	}
+
+	violatedNodes := make(map[string]int)
+	for _, mapping := range mappings {
+		maxAllowedRuns := h.getMaxAllowedRunsPerWindow(&mapping.RecoveryPolicy)
+		violatedNodes[mapping.NodeCondition] = 0
+		for _, node := range nodes.Items {
+			if h.getRecentRecoveryCount(node.Name, mapping.NodeCondition) >= maxAllowedRuns {
+				violatedNodes[mapping.NodeCondition]++
+			}
+		}
+	}
+	setRecoveryPolicyViolationsMetric(devConfig, violatedNodes)
+}
+
+// countFinishedWorkflows increments the remediation workflow counter once per workflow reaching a terminal phase.
+// The workflows already finished at the first reconcile of the DeviceConfig are only remembered, their completion
+// happened before this operator instance started. Workflows garbage collected by Argo are forgotten
+func (h *remediationMgrHelper) countFinishedWorkflows(devConfig *amdv1alpha1.DeviceConfig, workflows []workflowv1alpha1.Workflow) {
+	key := types.NamespacedName{Namespace: devConfig.Namespace, Name: devConfig.Name}
+	previous, seeded := h.finishedWorkflows.Load(key)
+	finished := make(map[types.UID]struct{})
+	for _, wf := range workflows {
+		condition, ok := wf.Labels[RemediationConditionLabelKey]
+		if !ok {
+			continue
+		}
+		result, done := getWorkflowResult(&wf)
+		if !done {
+			continue
+		}
+		finished[wf.UID] = struct{}{}
+		if !seeded {
+			continue
+		}
+		if _, counted := previous.(map[types.UID]struct{})[wf.UID]; !counted {
+			incRemediationWorkflowsMetric(devConfig, condition, result)
+		}
+	}
+	h.finishedWorkflows.Store(key, finished)
+}
+
 func (h *remediationMgrHelper) getWorkflowTemplate(ctx context.Context, workflowTemplateName, namespace string) (*workflowv1alpha1.WorkflowTemplate, error) {
 	wfTemplate := &workflowv1alpha1.WorkflowTemplate{}
 	err := h.client.Get(ctx, client.ObjectKey{
@@ -1504,11 +1597,17 @@ func (h *remediationMgrHelper) handleSuspendedWorkflowsOnNode(ctx context.Contex
 			logger.Info(fmt.Sprintf("Suspended workflow %s found on node %s", wf.Name, node.Name))
 			// Check if the workflow can be aborted, and attempt abort
 			// If aborted, return true so that new workflow can be created
-			canAbort, err := h.attemptAbortWorkflowOnNode(ctx, node, wf)
+			canAbort, err := h.attemptAbortWorkflowOnNode(ctx, devConfig, node, wf)
 			if canAbort && err == nil {
 				return true
 			}
 
+			// Resuming leads to the disruptive steps of the workflow, hold it until a maintenance window opens unless forced by the user
+			if !utils.IsMaintenanceWindowOpen(devConfig, time.Now()) && !h.isNodeLabelledForForceResume(ctx, node) {
+				logger.Info(fmt.Sprintf("Suspended workflow %s on node %s is held until the next maintenance window", wf.Name, node.Name))
+				return false
+			}
+
 			// Check if the workflow can be resumed, and attempt resume
 			h.attemptResumeWorkflowOnNode(ctx, node, mapping, wf, wfStage.DisplayName)
 			// irrespective of whether it was resumed or not, return false to avoid creating a new workflow
@@ -1518,7 +1617,7 @@ func (h *remediationMgrHelper) handleSuspendedWorkflowsOnNode(ctx context.Contex
 	return false
 }
 
-func (h *remediationMgrHelper) attemptAbortWorkflowOnNode(ctx context.Context, node *v1.Node, wf *workflowv1alpha1.Workflow) (bool, error) {
+func (h *remediationMgrHelper) attemptAbortWorkflowOnNode(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, node *v1.Node, wf *workflowv1alpha1.Workflow) (bool, error) {
 	logger := log.FromContext(ctx)
 	canAbort := h.isNodeLabelledForAbortWorkflow(node)
 	if canAbort {
@@ -1527,6 +1626,8 @@ func (h *remediationMgrHelper) attemptAbortWorkflowOnNode(ctx context.Context, n
 			logger.Error(err, fmt.Sprintf("Failed to abort workflow %s on node %s", wf.Name, node.Name))
 			return true, fmt.Errorf("Failed to abort workflow %s on node %s", wf.Name, node.Name)
 		}
+		recordEvent(h.recorder, devConfig, node, v1.EventTypeNormal, EventReasonRemediationWorkflowAborted,
+			fmt.Sprintf("Remediation workflow %s aborted on node %s", wf.Name, node.Name))
 		if err := h.removeAbortWorkflowLabelFromNode(ctx, node); err != nil {
 			return true, err
 		}
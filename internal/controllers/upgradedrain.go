@@ -0,0 +1,151 @@
+/*
+Copyright (c) Advanced Micro Devices, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the \"License\");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an \"AS IS\" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	amdv1alpha1 "github.com/ROCm/gpu-operator/api/v1alpha1"
+)
+
+const (
+	// podDrainDeadlineAnnotation sets how long the drain waits for a pod to complete, either as a duration
+	// measured from the pod start time (e.g. 72h) or as an RFC3339 timestamp
+	podDrainDeadlineAnnotation = "operator.amd.com/gpu-drain-deadline"
+	drainWaitPollInterval      = 30 * time.Second
+)
+
+// waitForGPUPodsCompletion holds the drain of the node until its GPU pods have completed or reached their deadline,
+// if the node drain policy is in WaitForCompletion mode. The node is tainted as soon as it has to wait, so that no new
+// pods are scheduled on it while the running ones complete. It returns false if the upgrade must not continue
+// because the DeviceConfig changed or the node upgrade got paused in the meantime
+func (h *upgradeMgrHelper) waitForGPUPodsCompletion(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, node *v1.Node) bool {
+	drainPolicy := deviceConfig.Spec.Driver.UpgradePolicy.NodeDrainPolicy
+	if drainPolicy == nil || drainPolicy.Mode != amdv1alpha1.DrainModeWaitForCompletion {
+		return true
+	}
+
+	logger := log.FromContext(ctx)
+	timeout := time.Duration(drainPolicy.WaitForCompletionTimeoutSeconds) * time.Second
+	waitStart := time.Now()
+	waited := false
+	lastWaiting := 0
+	ticker := time.NewTicker(drainWaitPollInterval)
+	defer ticker.Stop()
+
+	for {
+		pods, err := h.getPodsToDrainOrDelete(ctx, deviceConfig, node)
+		if err != nil {
+			logger.Error(err, fmt.Sprintf("Node: %v. Failed to list the GPU pods to wait for: %v", node.Name, err))
+		} else {
+			waiting := getPodsToWaitFor(pods, waitStart, timeout, time.Now())
+			if len(waiting) == 0 {
+				if waited {
+					logger.Info(fmt.Sprintf("Node: %v GPU pods completed after %v, proceeding with the drain", node.Name, time.Since(waitStart).Round(time.Second)))
+					// the upgrade timeout only covers the upgrade itself, not the time spent waiting for the workloads
+					h.setUpgradeStartTime(node.Name)
+					h.setNodeStatus(ctx, node.Name, amdv1alpha1.UpgradeStateStarted)
+				}
+				return true
+			}
+			if !waited {
+				waited = true
+				// the pods admitted during the wait would otherwise be evicted right away by the drain
+				if err := h.cordonOrUncordonNode(ctx, deviceConfig, node, true); err != nil {
+					logger.Error(err, fmt.Sprintf("Node: %v. Failed to cordon the node while waiting for the GPU pods: %v", node.Name, err))
+				}
+				h.setNodeStatus(ctx, node.Name, amdv1alpha1.UpgradeStateDrainWaiting)
+				recordEvent(h.recorder, deviceConfig, node, v1.EventTypeNormal, EventReasonNodeDrainWaiting,
+					fmt.Sprintf("Waiting for %v GPU pods to complete on node %v before it is drained", len(waiting), node.Name))
+			}
+			if len(waiting) != lastWaiting {
+				lastWaiting = len(waiting)
+				logger.Info(fmt.Sprintf("Node: %v waiting for %v GPU pods to complete before draining", node.Name, lastWaiting))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+		if !h.isDeviceConfigValid(ctx, deviceConfig) {
+			return false
+		}
+		if h.pauseNodeUpgradeIfRequested(ctx, deviceConfig, node) {
+			return false
+		}
+	}
+}
+
+// getPodsToWaitFor returns the pods the drain still waits for, the running pods whose deadline has not passed yet.
+// DaemonSet pods never complete on their own and are left to the eviction, and so are the pods tolerating the upgrade
+// taint that got scheduled on the node after the wait started
+func getPodsToWaitFor(pods []v1.Pod, waitStart time.Time, timeout time.Duration, now time.Time) []v1.Pod {
+	var waiting []v1.Pod
+	for _, pod := range pods {
+		if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		if isDaemonSetPod(&pod) {
+			continue
+		}
+		if pod.CreationTimestamp.After(waitStart) {
+			continue
+		}
+		if deadline, ok := getPodDrainDeadline(&pod, waitStart, timeout); ok && !now.Before(deadline) {
+			continue
+		}
+		waiting = append(waiting, pod)
+	}
+	return waiting
+}
+
+// getPodDrainDeadline returns the time until which the drain waits for the pod. The deadline annotation of the pod
+// takes precedence over the drain policy timeout, false is returned if the drain waits for the pod indefinitely
+func getPodDrainDeadline(pod *v1.Pod, waitStart time.Time, timeout time.Duration) (time.Time, bool) {
+	if value, ok := pod.Annotations[podDrainDeadlineAnnotation]; ok {
+		if deadline, err := time.Parse(time.RFC3339, value); err == nil {
+			return deadline, true
+		}
+		if duration, err := time.ParseDuration(value); err == nil && duration >= 0 {
+			podStart := waitStart
+			if pod.Status.StartTime != nil {
+				podStart = pod.Status.StartTime.Time
+			}
+			return podStart.Add(duration), true
+		}
+	}
+	if timeout > 0 {
+		return waitStart.Add(timeout), true
+	}
+	return time.Time{}, false
+}
+
+func isDaemonSetPod(pod *v1.Pod) bool {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind == "DaemonSet" {
+			return true
+		}
+	}
+	return false
+}
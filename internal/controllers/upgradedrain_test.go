@@ -0,0 +1,167 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	amdv1alpha1 "github.com/ROCm/gpu-operator/api/v1alpha1"
+	mock_client "github.com/ROCm/gpu-operator/internal/client"
+)
+
+var _ = Describe("getPodsToWaitFor", func() {
+	waitStart := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	newPod := func(name string, created time.Time, phase v1.PodPhase) v1.Pod {
+		return v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(created)},
+			Status:     v1.PodStatus{Phase: phase, StartTime: &metav1.Time{Time: created}},
+		}
+	}
+
+	It("waits for the running GPU pods that were on the node when the wait started", func() {
+		running := newPod("running", waitStart.Add(-time.Hour), v1.PodRunning)
+		succeeded := newPod("succeeded", waitStart.Add(-time.Hour), v1.PodSucceeded)
+		scheduledLater := newPod("scheduled-later", waitStart.Add(time.Minute), v1.PodRunning)
+		daemonSetPod := newPod("daemonset", waitStart.Add(-time.Hour), v1.PodRunning)
+		daemonSetPod.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "ds"}}
+		withDeadline := newPod("with-deadline", waitStart.Add(-time.Hour), v1.PodRunning)
+		withDeadline.Annotations = map[string]string{podDrainDeadlineAnnotation: "90m"}
+
+		pods := []v1.Pod{running, succeeded, scheduledLater, daemonSetPod, withDeadline}
+		names := func(pods []v1.Pod) []string {
+			var names []string
+			for _, pod := range pods {
+				names = append(names, pod.Name)
+			}
+			return names
+		}
+
+		Expect(names(getPodsToWaitFor(pods, waitStart, 0, waitStart.Add(time.Minute)))).To(Equal([]string{"running", "with-deadline"}))
+		// the annotated pod reached its deadline, 90 minutes after it started
+		Expect(names(getPodsToWaitFor(pods, waitStart, 0, waitStart.Add(time.Hour)))).To(Equal([]string{"running"}))
+		// the drain policy timeout applies to the pods without an annotation
+		Expect(getPodsToWaitFor(pods, waitStart, time.Hour, waitStart.Add(time.Hour))).To(BeEmpty())
+	})
+})
+
+var _ = Describe("handleNodeUpgrade", func() {
+	It("waits for the GPU workloads before the node is cordoned", func() {
+		ctx := context.Background()
+		ctrl := gomock.NewController(GinkgoT())
+		kubeClient := mock_client.NewMockClient(ctrl)
+		clientset := fake.NewSimpleClientset()
+		helper := newUpgradeMgrHelperHandler(kubeClient, clientset, record.NewFakeRecorder(10), false).(*upgradeMgrHelper)
+
+		enable := true
+		devConfig := amdv1alpha1.DeviceConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: devConfigName, Namespace: devConfigNamespace},
+			Spec: amdv1alpha1.DeviceConfigSpec{
+				Driver: amdv1alpha1.DriverSpec{
+					Enable:  &enable,
+					Version: "6.3",
+					UpgradePolicy: &amdv1alpha1.DriverUpgradePolicySpec{
+						Enable:          &enable,
+						NodeDrainPolicy: &amdv1alpha1.DrainSpec{Mode: amdv1alpha1.DrainModeWaitForCompletion},
+					},
+				},
+			},
+		}
+		node := v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "unit-test-node"}}
+
+		var steps []string
+		clientset.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			steps = append(steps, "wait")
+			return true, &v1.PodList{}, nil
+		})
+		kubeClient.EXPECT().Get(ctx, client.ObjectKey{Name: node.Name}, gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+				node.DeepCopyInto(obj.(*v1.Node))
+				return nil
+			}).AnyTimes()
+		kubeClient.EXPECT().Patch(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, obj client.Object, _ client.Patch, _ ...client.PatchOption) error {
+				steps = append(steps, "cordon")
+				return nil
+			})
+		// stop the upgrade once the node is cordoned
+		kubeClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&amdv1alpha1.DeviceConfig{})).Return(errors.New("deviceconfig changed"))
+
+		helper.handleNodeUpgrade(ctx, devConfig, node)
+		Expect(steps).To(Equal([]string{"wait", "cordon"}))
+	})
+
+	It("cordons the node while it waits for the running GPU workloads", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		ctrl := gomock.NewController(GinkgoT())
+		kubeClient := mock_client.NewMockClient(ctrl)
+		gpuPod := v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "training", Namespace: "default", CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Hour))},
+			Spec: v1.PodSpec{
+				NodeName: "unit-test-node",
+				Containers: []v1.Container{{Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+					"amd.com/gpu": resource.MustParse("1"),
+				}}}},
+			},
+			Status: v1.PodStatus{Phase: v1.PodRunning},
+		}
+		helper := newUpgradeMgrHelperHandler(kubeClient, fake.NewSimpleClientset(&gpuPod), record.NewFakeRecorder(10), false).(*upgradeMgrHelper)
+		devConfig := &amdv1alpha1.DeviceConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: devConfigName, Namespace: devConfigNamespace},
+			Spec: amdv1alpha1.DeviceConfigSpec{
+				Driver: amdv1alpha1.DriverSpec{
+					Version: "6.3",
+					UpgradePolicy: &amdv1alpha1.DriverUpgradePolicySpec{
+						NodeDrainPolicy: &amdv1alpha1.DrainSpec{Mode: amdv1alpha1.DrainModeWaitForCompletion},
+					},
+				},
+			},
+		}
+		node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "unit-test-node"}}
+
+		kubeClient.EXPECT().Get(ctx, client.ObjectKey{Name: node.Name}, gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+				node.DeepCopyInto(obj.(*v1.Node))
+				return nil
+			}).AnyTimes()
+		var taints []v1.Taint
+		kubeClient.EXPECT().Patch(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, obj client.Object, _ client.Patch, _ ...client.PatchOption) error {
+				taints = obj.(*v1.Node).Spec.Taints
+				// stop the wait once the node is cordoned
+				cancel()
+				return nil
+			})
+
+		Expect(helper.waitForGPUPodsCompletion(ctx, devConfig, node)).To(BeFalse())
+		Expect(taints).To(ContainElement(HaveField("Key", "amd-gpu-driver-upgrade")))
+		Expect(helper.getNodeStatus(node.Name)).To(Equal(amdv1alpha1.UpgradeStateDrainWaiting))
+	})
+})
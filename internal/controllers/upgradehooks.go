@@ -0,0 +1,394 @@
+/*
+Copyright (c) Advanced Micro Devices, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the \"License\");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an \"AS IS\" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	workflowv1alpha1 "github.com/argoproj/argo-workflows/v4/pkg/apis/workflow/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	amdv1alpha1 "github.com/ROCm/gpu-operator/api/v1alpha1"
+	utils "github.com/ROCm/gpu-operator/internal"
+)
+
+const (
+	upgradeHookLabelKey          = "operator.amd.com/gpu-upgrade-hook"
+	upgradeHookNodeAnnotationKey = "operator.amd.com/gpu-upgrade-hook-node"
+	upgradeHookVersionAnnotation = "operator.amd.com/gpu-upgrade-hook-version"
+
+	upgradeHookPhasePre  = "pre"
+	upgradeHookPhasePost = "post"
+
+	defaultUpgradeHookTimeoutSeconds = 1800
+)
+
+// runPreUpgradeHook runs the pre-upgrade hook of the node and waits for its result. It returns false if the node
+// upgrade must not continue, because the hook failed or the DeviceConfig changed in the meantime
+func (h *upgradeMgrHelper) runPreUpgradeHook(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, node *v1.Node) bool {
+	hooks := deviceConfig.Spec.Driver.UpgradePolicy.Hooks
+	if hooks == nil || hooks.PreUpgrade == nil {
+		return true
+	}
+
+	logger := log.FromContext(ctx)
+	logger.Info(fmt.Sprintf("Node: %v running pre-upgrade hook", node.Name))
+	h.setNodeStatus(ctx, node.Name, amdv1alpha1.UpgradeStatePreUpgradeHook)
+
+	// the hook deadline is enforced by the Job or Workflow itself, the extra minute covers reporting its result
+	timeout := time.Duration(getUpgradeHookTimeoutSeconds(hooks.PreUpgrade)) * time.Second
+	deadline := time.Now().Add(timeout + time.Minute)
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for time.Now().Before(deadline) {
+		done, passed, err := h.getUpgradeHookResult(ctx, deviceConfig, hooks.PreUpgrade, upgradeHookPhasePre, node.Name)
+		if err != nil {
+			logger.Error(err, fmt.Sprintf("Node: %v pre-upgrade hook error", node.Name))
+		} else if done {
+			if !passed {
+				h.failNodeUpgradeHook(ctx, deviceConfig, node, amdv1alpha1.UpgradeStatePreUpgradeHookFailed, "pre-upgrade hook failed")
+				return false
+			}
+			logger.Info(fmt.Sprintf("Node: %v pre-upgrade hook succeeded", node.Name))
+			// the upgrade timeout only covers the upgrade itself, not the hook
+			h.setUpgradeStartTime(node.Name)
+			h.setNodeStatus(ctx, node.Name, amdv1alpha1.UpgradeStateStarted)
+			return true
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+		if !h.isDeviceConfigValid(ctx, deviceConfig) {
+			return false
+		}
+		if h.pauseNodeUpgradeIfRequested(ctx, deviceConfig, node) {
+			return false
+		}
+	}
+
+	h.failNodeUpgradeHook(ctx, deviceConfig, node, amdv1alpha1.UpgradeStatePreUpgradeHookFailed,
+		fmt.Sprintf("pre-upgrade hook did not complete within %v", timeout))
+	return false
+}
+
+// isNodeStatePostUpgradeHook runs the post-upgrade hook and health check of a node whose new driver is loaded.
+// It returns true while they are running or once they failed, the node stays cordoned until they all succeeded
+func (h *upgradeMgrHelper) isNodeStatePostUpgradeHook(ctx context.Context, node *v1.Node, deviceConfig *amdv1alpha1.DeviceConfig) bool {
+	hooks := deviceConfig.Spec.Driver.UpgradePolicy.Hooks
+	if hooks == nil {
+		return false
+	}
+	state := h.getNodeStatus(node.Name)
+	if state != amdv1alpha1.UpgradeStateInProgress && state != amdv1alpha1.UpgradeStatePostUpgradeHook {
+		return false
+	}
+	nodeStatus, ok := deviceConfig.Status.NodeModuleStatus[node.Name]
+	driverVersion, _ := utils.GetDriverVersion(*node, *deviceConfig)
+	if !ok || nodeStatus.ContainerImage == "" || !strings.HasSuffix(nodeStatus.ContainerImage, driverVersion) {
+		return false
+	}
+
+	type postUpgradeStep struct {
+		name   string
+		result func() (bool, bool, error)
+	}
+	var steps []postUpgradeStep
+	if hooks.PostUpgrade != nil {
+		steps = append(steps, postUpgradeStep{"post-upgrade hook", func() (bool, bool, error) {
+			return h.getUpgradeHookResult(ctx, deviceConfig, hooks.PostUpgrade, upgradeHookPhasePost, node.Name)
+		}})
+	}
+	if healthCheck := hooks.HealthCheck; isHealthCheckEnabled(healthCheck) {
+		steps = append(steps, postUpgradeStep{"post-upgrade health check", func() (bool, bool, error) {
+			return h.getUpgradeHealthCheckResult(ctx, deviceConfig, healthCheck, upgradeHealthCheckPostUpgrade, node)
+		}})
+	}
+
+	for _, step := range steps {
+		done, passed, err := step.result()
+		if err != nil {
+			log.FromContext(ctx).Error(err, fmt.Sprintf("Node: %v %v error", node.Name, step.name))
+		}
+		if err != nil || !done {
+			h.setNodeStatus(ctx, node.Name, amdv1alpha1.UpgradeStatePostUpgradeHook)
+			return true
+		}
+		if !passed {
+			h.failNodeUpgradeHook(ctx, deviceConfig, node, amdv1alpha1.UpgradeStatePostUpgradeHookFailed, fmt.Sprintf("%v failed", step.name))
+			return true
+		}
+	}
+	return false
+}
+
+func (h *upgradeMgrHelper) failNodeUpgradeHook(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, node *v1.Node, state amdv1alpha1.UpgradeState, reason string) {
+	log.FromContext(ctx).Info(fmt.Sprintf("Node: %v State: %v UpgradeFailed: %v", node.Name, h.getNodeStatus(node.Name), reason))
+	h.setNodeFailureReason(node.Name, reason)
+	h.setNodeStatus(ctx, node.Name, state)
+	recordEvent(h.recorder, deviceConfig, node, v1.EventTypeWarning, EventReasonUpgradeHookFailed,
+		fmt.Sprintf("Driver upgrade to version %v on node %v failed: %v", deviceConfig.Spec.Driver.Version, node.Name, reason))
+}
+
+// getUpgradeHookResult runs the hook for the node through a Job or an Argo Workflow and returns its result.
+// The Job or Workflow is created on first call and kept until the driver version changes, so that the result survives operator restarts
+func (h *upgradeMgrHelper) getUpgradeHookResult(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, hook *amdv1alpha1.UpgradeHookSpec, phase string, nodeName string) (done bool, passed bool, err error) {
+	objectMeta := getUpgradeHookObjectMeta(deviceConfig, phase, nodeName)
+	key := types.NamespacedName{Namespace: objectMeta.Namespace, Name: objectMeta.Name}
+
+	switch {
+	case hook.WorkflowTemplate != "":
+		existingWorkflow := &workflowv1alpha1.Workflow{}
+		if err := h.client.Get(ctx, key, existingWorkflow); err == nil {
+			switch existingWorkflow.Status.Phase {
+			case workflowv1alpha1.WorkflowSucceeded:
+				return true, true, nil
+			case workflowv1alpha1.WorkflowFailed, workflowv1alpha1.WorkflowError:
+				return true, false, nil
+			}
+			return false, false, nil
+		} else if !k8serrors.IsNotFound(err) {
+			return false, false, fmt.Errorf("failed to get %v-upgrade hook workflow %v: %v", phase, key.Name, err)
+		}
+
+		wfTemplate := &workflowv1alpha1.WorkflowTemplate{}
+		if err := h.client.Get(ctx, types.NamespacedName{Namespace: deviceConfig.Namespace, Name: hook.WorkflowTemplate}, wfTemplate); err != nil {
+			return false, false, fmt.Errorf("failed to get workflow template %v: %v", hook.WorkflowTemplate, err)
+		}
+		workflow := getUpgradeHookWorkflow(deviceConfig, hook, wfTemplate, objectMeta, nodeName)
+		if err := h.client.Create(ctx, workflow); err != nil && !k8serrors.IsAlreadyExists(err) {
+			return false, false, fmt.Errorf("failed to create %v-upgrade hook workflow %v: %v", phase, key.Name, err)
+		}
+		log.FromContext(ctx).Info(fmt.Sprintf("Node: %v started %v-upgrade hook workflow %v", nodeName, phase, key.Name))
+		return false, false, nil
+
+	case hook.JobTemplate != nil:
+		existingJob := &batchv1.Job{}
+		if err := h.client.Get(ctx, key, existingJob); err == nil {
+			done, passed := getJobResult(existingJob)
+			return done, passed, nil
+		} else if !k8serrors.IsNotFound(err) {
+			return false, false, fmt.Errorf("failed to get %v-upgrade hook job %v: %v", phase, key.Name, err)
+		}
+
+		job := getUpgradeHookJob(deviceConfig, hook, objectMeta, nodeName)
+		if err := h.client.Create(ctx, job); err != nil && !k8serrors.IsAlreadyExists(err) {
+			return false, false, fmt.Errorf("failed to create %v-upgrade hook job %v: %v", phase, key.Name, err)
+		}
+		log.FromContext(ctx).Info(fmt.Sprintf("Node: %v started %v-upgrade hook job %v", nodeName, phase, key.Name))
+		return false, false, nil
+	}
+
+	return false, false, fmt.Errorf("%v-upgrade hook has neither a job template nor a workflow template", phase)
+}
+
+// deleteNodeUpgradeHooks deletes the hooks and health check run for the current driver version on the node, so that they run again when its upgrade is retried
+func (h *upgradeMgrHelper) deleteNodeUpgradeHooks(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, nodeName string) error {
+	var objects []client.Object
+	for _, phase := range []string{upgradeHookPhasePre, upgradeHookPhasePost} {
+		objectMeta := getUpgradeHookObjectMeta(deviceConfig, phase, nodeName)
+		objects = append(objects,
+			&batchv1.Job{ObjectMeta: *objectMeta.DeepCopy()},
+			&workflowv1alpha1.Workflow{ObjectMeta: *objectMeta.DeepCopy()})
+	}
+	if hooks := deviceConfig.Spec.Driver.UpgradePolicy.Hooks; hooks != nil && hooks.HealthCheck != nil {
+		job, configMap := h.getUpgradeHealthCheckJob(deviceConfig, hooks.HealthCheck, upgradeHealthCheckPostUpgrade, nodeName)
+		objects = append(objects, job, configMap)
+	}
+	if rollout := deviceConfig.Spec.Driver.UpgradePolicy.RolloutStrategy; rollout != nil && rollout.Canary != nil && rollout.Canary.HealthCheck != nil {
+		job, configMap := h.getUpgradeHealthCheckJob(deviceConfig, rollout.Canary.HealthCheck, upgradeHealthCheckCanary, nodeName)
+		objects = append(objects, job, configMap)
+	}
+
+	for _, object := range objects {
+		err := h.client.Delete(ctx, object, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil && !k8serrors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+			return fmt.Errorf("failed to delete upgrade hook %v: %v", object.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// cleanupStaleUpgradeHooks deletes the hook Jobs, Workflows and health checks created for another driver version
+func (h *upgradeMgrHelper) cleanupStaleUpgradeHooks(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig) error {
+	if err := h.cleanupStaleHealthChecks(ctx, deviceConfig); err != nil {
+		return err
+	}
+	listOpts := []client.ListOption{
+		client.InNamespace(deviceConfig.Namespace),
+		client.MatchingLabels{upgradeHookLabelKey: deviceConfig.Name},
+	}
+	jobs := &batchv1.JobList{}
+	if err := h.client.List(ctx, jobs, listOpts...); err != nil {
+		return err
+	}
+	for i := range jobs.Items {
+		if jobs.Items[i].Annotations[upgradeHookVersionAnnotation] == deviceConfig.Spec.Driver.Version {
+			continue
+		}
+		if err := h.client.Delete(ctx, &jobs.Items[i], client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+	}
+	workflows := &workflowv1alpha1.WorkflowList{}
+	if err := h.client.List(ctx, workflows, listOpts...); err != nil {
+		if meta.IsNoMatchError(err) {
+			// Argo is not installed, there is no workflow hook to clean up
+			return nil
+		}
+		return err
+	}
+	for i := range workflows.Items {
+		if workflows.Items[i].Annotations[upgradeHookVersionAnnotation] == deviceConfig.Spec.Driver.Version {
+			continue
+		}
+		if err := h.client.Delete(ctx, &workflows.Items[i]); err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func getUpgradeHookTimeoutSeconds(hook *amdv1alpha1.UpgradeHookSpec) int {
+	if hook.TimeoutSeconds > 0 {
+		return hook.TimeoutSeconds
+	}
+	return defaultUpgradeHookTimeoutSeconds
+}
+
+// getUpgradeHookObjectMeta returns the metadata of the Job or Workflow running the hook for the node
+func getUpgradeHookObjectMeta(deviceConfig *amdv1alpha1.DeviceConfig, phase string, nodeName string) metav1.ObjectMeta {
+	// names are used as pod label values, keep them within 63 characters whatever the node name
+	hash := fnv.New64a()
+	hash.Write([]byte(fmt.Sprintf("%v/%v/%v/%v/%v", deviceConfig.Namespace, deviceConfig.Name, nodeName, deviceConfig.Spec.Driver.Version, phase)))
+
+	return metav1.ObjectMeta{
+		Name:      fmt.Sprintf("amd-gpu-%v-upgrade-%016x", phase, hash.Sum64()),
+		Namespace: deviceConfig.Namespace,
+		Labels: map[string]string{
+			upgradeHookLabelKey: deviceConfig.Name,
+		},
+		Annotations: map[string]string{
+			upgradeHookNodeAnnotationKey: nodeName,
+			upgradeHookVersionAnnotation: deviceConfig.Spec.Driver.Version,
+		},
+		OwnerReferences: []metav1.OwnerReference{
+			{
+				APIVersion: amdv1alpha1.GroupVersion.String(),
+				Kind:       "DeviceConfig",
+				Name:       deviceConfig.Name,
+				UID:        deviceConfig.UID,
+				Controller: ptr.To(true),
+			},
+		},
+	}
+}
+
+// getUpgradeHookJob returns the Job running the hook for the node from the user provided Job template
+func getUpgradeHookJob(deviceConfig *amdv1alpha1.DeviceConfig, hook *amdv1alpha1.UpgradeHookSpec, objectMeta metav1.ObjectMeta, nodeName string) *batchv1.Job {
+	job := &batchv1.Job{
+		ObjectMeta: objectMeta,
+		Spec:       *hook.JobTemplate.Spec.DeepCopy(),
+	}
+	for key, value := range hook.JobTemplate.Labels {
+		if _, ok := job.Labels[key]; !ok {
+			job.Labels[key] = value
+		}
+	}
+	for key, value := range hook.JobTemplate.Annotations {
+		if _, ok := job.Annotations[key]; !ok {
+			job.Annotations[key] = value
+		}
+	}
+
+	job.Spec.ActiveDeadlineSeconds = ptr.To(int64(getUpgradeHookTimeoutSeconds(hook)))
+	if job.Spec.Template.Spec.RestartPolicy == "" {
+		job.Spec.Template.Spec.RestartPolicy = v1.RestartPolicyNever
+	}
+	hookEnv := []v1.EnvVar{
+		{Name: "NODE_NAME", Value: nodeName},
+		{Name: "DRIVER_VERSION", Value: deviceConfig.Spec.Driver.Version},
+	}
+	for i := range job.Spec.Template.Spec.InitContainers {
+		job.Spec.Template.Spec.InitContainers[i].Env = append(job.Spec.Template.Spec.InitContainers[i].Env, hookEnv...)
+	}
+	for i := range job.Spec.Template.Spec.Containers {
+		job.Spec.Template.Spec.Containers[i].Env = append(job.Spec.Template.Spec.Containers[i].Env, hookEnv...)
+	}
+	return job
+}
+
+// getUpgradeHookWorkflow returns the Argo Workflow running the hook for the node from the user provided WorkflowTemplate
+func getUpgradeHookWorkflow(deviceConfig *amdv1alpha1.DeviceConfig, hook *amdv1alpha1.UpgradeHookSpec, wfTemplate *workflowv1alpha1.WorkflowTemplate, objectMeta metav1.ObjectMeta, nodeName string) *workflowv1alpha1.Workflow {
+	workflow := &workflowv1alpha1.Workflow{
+		ObjectMeta: objectMeta,
+		Spec:       *wfTemplate.Spec.DeepCopy(),
+	}
+	workflow.Labels[ArgoWorkflowInstaceIDLabelKey] = ArgoWorkflowInstaceIDLabelValue
+	workflow.Spec.ActiveDeadlineSeconds = ptr.To(int64(getUpgradeHookTimeoutSeconds(hook)))
+
+	hookParameters := map[string]string{
+		"node_name":      nodeName,
+		"driver_version": deviceConfig.Spec.Driver.Version,
+	}
+	for i, parameter := range workflow.Spec.Arguments.Parameters {
+		if value, ok := hookParameters[parameter.Name]; ok {
+			workflow.Spec.Arguments.Parameters[i].Value = workflowv1alpha1.AnyStringPtr(value)
+			delete(hookParameters, parameter.Name)
+		}
+	}
+	for _, name := range []string{"node_name", "driver_version"} {
+		if value, ok := hookParameters[name]; ok {
+			workflow.Spec.Arguments.Parameters = append(workflow.Spec.Arguments.Parameters, workflowv1alpha1.Parameter{
+				Name:  name,
+				Value: workflowv1alpha1.AnyStringPtr(value),
+			})
+		}
+	}
+	return workflow
+}
+
+// getJobResult returns whether the Job is done and whether it succeeded
+func getJobResult(job *batchv1.Job) (done bool, passed bool) {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != v1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return true, true
+		case batchv1.JobFailed:
+			return true, false
+		}
+	}
+	return false, false
+}
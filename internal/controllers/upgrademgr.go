@@ -34,8 +34,10 @@ package controllers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -45,6 +47,7 @@ import (
 
 	utils "github.com/ROCm/gpu-operator/internal"
 	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
@@ -52,6 +55,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/kubectl/pkg/drain"
 	"k8s.io/utils/ptr"
@@ -66,6 +70,11 @@ const (
 	defaultSAName              = "amd-gpu-operator-utils-container"
 	driverUpgradeStateLabelKey = "operator.amd.com/gpu-driver-upgrade-state"
 	upgradeRequiredLabelValue  = "upgrade-required"
+	upgradeRetryLabelValue     = "upgrade-retry"
+	upgradeSkipLabelValue      = "upgrade-skip"
+	upgradePauseLabelValue     = "upgrade-pause"
+	upgradeForceLabelValue     = "upgrade-force"
+	upgradeForceNowLabelValue  = "upgrade-force-now"
 )
 
 var (
@@ -98,15 +107,18 @@ type upgradeMgrAPI interface {
 	GetNodeStatus(nodeName string) amdv1alpha1.UpgradeState
 	GetNodeUpgradeStartTime(nodeName string) string
 	GetNodeBootId(nodeName string) string
+	GetRolloutStatus(deviceConfig *amdv1alpha1.DeviceConfig) *amdv1alpha1.UpgradeRolloutStatus
+	GetNodeUpgradeAction(nodeName string) amdv1alpha1.NodeUpgradeAction
+	GetDriverRollback(deviceConfig *amdv1alpha1.DeviceConfig) (string, map[string]string)
 }
 
-func newUpgradeMgrHandler(client client.Client, k8sConfig *rest.Config, isOpenShift bool) upgradeMgrAPI {
+func newUpgradeMgrHandler(client client.Client, k8sConfig *rest.Config, recorder record.EventRecorder, isOpenShift bool) upgradeMgrAPI {
 	k8sIntf, err := kubernetes.NewForConfig(k8sConfig)
 	if err != nil {
 		return nil
 	}
 	return &upgradeMgr{
-		helper: newUpgradeMgrHelperHandler(client, k8sIntf, isOpenShift),
+		helper: newUpgradeMgrHelperHandler(client, k8sIntf, recorder, isOpenShift),
 	}
 }
 
@@ -116,7 +128,7 @@ func newUpgradeMgrHandler(client client.Client, k8sConfig *rest.Config, isOpenSh
 func (n *upgradeMgr) HandleUpgrade(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, nodeList *v1.NodeList) (ctrl.Result, error) {
 	res := ctrl.Result{}
 
-	var candidateNodes []v1.Node
+	var candidateNodes, forcedNodes []v1.Node
 	var upgradeDone, upgradeInProgress, upgradeFailedState, installInProgress int
 
 	// if driver section is disabled in device config, skip upgrade policy handler
@@ -130,14 +142,39 @@ func (n *upgradeMgr) HandleUpgrade(ctx context.Context, deviceConfig *amdv1alpha
 		return ctrl.Result{}, nil
 	}
 
+	// DriverUpgradeStatus is the durable record of the node upgrade states of this DeviceConfig
+	upgradeStatus, err := n.helper.getOrCreateUpgradeStatus(ctx, deviceConfig)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to get driver upgrade status")
+	}
+	n.helper.pruneUpgradeStatus(ctx, upgradeStatus, nodeList)
+	for i := 0; i < len(nodeList.Items); i++ {
+		n.helper.setNodeDeviceConfig(nodeList.Items[i].Name, deviceConfig)
+	}
+
 	initInternalNodeStates := func(deviceConfig *amdv1alpha1.DeviceConfig) {
-		for nodeName, moduleStatus := range deviceConfig.Status.NodeModuleStatus {
-			if moduleStatus.Status == amdv1alpha1.UpgradeStateStarted {
+		// Prefer the persisted upgrade states, fall back to the DeviceConfig status written by the previous leader
+		nodeStates := make(map[string]amdv1alpha1.NodeUpgradeStatus)
+		if upgradeStatus != nil && len(upgradeStatus.Status) > 0 {
+			nodeStates = upgradeStatus.Status
+		} else {
+			for nodeName, moduleStatus := range deviceConfig.Status.NodeModuleStatus {
+				nodeStates[nodeName] = amdv1alpha1.NodeUpgradeStatus{
+					State:            moduleStatus.Status,
+					UpgradeStartTime: moduleStatus.UpgradeStartTime,
+					BootID:           moduleStatus.BootId,
+				}
+			}
+		}
+		for nodeName, moduleStatus := range nodeStates {
+			n.helper.setNodeDeviceConfig(nodeName, deviceConfig)
+			n.helper.restoreNodeState(nodeName, moduleStatus)
+			if moduleStatus.State == amdv1alpha1.UpgradeStateStarted {
 				if deviceConfig.Spec.Driver.UpgradePolicy.RebootRequired != nil && *deviceConfig.Spec.Driver.UpgradePolicy.RebootRequired {
 					nodeObj, err := n.helper.getNode(ctx, nodeName)
 					if err == nil {
 						// trigger reboot only for nodes which are in UpgradeStarted but haven't rebooted yet
-						if nodeObj.Status.NodeInfo.BootID == moduleStatus.BootId {
+						if nodeObj.Status.NodeInfo.BootID == moduleStatus.BootID {
 							log.FromContext(ctx).Info(fmt.Sprintf("Node: %v: Reboot is required for driver upgrade, triggering node reboot", nodeName))
 							n.helper.handleNodeReboot(ctx, nodeObj, *deviceConfig)
 							// for nodes which are in UpgradeStarted but already rebooted. Schedule the reboot pod deletion
@@ -153,7 +190,7 @@ func (n *upgradeMgr) HandleUpgrade(ctx context.Context, deviceConfig *amdv1alpha
 					log.FromContext(ctx).Info(fmt.Sprintf("Node: %v: Resetting Upgrade State to UpgradeStateEmpty", nodeName))
 					n.helper.setNodeStatus(ctx, nodeName, amdv1alpha1.UpgradeStateEmpty)
 				}
-			} else if moduleStatus.Status == amdv1alpha1.UpgradeStateRebootInProgress {
+			} else if moduleStatus.State == amdv1alpha1.UpgradeStateRebootInProgress {
 				// Operator restarted during upgrade operation. Schedule the reboot pod deletion
 				log.FromContext(ctx).Info(fmt.Sprintf("Node: %v: Reboot is in progress, scheduling reboot pod deletion", nodeName))
 				// If the pod is still present, schedule reboot pod deletion, else, move ahead to Upgrade-In-Progress
@@ -163,11 +200,24 @@ func (n *upgradeMgr) HandleUpgrade(ctx context.Context, deviceConfig *amdv1alpha
 					log.FromContext(ctx).Info(fmt.Sprintf("Pod: %v: reboot pod not found: %v", podObj, err))
 					n.helper.setNodeStatus(ctx, nodeName, amdv1alpha1.UpgradeStateInProgress)
 				} else {
-					n.helper.setNodeStatus(ctx, nodeName, moduleStatus.Status)
+					n.helper.setNodeStatus(ctx, nodeName, moduleStatus.State)
 					go n.helper.deleteRebootPod(ctx, nodeName, *deviceConfig, false)
 				}
+			} else if moduleStatus.State == amdv1alpha1.UpgradeStateDrainWaiting ||
+				moduleStatus.State == amdv1alpha1.UpgradeStatePreUpgradeHook {
+				// Operator restarted while waiting for the GPU workloads or the pre-upgrade hook. Requeue the node so that the wait starts over,
+				// a hook that already completed is not run again
+				log.FromContext(ctx).Info(fmt.Sprintf("Node: %v: Resetting Upgrade State to UpgradeStateEmpty", nodeName))
+				n.helper.setNodeStatus(ctx, nodeName, amdv1alpha1.UpgradeStateEmpty)
+			} else if moduleStatus.State == amdv1alpha1.UpgradeStateRollbackInProgress {
+				// Operator restarted during rollback operation. Resume the rollback
+				n.helper.setNodeStatus(ctx, nodeName, moduleStatus.State)
+				if nodeObj, err := n.helper.getNode(ctx, nodeName); err == nil {
+					log.FromContext(ctx).Info(fmt.Sprintf("Node: %v: Rollback is in progress, resuming rollback", nodeName))
+					go n.helper.handleNodeRollback(ctx, *deviceConfig, *nodeObj)
+				}
 			} else {
-				n.helper.setNodeStatus(ctx, nodeName, moduleStatus.Status)
+				n.helper.setNodeStatus(ctx, nodeName, moduleStatus.State)
 			}
 		}
 	}
@@ -183,6 +233,12 @@ func (n *upgradeMgr) HandleUpgrade(ctx context.Context, deviceConfig *amdv1alpha
 	}
 	n.helper.setcurrentSpec(deviceConfig)
 
+	if deviceConfig.Spec.Driver.UpgradePolicy.Hooks != nil {
+		if err := n.helper.cleanupStaleUpgradeHooks(ctx, deviceConfig); err != nil {
+			log.FromContext(ctx).Error(err, "failed to cleanup stale upgrade hooks")
+		}
+	}
+
 	for i := 0; i < len(nodeList.Items); i++ {
 
 		// 1. Set init status for unprocessed nodes
@@ -191,60 +247,166 @@ func (n *upgradeMgr) HandleUpgrade(ctx context.Context, deviceConfig *amdv1alpha
 		// 2. Handle an upgrade going on for very long
 		n.helper.handleUpgradeTimedOut(ctx, &nodeList.Items[i], deviceConfig)
 
-		// 3. Handle failed nodes
+		// 3. Handle failed nodes, reverting them to the previous driver if rollback is enabled
 		if n.helper.isNodeStateUpgradeFailed(ctx, &nodeList.Items[i]) {
+			observeUpgradeDuration(deviceConfig, n.helper.getUpgradeStartTime(nodeList.Items[i].Name), upgradeResultFailed)
 			n.helper.clearUpgradeStartTime(nodeList.Items[i].Name)
+			if n.helper.isNodeRollbackRequired(&nodeList.Items[i], deviceConfig) {
+				if deferred, retryAfter := n.helper.isNodeRollbackDeferred(ctx, &nodeList.Items[i], deviceConfig); deferred {
+					res = ctrl.Result{Requeue: true, RequeueAfter: retryAfter}
+					upgradeFailedState++
+					continue
+				}
+				if n.helper.refuseConflictingNodeRollback(ctx, &nodeList.Items[i], deviceConfig, nodeList.Items) {
+					upgradeFailedState++
+					continue
+				}
+				// a boot ID recorded during the rollback tells a resumed rollback that the node was already rebooted
+				n.helper.setBootID(nodeList.Items[i].Name, "")
+				n.helper.setNodeStatus(ctx, nodeList.Items[i].Name, amdv1alpha1.UpgradeStateRollbackInProgress)
+				go n.helper.handleNodeRollback(ctx, *deviceConfig, nodeList.Items[i])
+				upgradeInProgress++
+				continue
+			}
 			upgradeFailedState++
 			continue
 		}
 
+		// Rolled back nodes keep running the previous driver until the upgrade is retried
+		switch n.helper.getNodeStatus(nodeList.Items[i].Name) {
+		case amdv1alpha1.UpgradeStateRollbackInProgress:
+			upgradeInProgress++
+			continue
+		case amdv1alpha1.UpgradeStateRollbackComplete:
+			continue
+		}
+
+		// Skipped and paused nodes are held back until the user removes the label
+		if n.helper.isNodeUpgradeHeld(ctx, &nodeList.Items[i]) {
+			continue
+		}
+
 		// 4. Untaint to let upgrade continue in case of KMM bug after node reboot
 		if n.helper.isNodeNmcStatusMissing(ctx, &nodeList.Items[i], deviceConfig) {
 			upgradeInProgress++
 			continue
 		}
 
-		// 5. Handle Started Nodes
+		// 5. Handle Started Nodes, a node labeled for pause before it is drained gives up its upgrade slot right away
 		if n.helper.isNodeStateUpgradeStarted(&nodeList.Items[i]) {
+			if !n.helper.isNodeUpgradePausing(&nodeList.Items[i]) {
+				upgradeInProgress++
+			}
+			continue
+		}
+
+		// 6. Run the post-upgrade hook and health check before releasing the node
+		if n.helper.isNodeStatePostUpgradeHook(ctx, &nodeList.Items[i], deviceConfig) {
 			upgradeInProgress++
 			continue
 		}
 
-		// 6. Handle Completed nodes
+		// 7. Handle Completed nodes
 		if n.helper.isNodeReady(ctx, &nodeList.Items[i], deviceConfig) {
+			observeUpgradeDuration(deviceConfig, n.helper.getUpgradeStartTime(nodeList.Items[i].Name), upgradeResultSucceeded)
 			n.helper.clearUpgradeStartTime(nodeList.Items[i].Name)
 			upgradeDone++
 			continue
 		}
 
-		// 7. Handle New nodes
+		// 8. Handle New nodes
 		if n.helper.isNodeNew(ctx, &nodeList.Items[i], deviceConfig) {
 			// Driver will be unconditionally installed on new node
 			installInProgress++
 			continue
 		}
 
-		// 8. Handle Driver Install In Progres nodes
+		// 9. Handle Driver Install In Progres nodes
 		if n.helper.isNodeStateInstallInProgress(ctx, &nodeList.Items[i], deviceConfig) {
 			installInProgress++
 			continue
 		}
 
-		// 9. Handle Driver Upgrade InProgress nodes
+		// 10. Handle Driver Upgrade InProgress nodes
 		if n.helper.isNodeStateUpgradeInProgress(ctx, &nodeList.Items[i], deviceConfig) {
 			upgradeInProgress++
 			continue
 		}
 
+		// 11. Hold back nodes labeled for skip or pause, forced nodes go ahead of the other candidates
+		switch action := n.helper.getRequestedUpgradeAction(&nodeList.Items[i]); action {
+		case amdv1alpha1.NodeUpgradeActionSkip, amdv1alpha1.NodeUpgradeActionPause:
+			n.helper.holdNodeUpgrade(ctx, deviceConfig, &nodeList.Items[i], action)
+			continue
+		}
+
 		if !n.helper.isNodeReadyForUpgrade(ctx, &nodeList.Items[i]) {
 			res = ctrl.Result{Requeue: true, RequeueAfter: time.Second * 20}
 			continue
 		}
 
+		if n.helper.getRequestedUpgradeAction(&nodeList.Items[i]) == amdv1alpha1.NodeUpgradeActionForce {
+			forcedNodes = append(forcedNodes, nodeList.Items[i])
+			continue
+		}
+
 		//This node is a candidate for selection
 		candidateNodes = append(candidateNodes, nodeList.Items[i])
 	}
 
+	nodeStates := make(map[amdv1alpha1.UpgradeState]int)
+	for i := 0; i < len(nodeList.Items); i++ {
+		nodeStates[n.helper.getNodeStatus(nodeList.Items[i].Name)]++
+	}
+	setUpgradeNodesMetric(deviceConfig, nodeStates)
+
+	// Staged rollout only lets the nodes of the current canary set or wave start their upgrade
+	if rolloutStatus, allowedNodes := n.helper.planUpgradeRollout(ctx, deviceConfig, nodeList.Items, candidateNodes); rolloutStatus != nil {
+		var allowedCandidates []v1.Node
+		for _, node := range candidateNodes {
+			if allowedNodes[node.Name] {
+				allowedCandidates = append(allowedCandidates, node)
+			}
+		}
+		candidateNodes = allowedCandidates
+		if rolloutStatus.Phase != amdv1alpha1.UpgradeRolloutPhaseComplete {
+			res = ctrl.Result{Requeue: true, RequeueAfter: time.Second * 20}
+		}
+	}
+
+	// Forced nodes bypass the staged rollout and are upgraded first
+	candidateNodes = append(forcedNodes, candidateNodes...)
+
+	// Dry run mode only plans the spec, node upgrades in flight are driven to completion but no new one starts
+	if len(candidateNodes) > 0 && isDryRun(deviceConfig) {
+		log.FromContext(ctx).Info(fmt.Sprintf("Dry run enabled, not starting the driver upgrade of %v nodes", len(candidateNodes)))
+		candidateNodes = nil
+	}
+
+	// New node upgrades only start inside a maintenance window, upgrades already in flight are allowed to finish.
+	// Only nodes the user explicitly labeled with upgrade-force-now start outside of the windows
+	if len(candidateNodes) > 0 {
+		if open, nextWindow := checkMaintenanceWindow(deviceConfig); !open {
+			var overriddenNodes []v1.Node
+			for i := range candidateNodes {
+				if n.helper.isMaintenanceWindowOverridden(&candidateNodes[i]) {
+					overriddenNodes = append(overriddenNodes, candidateNodes[i])
+				}
+			}
+			log.FromContext(ctx).Info(fmt.Sprintf("Maintenance window closed, deferring driver upgrade of %v nodes by %v",
+				len(candidateNodes)-len(overriddenNodes), nextWindow.Round(time.Second)))
+			candidateNodes = overriddenNodes
+			res = ctrl.Result{Requeue: true, RequeueAfter: nextWindow}
+		}
+	}
+
+	// New node upgrades wait for the prebuilt driver images, upgrades already in flight are allowed to finish
+	if len(candidateNodes) > 0 && !areDriverImagesReady(deviceConfig) {
+		log.FromContext(ctx).Info(fmt.Sprintf("Driver images are not ready yet, deferring driver upgrade of %v nodes", len(candidateNodes)))
+		candidateNodes = nil
+		res = ctrl.Result{Requeue: true, RequeueAfter: driverImagePrebuildRequeueInterval}
+	}
+
 	if len(candidateNodes) == 0 && ((upgradeInProgress > 0) || (upgradeFailedState > 0) || (installInProgress > 0)) {
 		return ctrl.Result{Requeue: true, RequeueAfter: time.Second * 20}, nil
 	}
@@ -263,9 +425,17 @@ func (n *upgradeMgr) HandleUpgrade(ctx context.Context, deviceConfig *amdv1alpha
 	// Add nodes per policy
 	for i := 0; i < (maxParallelUpgrades-upgradeInProgress) && i < len(candidateNodes); i++ {
 
-		// Mark the state as progress
-		n.helper.setNodeStatus(ctx, candidateNodes[i].Name, amdv1alpha1.UpgradeStateStarted)
+		// The force label is consumed by the upgrade it triggers
+		if n.helper.getRequestedUpgradeAction(&candidateNodes[i]) == amdv1alpha1.NodeUpgradeActionForce {
+			if err := n.helper.handleForcedNodeUpgrade(ctx, deviceConfig, &candidateNodes[i]); err != nil {
+				continue
+			}
+		}
+
+		// Mark the state as progress, start time is recorded first so that it is persisted along with the state
 		n.helper.setUpgradeStartTime(candidateNodes[i].Name)
+		n.helper.setPreviousDriver(&candidateNodes[i], deviceConfig)
+		n.helper.setNodeStatus(ctx, candidateNodes[i].Name, amdv1alpha1.UpgradeStateStarted)
 		// Drain/Delete the pods and set the expected module version in module-config label of the ndoe
 		go n.helper.handleNodeUpgrade(ctx, *deviceConfig, candidateNodes[i])
 
@@ -284,6 +454,7 @@ func (n *upgradeMgr) HandleDelete(ctx context.Context, deviceConfig *amdv1alpha1
 		n.helper.deleteRebootPod(ctx, nodeList.Items[i].Name, *deviceConfig, true)
 	}
 	n.helper.clearNodeStatus()
+	n.helper.clearRolloutStatus(deviceConfig)
 	return
 }
 
@@ -302,6 +473,22 @@ func (n *upgradeMgr) GetNodeBootId(nodeName string) string {
 	return n.helper.getBootID(nodeName)
 }
 
+// GetRolloutStatus returns the staged rollout status of the device config, nil if no rollout strategy is configured
+func (n *upgradeMgr) GetRolloutStatus(deviceConfig *amdv1alpha1.DeviceConfig) *amdv1alpha1.UpgradeRolloutStatus {
+	return n.helper.getRolloutStatus(deviceConfig)
+}
+
+// GetNodeUpgradeAction returns the last user requested upgrade action the operator acted on for the node
+func (n *upgradeMgr) GetNodeUpgradeAction(nodeName string) amdv1alpha1.NodeUpgradeAction {
+	return n.helper.getNodeUpgradeAction(nodeName)
+}
+
+// GetDriverRollback returns the previous driver version the nodes of the device config are rolled back to, along with
+// the driver image each of these nodes ran before its upgrade. An empty version is returned if no rollback is in progress
+func (n *upgradeMgr) GetDriverRollback(deviceConfig *amdv1alpha1.DeviceConfig) (string, map[string]string) {
+	return n.helper.getDriverRollback(deviceConfig)
+}
+
 /*=========================================== Upgrade Manager Helper APIs ==========================================*/
 
 //go:generate mockgen -source=upgrademgr.go -package=controllers -destination=mock_upgrademgr.go upgradeMgrHelperAPI
@@ -338,6 +525,29 @@ type upgradeMgrHelperAPI interface {
 	hasUpgradeTimeExceeded(ctx context.Context, nodeName string, deviceConfig *amdv1alpha1.DeviceConfig) bool
 	handleUpgradeTimedOut(ctx context.Context, node *v1.Node, deviceConfig *amdv1alpha1.DeviceConfig)
 
+	// Helper APIs for rollback of failed nodes
+	isNodeRollbackRequired(node *v1.Node, deviceConfig *amdv1alpha1.DeviceConfig) bool
+	isNodeRollbackDeferred(ctx context.Context, node *v1.Node, deviceConfig *amdv1alpha1.DeviceConfig) (bool, time.Duration)
+	getDriverRollback(deviceConfig *amdv1alpha1.DeviceConfig) (string, map[string]string)
+	refuseConflictingNodeRollback(ctx context.Context, node *v1.Node, deviceConfig *amdv1alpha1.DeviceConfig, nodes []v1.Node) bool
+	handleNodeRollback(ctx context.Context, deviceConfig amdv1alpha1.DeviceConfig, node v1.Node)
+
+	// Helper APIs for the per node upgrade actions requested through node labels
+	getRequestedUpgradeAction(node *v1.Node) amdv1alpha1.NodeUpgradeAction
+	isNodeUpgradeHeld(ctx context.Context, node *v1.Node) bool
+	holdNodeUpgrade(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, node *v1.Node, action amdv1alpha1.NodeUpgradeAction)
+	pauseNodeUpgradeIfRequested(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, node *v1.Node) bool
+	isNodeUpgradePausing(node *v1.Node) bool
+	isMaintenanceWindowOverridden(node *v1.Node) bool
+	handleForcedNodeUpgrade(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, node *v1.Node) error
+
+	// Helper APIs for the WaitForCompletion drain mode
+	waitForGPUPodsCompletion(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, node *v1.Node) bool
+	runPreUpgradeHook(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, node *v1.Node) bool
+	isNodeStatePostUpgradeHook(ctx context.Context, node *v1.Node, deviceConfig *amdv1alpha1.DeviceConfig) bool
+	cleanupStaleUpgradeHooks(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig) error
+	removeUpgradeActionLabelOnNode(ctx context.Context, node *v1.Node, labelValue string) error
+
 	// getters and setters
 	specChanged(deviceConfig *amdv1alpha1.DeviceConfig) bool
 	setcurrentSpec(deviceConfig *amdv1alpha1.DeviceConfig)
@@ -350,17 +560,42 @@ type upgradeMgrHelperAPI interface {
 	clearUpgradeStartTime(nodeName string)
 	getBootID(nodeName string) string
 	setBootID(nodeName string, bootID string)
+	getNodeFailureReason(nodeName string) string
+	setNodeFailureReason(nodeName string, reason string)
+	setPreviousDriver(node *v1.Node, deviceConfig *amdv1alpha1.DeviceConfig)
+	getNodeUpgradeAction(nodeName string) amdv1alpha1.NodeUpgradeAction
+	setNodeUpgradeAction(nodeName string, action amdv1alpha1.NodeUpgradeAction)
 	clearNodeStatus()
 	isInit() bool
+
+	// persisted upgrade states
+	getOrCreateUpgradeStatus(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig) (*amdv1alpha1.DriverUpgradeStatus, error)
+	pruneUpgradeStatus(ctx context.Context, upgradeStatus *amdv1alpha1.DriverUpgradeStatus, nodeList *v1.NodeList)
+	setNodeDeviceConfig(nodeName string, deviceConfig *amdv1alpha1.DeviceConfig)
+	restoreNodeState(nodeName string, nodeState amdv1alpha1.NodeUpgradeStatus)
+	persistNodeState(ctx context.Context, nodeName string)
+
+	// staged rollout
+	planUpgradeRollout(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, nodes []v1.Node, candidateNodes []v1.Node) (*amdv1alpha1.UpgradeRolloutStatus, map[string]bool)
+	getRolloutStatus(deviceConfig *amdv1alpha1.DeviceConfig) *amdv1alpha1.UpgradeRolloutStatus
+	clearRolloutStatus(deviceConfig *amdv1alpha1.DeviceConfig)
 }
 
 type upgradeMgrHelper struct {
 	client               client.Client
 	k8sInterface         kubernetes.Interface
+	recorder             record.EventRecorder
 	drainHelper          *drain.Helper
 	nodeStatus           *sync.Map
 	nodeUpgradeStartTime *sync.Map
 	nodeBootID           *sync.Map
+	nodeFailureReason    *sync.Map
+	nodeTransitionTime   *sync.Map
+	nodeDeviceConfig     *sync.Map
+	rolloutStatus        *sync.Map
+	nodePreviousDriver   *sync.Map
+	nodeUpgradeAction    *sync.Map
+	nodeUpgradeStarted   *sync.Map
 	init                 bool
 	currentSpec          driverSpec
 	isOpenShift          bool
@@ -371,14 +606,28 @@ type driverSpec struct {
 	enable  bool
 }
 
+// previousDriver is the driver a node was running before its upgrade
+type previousDriver struct {
+	version string
+	image   string
+}
+
 // Initialize upgrade manager helper interface
-func newUpgradeMgrHelperHandler(client client.Client, k8sInterface kubernetes.Interface, isOpenShift bool) upgradeMgrHelperAPI {
+func newUpgradeMgrHelperHandler(client client.Client, k8sInterface kubernetes.Interface, recorder record.EventRecorder, isOpenShift bool) upgradeMgrHelperAPI {
 	return &upgradeMgrHelper{
 		client:               client,
 		k8sInterface:         k8sInterface,
+		recorder:             recorder,
 		nodeStatus:           new(sync.Map),
 		nodeUpgradeStartTime: new(sync.Map),
 		nodeBootID:           new(sync.Map),
+		nodeFailureReason:    new(sync.Map),
+		nodeTransitionTime:   new(sync.Map),
+		nodeDeviceConfig:     new(sync.Map),
+		rolloutStatus:        new(sync.Map),
+		nodePreviousDriver:   new(sync.Map),
+		nodeUpgradeAction:    new(sync.Map),
+		nodeUpgradeStarted:   new(sync.Map),
 		isOpenShift:          isOpenShift,
 	}
 }
@@ -400,7 +649,7 @@ func (h *upgradeMgrHelper) handleInitStatus(ctx context.Context, node *v1.Node,
 		h.setNodeStatus(ctx, node.Name, amdv1alpha1.UpgradeStateNotStarted)
 	}
 	nodeStatus := h.getNodeStatus(node.Name)
-	if h.isNodeInFailedUpgradeStates(nodeStatus) {
+	if h.isNodeInFailedUpgradeStates(nodeStatus) || nodeStatus == amdv1alpha1.UpgradeStateRollbackComplete {
 		// User will be adding this label on the node to requeue failed node for upgrade
 		if h.isLabelUpgradeRequiredOnNode(ctx, deviceConfig, node) {
 			// Remove label ready for upgrade on the node
@@ -422,8 +671,16 @@ func (h *upgradeMgrHelper) handleInitStatus(ctx context.Context, node *v1.Node,
 			if err := h.cleanupDanglingKMMPods(ctx, node, deviceConfig); err != nil {
 				return
 			}
+			// Run the upgrade hooks again on retry
+			if err := h.deleteNodeUpgradeHooks(ctx, deviceConfig, node.Name); err != nil {
+				log.FromContext(ctx).Error(err, fmt.Sprintf("Node: %v. Failed to delete upgrade hooks with error: %v", node.Name, err))
+				return
+			}
 			// Restart Upgrade flow on the node
 			log.FromContext(ctx).Info(fmt.Sprintf("Node: %v: Setting upgrade state to UpgradeNotStarted", node.Name))
+			h.setNodeUpgradeAction(node.Name, amdv1alpha1.NodeUpgradeActionRetry)
+			recordEvent(h.recorder, deviceConfig, node, v1.EventTypeNormal, EventReasonDriverUpgradeRetried,
+				fmt.Sprintf("Driver upgrade to version %v retried on node %v after %v", deviceConfig.Spec.Driver.Version, node.Name, nodeStatus))
 			h.setNodeStatus(ctx, node.Name, amdv1alpha1.UpgradeStateNotStarted)
 		} else {
 			log.FromContext(ctx).Info(fmt.Sprintf("Node: %v is not labeled with upgrade-required yet", node.Name))
@@ -488,6 +745,7 @@ func (h *upgradeMgrHelper) isNodeNew(ctx context.Context, node *v1.Node, deviceC
 				if err := h.updateModuleVersionOnNode(ctx, deviceConfig, node); err != nil {
 					log.FromContext(ctx).Error(err, fmt.Sprintf("Node: %v State: amdv1alpha1.UpgradeStateInstallInProgress UpgradeFailed with Error: %v", node.Name, err))
 					// Mark the state as failed
+					h.setNodeFailureReason(node.Name, fmt.Sprintf("failed to update driver version label on node: %v", err))
 					h.setNodeStatus(ctx, node.Name, amdv1alpha1.UpgradeStateFailed)
 				}
 
@@ -514,6 +772,7 @@ func (h *upgradeMgrHelper) isNodeNmcStatusMissing(ctx context.Context, node *v1.
 			// Uncordon the node
 			if err := h.cordonOrUncordonNode(ctx, deviceConfig, node, false); err != nil {
 				// Move to failure state if uncordon fails
+				h.setNodeFailureReason(node.Name, fmt.Sprintf("failed to uncordon node: %v", err))
 				h.setNodeStatus(ctx, node.Name, amdv1alpha1.UpgradeStateUncordonFailed)
 				return false
 			}
@@ -545,6 +804,7 @@ func (h *upgradeMgrHelper) isNodeReady(ctx context.Context, node *v1.Node, devic
 			// Uncordon the node
 			if err := h.cordonOrUncordonNode(ctx, deviceConfig, node, false); err != nil {
 				// Move to failure state if uncordon fails
+				h.setNodeFailureReason(node.Name, fmt.Sprintf("failed to uncordon node: %v", err))
 				h.setNodeStatus(ctx, node.Name, amdv1alpha1.UpgradeStateUncordonFailed)
 				return false
 			}
@@ -566,7 +826,8 @@ func (h *upgradeMgrHelper) isNodeReady(ctx context.Context, node *v1.Node, devic
 // Handle Driver installation for reboot pending nodes (new).
 func (h *upgradeMgrHelper) isNodeStateUpgradeStarted(node *v1.Node) bool {
 
-	return h.getNodeStatus(node.Name) == amdv1alpha1.UpgradeStateStarted || h.getNodeStatus(node.Name) == amdv1alpha1.UpgradeStateRebootInProgress
+	return h.getNodeStatus(node.Name) == amdv1alpha1.UpgradeStateStarted || h.getNodeStatus(node.Name) == amdv1alpha1.UpgradeStateRebootInProgress ||
+		h.getNodeStatus(node.Name) == amdv1alpha1.UpgradeStateDrainWaiting || h.getNodeStatus(node.Name) == amdv1alpha1.UpgradeStatePreUpgradeHook
 
 }
 
@@ -589,6 +850,7 @@ func (h *upgradeMgrHelper) isNodeStateUpgradeInProgress(ctx context.Context, nod
 			if err := h.updateModuleVersionOnNode(ctx, deviceConfig, node); err != nil {
 				log.FromContext(ctx).Error(err, fmt.Sprintf("Node: %v State: %v UpgradeFailed with Error: %v", node.Name, currentStatus, err))
 				// Mark the state as failed
+				h.setNodeFailureReason(node.Name, fmt.Sprintf("failed to update driver version label on node: %v", err))
 				h.setNodeStatus(ctx, node.Name, amdv1alpha1.UpgradeStateFailed)
 			}
 			return true
@@ -603,7 +865,10 @@ func (h *upgradeMgrHelper) isNodeInFailedUpgradeStates(state amdv1alpha1.Upgrade
 		state == amdv1alpha1.UpgradeStateCordonFailed ||
 		state == amdv1alpha1.UpgradeStateUncordonFailed ||
 		state == amdv1alpha1.UpgradeStateDrainFailed ||
-		state == amdv1alpha1.UpgradeStateTimedOut
+		state == amdv1alpha1.UpgradeStateTimedOut ||
+		state == amdv1alpha1.UpgradeStateRollbackFailed ||
+		state == amdv1alpha1.UpgradeStatePreUpgradeHookFailed ||
+		state == amdv1alpha1.UpgradeStatePostUpgradeHookFailed
 }
 
 // Check the Failure status for nodes that are being upgraded.
@@ -705,6 +970,7 @@ func (h *upgradeMgrHelper) handleUpgradeTimedOut(ctx context.Context, node *v1.N
 	if nodeStatus == amdv1alpha1.UpgradeStateStarted || nodeStatus == amdv1alpha1.UpgradeStateInProgress || nodeStatus == amdv1alpha1.UpgradeStateRebootInProgress {
 		if h.hasUpgradeTimeExceeded(ctx, node.Name, deviceConfig) {
 			log.FromContext(ctx).Info(fmt.Sprintf("Node: %v, Upgrade Timeout exceeded", node.Name))
+			h.setNodeFailureReason(node.Name, "upgrade did not complete within 2 hours")
 			h.setNodeStatus(ctx, node.Name, amdv1alpha1.UpgradeStateTimedOut)
 		}
 	}
@@ -734,6 +1000,237 @@ func (h *upgradeMgrHelper) setNodeStatus(ctx context.Context, nodeName string, s
 	if h.getNodeStatus(nodeName) != status {
 		log.FromContext(ctx).Info(fmt.Sprintf("UpgradeStateTransition Node: %v from %v state to %v", nodeName, h.getNodeStatus(nodeName), status))
 		h.nodeStatus.Store(nodeName, status)
+		h.nodeTransitionTime.Store(nodeName, time.Now().UTC().Format(DefaultTimeFormatLayout))
+		// the failure reason is kept while the node is rolled back so that it explains why the rollback happened
+		if !h.isNodeInFailedUpgradeStates(status) && status != amdv1alpha1.UpgradeStateRebootFailed &&
+			status != amdv1alpha1.UpgradeStateRollbackInProgress && status != amdv1alpha1.UpgradeStateRollbackComplete {
+			h.nodeFailureReason.Delete(nodeName)
+		}
+		// the upgrade of the node is over, the next one records its own started event
+		if status == amdv1alpha1.UpgradeStateComplete || status == amdv1alpha1.UpgradeStateInstallComplete ||
+			status == amdv1alpha1.UpgradeStateSkipped || h.isNodeInFailedUpgradeStates(status) {
+			h.nodeUpgradeStarted.Delete(nodeName)
+		}
+		h.persistNodeState(ctx, nodeName)
+	}
+}
+
+// recordUpgradeStarted emits the upgrade started event when the node enters its upgrade. A node that re-enters the
+// upgrade after a pause or an operator restart is still in the same upgrade and doesn't emit it again
+func (h *upgradeMgrHelper) recordUpgradeStarted(deviceConfig *amdv1alpha1.DeviceConfig, node *v1.Node) {
+	if _, started := h.nodeUpgradeStarted.LoadOrStore(node.Name, true); started {
+		return
+	}
+	recordEvent(h.recorder, deviceConfig, node, v1.EventTypeNormal, EventReasonDriverUpgradeStarted,
+		fmt.Sprintf("Driver upgrade to version %v started on node %v", deviceConfig.Spec.Driver.Version, node.Name))
+}
+
+func (h *upgradeMgrHelper) getNodeFailureReason(nodeName string) string {
+	if value, ok := h.nodeFailureReason.Load(nodeName); ok {
+		return value.(string)
+	}
+	return ""
+}
+
+// setNodeFailureReason records why the node upgrade failed, it is persisted with the next failed state transition
+func (h *upgradeMgrHelper) setNodeFailureReason(nodeName string, reason string) {
+	h.nodeFailureReason.Store(nodeName, reason)
+}
+
+// setPreviousDriver records the driver the node is running before its upgrade starts, it is the rollback target
+func (h *upgradeMgrHelper) setPreviousDriver(node *v1.Node, deviceConfig *amdv1alpha1.DeviceConfig) {
+	version, ok := node.Labels[fmt.Sprintf("kmm.node.kubernetes.io/version-module.%s.%s", deviceConfig.Namespace, deviceConfig.Name)]
+	if !ok || version == deviceConfig.Spec.Driver.Version {
+		return
+	}
+	h.nodePreviousDriver.Store(node.Name, previousDriver{
+		version: version,
+		image:   deviceConfig.Status.NodeModuleStatus[node.Name].ContainerImage,
+	})
+}
+
+func (h *upgradeMgrHelper) getPreviousDriver(nodeName string) (previousDriver, bool) {
+	if value, ok := h.nodePreviousDriver.Load(nodeName); ok {
+		return value.(previousDriver), true
+	}
+	return previousDriver{}, false
+}
+
+func (h *upgradeMgrHelper) getNodeUpgradeAction(nodeName string) amdv1alpha1.NodeUpgradeAction {
+	if value, ok := h.nodeUpgradeAction.Load(nodeName); ok {
+		return value.(amdv1alpha1.NodeUpgradeAction)
+	}
+	return ""
+}
+
+// setNodeUpgradeAction records the user requested action the operator acted on, it is persisted with the next state transition
+func (h *upgradeMgrHelper) setNodeUpgradeAction(nodeName string, action amdv1alpha1.NodeUpgradeAction) {
+	h.nodeUpgradeAction.Store(nodeName, action)
+}
+
+// getOrCreateUpgradeStatus returns the DriverUpgradeStatus of the DeviceConfig, creating it if it doesn't exist yet
+func (h *upgradeMgrHelper) getOrCreateUpgradeStatus(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig) (*amdv1alpha1.DriverUpgradeStatus, error) {
+	upgradeStatus := &amdv1alpha1.DriverUpgradeStatus{}
+	err := h.client.Get(ctx, types.NamespacedName{Namespace: deviceConfig.Namespace, Name: deviceConfig.Name}, upgradeStatus)
+	if err == nil {
+		return upgradeStatus, nil
+	}
+	if !k8serrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get driver upgrade status: %v", err)
+	}
+
+	upgradeStatus = &amdv1alpha1.DriverUpgradeStatus{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      deviceConfig.Name,
+			Namespace: deviceConfig.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: amdv1alpha1.GroupVersion.String(),
+					Kind:       "DeviceConfig",
+					Name:       deviceConfig.Name,
+					UID:        deviceConfig.UID,
+					Controller: ptr.To(true),
+				},
+			},
+		},
+	}
+	if err := h.client.Create(ctx, upgradeStatus); err != nil && !k8serrors.IsAlreadyExists(err) {
+		return nil, fmt.Errorf("failed to create driver upgrade status: %v", err)
+	}
+	return upgradeStatus, nil
+}
+
+// setNodeDeviceConfig records the DeviceConfig managing the node, whose DriverUpgradeStatus keeps the node state
+func (h *upgradeMgrHelper) setNodeDeviceConfig(nodeName string, deviceConfig *amdv1alpha1.DeviceConfig) {
+	h.nodeDeviceConfig.Store(nodeName, types.NamespacedName{Namespace: deviceConfig.Namespace, Name: deviceConfig.Name})
+}
+
+// restoreNodeState loads the persisted details of the node into the internal maps, the state itself
+// goes through setNodeStatus as part of the init handling
+func (h *upgradeMgrHelper) restoreNodeState(nodeName string, nodeState amdv1alpha1.NodeUpgradeStatus) {
+	switch nodeState.State {
+	case amdv1alpha1.UpgradeStateStarted, amdv1alpha1.UpgradeStateInstallInProgress, amdv1alpha1.UpgradeStateInProgress, amdv1alpha1.UpgradeStateRebootInProgress:
+		if nodeState.UpgradeStartTime != "" {
+			h.nodeUpgradeStartTime.Store(nodeName, nodeState.UpgradeStartTime)
+		}
+	}
+	switch nodeState.State {
+	case amdv1alpha1.UpgradeStateStarted, amdv1alpha1.UpgradeStatePreUpgradeHook, amdv1alpha1.UpgradeStateDrainWaiting,
+		amdv1alpha1.UpgradeStateInProgress, amdv1alpha1.UpgradeStateRebootInProgress, amdv1alpha1.UpgradeStatePostUpgradeHook:
+		// the started event of the upgrade was already emitted by the previous leader
+		h.nodeUpgradeStarted.Store(nodeName, true)
+	}
+	if nodeState.BootID != "" {
+		h.nodeBootID.Store(nodeName, nodeState.BootID)
+	}
+	if nodeState.FailureReason != "" {
+		h.nodeFailureReason.Store(nodeName, nodeState.FailureReason)
+	}
+	if nodeState.LastTransitionTime != "" {
+		h.nodeTransitionTime.Store(nodeName, nodeState.LastTransitionTime)
+	}
+	if nodeState.PreviousVersion != "" {
+		h.nodePreviousDriver.Store(nodeName, previousDriver{version: nodeState.PreviousVersion, image: nodeState.PreviousImage})
+	}
+	if nodeState.UpgradeAction != "" {
+		h.nodeUpgradeAction.Store(nodeName, nodeState.UpgradeAction)
+	}
+}
+
+// persistNodeState writes the internal state of the node to the DriverUpgradeStatus of its DeviceConfig.
+// Only the entry of the node is merged, so that concurrent node upgrades don't overwrite each other
+func (h *upgradeMgrHelper) persistNodeState(ctx context.Context, nodeName string) {
+	value, ok := h.nodeDeviceConfig.Load(nodeName)
+	if !ok {
+		return
+	}
+	dcName := value.(types.NamespacedName)
+	nodeState := amdv1alpha1.NodeUpgradeStatus{
+		State:            h.getNodeStatus(nodeName),
+		UpgradeStartTime: h.getUpgradeStartTime(nodeName),
+		BootID:           h.getBootID(nodeName),
+		FailureReason:    h.getNodeFailureReason(nodeName),
+		UpgradeAction:    h.getNodeUpgradeAction(nodeName),
+	}
+	if value, ok := h.nodeTransitionTime.Load(nodeName); ok {
+		nodeState.LastTransitionTime = value.(string)
+	}
+	if value, ok := h.nodePreviousDriver.Load(nodeName); ok {
+		nodeState.PreviousVersion = value.(previousDriver).version
+		nodeState.PreviousImage = value.(previousDriver).image
+	}
+
+	if err := h.patchUpgradeStatus(ctx, dcName, nodeName, nodeUpgradeStatusPatch(nodeState)); err != nil {
+		log.FromContext(ctx).Error(err, fmt.Sprintf("Node: %v failed to persist upgrade state %v: %v", nodeName, nodeState.State, err))
+	}
+}
+
+// nodeUpgradeStatusPatch returns the merge patch value of the node entry. The empty fields are sent as explicit nulls,
+// a merge patch would keep their previous value otherwise and a new leader would restore them
+func nodeUpgradeStatusPatch(nodeState amdv1alpha1.NodeUpgradeStatus) map[string]interface{} {
+	fields := map[string]string{
+		"state":              string(nodeState.State),
+		"upgradeStartTime":   nodeState.UpgradeStartTime,
+		"bootId":             nodeState.BootID,
+		"failureReason":      nodeState.FailureReason,
+		"lastTransitionTime": nodeState.LastTransitionTime,
+		"previousVersion":    nodeState.PreviousVersion,
+		"previousImage":      nodeState.PreviousImage,
+		"upgradeAction":      string(nodeState.UpgradeAction),
+	}
+	patch := make(map[string]interface{}, len(fields))
+	for field, value := range fields {
+		if value == "" {
+			patch[field] = nil
+		} else {
+			patch[field] = value
+		}
+	}
+	return patch
+}
+
+// patchUpgradeStatus merges the entry of the node into the DriverUpgradeStatus, a nil entry removes the node
+func (h *upgradeMgrHelper) patchUpgradeStatus(ctx context.Context, dcName types.NamespacedName, nodeName string, nodeEntry map[string]interface{}) error {
+	patchBytes, err := json.Marshal(map[string]interface{}{
+		"status": map[string]interface{}{nodeName: nodeEntry},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal upgrade state: %v", err)
+	}
+	upgradeStatus := &amdv1alpha1.DriverUpgradeStatus{
+		ObjectMeta: metav1.ObjectMeta{Namespace: dcName.Namespace, Name: dcName.Name},
+	}
+	return h.client.Status().Patch(ctx, upgradeStatus, client.RawPatch(types.MergePatchType, patchBytes))
+}
+
+// pruneUpgradeStatus removes the upgrade states of the nodes that were deleted from the cluster, both from the
+// DriverUpgradeStatus and from the internal maps, so that they are neither restored nor reported anymore
+func (h *upgradeMgrHelper) pruneUpgradeStatus(ctx context.Context, upgradeStatus *amdv1alpha1.DriverUpgradeStatus, nodeList *v1.NodeList) {
+	if upgradeStatus == nil {
+		return
+	}
+	selected := make(map[string]bool, len(nodeList.Items))
+	for _, node := range nodeList.Items {
+		selected[node.Name] = true
+	}
+	dcName := types.NamespacedName{Namespace: upgradeStatus.Namespace, Name: upgradeStatus.Name}
+	for nodeName := range upgradeStatus.Status {
+		if selected[nodeName] {
+			continue
+		}
+		if _, err := h.getNode(ctx, nodeName); err == nil || !k8serrors.IsNotFound(err) {
+			continue
+		}
+		if err := h.patchUpgradeStatus(ctx, dcName, nodeName, nil); err != nil {
+			log.FromContext(ctx).Error(err, fmt.Sprintf("Node: %v failed to prune upgrade state of deleted node: %v", nodeName, err))
+			continue
+		}
+		log.FromContext(ctx).Info(fmt.Sprintf("Node: %v was deleted, pruned its upgrade state", nodeName))
+		delete(upgradeStatus.Status, nodeName)
+		for _, nodeMap := range []*sync.Map{h.nodeStatus, h.nodeUpgradeStartTime, h.nodeBootID, h.nodeFailureReason, h.nodeTransitionTime,
+			h.nodeDeviceConfig, h.nodePreviousDriver, h.nodeUpgradeAction, h.nodeUpgradeStarted} {
+			nodeMap.Delete(nodeName)
+		}
 	}
 }
 
@@ -757,6 +1254,7 @@ func (h *upgradeMgrHelper) getPod(ctx context.Context, podName string, namespace
 func (h *upgradeMgrHelper) clearNodeStatus() {
 
 	h.nodeStatus = new(sync.Map)
+	h.nodeUpgradeStarted = new(sync.Map)
 }
 
 func (h *upgradeMgrHelper) specChanged(deviceConfig *amdv1alpha1.DeviceConfig) bool {
@@ -860,6 +1358,7 @@ func (h *upgradeMgrHelper) handleNodeUpgrade(ctx context.Context, deviceConfig a
 	logger := log.FromContext(ctx)
 
 	logger.Info(fmt.Sprintf("Node: %v Upgrade begin", node.Name))
+	h.recordUpgradeStarted(&deviceConfig, &node)
 
 	// Nothing more to do if the label is already set. Node might have rebooted
 	nodeObj := &v1.Node{}
@@ -873,13 +1372,30 @@ func (h *upgradeMgrHelper) handleNodeUpgrade(ctx context.Context, deviceConfig a
 		}
 	}
 
+	// The node is cordoned only once the pre-upgrade hook succeeded
+	if !h.runPreUpgradeHook(ctx, &deviceConfig, &node) {
+		return
+	}
+
+	if h.pauseNodeUpgradeIfRequested(ctx, &deviceConfig, &node) {
+		return
+	}
+
+	// Give the GPU workloads time to complete before the node is drained, the node is cordoned while it waits
+	if !h.waitForGPUPodsCompletion(ctx, &deviceConfig, &node) {
+		return
+	}
+
 	// Cordon the node to prevent scheduling of new nodes
 	cordonErr := h.cordonOrUncordonNode(ctx, &deviceConfig, &node, true)
 	if deviceConfigValid := h.isDeviceConfigValid(context.TODO(), &deviceConfig); deviceConfigValid {
 		if cordonErr != nil {
 			logger.Error(cordonErr, fmt.Sprintf("Node: %v State: %v UpgradeFailed with Error: %v", node.Name, h.getNodeStatus(node.Name), cordonErr))
 			// Cordoning failed. Mark the state as failed
+			h.setNodeFailureReason(node.Name, fmt.Sprintf("failed to cordon node: %v", cordonErr))
 			h.setNodeStatus(ctx, node.Name, amdv1alpha1.UpgradeStateCordonFailed)
+			recordEvent(h.recorder, &deviceConfig, &node, v1.EventTypeWarning, EventReasonNodeCordonFailed,
+				fmt.Sprintf("Failed to cordon node %v for driver upgrade: %v", node.Name, cordonErr))
 			return
 		}
 		// Proceed if the device config is valid and cordoning is successful
@@ -888,13 +1404,21 @@ func (h *upgradeMgrHelper) handleNodeUpgrade(ctx context.Context, deviceConfig a
 		return
 	}
 
+	if h.pauseNodeUpgradeIfRequested(ctx, &deviceConfig, &node) {
+		return
+	}
+
 	// Drain the pods that are using amdgpu
 	drainErr := h.deleteOrDrainPods(ctx, &deviceConfig, &node)
 	if deviceConfigValid := h.isDeviceConfigValid(context.TODO(), &deviceConfig); deviceConfigValid {
 		if drainErr != nil {
 			logger.Error(drainErr, fmt.Sprintf("Node: %v State: %v UpgradeFailed with Error: %v", node.Name, h.getNodeStatus(node.Name), drainErr))
 			// Pod Draining failed. Mark the state as failed
+			h.setNodeFailureReason(node.Name, fmt.Sprintf("failed to drain node: %v", drainErr))
 			h.setNodeStatus(ctx, node.Name, amdv1alpha1.UpgradeStateDrainFailed)
+			incDrainFailuresMetric(&deviceConfig)
+			recordEvent(h.recorder, &deviceConfig, &node, v1.EventTypeWarning, EventReasonNodeDrainFailed,
+				fmt.Sprintf("Failed to drain node %v for driver upgrade: %v", node.Name, drainErr))
 			return
 		}
 		// Proceed if the device config is valid and cordoning is successful
@@ -903,6 +1427,10 @@ func (h *upgradeMgrHelper) handleNodeUpgrade(ctx context.Context, deviceConfig a
 		return
 	}
 
+	if h.pauseNodeUpgradeIfRequested(ctx, &deviceConfig, &node) {
+		return
+	}
+
 	// Reboot the node if required
 	if deviceConfig.Spec.Driver.UpgradePolicy.RebootRequired != nil && *deviceConfig.Spec.Driver.UpgradePolicy.RebootRequired {
 		h.handleNodeReboot(ctx, &node, deviceConfig)
@@ -911,6 +1439,7 @@ func (h *upgradeMgrHelper) handleNodeUpgrade(ctx context.Context, deviceConfig a
 		if err := h.updateModuleVersionOnNode(ctx, &deviceConfig, &node); err != nil {
 			logger.Error(err, fmt.Sprintf("Node: %v State: %v UpgradeFailed with Error: %v", node.Name, h.getNodeStatus(node.Name), err))
 			// Mark the state as failed
+			h.setNodeFailureReason(node.Name, fmt.Sprintf("failed to update driver version label on node: %v", err))
 			h.setNodeStatus(ctx, node.Name, amdv1alpha1.UpgradeStateFailed)
 			return
 		}
@@ -1107,16 +1636,15 @@ func (h *upgradeMgrHelper) resetModuleVersionOnNode(ctx context.Context, deviceC
 
 func (h *upgradeMgrHelper) isLabelUpgradeRequiredOnNode(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, node *v1.Node) bool {
 
-	if labelValue, exists := node.Labels[driverUpgradeStateLabelKey]; exists {
-		if labelValue == upgradeRequiredLabelValue {
-			return true
-		}
-	}
-
-	return false
+	return h.getRequestedUpgradeAction(node) == amdv1alpha1.NodeUpgradeActionRetry
 }
 
 func (h *upgradeMgrHelper) removeLabelUpgradeRequiredOnNode(ctx context.Context, node *v1.Node) error {
+	return h.removeUpgradeActionLabelOnNode(ctx, node, node.Labels[driverUpgradeStateLabelKey])
+}
+
+// removeUpgradeActionLabelOnNode removes the upgrade action label from the node if it still carries the given value
+func (h *upgradeMgrHelper) removeUpgradeActionLabelOnNode(ctx context.Context, node *v1.Node, labelValue string) error {
 	logger := log.FromContext(ctx)
 
 	nodeObj := &v1.Node{}
@@ -1125,8 +1653,8 @@ func (h *upgradeMgrHelper) removeLabelUpgradeRequiredOnNode(ctx context.Context,
 		return err
 	}
 
-	if labelValue, exists := nodeObj.Labels[driverUpgradeStateLabelKey]; exists {
-		if labelValue == upgradeRequiredLabelValue {
+	if value, exists := nodeObj.Labels[driverUpgradeStateLabelKey]; exists {
+		if value == labelValue {
 			original := nodeObj.DeepCopy()
 			delete(nodeObj.Labels, driverUpgradeStateLabelKey)
 
@@ -1149,6 +1677,7 @@ func (h *upgradeMgrHelper) handleNodeReboot(ctx context.Context, node *v1.Node,
 	if err := h.client.Get(ctx, types.NamespacedName{Namespace: dc.Namespace, Name: rebootPod.Name}, pod); err == nil {
 		if err := h.client.Delete(ctx, pod); err != nil {
 			logger.Error(err, fmt.Sprintf("Node: %v State: %v RebootPod Delete failed with Error: %v", node.Name, h.getNodeStatus(node.Name), err))
+			h.setNodeFailureReason(node.Name, fmt.Sprintf("failed to delete stale reboot pod: %v", err))
 			h.setNodeStatus(ctx, node.Name, amdv1alpha1.UpgradeStateRebootFailed)
 			return
 		}
@@ -1158,6 +1687,7 @@ func (h *upgradeMgrHelper) handleNodeReboot(ctx context.Context, node *v1.Node,
 	if err := h.updateModuleVersionOnNode(ctx, &dc, node); err != nil {
 		logger.Error(err, fmt.Sprintf("Node: %v State: %v UpgradeFailed with Error: %v", node.Name, h.getNodeStatus(node.Name), err))
 		// Mark the state as failed
+		h.setNodeFailureReason(node.Name, fmt.Sprintf("failed to update driver version label on node: %v", err))
 		h.setNodeStatus(ctx, node.Name, amdv1alpha1.UpgradeStateFailed)
 		return
 	}
@@ -1180,12 +1710,17 @@ func (h *upgradeMgrHelper) handleNodeReboot(ctx context.Context, node *v1.Node,
 
 	currentBootID := node.Status.NodeInfo.BootID
 	h.setBootID(node.Name, currentBootID)
+	// Persist the pre-reboot boot ID, a new leader relies on it to tell whether the reboot already happened
+	h.persistNodeState(ctx, node.Name)
 	if err := h.client.Create(ctx, rebootPod); err != nil {
 		logger.Error(err, fmt.Sprintf("Node: %v State: %v RebootPod Create failed with Error: %v", node.Name, h.getNodeStatus(node.Name), err))
 		// Mark the state as failed
+		h.setNodeFailureReason(node.Name, fmt.Sprintf("failed to create reboot pod: %v", err))
 		h.setNodeStatus(ctx, node.Name, amdv1alpha1.UpgradeStateRebootFailed)
 		return
 	}
+	recordEvent(h.recorder, &dc, node, v1.EventTypeNormal, EventReasonNodeRebootIssued,
+		fmt.Sprintf("Reboot issued on node %v for driver upgrade to version %v", node.Name, dc.Spec.Driver.Version))
 
 	waitForRebootPod := func() {
 		for i := uint(0); i < 300; _, i = <-time.NewTicker(2*time.Second).C, i+1 {
@@ -1298,6 +1833,288 @@ func (h *upgradeMgrHelper) deleteRebootPod(ctx context.Context, nodeName string,
 	}
 }
 
+// isNodeRollbackRequired returns true if the failed node has to be reverted to the driver it was running before the upgrade
+func (h *upgradeMgrHelper) isNodeRollbackRequired(node *v1.Node, deviceConfig *amdv1alpha1.DeviceConfig) bool {
+	upgradePolicy := deviceConfig.Spec.Driver.UpgradePolicy
+	if upgradePolicy == nil || upgradePolicy.AutoRollback == nil || !*upgradePolicy.AutoRollback {
+		return false
+	}
+	switch h.getNodeStatus(node.Name) {
+	case amdv1alpha1.UpgradeStateFailed, amdv1alpha1.UpgradeStateDrainFailed, amdv1alpha1.UpgradeStateTimedOut,
+		amdv1alpha1.UpgradeStatePostUpgradeHookFailed:
+	default:
+		return false
+	}
+	_, ok := h.getPreviousDriver(node.Name)
+	return ok
+}
+
+// isNodeRollbackDeferred returns true if the rollback of the failed node can't start yet. A rollback that reboots the node
+// waits for a maintenance window like an upgrade does, and the KMM Module can only be rendered for one previous driver
+// version at a time, so nodes rolled back to another version wait for the rollbacks in progress
+func (h *upgradeMgrHelper) isNodeRollbackDeferred(ctx context.Context, node *v1.Node, deviceConfig *amdv1alpha1.DeviceConfig) (bool, time.Duration) {
+	logger := log.FromContext(ctx)
+	if rebootRequired := deviceConfig.Spec.Driver.UpgradePolicy.RebootRequired; rebootRequired != nil && *rebootRequired {
+		if open, nextWindow := checkMaintenanceWindow(deviceConfig); !open {
+			logger.Info(fmt.Sprintf("Node: %v maintenance window closed, deferring driver rollback by %v", node.Name, nextWindow.Round(time.Second)))
+			return true, nextWindow
+		}
+	}
+	previous, _ := h.getPreviousDriver(node.Name)
+	if version, _ := h.getDriverRollback(deviceConfig); version != "" && version != previous.version {
+		logger.Info(fmt.Sprintf("Node: %v rollback to driver version %v waits for the rollbacks to version %v", node.Name, previous.version, version))
+		return true, time.Second * 20
+	}
+	return false, 0
+}
+
+// getDriverRollback returns the previous driver version the nodes of the device config are rolled back to and the driver
+// image of each of these nodes, the version of the first rolling back node is returned if they differ
+func (h *upgradeMgrHelper) getDriverRollback(deviceConfig *amdv1alpha1.DeviceConfig) (string, map[string]string) {
+	dcName := types.NamespacedName{Namespace: deviceConfig.Namespace, Name: deviceConfig.Name}
+	var rollbackNodes []string
+	h.nodeStatus.Range(func(key, value any) bool {
+		if value.(amdv1alpha1.UpgradeState) != amdv1alpha1.UpgradeStateRollbackInProgress {
+			return true
+		}
+		if owner, ok := h.nodeDeviceConfig.Load(key); ok && owner.(types.NamespacedName) == dcName {
+			rollbackNodes = append(rollbackNodes, key.(string))
+		}
+		return true
+	})
+	sort.Strings(rollbackNodes)
+
+	version := ""
+	nodeImages := map[string]string{}
+	for _, nodeName := range rollbackNodes {
+		previous, ok := h.getPreviousDriver(nodeName)
+		if !ok || (version != "" && previous.version != version) {
+			continue
+		}
+		version = previous.version
+		if previous.image != "" {
+			nodeImages[nodeName] = previous.image
+		}
+	}
+	return version, nodeImages
+}
+
+// refuseConflictingNodeRollback fails the rollback of the node if another node running the same kernel has a driver image
+// other than the previous driver of the node. The KMM Module maps a kernel to a single driver image, pinning the previous
+// image of the node for its kernel would also replace the driver of the other node, so the rollback is refused instead
+func (h *upgradeMgrHelper) refuseConflictingNodeRollback(ctx context.Context, node *v1.Node, deviceConfig *amdv1alpha1.DeviceConfig, nodes []v1.Node) bool {
+	previous, _ := h.getPreviousDriver(node.Name)
+	conflict := ""
+	for i := range nodes {
+		other := &nodes[i]
+		if other.Name == node.Name || other.Status.NodeInfo.KernelVersion != node.Status.NodeInfo.KernelVersion {
+			continue
+		}
+		// the nodes rolled back to the same driver share its image
+		if otherPrevious, ok := h.getPreviousDriver(other.Name); ok && otherPrevious == previous &&
+			h.getNodeStatus(other.Name) == amdv1alpha1.UpgradeStateRollbackInProgress {
+			continue
+		}
+		image := deviceConfig.Status.NodeModuleStatus[other.Name].ContainerImage
+		if image != "" && !isPreviousDriverImage(image, previous, other) {
+			conflict = other.Name
+			break
+		}
+	}
+	if conflict == "" {
+		return false
+	}
+
+	reason := fmt.Sprintf("node %v runs the same kernel with driver image %v, rolling back would also replace its driver",
+		conflict, deviceConfig.Status.NodeModuleStatus[conflict].ContainerImage)
+	log.FromContext(ctx).Info(fmt.Sprintf("Node: %v refusing rollback to driver version %v: %v", node.Name, previous.version, reason))
+	h.setNodeFailureReason(node.Name, reason)
+	h.setNodeStatus(ctx, node.Name, amdv1alpha1.UpgradeStateRollbackFailed)
+	recordEvent(h.recorder, deviceConfig, node, v1.EventTypeWarning, EventReasonDriverRollbackFailed,
+		fmt.Sprintf("Failed to roll back node %v to driver version %v: %v", node.Name, previous.version, reason))
+	return true
+}
+
+// handleNodeRollback reverts the KMM version label of the failed node to the previous driver, reboots the node if required
+// and waits for the previous driver to be loaded before uncordoning the node
+func (h *upgradeMgrHelper) handleNodeRollback(ctx context.Context, deviceConfig amdv1alpha1.DeviceConfig, node v1.Node) {
+	logger := log.FromContext(ctx)
+	previous, _ := h.getPreviousDriver(node.Name)
+
+	rollbackFailed := func(reason string) {
+		logger.Info(fmt.Sprintf("Node: %v State: %v RollbackFailed: %v", node.Name, h.getNodeStatus(node.Name), reason))
+		if !h.isDeviceConfigValid(ctx, &deviceConfig) {
+			// Device config changed during the rollback, the new upgrade flow takes care of the node
+			return
+		}
+		h.setNodeFailureReason(node.Name, reason)
+		h.setNodeStatus(ctx, node.Name, amdv1alpha1.UpgradeStateRollbackFailed)
+		recordEvent(h.recorder, &deviceConfig, &node, v1.EventTypeWarning, EventReasonDriverRollbackFailed,
+			fmt.Sprintf("Failed to roll back node %v to driver version %v: %v", node.Name, previous.version, reason))
+	}
+
+	logger.Info(fmt.Sprintf("Node: %v Rollback to driver version %v begin", node.Name, previous.version))
+	recordEvent(h.recorder, &deviceConfig, &node, v1.EventTypeNormal, EventReasonDriverRollbackStarted,
+		fmt.Sprintf("Rolling back node %v to driver version %v after failed upgrade to version %v: %v",
+			node.Name, previous.version, deviceConfig.Spec.Driver.Version, h.getNodeFailureReason(node.Name)))
+
+	// Build and worker pods of the failed version would otherwise keep retrying on the node
+	if err := h.cleanupDanglingKMMPods(ctx, &node, &deviceConfig); err != nil {
+		rollbackFailed(fmt.Sprintf("failed to cleanup KMM pods: %v", err))
+		return
+	}
+
+	// Reset the KMM label to the version loaded on the node, then pin it to the previous version
+	// in case the new driver already got loaded. The KMM Module is rendered with the previous driver
+	// by the DeviceConfig reconciler as long as the node is in RollbackInProgress
+	if err := h.resetModuleVersionOnNode(ctx, &deviceConfig, &node); err != nil {
+		rollbackFailed(fmt.Sprintf("failed to reset driver version label on node: %v", err))
+		return
+	}
+	if err := h.setModuleVersionOnNode(ctx, &deviceConfig, &node, previous.version); err != nil {
+		rollbackFailed(fmt.Sprintf("failed to set driver version label on node: %v", err))
+		return
+	}
+
+	if deviceConfig.Spec.Driver.UpgradePolicy.RebootRequired != nil && *deviceConfig.Spec.Driver.UpgradePolicy.RebootRequired {
+		if err := h.rebootNodeForRollback(ctx, &node, deviceConfig); err != nil {
+			rollbackFailed(fmt.Sprintf("failed to reboot node: %v", err))
+			return
+		}
+	}
+
+	// Wait (max 1 hour) for the previous driver to be loaded
+	previousDriverLoaded := false
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+	for i := uint(0); i < 360 && !previousDriverLoaded; _, i = <-ticker.C, i+1 {
+		nmcObj := &kmmv1beta1.NodeModulesConfig{}
+		if err := h.client.Get(ctx, types.NamespacedName{Name: node.Name}, nmcObj); err == nil {
+			for _, status := range nmcObj.Status.Modules {
+				if status.Name == deviceConfig.Name && status.Namespace == deviceConfig.Namespace &&
+					isPreviousDriverImage(status.Config.ContainerImage, previous, &node) {
+					previousDriverLoaded = true
+					break
+				}
+			}
+		}
+		if !h.isDeviceConfigValid(ctx, &deviceConfig) {
+			logger.Info(fmt.Sprintf("Node: %v device config changed, stopping rollback", node.Name))
+			return
+		}
+	}
+	if !previousDriverLoaded {
+		rollbackFailed(fmt.Sprintf("driver version %v was not loaded within 1 hour", previous.version))
+		return
+	}
+
+	if err := h.cordonOrUncordonNode(ctx, &deviceConfig, &node, false); err != nil {
+		rollbackFailed(fmt.Sprintf("failed to uncordon node: %v", err))
+		return
+	}
+
+	if !h.isDeviceConfigValid(ctx, &deviceConfig) {
+		return
+	}
+	// the rollback is over, the next upgrade of the node compares its reboot against the current boot ID
+	if nodeObj, err := h.getNode(ctx, node.Name); err == nil {
+		h.setBootID(node.Name, nodeObj.Status.NodeInfo.BootID)
+	}
+	h.setNodeStatus(ctx, node.Name, amdv1alpha1.UpgradeStateRollbackComplete)
+	recordEvent(h.recorder, &deviceConfig, &node, v1.EventTypeNormal, EventReasonDriverRollbackComplete,
+		fmt.Sprintf("Node %v rolled back to driver version %v", node.Name, previous.version))
+}
+
+// isPreviousDriverImage tells whether the driver image loaded on the node is the previous driver of the node. The image
+// recorded before the upgrade is compared as is, catalog images and pinned references don't necessarily end with the version
+func isPreviousDriverImage(image string, previous previousDriver, node *v1.Node) bool {
+	if previous.image == "" {
+		return strings.HasSuffix(image, previous.version)
+	}
+	kernel := strings.TrimSuffix(node.Status.NodeInfo.KernelVersion, "+")
+	return image == strings.ReplaceAll(previous.image, "${KERNEL_FULL_VERSION}", kernel)
+}
+
+// setModuleVersionOnNode sets the KMM version label of the node to the given driver version
+func (h *upgradeMgrHelper) setModuleVersionOnNode(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, node *v1.Node, version string) error {
+	labelKey := fmt.Sprintf("kmm.node.kubernetes.io/version-module.%s.%s", deviceConfig.Namespace, deviceConfig.Name)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		nodeObj := &v1.Node{}
+		if err := h.client.Get(ctx, client.ObjectKey{Name: node.Name}, nodeObj); err != nil {
+			return err
+		}
+		if nodeObj.Labels[labelKey] == version {
+			return nil
+		}
+		nodeObjCopy := nodeObj.DeepCopy()
+		if nodeObj.Labels == nil {
+			nodeObj.Labels = map[string]string{}
+		}
+		nodeObj.Labels[labelKey] = version
+		return h.client.Patch(ctx, nodeObj, client.MergeFrom(nodeObjCopy))
+	})
+}
+
+// rebootNodeForRollback reboots the node through the reboot pod and waits (max 1 hour) for the node to come back Ready.
+// The boot ID of the node before the reboot is kept, a rollback resumed after an operator restart skips the reboot
+// when the node already runs with another boot ID
+func (h *upgradeMgrHelper) rebootNodeForRollback(ctx context.Context, node *v1.Node, dc amdv1alpha1.DeviceConfig) error {
+	logger := log.FromContext(ctx)
+	rebootPod := h.getRebootPod(node.Name, &dc)
+
+	nodeObj, err := h.getNode(ctx, node.Name)
+	if err != nil {
+		return err
+	}
+	if bootID := h.getBootID(node.Name); bootID != "" && bootID != nodeObj.Status.NodeInfo.BootID {
+		logger.Info(fmt.Sprintf("Node: %v already rebooted for driver rollback", node.Name))
+		if err := h.client.Delete(ctx, rebootPod); err != nil && !k8serrors.IsNotFound(err) {
+			logger.Error(err, fmt.Sprintf("Node: %v RebootPod Delete failed with Error: %v", node.Name, err))
+		}
+		return nil
+	}
+
+	// Delete the existing pod if present
+	pod := &v1.Pod{}
+	if err := h.client.Get(ctx, types.NamespacedName{Namespace: dc.Namespace, Name: rebootPod.Name}, pod); err == nil {
+		if err := h.client.Delete(ctx, pod); err != nil {
+			return fmt.Errorf("failed to delete stale reboot pod: %v", err)
+		}
+	}
+
+	bootID := nodeObj.Status.NodeInfo.BootID
+	h.setBootID(node.Name, bootID)
+	h.persistNodeState(ctx, node.Name)
+
+	if err := h.client.Create(ctx, rebootPod); err != nil {
+		return fmt.Errorf("failed to create reboot pod: %v", err)
+	}
+	recordEvent(h.recorder, &dc, node, v1.EventTypeNormal, EventReasonNodeRebootIssued,
+		fmt.Sprintf("Reboot issued on node %v for driver rollback", node.Name))
+	defer func() {
+		if err := h.client.Delete(ctx, rebootPod); err != nil && !k8serrors.IsNotFound(err) {
+			logger.Error(err, fmt.Sprintf("Node: %v RebootPod Delete failed with Error: %v", node.Name, err))
+		}
+	}()
+
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+	for i := uint(0); i < 360; _, i = <-ticker.C, i+1 {
+		nodeObj, err := h.getNode(ctx, node.Name)
+		if err != nil || nodeObj.Status.NodeInfo.BootID == bootID {
+			continue
+		}
+		for _, condition := range nodeObj.Status.Conditions {
+			if condition.Type == v1.NodeReady && condition.Status == v1.ConditionTrue {
+				logger.Info(fmt.Sprintf("Node: %v has rebooted", node.Name))
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("node did not come back Ready within 1 hour")
+}
+
 func (h *upgradeMgrHelper) getRebootPod(nodeName string, dc *amdv1alpha1.DeviceConfig) *v1.Pod {
 	nodeSelector := map[string]string{}
 	nodeSelector["kubernetes.io/hostname"] = nodeName
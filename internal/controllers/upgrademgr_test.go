@@ -0,0 +1,726 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	amdv1alpha1 "github.com/ROCm/gpu-operator/api/v1alpha1"
+	mock_client "github.com/ROCm/gpu-operator/internal/client"
+)
+
+var _ = Describe("recordUpgradeStarted", func() {
+	var (
+		recorder *record.FakeRecorder
+		helper   *upgradeMgrHelper
+	)
+
+	ctx := context.Background()
+	devConfig := &amdv1alpha1.DeviceConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: devConfigName, Namespace: devConfigNamespace},
+		Spec: amdv1alpha1.DeviceConfigSpec{
+			Driver: amdv1alpha1.DriverSpec{Version: "6.3"},
+		},
+	}
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "unit-test-node"}}
+
+	BeforeEach(func() {
+		recorder = record.NewFakeRecorder(10)
+		helper = newUpgradeMgrHelperHandler(nil, nil, recorder, false).(*upgradeMgrHelper)
+	})
+
+	It("emits the event once when the node re-enters its upgrade", func() {
+		helper.setNodeStatus(ctx, node.Name, amdv1alpha1.UpgradeStateStarted)
+		helper.recordUpgradeStarted(devConfig, node)
+		// paused and released again
+		helper.setNodeStatus(ctx, node.Name, amdv1alpha1.UpgradeStatePaused)
+		helper.setNodeStatus(ctx, node.Name, amdv1alpha1.UpgradeStateNotStarted)
+		helper.setNodeStatus(ctx, node.Name, amdv1alpha1.UpgradeStateStarted)
+		helper.recordUpgradeStarted(devConfig, node)
+
+		// one event on the DeviceConfig and one on the node
+		Expect(recorder.Events).To(HaveLen(2))
+		Expect(<-recorder.Events).To(ContainSubstring(EventReasonDriverUpgradeStarted))
+	})
+
+	It("emits the event again for the next upgrade of the node", func() {
+		helper.recordUpgradeStarted(devConfig, node)
+		helper.setNodeStatus(ctx, node.Name, amdv1alpha1.UpgradeStateComplete)
+		helper.recordUpgradeStarted(devConfig, node)
+
+		Expect(recorder.Events).To(HaveLen(4))
+	})
+
+	It("doesn't emit the event for an upgrade restored after an operator restart", func() {
+		helper.restoreNodeState(node.Name, amdv1alpha1.NodeUpgradeStatus{State: amdv1alpha1.UpgradeStateDrainWaiting})
+		helper.setNodeStatus(ctx, node.Name, amdv1alpha1.UpgradeStateEmpty)
+		helper.recordUpgradeStarted(devConfig, node)
+
+		Expect(recorder.Events).To(BeEmpty())
+	})
+})
+
+var _ = Describe("upgrade state failover", func() {
+	var (
+		kubeClient   *mock_client.MockClient
+		statusWriter *mock_client.MockStatusWriter
+		// persisted is the DriverUpgradeStatus object as stored by the API server
+		persisted []byte
+	)
+
+	ctx := context.Background()
+	devConfig := &amdv1alpha1.DeviceConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: devConfigName, Namespace: devConfigNamespace},
+	}
+	nodeName := "unit-test-node"
+
+	BeforeEach(func() {
+		mockCtrl := gomock.NewController(GinkgoT())
+		kubeClient = mock_client.NewMockClient(mockCtrl)
+		statusWriter = mock_client.NewMockStatusWriter(mockCtrl)
+		persisted = []byte(`{"metadata":{"name":"` + devConfigName + `"}}`)
+		kubeClient.EXPECT().Status().Return(statusWriter).AnyTimes()
+		statusWriter.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ client.Object, patch client.Patch, _ ...client.SubResourcePatchOption) error {
+				data, err := patch.Data(nil)
+				Expect(err).NotTo(HaveOccurred())
+				persisted, err = jsonpatch.MergePatch(persisted, data)
+				return err
+			}).AnyTimes()
+	})
+
+	// newLeader returns the helper of a newly elected leader restored from the persisted upgrade states
+	newLeader := func() (*upgradeMgrHelper, *amdv1alpha1.DriverUpgradeStatus) {
+		upgradeStatus := &amdv1alpha1.DriverUpgradeStatus{}
+		Expect(json.Unmarshal(persisted, upgradeStatus)).To(Succeed())
+		helper := newUpgradeMgrHelperHandler(kubeClient, nil, nil, false).(*upgradeMgrHelper)
+		for name, nodeState := range upgradeStatus.Status {
+			helper.setNodeDeviceConfig(name, devConfig)
+			helper.restoreNodeState(name, nodeState)
+			helper.nodeStatus.Store(name, nodeState.State)
+		}
+		return helper, upgradeStatus
+	}
+
+	It("doesn't restore the details cleared before the failover", func() {
+		leader := newUpgradeMgrHelperHandler(kubeClient, nil, nil, false).(*upgradeMgrHelper)
+		leader.setNodeDeviceConfig(nodeName, devConfig)
+		leader.setUpgradeStartTime(nodeName)
+		leader.setBootID(nodeName, "boot-1")
+		leader.setNodeStatus(ctx, nodeName, amdv1alpha1.UpgradeStateStarted)
+		leader.setNodeFailureReason(nodeName, "failed to drain node")
+		leader.setNodeStatus(ctx, nodeName, amdv1alpha1.UpgradeStateDrainFailed)
+
+		restored, _ := newLeader()
+		Expect(restored.getNodeFailureReason(nodeName)).To(Equal("failed to drain node"))
+
+		// the node got upgraded after a retry
+		restored.clearUpgradeStartTime(nodeName)
+		restored.nodeBootID.Delete(nodeName)
+		restored.setNodeStatus(ctx, nodeName, amdv1alpha1.UpgradeStateComplete)
+
+		restored, upgradeStatus := newLeader()
+		Expect(upgradeStatus.Status[nodeName].State).To(Equal(amdv1alpha1.UpgradeStateComplete))
+		Expect(upgradeStatus.Status[nodeName].FailureReason).To(BeEmpty())
+		Expect(upgradeStatus.Status[nodeName].UpgradeStartTime).To(BeEmpty())
+		Expect(upgradeStatus.Status[nodeName].BootID).To(BeEmpty())
+		Expect(restored.getNodeFailureReason(nodeName)).To(BeEmpty())
+		Expect(restored.getBootID(nodeName)).To(BeEmpty())
+	})
+
+	It("prunes the upgrade states of deleted nodes", func() {
+		leader := newUpgradeMgrHelperHandler(kubeClient, nil, nil, false).(*upgradeMgrHelper)
+		for _, name := range []string{nodeName, "deleted-node", "unselected-node"} {
+			leader.setNodeDeviceConfig(name, devConfig)
+			leader.setNodeStatus(ctx, name, amdv1alpha1.UpgradeStateComplete)
+		}
+
+		restored, upgradeStatus := newLeader()
+		Expect(upgradeStatus.Status).To(HaveLen(3))
+		kubeClient.EXPECT().Get(gomock.Any(), client.ObjectKey{Name: "deleted-node"}, gomock.Any()).
+			Return(k8serrors.NewNotFound(schema.GroupResource{Resource: "nodes"}, "deleted-node"))
+		kubeClient.EXPECT().Get(gomock.Any(), client.ObjectKey{Name: "unselected-node"}, gomock.Any()).Return(nil)
+		restored.pruneUpgradeStatus(ctx, upgradeStatus, &v1.NodeList{Items: []v1.Node{{ObjectMeta: metav1.ObjectMeta{Name: nodeName}}}})
+		Expect(upgradeStatus.Status).NotTo(HaveKey("deleted-node"))
+		Expect(restored.getNodeStatus("deleted-node")).To(BeEmpty())
+
+		_, upgradeStatus = newLeader()
+		Expect(upgradeStatus.Status).To(HaveLen(2))
+		Expect(upgradeStatus.Status).To(HaveKey(nodeName))
+		Expect(upgradeStatus.Status).To(HaveKey("unselected-node"))
+	})
+})
+
+var _ = Describe("computeUpgradeRollout", func() {
+	var helper *upgradeMgrHelper
+
+	ctx := context.Background()
+	devConfig := &amdv1alpha1.DeviceConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: devConfigName, Namespace: devConfigNamespace},
+		Spec: amdv1alpha1.DeviceConfigSpec{
+			Driver: amdv1alpha1.DriverSpec{
+				Version: "6.3",
+				UpgradePolicy: &amdv1alpha1.DriverUpgradePolicySpec{
+					RolloutStrategy: &amdv1alpha1.UpgradeRolloutSpec{
+						Canary: &amdv1alpha1.UpgradeCanarySpec{Selector: map[string]string{"canary": "true"}},
+						Waves:  &amdv1alpha1.UpgradeWavesSpec{Size: intstr.FromInt(2)},
+					},
+				},
+			},
+		},
+	}
+	newNode := func(name string, canary bool) v1.Node {
+		node := v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{}}}
+		if canary {
+			node.Labels["canary"] = "true"
+		}
+		return node
+	}
+	// canary node c1, waves [n1 n2] and [n3]
+	nodes := []v1.Node{newNode("c1", true), newNode("n1", false), newNode("n2", false), newNode("n3", false)}
+
+	BeforeEach(func() {
+		helper = newUpgradeMgrHelperHandler(nil, nil, nil, false).(*upgradeMgrHelper)
+	})
+
+	It("starts with the canary candidates", func() {
+		status, allowed := helper.computeUpgradeRollout(ctx, devConfig, nodes, nodes)
+		Expect(status.Phase).To(Equal(amdv1alpha1.UpgradeRolloutPhaseCanary))
+		Expect(allowed).To(HaveKeyWithValue("c1", true))
+		Expect(allowed).NotTo(HaveKeyWithValue("n1", true))
+	})
+
+	It("pauses the rollout when a canary node failed", func() {
+		helper.nodeStatus.Store("c1", amdv1alpha1.UpgradeStateDrainFailed)
+		status, allowed := helper.computeUpgradeRollout(ctx, devConfig, nodes, nodes[1:])
+		Expect(status.Phase).To(Equal(amdv1alpha1.UpgradeRolloutPhasePaused))
+		Expect(allowed).To(BeEmpty())
+	})
+
+	It("holds the next wave while a node of the current wave is upgrading", func() {
+		helper.nodeStatus.Store("c1", amdv1alpha1.UpgradeStateComplete)
+		helper.nodeStatus.Store("n1", amdv1alpha1.UpgradeStateStarted)
+		status, allowed := helper.computeUpgradeRollout(ctx, devConfig, nodes, []v1.Node{nodes[2], nodes[3]})
+		Expect(status.Phase).To(Equal(amdv1alpha1.UpgradeRolloutPhaseWave))
+		Expect(status.CurrentWave).To(Equal(1))
+		Expect(allowed).To(HaveKeyWithValue("n2", true))
+		Expect(allowed).NotTo(HaveKeyWithValue("n3", true))
+	})
+
+	It("doesn't block on nodes that can't start their upgrade", func() {
+		helper.nodeStatus.Store("c1", amdv1alpha1.UpgradeStateComplete)
+		helper.nodeStatus.Store("n1", amdv1alpha1.UpgradeStateComplete)
+		// n2 is not ready for its upgrade and stays not started, n3 is paused
+		helper.nodeStatus.Store("n2", amdv1alpha1.UpgradeStateNotStarted)
+		helper.nodeStatus.Store("n3", amdv1alpha1.UpgradeStatePaused)
+		status, allowed := helper.computeUpgradeRollout(ctx, devConfig, nodes, nil)
+		Expect(status.Phase).To(Equal(amdv1alpha1.UpgradeRolloutPhaseComplete))
+		Expect(allowed).To(BeEmpty())
+
+		// n3 got released and is a candidate again
+		helper.nodeStatus.Store("n3", amdv1alpha1.UpgradeStateNotStarted)
+		status, allowed = helper.computeUpgradeRollout(ctx, devConfig, nodes, []v1.Node{nodes[3]})
+		Expect(status.Phase).To(Equal(amdv1alpha1.UpgradeRolloutPhaseWave))
+		Expect(status.CurrentWave).To(Equal(2))
+		Expect(allowed).To(HaveKeyWithValue("n3", true))
+	})
+
+	It("pins the health check job to the upgraded node", func() {
+		job, _ := helper.getUpgradeHealthCheckJob(devConfig, &amdv1alpha1.UpgradeHealthCheckSpec{}, upgradeHealthCheckCanary, "n1")
+		Expect(job.Spec.Template.Spec.NodeName).To(Equal("n1"))
+	})
+})
+
+var _ = Describe("driver rollback", func() {
+	var helper *upgradeMgrHelper
+
+	ctx := context.Background()
+	newDevConfig := func(name string) *amdv1alpha1.DeviceConfig {
+		return &amdv1alpha1.DeviceConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: devConfigNamespace},
+			Spec: amdv1alpha1.DeviceConfigSpec{
+				Driver: amdv1alpha1.DriverSpec{
+					Version:       "6.3",
+					UpgradePolicy: &amdv1alpha1.DriverUpgradePolicySpec{},
+				},
+			},
+		}
+	}
+	devConfig := newDevConfig(devConfigName)
+	otherDevConfig := newDevConfig("other-deviceconfig")
+
+	rollingBack := func(nodeName string, dc *amdv1alpha1.DeviceConfig, version string) {
+		helper.setNodeDeviceConfig(nodeName, dc)
+		helper.nodePreviousDriver.Store(nodeName, previousDriver{version: version, image: "test.repo/driver:" + nodeName + "-" + version})
+		helper.nodeStatus.Store(nodeName, amdv1alpha1.UpgradeStateRollbackInProgress)
+	}
+
+	BeforeEach(func() {
+		helper = newUpgradeMgrHelperHandler(nil, nil, record.NewFakeRecorder(10), false).(*upgradeMgrHelper)
+	})
+
+	It("returns the previous driver of the nodes of the device config rolling back", func() {
+		version, images := helper.getDriverRollback(devConfig)
+		Expect(version).To(BeEmpty())
+		Expect(images).To(BeEmpty())
+
+		rollingBack("node-a", devConfig, "6.2")
+		rollingBack("node-b", devConfig, "6.2")
+		rollingBack("node-c", otherDevConfig, "6.1")
+		helper.setNodeDeviceConfig("node-d", devConfig)
+		helper.nodePreviousDriver.Store("node-d", previousDriver{version: "6.2", image: "test.repo/driver:node-d-6.2"})
+		helper.nodeStatus.Store("node-d", amdv1alpha1.UpgradeStateFailed)
+
+		version, images = helper.getDriverRollback(devConfig)
+		Expect(version).To(Equal("6.2"))
+		Expect(images).To(Equal(map[string]string{
+			"node-a": "test.repo/driver:node-a-6.2",
+			"node-b": "test.repo/driver:node-b-6.2",
+		}))
+	})
+
+	It("defers the rollback to another driver version until the rollbacks in progress finish", func() {
+		node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-b"}}
+		helper.nodePreviousDriver.Store(node.Name, previousDriver{version: "6.1"})
+
+		deferred, _ := helper.isNodeRollbackDeferred(ctx, node, devConfig)
+		Expect(deferred).To(BeFalse())
+
+		rollingBack("node-a", devConfig, "6.2")
+		deferred, retryAfter := helper.isNodeRollbackDeferred(ctx, node, devConfig)
+		Expect(deferred).To(BeTrue())
+		Expect(retryAfter).To(BeNumerically(">", 0))
+
+		helper.nodePreviousDriver.Store(node.Name, previousDriver{version: "6.2"})
+		deferred, _ = helper.isNodeRollbackDeferred(ctx, node, devConfig)
+		Expect(deferred).To(BeFalse())
+	})
+
+	It("defers rollback reboots until a maintenance window opens", func() {
+		node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}}
+		helper.nodePreviousDriver.Store(node.Name, previousDriver{version: "6.2"})
+		dc := devConfig.DeepCopy()
+		windowStart := time.Now().UTC().Add(2 * time.Hour)
+		dc.Spec.MaintenanceWindows = []amdv1alpha1.MaintenanceWindow{{
+			Schedule: fmt.Sprintf("%d %d * * *", windowStart.Minute(), windowStart.Hour()),
+			Duration: "30m",
+			TimeZone: "UTC",
+		}}
+
+		// the rollback doesn't reboot the node
+		deferred, _ := helper.isNodeRollbackDeferred(ctx, node, dc)
+		Expect(deferred).To(BeFalse())
+
+		rebootRequired := true
+		dc.Spec.Driver.UpgradePolicy.RebootRequired = &rebootRequired
+		deferred, retryAfter := helper.isNodeRollbackDeferred(ctx, node, dc)
+		Expect(deferred).To(BeTrue())
+		Expect(retryAfter).To(BeNumerically(">", 0))
+
+		dc.Spec.MaintenanceWindows = nil
+		deferred, _ = helper.isNodeRollbackDeferred(ctx, node, dc)
+		Expect(deferred).To(BeFalse())
+	})
+
+	It("matches the loaded driver image against the recorded previous driver image", func() {
+		node := &v1.Node{Status: v1.NodeStatus{NodeInfo: v1.NodeSystemInfo{KernelVersion: "6.8.0-40-generic"}}}
+		catalogImage := previousDriver{version: "6.2", image: "registry.example.com/amdgpu@sha256:0123"}
+		Expect(isPreviousDriverImage("registry.example.com/amdgpu@sha256:0123", catalogImage, node)).To(BeTrue())
+		Expect(isPreviousDriverImage("registry.example.com/amdgpu:ubuntu-22.04-6.8.0-40-generic-6.2", catalogImage, node)).To(BeFalse())
+
+		kernelImage := previousDriver{version: "6.2", image: "registry.example.com/amdgpu:${KERNEL_FULL_VERSION}-6.2-prod"}
+		Expect(isPreviousDriverImage("registry.example.com/amdgpu:6.8.0-40-generic-6.2-prod", kernelImage, node)).To(BeTrue())
+
+		// without recorded image the version suffix of the default image tag is matched
+		Expect(isPreviousDriverImage("registry.example.com/amdgpu:ubuntu-22.04-6.8.0-40-generic-6.2", previousDriver{version: "6.2"}, node)).To(BeTrue())
+	})
+
+	It("refuses the rollback of a node sharing its kernel with a node running another driver image", func() {
+		newKernelNode := func(name string) v1.Node {
+			return v1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Status:     v1.NodeStatus{NodeInfo: v1.NodeSystemInfo{KernelVersion: "6.8.0-40-generic"}},
+			}
+		}
+		nodes := []v1.Node{newKernelNode("node-a"), newKernelNode("node-b")}
+		dc := devConfig.DeepCopy()
+		dc.Status.NodeModuleStatus = map[string]amdv1alpha1.ModuleStatus{
+			"node-a": {ContainerImage: "test.repo/driver:6.8.0-40-generic-6.3"},
+			"node-b": {ContainerImage: "test.repo/driver:6.8.0-40-generic-6.3"},
+		}
+		helper.nodePreviousDriver.Store("node-a", previousDriver{version: "6.2", image: "test.repo/driver:${KERNEL_FULL_VERSION}-6.2"})
+		helper.nodeStatus.Store("node-a", amdv1alpha1.UpgradeStateFailed)
+		helper.nodeStatus.Store("node-b", amdv1alpha1.UpgradeStateComplete)
+
+		// node-b upgraded successfully, the previous image of node-a mapped to their kernel would downgrade it
+		Expect(helper.refuseConflictingNodeRollback(ctx, &nodes[0], dc, nodes)).To(BeTrue())
+		Expect(helper.getNodeStatus("node-a")).To(Equal(amdv1alpha1.UpgradeStateRollbackFailed))
+		Expect(helper.getNodeFailureReason("node-a")).To(ContainSubstring("node node-b runs the same kernel"))
+		Expect(helper.getNodeStatus("node-b")).To(Equal(amdv1alpha1.UpgradeStateComplete))
+
+		// node-b still runs the previous driver of node-a
+		helper.nodeStatus.Store("node-a", amdv1alpha1.UpgradeStateFailed)
+		dc.Status.NodeModuleStatus["node-b"] = amdv1alpha1.ModuleStatus{ContainerImage: "test.repo/driver:6.8.0-40-generic-6.2"}
+		Expect(helper.refuseConflictingNodeRollback(ctx, &nodes[0], dc, nodes)).To(BeFalse())
+		Expect(helper.getNodeStatus("node-a")).To(Equal(amdv1alpha1.UpgradeStateFailed))
+	})
+
+	It("skips the rollback reboot of a node that already rebooted", func() {
+		mockCtrl := gomock.NewController(GinkgoT())
+		kubeClient := mock_client.NewMockClient(mockCtrl)
+		helper.client = kubeClient
+		node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}}
+		helper.setBootID(node.Name, "boot-before-rollback")
+
+		kubeClient.EXPECT().Get(ctx, client.ObjectKey{Name: node.Name}, gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ client.ObjectKey, obj *v1.Node, _ ...client.GetOption) error {
+				obj.Name = node.Name
+				obj.Status.NodeInfo.BootID = "boot-after-rollback"
+				return nil
+			})
+		kubeClient.EXPECT().Delete(ctx, gomock.Any()).Return(k8serrors.NewNotFound(schema.GroupResource{Resource: "pods"}, "reboot-pod"))
+
+		Expect(helper.rebootNodeForRollback(ctx, node, *devConfig)).To(Succeed())
+		Expect(helper.getBootID(node.Name)).To(Equal("boot-before-rollback"))
+	})
+})
+
+var _ = Describe("per node upgrade actions", func() {
+	var helper *upgradeMgrHelper
+
+	ctx := context.Background()
+	newLabeledNode := func(name, labelValue string) v1.Node {
+		node := v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{}}}
+		if labelValue != "" {
+			node.Labels[driverUpgradeStateLabelKey] = labelValue
+		}
+		return node
+	}
+
+	BeforeEach(func() {
+		helper = newUpgradeMgrHelperHandler(nil, nil, record.NewFakeRecorder(10), false).(*upgradeMgrHelper)
+	})
+
+	It("releases the upgrade slot of a node labeled for pause before it is drained", func() {
+		testCases := []struct {
+			state      amdv1alpha1.UpgradeState
+			labelValue string
+			pausing    bool
+		}{
+			{state: amdv1alpha1.UpgradeStateDrainWaiting, labelValue: upgradePauseLabelValue, pausing: true},
+			{state: amdv1alpha1.UpgradeStatePreUpgradeHook, labelValue: upgradePauseLabelValue, pausing: true},
+			{state: amdv1alpha1.UpgradeStateDrainWaiting},
+			{state: amdv1alpha1.UpgradeStateDrainWaiting, labelValue: upgradeSkipLabelValue},
+			// the node may already be drained, it is paused before the new driver is loaded
+			{state: amdv1alpha1.UpgradeStateStarted, labelValue: upgradePauseLabelValue},
+			{state: amdv1alpha1.UpgradeStateRebootInProgress, labelValue: upgradePauseLabelValue},
+		}
+		for _, tc := range testCases {
+			node := newLabeledNode("node-a", tc.labelValue)
+			helper.nodeStatus.Store(node.Name, tc.state)
+			Expect(helper.isNodeUpgradePausing(&node)).To(Equal(tc.pausing), "state %v label %v", tc.state, tc.labelValue)
+		}
+	})
+
+	It("only overrides the maintenance windows for upgrade-force-now", func() {
+		forced := newLabeledNode("node-a", upgradeForceLabelValue)
+		forcedNow := newLabeledNode("node-b", upgradeForceNowLabelValue)
+
+		Expect(helper.getRequestedUpgradeAction(&forced)).To(Equal(amdv1alpha1.NodeUpgradeActionForce))
+		Expect(helper.getRequestedUpgradeAction(&forcedNow)).To(Equal(amdv1alpha1.NodeUpgradeActionForce))
+		Expect(helper.isMaintenanceWindowOverridden(&forced)).To(BeFalse())
+		Expect(helper.isMaintenanceWindowOverridden(&forcedNow)).To(BeTrue())
+	})
+
+	It("keeps forced nodes out of a closed maintenance window and doesn't count pausing nodes", func() {
+		ctrl := gomock.NewController(GinkgoT())
+		mockHelper := NewMockupgradeMgrHelperAPI(ctrl)
+		upgradeMgr := &upgradeMgr{helper: mockHelper}
+
+		enable := true
+		windowStart := time.Now().UTC().Add(2 * time.Hour)
+		devConfig := &amdv1alpha1.DeviceConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: devConfigName, Namespace: devConfigNamespace},
+			Spec: amdv1alpha1.DeviceConfigSpec{
+				Driver: amdv1alpha1.DriverSpec{
+					Enable:        &enable,
+					Version:       "6.3",
+					UpgradePolicy: &amdv1alpha1.DriverUpgradePolicySpec{Enable: &enable, MaxParallelUpgrades: 1},
+				},
+				MaintenanceWindows: []amdv1alpha1.MaintenanceWindow{{
+					Schedule: fmt.Sprintf("%d %d * * *", windowStart.Minute(), windowStart.Hour()),
+					Duration: "30m",
+					TimeZone: "UTC",
+				}},
+			},
+		}
+		nodeList := &v1.NodeList{Items: []v1.Node{
+			newLabeledNode("node-forced", upgradeForceLabelValue),
+			newLabeledNode("node-forced-now", upgradeForceNowLabelValue),
+			newLabeledNode("node-pausing", upgradePauseLabelValue),
+		}}
+		actions := map[string]amdv1alpha1.NodeUpgradeAction{
+			"node-forced":     amdv1alpha1.NodeUpgradeActionForce,
+			"node-forced-now": amdv1alpha1.NodeUpgradeActionForce,
+			"node-pausing":    amdv1alpha1.NodeUpgradeActionPause,
+		}
+		isPausing := func(node *v1.Node) bool { return node.Name == "node-pausing" }
+
+		mockHelper.EXPECT().getOrCreateUpgradeStatus(gomock.Any(), devConfig).Return(nil, nil)
+		mockHelper.EXPECT().pruneUpgradeStatus(gomock.Any(), nil, nodeList)
+		mockHelper.EXPECT().setNodeDeviceConfig(gomock.Any(), devConfig).AnyTimes()
+		mockHelper.EXPECT().isInit().Return(false)
+		mockHelper.EXPECT().specChanged(devConfig).Return(false)
+		mockHelper.EXPECT().setcurrentSpec(devConfig)
+		mockHelper.EXPECT().handleInitStatus(gomock.Any(), gomock.Any(), devConfig).AnyTimes()
+		mockHelper.EXPECT().handleUpgradeTimedOut(gomock.Any(), gomock.Any(), devConfig).AnyTimes()
+		mockHelper.EXPECT().isNodeStateUpgradeFailed(gomock.Any(), gomock.Any()).Return(false).AnyTimes()
+		mockHelper.EXPECT().getNodeStatus(gomock.Any()).Return(amdv1alpha1.UpgradeStateNotStarted).AnyTimes()
+		mockHelper.EXPECT().isNodeUpgradeHeld(gomock.Any(), gomock.Any()).Return(false).AnyTimes()
+		mockHelper.EXPECT().isNodeNmcStatusMissing(gomock.Any(), gomock.Any(), devConfig).Return(false).AnyTimes()
+		mockHelper.EXPECT().isNodeStateUpgradeStarted(gomock.Any()).DoAndReturn(isPausing).AnyTimes()
+		mockHelper.EXPECT().isNodeUpgradePausing(gomock.Any()).DoAndReturn(isPausing).AnyTimes()
+		mockHelper.EXPECT().isNodeStatePostUpgradeHook(gomock.Any(), gomock.Any(), devConfig).Return(false).AnyTimes()
+		mockHelper.EXPECT().isNodeReady(gomock.Any(), gomock.Any(), devConfig).Return(false).AnyTimes()
+		mockHelper.EXPECT().isNodeNew(gomock.Any(), gomock.Any(), devConfig).Return(false).AnyTimes()
+		mockHelper.EXPECT().isNodeStateInstallInProgress(gomock.Any(), gomock.Any(), devConfig).Return(false).AnyTimes()
+		mockHelper.EXPECT().isNodeStateUpgradeInProgress(gomock.Any(), gomock.Any(), devConfig).Return(false).AnyTimes()
+		mockHelper.EXPECT().isNodeReadyForUpgrade(gomock.Any(), gomock.Any()).Return(true).AnyTimes()
+		mockHelper.EXPECT().getRequestedUpgradeAction(gomock.Any()).DoAndReturn(
+			func(node *v1.Node) amdv1alpha1.NodeUpgradeAction { return actions[node.Name] }).AnyTimes()
+		mockHelper.EXPECT().planUpgradeRollout(gomock.Any(), devConfig, nodeList.Items, gomock.Any()).Return(nil, nil)
+		mockHelper.EXPECT().isMaintenanceWindowOverridden(gomock.Any()).DoAndReturn(
+			func(node *v1.Node) bool { return node.Labels[driverUpgradeStateLabelKey] == upgradeForceNowLabelValue }).Times(2)
+		// the pausing node gives up its upgrade slot
+		mockHelper.EXPECT().isUpgradePolicyViolated(0, 0, 3, devConfig).Return(1, false)
+
+		// only the node forced to start now is upgraded while the window is closed
+		upgradeStarted := make(chan string, 3)
+		mockHelper.EXPECT().handleForcedNodeUpgrade(gomock.Any(), devConfig, gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ *amdv1alpha1.DeviceConfig, node *v1.Node) error {
+				Expect(node.Name).To(Equal("node-forced-now"))
+				return nil
+			})
+		mockHelper.EXPECT().setUpgradeStartTime("node-forced-now")
+		mockHelper.EXPECT().setPreviousDriver(gomock.Any(), devConfig)
+		mockHelper.EXPECT().setNodeStatus(gomock.Any(), "node-forced-now", amdv1alpha1.UpgradeStateStarted)
+		mockHelper.EXPECT().handleNodeUpgrade(gomock.Any(), *devConfig, gomock.Any()).Do(
+			func(_ context.Context, _ amdv1alpha1.DeviceConfig, node v1.Node) {
+				upgradeStarted <- node.Name
+			})
+
+		_, err := upgradeMgr.HandleUpgrade(ctx, devConfig, nodeList)
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(upgradeStarted).Should(Receive(Equal("node-forced-now")))
+	})
+
+	It("holds back new node upgrades until the prebuilt driver images are ready", func() {
+		ctrl := gomock.NewController(GinkgoT())
+		mockHelper := NewMockupgradeMgrHelperAPI(ctrl)
+		upgradeMgr := &upgradeMgr{helper: mockHelper}
+
+		enable := true
+		devConfig := &amdv1alpha1.DeviceConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: devConfigName, Namespace: devConfigNamespace},
+			Spec: amdv1alpha1.DeviceConfigSpec{
+				Driver: amdv1alpha1.DriverSpec{
+					Enable:        &enable,
+					Version:       "6.4",
+					UpgradePolicy: &amdv1alpha1.DriverUpgradePolicySpec{Enable: &enable, MaxParallelUpgrades: 1},
+				},
+			},
+			Status: amdv1alpha1.DeviceConfigStatus{
+				DriverImages: []amdv1alpha1.DriverImageStatus{
+					{KernelVersion: "5.15.0-40-generic", Phase: amdv1alpha1.DriverImageReady},
+					{KernelVersion: "6.8.0-40-generic", Phase: amdv1alpha1.DriverImageBuilding},
+				},
+			},
+		}
+		nodeList := &v1.NodeList{Items: []v1.Node{newLabeledNode("node-1", "")}}
+
+		mockHelper.EXPECT().getOrCreateUpgradeStatus(gomock.Any(), devConfig).Return(nil, nil)
+		mockHelper.EXPECT().pruneUpgradeStatus(gomock.Any(), nil, nodeList)
+		mockHelper.EXPECT().setNodeDeviceConfig(gomock.Any(), devConfig).AnyTimes()
+		mockHelper.EXPECT().isInit().Return(false)
+		mockHelper.EXPECT().specChanged(devConfig).Return(false)
+		mockHelper.EXPECT().setcurrentSpec(devConfig)
+		mockHelper.EXPECT().handleInitStatus(gomock.Any(), gomock.Any(), devConfig).AnyTimes()
+		mockHelper.EXPECT().handleUpgradeTimedOut(gomock.Any(), gomock.Any(), devConfig).AnyTimes()
+		mockHelper.EXPECT().isNodeStateUpgradeFailed(gomock.Any(), gomock.Any()).Return(false).AnyTimes()
+		mockHelper.EXPECT().getNodeStatus(gomock.Any()).Return(amdv1alpha1.UpgradeStateNotStarted).AnyTimes()
+		mockHelper.EXPECT().isNodeUpgradeHeld(gomock.Any(), gomock.Any()).Return(false).AnyTimes()
+		mockHelper.EXPECT().isNodeNmcStatusMissing(gomock.Any(), gomock.Any(), devConfig).Return(false).AnyTimes()
+		mockHelper.EXPECT().isNodeStateUpgradeStarted(gomock.Any()).Return(false).AnyTimes()
+		mockHelper.EXPECT().isNodeUpgradePausing(gomock.Any()).Return(false).AnyTimes()
+		mockHelper.EXPECT().isNodeStatePostUpgradeHook(gomock.Any(), gomock.Any(), devConfig).Return(false).AnyTimes()
+		mockHelper.EXPECT().isNodeReady(gomock.Any(), gomock.Any(), devConfig).Return(false).AnyTimes()
+		mockHelper.EXPECT().isNodeNew(gomock.Any(), gomock.Any(), devConfig).Return(false).AnyTimes()
+		mockHelper.EXPECT().isNodeStateInstallInProgress(gomock.Any(), gomock.Any(), devConfig).Return(false).AnyTimes()
+		mockHelper.EXPECT().isNodeStateUpgradeInProgress(gomock.Any(), gomock.Any(), devConfig).Return(false).AnyTimes()
+		mockHelper.EXPECT().isNodeReadyForUpgrade(gomock.Any(), gomock.Any()).Return(true).AnyTimes()
+		mockHelper.EXPECT().getRequestedUpgradeAction(gomock.Any()).Return(amdv1alpha1.NodeUpgradeAction("")).AnyTimes()
+		mockHelper.EXPECT().planUpgradeRollout(gomock.Any(), devConfig, nodeList.Items, gomock.Any()).Return(nil, nil)
+
+		// no node upgrade is started while an image is being built
+		res, err := upgradeMgr.HandleUpgrade(ctx, devConfig, nodeList)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.RequeueAfter).To(Equal(driverImagePrebuildRequeueInterval))
+	})
+
+	It("drives the node upgrades in flight to completion without starting new ones in dry run mode", func() {
+		ctrl := gomock.NewController(GinkgoT())
+		mockHelper := NewMockupgradeMgrHelperAPI(ctrl)
+		upgradeMgr := &upgradeMgr{helper: mockHelper}
+
+		enable := true
+		devConfig := &amdv1alpha1.DeviceConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: devConfigName, Namespace: devConfigNamespace},
+			Spec: amdv1alpha1.DeviceConfigSpec{
+				DryRun: &enable,
+				Driver: amdv1alpha1.DriverSpec{
+					Enable:        &enable,
+					Version:       "6.4",
+					UpgradePolicy: &amdv1alpha1.DriverUpgradePolicySpec{Enable: &enable, MaxParallelUpgrades: 2},
+				},
+			},
+		}
+		nodeList := &v1.NodeList{Items: []v1.Node{newLabeledNode("node-in-flight", ""), newLabeledNode("node-candidate", "")}}
+		isInFlight := func(_ context.Context, node *v1.Node, _ *amdv1alpha1.DeviceConfig) bool {
+			return node.Name == "node-in-flight"
+		}
+
+		mockHelper.EXPECT().getOrCreateUpgradeStatus(gomock.Any(), devConfig).Return(nil, nil)
+		mockHelper.EXPECT().pruneUpgradeStatus(gomock.Any(), nil, nodeList)
+		mockHelper.EXPECT().setNodeDeviceConfig(gomock.Any(), devConfig).AnyTimes()
+		mockHelper.EXPECT().isInit().Return(false)
+		mockHelper.EXPECT().specChanged(devConfig).Return(false)
+		mockHelper.EXPECT().setcurrentSpec(devConfig)
+		mockHelper.EXPECT().handleInitStatus(gomock.Any(), gomock.Any(), devConfig).AnyTimes()
+		mockHelper.EXPECT().handleUpgradeTimedOut(gomock.Any(), gomock.Any(), devConfig).AnyTimes()
+		mockHelper.EXPECT().isNodeStateUpgradeFailed(gomock.Any(), gomock.Any()).Return(false).AnyTimes()
+		mockHelper.EXPECT().getNodeStatus(gomock.Any()).Return(amdv1alpha1.UpgradeStateNotStarted).AnyTimes()
+		mockHelper.EXPECT().isNodeUpgradeHeld(gomock.Any(), gomock.Any()).Return(false).AnyTimes()
+		mockHelper.EXPECT().isNodeNmcStatusMissing(gomock.Any(), gomock.Any(), devConfig).Return(false).AnyTimes()
+		mockHelper.EXPECT().isNodeStateUpgradeStarted(gomock.Any()).Return(false).AnyTimes()
+		mockHelper.EXPECT().isNodeStatePostUpgradeHook(gomock.Any(), gomock.Any(), devConfig).Return(false).AnyTimes()
+		// the node upgraded before the dry run was enabled is uncordoned and completed
+		mockHelper.EXPECT().isNodeReady(gomock.Any(), gomock.Any(), devConfig).DoAndReturn(isInFlight).Times(2)
+		mockHelper.EXPECT().getUpgradeStartTime("node-in-flight").Return("")
+		mockHelper.EXPECT().clearUpgradeStartTime("node-in-flight")
+		mockHelper.EXPECT().isNodeNew(gomock.Any(), gomock.Any(), devConfig).Return(false).AnyTimes()
+		mockHelper.EXPECT().isNodeStateInstallInProgress(gomock.Any(), gomock.Any(), devConfig).Return(false).AnyTimes()
+		mockHelper.EXPECT().isNodeStateUpgradeInProgress(gomock.Any(), gomock.Any(), devConfig).Return(false).AnyTimes()
+		mockHelper.EXPECT().isNodeReadyForUpgrade(gomock.Any(), gomock.Any()).Return(true).AnyTimes()
+		mockHelper.EXPECT().getRequestedUpgradeAction(gomock.Any()).Return(amdv1alpha1.NodeUpgradeAction("")).AnyTimes()
+		mockHelper.EXPECT().planUpgradeRollout(gomock.Any(), devConfig, nodeList.Items, gomock.Any()).Return(nil, nil)
+
+		// the other node is not upgraded, no handleNodeUpgrade call is expected
+		_, err := upgradeMgr.HandleUpgrade(ctx, devConfig, nodeList)
+		Expect(err).ToNot(HaveOccurred())
+	})
+})
+
+var _ = Describe("upgrade health checks", func() {
+	ctx := context.Background()
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "unit-test-node"}}
+	newDevConfig := func() *amdv1alpha1.DeviceConfig {
+		return &amdv1alpha1.DeviceConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: devConfigName, Namespace: devConfigNamespace},
+			Spec: amdv1alpha1.DeviceConfigSpec{
+				Driver: amdv1alpha1.DriverSpec{
+					Version: "6.3",
+					UpgradePolicy: &amdv1alpha1.DriverUpgradePolicySpec{
+						Hooks: &amdv1alpha1.UpgradeHooksSpec{},
+					},
+				},
+			},
+		}
+	}
+
+	It("runs the canary and the post-upgrade health checks of a node in distinct jobs", func() {
+		helper := newUpgradeMgrHelperHandler(nil, nil, nil, false).(*upgradeMgrHelper)
+		devConfig := newDevConfig()
+		healthCheck := &amdv1alpha1.UpgradeHealthCheckSpec{}
+
+		canaryJob, canaryConfigMap := helper.getUpgradeHealthCheckJob(devConfig, healthCheck, upgradeHealthCheckCanary, node.Name)
+		postJob, postConfigMap := helper.getUpgradeHealthCheckJob(devConfig, healthCheck, upgradeHealthCheckPostUpgrade, node.Name)
+		Expect(canaryJob.Name).ToNot(Equal(postJob.Name))
+		Expect(canaryConfigMap.Name).ToNot(Equal(postConfigMap.Name))
+		Expect(len(postJob.Name)).To(BeNumerically("<=", 63))
+	})
+
+	It("returns the result of the existing health check job", func() {
+		ctrl := gomock.NewController(GinkgoT())
+		kubeClient := mock_client.NewMockClient(ctrl)
+		helper := newUpgradeMgrHelperHandler(kubeClient, nil, nil, false).(*upgradeMgrHelper)
+		devConfig := newDevConfig()
+		healthCheck := &amdv1alpha1.UpgradeHealthCheckSpec{}
+		job, _ := helper.getUpgradeHealthCheckJob(devConfig, healthCheck, upgradeHealthCheckPostUpgrade, node.Name)
+
+		// the job and its config are created on first call
+		kubeClient.EXPECT().Get(ctx, types.NamespacedName{Namespace: job.Namespace, Name: job.Name}, gomock.Any()).
+			Return(k8serrors.NewNotFound(schema.GroupResource{}, job.Name))
+		kubeClient.EXPECT().Create(ctx, gomock.AssignableToTypeOf(&v1.ConfigMap{})).Return(nil)
+		kubeClient.EXPECT().Create(ctx, gomock.AssignableToTypeOf(&batchv1.Job{})).Return(nil)
+		done, _, err := helper.getUpgradeHealthCheckResult(ctx, devConfig, healthCheck, upgradeHealthCheckPostUpgrade, node)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(done).To(BeFalse())
+
+		kubeClient.EXPECT().Get(ctx, types.NamespacedName{Namespace: job.Namespace, Name: job.Name}, gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ types.NamespacedName, obj client.Object, _ ...client.GetOption) error {
+				obj.(*batchv1.Job).Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: v1.ConditionTrue}}
+				return nil
+			})
+		done, passed, err := helper.getUpgradeHealthCheckResult(ctx, devConfig, healthCheck, upgradeHealthCheckPostUpgrade, node)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(done).To(BeTrue())
+		Expect(passed).To(BeFalse())
+	})
+
+	It("doesn't run the canary health check again if the post-upgrade hook runs the same check", func() {
+		devConfig := newDevConfig()
+		canaryCheck := &amdv1alpha1.UpgradeHealthCheckSpec{Recipe: "gst_single"}
+		Expect(isCanaryHealthCheckCovered(devConfig, canaryCheck)).To(BeFalse())
+
+		devConfig.Spec.Driver.UpgradePolicy.Hooks.HealthCheck = &amdv1alpha1.UpgradeHealthCheckSpec{Recipe: "gst_single"}
+		Expect(isCanaryHealthCheckCovered(devConfig, canaryCheck)).To(BeTrue())
+
+		disabled := false
+		devConfig.Spec.Driver.UpgradePolicy.Hooks.HealthCheck.Enable = &disabled
+		Expect(isCanaryHealthCheckCovered(devConfig, canaryCheck)).To(BeFalse())
+
+		devConfig.Spec.Driver.UpgradePolicy.Hooks.HealthCheck = &amdv1alpha1.UpgradeHealthCheckSpec{Recipe: "gst_stress"}
+		Expect(isCanaryHealthCheckCovered(devConfig, canaryCheck)).To(BeFalse())
+	})
+})
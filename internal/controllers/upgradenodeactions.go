@@ -0,0 +1,127 @@
+/*
+Copyright (c) Advanced Micro Devices, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the \"License\");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an \"AS IS\" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	amdv1alpha1 "github.com/ROCm/gpu-operator/api/v1alpha1"
+)
+
+// getRequestedUpgradeAction returns the upgrade action the user requested through the upgrade state label of the node
+func (h *upgradeMgrHelper) getRequestedUpgradeAction(node *v1.Node) amdv1alpha1.NodeUpgradeAction {
+	switch node.Labels[driverUpgradeStateLabelKey] {
+	case upgradeRequiredLabelValue, upgradeRetryLabelValue:
+		return amdv1alpha1.NodeUpgradeActionRetry
+	case upgradeSkipLabelValue:
+		return amdv1alpha1.NodeUpgradeActionSkip
+	case upgradePauseLabelValue:
+		return amdv1alpha1.NodeUpgradeActionPause
+	case upgradeForceLabelValue, upgradeForceNowLabelValue:
+		return amdv1alpha1.NodeUpgradeActionForce
+	}
+	return ""
+}
+
+// isNodeUpgradeHeld returns true while a skipped or paused node still carries the label that put it there.
+// Once the label is removed or changed, the node goes back to Upgrade-Not-Started and is handled like any other node
+func (h *upgradeMgrHelper) isNodeUpgradeHeld(ctx context.Context, node *v1.Node) bool {
+	state := h.getNodeStatus(node.Name)
+	if state != amdv1alpha1.UpgradeStateSkipped && state != amdv1alpha1.UpgradeStatePaused {
+		return false
+	}
+
+	action := h.getRequestedUpgradeAction(node)
+	if (state == amdv1alpha1.UpgradeStateSkipped && action == amdv1alpha1.NodeUpgradeActionSkip) ||
+		(state == amdv1alpha1.UpgradeStatePaused && action == amdv1alpha1.NodeUpgradeActionPause) {
+		return true
+	}
+
+	log.FromContext(ctx).Info(fmt.Sprintf("Node: %v is no longer labeled for %v, releasing the node upgrade", node.Name, state))
+	h.nodeUpgradeAction.Delete(node.Name)
+	h.setNodeStatus(ctx, node.Name, amdv1alpha1.UpgradeStateNotStarted)
+	return false
+}
+
+// holdNodeUpgrade moves the node to Upgrade-Skipped or Upgrade-Paused, the node is not upgraded until the label is removed
+func (h *upgradeMgrHelper) holdNodeUpgrade(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, node *v1.Node, action amdv1alpha1.NodeUpgradeAction) {
+	state, reason, verb := amdv1alpha1.UpgradeStateSkipped, EventReasonDriverUpgradeSkipped, "skipped"
+	if action == amdv1alpha1.NodeUpgradeActionPause {
+		state, reason, verb = amdv1alpha1.UpgradeStatePaused, EventReasonDriverUpgradePaused, "paused"
+	}
+	if h.getNodeStatus(node.Name) == state {
+		return
+	}
+
+	log.FromContext(ctx).Info(fmt.Sprintf("Node: %v is labeled for %v, holding the driver upgrade", node.Name, action))
+	h.clearUpgradeStartTime(node.Name)
+	h.setNodeUpgradeAction(node.Name, action)
+	h.setNodeStatus(ctx, node.Name, state)
+	recordEvent(h.recorder, deviceConfig, node, v1.EventTypeNormal, reason,
+		fmt.Sprintf("Driver upgrade to version %v %v on node %v", deviceConfig.Spec.Driver.Version, verb, node.Name))
+}
+
+// pauseNodeUpgradeIfRequested pauses an ongoing node upgrade if the node got labeled for pause. It is checked at
+// the safe points of the upgrade, before the node is drained and before the new driver is loaded, the node is
+// uncordoned and keeps running the current driver
+func (h *upgradeMgrHelper) pauseNodeUpgradeIfRequested(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, node *v1.Node) bool {
+	nodeObj, err := h.getNode(ctx, node.Name)
+	if err != nil || h.getRequestedUpgradeAction(nodeObj) != amdv1alpha1.NodeUpgradeActionPause {
+		return false
+	}
+
+	if err := h.cordonOrUncordonNode(ctx, deviceConfig, nodeObj, false); err != nil {
+		log.FromContext(ctx).Error(err, fmt.Sprintf("Node: %v. Failed to uncordon node for upgrade pause: %v", node.Name, err))
+		return false
+	}
+	h.holdNodeUpgrade(ctx, deviceConfig, nodeObj, amdv1alpha1.NodeUpgradeActionPause)
+	return true
+}
+
+// isNodeUpgradePausing returns true if the node got labeled for pause while its upgrade waits for the pre-upgrade hook or
+// for the GPU workloads. The node is not drained anymore and is paused at the next poll, so it no longer takes an upgrade slot
+func (h *upgradeMgrHelper) isNodeUpgradePausing(node *v1.Node) bool {
+	state := h.getNodeStatus(node.Name)
+	if state != amdv1alpha1.UpgradeStatePreUpgradeHook && state != amdv1alpha1.UpgradeStateDrainWaiting {
+		return false
+	}
+	return h.getRequestedUpgradeAction(node) == amdv1alpha1.NodeUpgradeActionPause
+}
+
+// isMaintenanceWindowOverridden returns true if the user forced the node upgrade to start outside of the maintenance windows
+func (h *upgradeMgrHelper) isMaintenanceWindowOverridden(node *v1.Node) bool {
+	return node.Labels[driverUpgradeStateLabelKey] == upgradeForceNowLabelValue
+}
+
+// handleForcedNodeUpgrade consumes the force label of a node whose upgrade is about to start
+func (h *upgradeMgrHelper) handleForcedNodeUpgrade(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, node *v1.Node) error {
+	labelValue := node.Labels[driverUpgradeStateLabelKey]
+	if err := h.removeUpgradeActionLabelOnNode(ctx, node, labelValue); err != nil {
+		log.FromContext(ctx).Error(err, fmt.Sprintf("Node: %v. Failed to remove label %v with error: %v", node.Name, labelValue, err))
+		return err
+	}
+
+	log.FromContext(ctx).Info(fmt.Sprintf("Node: %v is labeled for force upgrade", node.Name))
+	h.setNodeUpgradeAction(node.Name, amdv1alpha1.NodeUpgradeActionForce)
+	recordEvent(h.recorder, deviceConfig, node, v1.EventTypeNormal, EventReasonDriverUpgradeForced,
+		fmt.Sprintf("Driver upgrade to version %v forced on node %v", deviceConfig.Spec.Driver.Version, node.Name))
+	return nil
+}
@@ -0,0 +1,554 @@
+/*
+Copyright (c) Advanced Micro Devices, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the \"License\");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an \"AS IS\" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	amdv1alpha1 "github.com/ROCm/gpu-operator/api/v1alpha1"
+	utils "github.com/ROCm/gpu-operator/internal"
+)
+
+const (
+	upgradeHealthCheckLabelKey          = "operator.amd.com/gpu-upgrade-health-check"
+	upgradeHealthCheckNodeAnnotationKey = "operator.amd.com/gpu-upgrade-health-check-node"
+	upgradeHealthCheckVersionAnnotation = "operator.amd.com/gpu-upgrade-health-check-version"
+
+	// the canary and the post-upgrade hook health checks of a node run in distinct Jobs
+	upgradeHealthCheckCanary      = "canary"
+	upgradeHealthCheckPostUpgrade = "post-upgrade"
+
+	defaultHealthCheckFramework      = "RVS"
+	defaultHealthCheckRecipe         = "gst_single"
+	defaultHealthCheckIterations     = 1
+	defaultHealthCheckTimeoutSeconds = 1200
+)
+
+// planUpgradeRollout computes the current stage of the staged rollout of the DeviceConfig and returns the candidate nodes
+// that are allowed to start their upgrade. A nil status is returned if no rollout strategy is configured
+func (h *upgradeMgrHelper) planUpgradeRollout(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, nodes []v1.Node, candidateNodes []v1.Node) (*amdv1alpha1.UpgradeRolloutStatus, map[string]bool) {
+	dcName := types.NamespacedName{Namespace: deviceConfig.Namespace, Name: deviceConfig.Name}
+	rollout := deviceConfig.Spec.Driver.UpgradePolicy.RolloutStrategy
+	if rollout == nil {
+		h.rolloutStatus.Delete(dcName)
+		return nil, nil
+	}
+
+	if err := h.cleanupStaleHealthChecks(ctx, deviceConfig); err != nil {
+		log.FromContext(ctx).Error(err, "failed to cleanup stale post-upgrade health checks")
+	}
+	status, allowedNodes := h.computeUpgradeRollout(ctx, deviceConfig, nodes, candidateNodes)
+
+	if previous := h.getRolloutStatus(deviceConfig); status.Phase == amdv1alpha1.UpgradeRolloutPhasePaused &&
+		(previous == nil || previous.Phase != amdv1alpha1.UpgradeRolloutPhasePaused) {
+		log.FromContext(ctx).Info(fmt.Sprintf("Driver upgrade rollout paused: %v", status.Message))
+		recordEvent(h.recorder, deviceConfig, nil, v1.EventTypeWarning, EventReasonUpgradeRolloutPaused,
+			fmt.Sprintf("Driver upgrade rollout to version %v paused: %v", deviceConfig.Spec.Driver.Version, status.Message))
+	}
+	h.rolloutStatus.Store(dcName, status)
+
+	return status, allowedNodes
+}
+
+func (h *upgradeMgrHelper) computeUpgradeRollout(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, nodes []v1.Node, candidateNodes []v1.Node) (*amdv1alpha1.UpgradeRolloutStatus, map[string]bool) {
+	rollout := deviceConfig.Spec.Driver.UpgradePolicy.RolloutStrategy
+	allowedNodes := map[string]bool{}
+	candidates := map[string]bool{}
+	for _, node := range candidateNodes {
+		candidates[node.Name] = true
+	}
+	// a stage is pending while some of its nodes are upgrading or can start their upgrade, nodes that can't start
+	// (not ready, paused or skipped) don't hold the rollout back
+	isNodePending := func(nodeName string) bool {
+		return candidates[nodeName] || h.isNodeRolloutInProgress(nodeName)
+	}
+
+	var canaryNodes, otherNodes []v1.Node
+	for _, node := range nodes {
+		if rollout.Canary != nil && len(rollout.Canary.Selector) > 0 &&
+			labels.SelectorFromSet(labels.Set(rollout.Canary.Selector)).Matches(labels.Set(node.Labels)) {
+			canaryNodes = append(canaryNodes, node)
+		} else {
+			otherNodes = append(otherNodes, node)
+		}
+	}
+	waves := buildUpgradeWaves(rollout.Waves, otherNodes)
+	status := &amdv1alpha1.UpgradeRolloutStatus{TotalWaves: len(waves)}
+
+	if len(canaryNodes) > 0 {
+		if failed := h.getRolloutNodes(canaryNodes, h.isNodeRolloutFailed); len(failed) > 0 {
+			status.Phase = amdv1alpha1.UpgradeRolloutPhasePaused
+			status.Message = fmt.Sprintf("canary nodes %v failed to upgrade", strings.Join(failed, ", "))
+			return status, allowedNodes
+		}
+		if pending := h.getRolloutNodes(canaryNodes, isNodePending); len(pending) > 0 {
+			status.Phase = amdv1alpha1.UpgradeRolloutPhaseCanary
+			status.Message = fmt.Sprintf("upgrading canary nodes %v", strings.Join(pending, ", "))
+			for _, nodeName := range pending {
+				allowedNodes[nodeName] = candidates[nodeName]
+			}
+			return status, allowedNodes
+		}
+
+		if healthCheck := rollout.Canary.HealthCheck; isHealthCheckEnabled(healthCheck) && !isCanaryHealthCheckCovered(deviceConfig, healthCheck) {
+			var running, failed []string
+			for i := range canaryNodes {
+				// fresh driver installs are not subject to the post-upgrade health check
+				if h.getNodeStatus(canaryNodes[i].Name) != amdv1alpha1.UpgradeStateComplete {
+					continue
+				}
+				done, passed, err := h.getUpgradeHealthCheckResult(ctx, deviceConfig, healthCheck, upgradeHealthCheckCanary, &canaryNodes[i])
+				if err != nil {
+					log.FromContext(ctx).Error(err, fmt.Sprintf("Node: %v post-upgrade health check error", canaryNodes[i].Name))
+				}
+				if !done {
+					running = append(running, canaryNodes[i].Name)
+				} else if !passed {
+					failed = append(failed, canaryNodes[i].Name)
+				}
+			}
+			if len(failed) > 0 {
+				status.Phase = amdv1alpha1.UpgradeRolloutPhasePaused
+				status.Message = fmt.Sprintf("canary nodes %v failed the post-upgrade health check", strings.Join(failed, ", "))
+				return status, allowedNodes
+			}
+			if len(running) > 0 {
+				status.Phase = amdv1alpha1.UpgradeRolloutPhaseCanaryHealthCheck
+				status.Message = fmt.Sprintf("running post-upgrade health check on canary nodes %v", strings.Join(running, ", "))
+				return status, allowedNodes
+			}
+		}
+
+		if remaining := h.getRolloutSoakRemaining(canaryNodes, rollout.Canary.SoakSeconds); remaining > 0 {
+			status.Phase = amdv1alpha1.UpgradeRolloutPhaseCanarySoak
+			status.Message = fmt.Sprintf("canary nodes soaking, waves start in %v", remaining.Round(time.Second))
+			return status, allowedNodes
+		}
+	}
+
+	soakSeconds := 0
+	if rollout.Waves != nil {
+		soakSeconds = rollout.Waves.SoakSeconds
+	}
+	for i, wave := range waves {
+		status.CurrentWave = i + 1
+		if pending := h.getRolloutNodes(wave, isNodePending); len(pending) > 0 {
+			status.Phase = amdv1alpha1.UpgradeRolloutPhaseWave
+			status.Message = fmt.Sprintf("upgrading wave %v of %v, %v nodes remaining", i+1, len(waves), len(pending))
+			for _, nodeName := range pending {
+				allowedNodes[nodeName] = candidates[nodeName]
+			}
+			return status, allowedNodes
+		}
+		if i == len(waves)-1 {
+			break
+		}
+		if remaining := h.getRolloutSoakRemaining(wave, soakSeconds); remaining > 0 {
+			status.Phase = amdv1alpha1.UpgradeRolloutPhaseWaveSoak
+			status.Message = fmt.Sprintf("wave %v soaking, wave %v starts in %v", i+1, i+2, remaining.Round(time.Second))
+			return status, allowedNodes
+		}
+	}
+
+	status.Phase = amdv1alpha1.UpgradeRolloutPhaseComplete
+	status.Message = ""
+	return status, allowedNodes
+}
+
+// buildUpgradeWaves splits the nodes into waves, either grouped by topology label or by wave size
+func buildUpgradeWaves(wavesSpec *amdv1alpha1.UpgradeWavesSpec, nodes []v1.Node) [][]v1.Node {
+	if len(nodes) == 0 {
+		return nil
+	}
+	sortedNodes := append([]v1.Node{}, nodes...)
+	sort.Slice(sortedNodes, func(i, j int) bool { return sortedNodes[i].Name < sortedNodes[j].Name })
+
+	if wavesSpec == nil {
+		return [][]v1.Node{sortedNodes}
+	}
+
+	if wavesSpec.TopologyLabel != "" {
+		groups := map[string][]v1.Node{}
+		var unlabeled []v1.Node
+		for _, node := range sortedNodes {
+			if value, ok := node.Labels[wavesSpec.TopologyLabel]; ok {
+				groups[value] = append(groups[value], node)
+			} else {
+				unlabeled = append(unlabeled, node)
+			}
+		}
+		values := make([]string, 0, len(groups))
+		for value := range groups {
+			values = append(values, value)
+		}
+		sort.Strings(values)
+		waves := make([][]v1.Node, 0, len(values)+1)
+		for _, value := range values {
+			waves = append(waves, groups[value])
+		}
+		if len(unlabeled) > 0 {
+			waves = append(waves, unlabeled)
+		}
+		return waves
+	}
+
+	size, err := intstr.GetScaledValueFromIntOrPercent(&wavesSpec.Size, len(sortedNodes), true)
+	if err != nil || size <= 0 {
+		return [][]v1.Node{sortedNodes}
+	}
+	var waves [][]v1.Node
+	for start := 0; start < len(sortedNodes); start += size {
+		waves = append(waves, sortedNodes[start:min(start+size, len(sortedNodes))])
+	}
+	return waves
+}
+
+// isNodeRolloutFailed returns true if the node upgrade ended in a failed state or was rolled back
+func (h *upgradeMgrHelper) isNodeRolloutFailed(nodeName string) bool {
+	state := h.getNodeStatus(nodeName)
+	return h.isNodeInFailedUpgradeStates(state) || state == amdv1alpha1.UpgradeStateRebootFailed ||
+		state == amdv1alpha1.UpgradeStateRollbackInProgress || state == amdv1alpha1.UpgradeStateRollbackComplete
+}
+
+// isNodeRolloutInProgress returns true if the node upgrade has started and has neither completed nor failed yet.
+// Nodes that didn't start their upgrade, or that are held back by a skip or pause, are not in progress
+func (h *upgradeMgrHelper) isNodeRolloutInProgress(nodeName string) bool {
+	switch h.getNodeStatus(nodeName) {
+	case amdv1alpha1.UpgradeStateEmpty, amdv1alpha1.UpgradeStateNotStarted, amdv1alpha1.UpgradeStateComplete,
+		amdv1alpha1.UpgradeStateInstallComplete, amdv1alpha1.UpgradeStateSkipped, amdv1alpha1.UpgradeStatePaused:
+		return false
+	}
+	return !h.isNodeRolloutFailed(nodeName)
+}
+
+func (h *upgradeMgrHelper) getRolloutNodes(nodes []v1.Node, match func(nodeName string) bool) []string {
+	var nodeNames []string
+	for _, node := range nodes {
+		if match(node.Name) {
+			nodeNames = append(nodeNames, node.Name)
+		}
+	}
+	return nodeNames
+}
+
+// getRolloutSoakRemaining returns how long the nodes still have to soak, measured from the latest node state transition
+func (h *upgradeMgrHelper) getRolloutSoakRemaining(nodes []v1.Node, soakSeconds int) time.Duration {
+	if soakSeconds <= 0 {
+		return 0
+	}
+	var lastTransition time.Time
+	for _, node := range nodes {
+		transitionTime, err := time.Parse(DefaultTimeFormatLayout, h.getNodeTransitionTime(node.Name))
+		if err != nil {
+			continue
+		}
+		if transitionTime.After(lastTransition) {
+			lastTransition = transitionTime
+		}
+	}
+	if lastTransition.IsZero() {
+		return 0
+	}
+	return time.Until(lastTransition.Add(time.Duration(soakSeconds) * time.Second))
+}
+
+func (h *upgradeMgrHelper) getNodeTransitionTime(nodeName string) string {
+	if value, ok := h.nodeTransitionTime.Load(nodeName); ok {
+		return value.(string)
+	}
+	return ""
+}
+
+func (h *upgradeMgrHelper) clearRolloutStatus(deviceConfig *amdv1alpha1.DeviceConfig) {
+	h.rolloutStatus.Delete(types.NamespacedName{Namespace: deviceConfig.Namespace, Name: deviceConfig.Name})
+}
+
+func (h *upgradeMgrHelper) getRolloutStatus(deviceConfig *amdv1alpha1.DeviceConfig) *amdv1alpha1.UpgradeRolloutStatus {
+	if value, ok := h.rolloutStatus.Load(types.NamespacedName{Namespace: deviceConfig.Namespace, Name: deviceConfig.Name}); ok {
+		return value.(*amdv1alpha1.UpgradeRolloutStatus).DeepCopy()
+	}
+	return nil
+}
+
+func isHealthCheckEnabled(healthCheck *amdv1alpha1.UpgradeHealthCheckSpec) bool {
+	return healthCheck != nil && (healthCheck.Enable == nil || *healthCheck.Enable)
+}
+
+// isCanaryHealthCheckCovered returns true if the post-upgrade hook runs the same health check as the canary, every node
+// only completes its upgrade once it passed the hook health check so the canary nodes don't run it a second time
+func isCanaryHealthCheckCovered(deviceConfig *amdv1alpha1.DeviceConfig, healthCheck *amdv1alpha1.UpgradeHealthCheckSpec) bool {
+	hooks := deviceConfig.Spec.Driver.UpgradePolicy.Hooks
+	return hooks != nil && isHealthCheckEnabled(hooks.HealthCheck) && reflect.DeepEqual(hooks.HealthCheck, healthCheck)
+}
+
+// getUpgradeHealthCheckResult runs the test runner on the upgraded node through a Job and returns its result.
+// The Job is created on first call and kept until the driver version changes, so that the result survives operator restarts
+func (h *upgradeMgrHelper) getUpgradeHealthCheckResult(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig, healthCheck *amdv1alpha1.UpgradeHealthCheckSpec, check string, node *v1.Node) (done bool, passed bool, err error) {
+	logger := log.FromContext(ctx)
+
+	job, configMap := h.getUpgradeHealthCheckJob(deviceConfig, healthCheck, check, node.Name)
+	existingJob := &batchv1.Job{}
+	if err := h.client.Get(ctx, types.NamespacedName{Namespace: job.Namespace, Name: job.Name}, existingJob); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return false, false, fmt.Errorf("failed to get health check job %v: %v", job.Name, err)
+		}
+		if err := h.client.Create(ctx, configMap); err != nil && !k8serrors.IsAlreadyExists(err) {
+			return false, false, fmt.Errorf("failed to create health check configmap %v: %v", configMap.Name, err)
+		}
+		if err := h.client.Create(ctx, job); err != nil && !k8serrors.IsAlreadyExists(err) {
+			return false, false, fmt.Errorf("failed to create health check job %v: %v", job.Name, err)
+		}
+		logger.Info(fmt.Sprintf("Node: %v started %v health check job %v", node.Name, check, job.Name))
+		return false, false, nil
+	}
+
+	done, passed = getJobResult(existingJob)
+	return done, passed, nil
+}
+
+// cleanupStaleHealthChecks deletes the health check Jobs and ConfigMaps created for another driver version
+func (h *upgradeMgrHelper) cleanupStaleHealthChecks(ctx context.Context, deviceConfig *amdv1alpha1.DeviceConfig) error {
+	listOpts := []client.ListOption{
+		client.InNamespace(deviceConfig.Namespace),
+		client.MatchingLabels{upgradeHealthCheckLabelKey: deviceConfig.Name},
+	}
+	jobs := &batchv1.JobList{}
+	if err := h.client.List(ctx, jobs, listOpts...); err != nil {
+		return err
+	}
+	for i := range jobs.Items {
+		if jobs.Items[i].Annotations[upgradeHealthCheckVersionAnnotation] == deviceConfig.Spec.Driver.Version {
+			continue
+		}
+		if err := h.client.Delete(ctx, &jobs.Items[i], client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+	}
+	configMaps := &v1.ConfigMapList{}
+	if err := h.client.List(ctx, configMaps, listOpts...); err != nil {
+		return err
+	}
+	for i := range configMaps.Items {
+		if configMaps.Items[i].Annotations[upgradeHealthCheckVersionAnnotation] == deviceConfig.Spec.Driver.Version {
+			continue
+		}
+		if err := h.client.Delete(ctx, &configMaps.Items[i]); err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// getUpgradeHealthCheckJob returns the test runner Job and its test config of the canary or post-upgrade health check of the node
+func (h *upgradeMgrHelper) getUpgradeHealthCheckJob(deviceConfig *amdv1alpha1.DeviceConfig, healthCheck *amdv1alpha1.UpgradeHealthCheckSpec, check string, nodeName string) (*batchv1.Job, *v1.ConfigMap) {
+	framework := defaultHealthCheckFramework
+	if healthCheck.Framework != "" {
+		framework = healthCheck.Framework
+	}
+	recipe := defaultHealthCheckRecipe
+	if healthCheck.Recipe != "" {
+		recipe = healthCheck.Recipe
+	}
+	iterations := defaultHealthCheckIterations
+	if healthCheck.Iterations > 0 {
+		iterations = healthCheck.Iterations
+	}
+	timeoutSeconds := defaultHealthCheckTimeoutSeconds
+	if healthCheck.TimeoutSeconds > 0 {
+		timeoutSeconds = healthCheck.TimeoutSeconds
+	}
+
+	// job names are used as pod label values, keep them within 63 characters whatever the node name
+	hash := fnv.New64a()
+	hash.Write([]byte(fmt.Sprintf("%v/%v/%v/%v/%v", deviceConfig.Namespace, deviceConfig.Name, nodeName, deviceConfig.Spec.Driver.Version, check)))
+	name := fmt.Sprintf("amd-gpu-%v-check-%016x", check, hash.Sum64())
+
+	objectMeta := metav1.ObjectMeta{
+		Name:      name,
+		Namespace: deviceConfig.Namespace,
+		Labels: map[string]string{
+			upgradeHealthCheckLabelKey: deviceConfig.Name,
+		},
+		Annotations: map[string]string{
+			upgradeHealthCheckNodeAnnotationKey: nodeName,
+			upgradeHealthCheckVersionAnnotation: deviceConfig.Spec.Driver.Version,
+		},
+		OwnerReferences: []metav1.OwnerReference{
+			{
+				APIVersion: amdv1alpha1.GroupVersion.String(),
+				Kind:       "DeviceConfig",
+				Name:       deviceConfig.Name,
+				UID:        deviceConfig.UID,
+				Controller: ptr.To(true),
+			},
+		},
+	}
+
+	testConfig := map[string]interface{}{
+		"TestConfig": map[string]interface{}{
+			"GPU_HEALTH_CHECK": map[string]interface{}{
+				"TestLocationTrigger": map[string]interface{}{
+					nodeName: map[string]interface{}{
+						"TestParameters": map[string]interface{}{
+							"MANUAL": map[string]interface{}{
+								"TestCases": []map[string]interface{}{
+									{
+										"Framework":      framework,
+										"Recipe":         recipe,
+										"Iterations":     iterations,
+										"StopOnFailure":  true,
+										"TimeoutSeconds": timeoutSeconds,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	testConfigBytes, _ := json.MarshalIndent(testConfig, "", "  ")
+	configMap := &v1.ConfigMap{
+		ObjectMeta: *objectMeta.DeepCopy(),
+		Data: map[string]string{
+			"config.json": string(testConfigBytes),
+		},
+	}
+
+	testRunnerImage := utils.DefaultTestRunnerImage
+	if deviceConfig.Spec.TestRunner.Image != "" {
+		testRunnerImage = deviceConfig.Spec.TestRunner.Image
+	}
+	initContainerImage := utils.DefaultInitContainerImage
+	if deviceConfig.Spec.CommonConfig.InitContainerImage != "" {
+		initContainerImage = deviceConfig.Spec.CommonConfig.InitContainerImage
+	}
+	imagePullSecrets := []v1.LocalObjectReference{}
+	if deviceConfig.Spec.TestRunner.ImageRegistrySecret != nil {
+		imagePullSecrets = append(imagePullSecrets, *deviceConfig.Spec.TestRunner.ImageRegistrySecret)
+	}
+	imagePullSecrets = append(imagePullSecrets, deviceConfig.Spec.CommonConfig.ImageRegistrySecrets...)
+
+	hostPathCharDev := v1.HostPathCharDev
+	hostPathDirectory := v1.HostPathDirectory
+	hostPathDirectoryOrCreate := v1.HostPathDirectoryOrCreate
+	job := &batchv1.Job{
+		ObjectMeta: objectMeta,
+		Spec: batchv1.JobSpec{
+			BackoffLimit:          ptr.To(int32(0)),
+			ActiveDeadlineSeconds: ptr.To(int64(timeoutSeconds * iterations)),
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						upgradeHealthCheckLabelKey: deviceConfig.Name,
+					},
+				},
+				Spec: v1.PodSpec{
+					ServiceAccountName: TestRunnerServiceAccount,
+					RestartPolicy:      v1.RestartPolicyNever,
+					// bound to the upgraded node directly, the hostname label is not guaranteed to match the node name
+					NodeName: nodeName,
+					// the node is still cordoned when the check runs as a post-upgrade hook
+					Tolerations: append([]v1.Toleration{
+						{Key: "amd-gpu-driver-upgrade", Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule},
+					}, deviceConfig.Spec.TestRunner.Tolerations...),
+					ImagePullSecrets: imagePullSecrets,
+					Volumes: []v1.Volume{
+						{
+							Name:         "kfd",
+							VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/dev/kfd", Type: &hostPathCharDev}},
+						},
+						{
+							Name:         "dri",
+							VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/dev/dri", Type: &hostPathDirectory}},
+						},
+						{
+							Name: "config-volume",
+							VolumeSource: v1.VolumeSource{ConfigMap: &v1.ConfigMapVolumeSource{
+								LocalObjectReference: v1.LocalObjectReference{Name: name},
+							}},
+						},
+						{
+							Name:         "test-runner-volume",
+							VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/var/log/amd-test-runner", Type: &hostPathDirectoryOrCreate}},
+						},
+						{
+							Name:         "host-sys",
+							VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/sys", Type: &hostPathDirectory}},
+						},
+					},
+					InitContainers: []v1.Container{
+						{
+							Name:            "driver-init",
+							Image:           initContainerImage,
+							ImagePullPolicy: v1.PullIfNotPresent,
+							Command:         []string{"sh", "-c", "while [ ! -d /host-sys/class/kfd ] || [ ! -d /host-sys/module/amdgpu/drivers/ ]; do echo \"amdgpu driver is not loaded \"; sleep 2 ;done; echo \"amdgpu driver is loaded\""},
+							SecurityContext: &v1.SecurityContext{Privileged: ptr.To(true)},
+							VolumeMounts: []v1.VolumeMount{
+								{Name: "host-sys", MountPath: "/host-sys"},
+							},
+						},
+					},
+					Containers: []v1.Container{
+						{
+							Name:            "amd-test-runner",
+							Image:           testRunnerImage,
+							ImagePullPolicy: v1.PullIfNotPresent,
+							SecurityContext: &v1.SecurityContext{Privileged: ptr.To(true)},
+							VolumeMounts: []v1.VolumeMount{
+								{Name: "dri", MountPath: "/dev/dri"},
+								{Name: "kfd", MountPath: "/dev/kfd"},
+								{Name: "test-runner-volume", MountPath: "/var/log/amd-test-runner"},
+								{Name: "config-volume", MountPath: "/etc/test-runner/"},
+							},
+							Env: []v1.EnvVar{
+								{Name: "LOG_MOUNT_DIR", Value: "/var/log/amd-test-runner"},
+								{Name: "TEST_TRIGGER", Value: "MANUAL"},
+								{Name: "POD_NAME", ValueFrom: &v1.EnvVarSource{FieldRef: &v1.ObjectFieldSelector{FieldPath: "metadata.name"}}},
+								{Name: "POD_NAMESPACE", ValueFrom: &v1.EnvVarSource{FieldRef: &v1.ObjectFieldSelector{FieldPath: "metadata.namespace"}}},
+								{Name: "NODE_NAME", ValueFrom: &v1.EnvVarSource{FieldRef: &v1.ObjectFieldSelector{FieldPath: "spec.nodeName"}}},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	if deviceConfig.Spec.TestRunner.ImagePullPolicy != "" {
+		job.Spec.Template.Spec.Containers[0].ImagePullPolicy = v1.PullPolicy(deviceConfig.Spec.TestRunner.ImagePullPolicy)
+	}
+
+	return job, configMap
+}
@@ -52,6 +52,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
 
 	amdv1alpha1 "github.com/ROCm/gpu-operator/api/v1alpha1"
 	utils "github.com/ROCm/gpu-operator/internal"
@@ -122,8 +123,85 @@ func slesPrebuiltDriverImage(codestream, driverVersion, customRepo string) (stri
 //go:generate mockgen -source=kmmmodule.go -package=kmmmodule -destination=mock_kmmmodule.go KMMModuleAPI
 type KMMModuleAPI interface {
 	SetNodeVersionLabelAsDesired(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) error
-	SetBuildConfigMapAsDesired(buildCM *v1.ConfigMap, devConfig *amdv1alpha1.DeviceConfig) error
+	SetBuildConfigMapAsDesired(ctx context.Context, buildCM *v1.ConfigMap, devConfig *amdv1alpha1.DeviceConfig) error
 	SetKMMModuleAsDesired(ctx context.Context, mod *kmmv1beta1.Module, devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) error
+	GetDriverImages(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) ([]DriverImage, error)
+}
+
+// DriverImage is the driver image of an OS, kernel and driver version combination
+type DriverImage struct {
+	OSName        string
+	KernelVersion string
+	DriverVersion string
+	// Image is the image reference with the kernel version rendered
+	Image string
+	// ContainerImage is the image of the kernel mapping, as templated in the KMM Module
+	ContainerImage string
+}
+
+// DriverImageCatalogKey is the ConfigMap key holding the precompiled driver image catalog
+const DriverImageCatalogKey = "catalog.yaml"
+
+// DriverImageCatalog lists precompiled driver images, so that nodes with a matching
+// OS, kernel and driver version don't need an in-cluster image build
+type DriverImageCatalog struct {
+	Images []CatalogImage `json:"images"`
+}
+
+// CatalogImage is a precompiled driver image of an OS, kernel and driver version combination
+type CatalogImage struct {
+	// OS is the OS name of the node, e.g. ubuntu-22.04
+	OS string `json:"os"`
+	// Kernel is the full kernel version of the node
+	Kernel string `json:"kernel"`
+	// DriverVersion is the amdgpu driver version, e.g. 6.4
+	DriverVersion string `json:"driverVersion"`
+	// DriverType is the driver type the image is built for, empty means container
+	DriverType string `json:"driverType,omitempty"`
+	// Image is the image reference, ${KERNEL_FULL_VERSION} is rendered by KMM
+	Image string `json:"image"`
+}
+
+// ParseDriverImageCatalog parses and validates the catalog data
+func ParseDriverImageCatalog(data string) (*DriverImageCatalog, error) {
+	catalog := &DriverImageCatalog{}
+	if err := yaml.UnmarshalStrict([]byte(data), catalog); err != nil {
+		return nil, fmt.Errorf("failed to parse driver image catalog: %v", err)
+	}
+	seen := map[string]bool{}
+	for i, image := range catalog.Images {
+		if image.OS == "" || image.Kernel == "" || image.DriverVersion == "" || image.Image == "" {
+			return nil, fmt.Errorf("driver image catalog entry %d requires os, kernel, driverVersion and image", i)
+		}
+		key := catalogKey(image.OS, image.Kernel, image.DriverVersion, image.DriverType)
+		if seen[key] {
+			return nil, fmt.Errorf("driver image catalog has duplicate entries for os %s, kernel %s and driver version %s",
+				image.OS, image.Kernel, image.DriverVersion)
+		}
+		seen[key] = true
+	}
+	return catalog, nil
+}
+
+// lookup returns the catalog image of the OS, kernel, driver version and driver type
+func (c *DriverImageCatalog) lookup(osName, kernel, driverVersion, driverType string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	key := catalogKey(osName, kernel, driverVersion, driverType)
+	for _, image := range c.Images {
+		if catalogKey(image.OS, image.Kernel, image.DriverVersion, image.DriverType) == key {
+			return image.Image, true
+		}
+	}
+	return "", false
+}
+
+func catalogKey(osName, kernel, driverVersion, driverType string) string {
+	if driverType == "" {
+		driverType = utils.DriverTypeContainer
+	}
+	return strings.Join([]string{osName, strings.TrimSuffix(kernel, "+"), driverVersion, driverType}, "/")
 }
 
 type kmmModule struct {
@@ -151,7 +229,7 @@ func (n *noOpKMMModule) SetNodeVersionLabelAsDesired(ctx context.Context, devCon
 	return nil
 }
 
-func (n *noOpKMMModule) SetBuildConfigMapAsDesired(buildCM *v1.ConfigMap, devConfig *amdv1alpha1.DeviceConfig) error {
+func (n *noOpKMMModule) SetBuildConfigMapAsDesired(ctx context.Context, buildCM *v1.ConfigMap, devConfig *amdv1alpha1.DeviceConfig) error {
 	return nil
 }
 
@@ -159,6 +237,10 @@ func (n *noOpKMMModule) SetKMMModuleAsDesired(ctx context.Context, mod *kmmv1bet
 	return nil
 }
 
+func (n *noOpKMMModule) GetDriverImages(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) ([]DriverImage, error) {
+	return nil, nil
+}
+
 func (km *kmmModule) SetNodeVersionLabelAsDesired(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) error {
 	// for each selected node
 	// put the KMM version label given by CR's driver version
@@ -199,7 +281,7 @@ func (km *kmmModule) SetNodeVersionLabelAsDesired(ctx context.Context, devConfig
 	return nil
 }
 
-func (km *kmmModule) SetBuildConfigMapAsDesired(buildCM *v1.ConfigMap, devConfig *amdv1alpha1.DeviceConfig) error {
+func (km *kmmModule) SetBuildConfigMapAsDesired(ctx context.Context, buildCM *v1.ConfigMap, devConfig *amdv1alpha1.DeviceConfig) error {
 	if buildCM.Data == nil {
 		buildCM.Data = make(map[string]string)
 	}
@@ -216,7 +298,11 @@ func (km *kmmModule) SetBuildConfigMapAsDesired(buildCM *v1.ConfigMap, devConfig
 			}
 		}
 	} else {
-		dockerfile, err := resolveDockerfile(buildCM.Name, devConfig)
+		customTemplates, err := km.getCustomDockerfileTemplates(ctx, devConfig)
+		if err != nil {
+			return err
+		}
+		dockerfile, err := resolveDockerfile(buildCM.Name, devConfig, customTemplates)
 		if err != nil {
 			return err
 		}
@@ -225,6 +311,44 @@ func (km *kmmModule) SetBuildConfigMapAsDesired(buildCM *v1.ConfigMap, devConfig
 	return controllerutil.SetControllerReference(devConfig, buildCM, km.scheme)
 }
 
+// getCustomDockerfileTemplates returns the user supplied Dockerfile templates keyed by distribution name
+func (km *kmmModule) getCustomDockerfileTemplates(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig) (map[string]string, error) {
+	templates := devConfig.Spec.Driver.ImageBuild.DockerfileTemplates
+	if templates == nil {
+		return nil, nil
+	}
+	cm := &v1.ConfigMap{}
+	if err := km.client.Get(ctx, types.NamespacedName{Namespace: devConfig.Namespace, Name: templates.ConfigMap.Name}, cm); err != nil {
+		return nil, fmt.Errorf("failed to get Dockerfile templates ConfigMap %s: %v", templates.ConfigMap.Name, err)
+	}
+	customTemplates := map[string]string{}
+	for _, distro := range templates.Distributions {
+		dockerfile, ok := cm.Data[distro.Name]
+		if !ok {
+			return nil, fmt.Errorf("Dockerfile templates ConfigMap %s has no template for distribution %s", cm.Name, distro.Name)
+		}
+		customTemplates[distro.Name] = dockerfile
+	}
+	return customTemplates, nil
+}
+
+// getDriverImageCatalog returns the precompiled driver image catalog of the DeviceConfig, if any
+func (km *kmmModule) getDriverImageCatalog(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig) (*DriverImageCatalog, error) {
+	catalogRef := devConfig.Spec.Driver.ImageCatalog
+	if catalogRef == nil || catalogRef.Name == "" {
+		return nil, nil
+	}
+	cm := &v1.ConfigMap{}
+	if err := km.client.Get(ctx, types.NamespacedName{Namespace: devConfig.Namespace, Name: catalogRef.Name}, cm); err != nil {
+		return nil, fmt.Errorf("failed to get driver image catalog ConfigMap %s: %v", catalogRef.Name, err)
+	}
+	data, ok := cm.Data[DriverImageCatalogKey]
+	if !ok {
+		return nil, fmt.Errorf("driver image catalog ConfigMap %s has no %s key", cm.Name, DriverImageCatalogKey)
+	}
+	return ParseDriverImageCatalog(data)
+}
+
 var driverLabels = map[string]string{
 	"20.04": "focal",
 	"22.04": "jammy",
@@ -270,13 +394,15 @@ func parseRHELVersion(labels map[string]string, osImage string) string {
 	return ""
 }
 
-func resolveDockerfile(cmName string, devConfig *amdv1alpha1.DeviceConfig) (string, error) {
+func resolveDockerfile(cmName string, devConfig *amdv1alpha1.DeviceConfig, customTemplates map[string]string) (string, error) {
 	splits := strings.SplitN(cmName, "-", -1)
 	osDistro := splits[0]
 	version := splits[1]
-	var dockerfileTemplate string
-	switch osDistro {
-	case "ubuntu":
+	dockerfileTemplate, isCustom := customTemplates[osDistro]
+	switch {
+	case isCustom:
+		// user supplied templates take precedence over the built-in ones
+	case osDistro == "ubuntu":
 		dockerfileTemplate = dockerfileTemplateUbuntu
 		switch devConfig.Spec.Driver.DriverType {
 		case utils.DriverTypeVFPassthrough:
@@ -309,7 +435,7 @@ func resolveDockerfile(cmName string, devConfig *amdv1alpha1.DeviceConfig) (stri
 		if isCIEnvSet && internalUbuntuBaseSet {
 			dockerfileTemplate = strings.Replace(dockerfileTemplate, "$$BASEIMG_REGISTRY/ubuntu:$$VERSION", fmt.Sprintf("%v:$$VERSION", internalUbuntuBaseImage), -1)
 		}
-	case "coreos":
+	case osDistro == "coreos":
 		switch devConfig.Spec.Driver.DriverType {
 		case utils.DriverTypeVFPassthrough:
 			dockerfileTemplate = dockerfileTemplateGIMCoreOS
@@ -321,7 +447,7 @@ func resolveDockerfile(cmName string, devConfig *amdv1alpha1.DeviceConfig) (stri
 				dockerfileTemplate = dockerfileTemplateCoreOSFromSrcImage
 			}
 		}
-	case "sles":
+	case osDistro == "sles":
 		dockerfileTemplate = dockerfileTemplateSLES
 	default:
 		return "", fmt.Errorf("not supported OS: %s", osDistro)
@@ -331,7 +457,7 @@ func resolveDockerfile(cmName string, devConfig *amdv1alpha1.DeviceConfig) (stri
 	if devConfig.Spec.Driver.ImageBuild.BaseImageRegistry != "" {
 		// user-specified registry takes precedence
 		baseImageRegistry = devConfig.Spec.Driver.ImageBuild.BaseImageRegistry
-	} else if osDistro == "sles" {
+	} else if osDistro == "sles" && !isCustom {
 		// if OS == "sles", use default image registry as "registry.suse.com"
 		baseImageRegistry = "registry.suse.com"
 	}
@@ -342,18 +468,22 @@ func resolveDockerfile(cmName string, devConfig *amdv1alpha1.DeviceConfig) (stri
 }
 
 func (km *kmmModule) SetKMMModuleAsDesired(ctx context.Context, mod *kmmv1beta1.Module, devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) error {
-	err := setKMMModuleLoader(ctx, mod, devConfig, km.isOpenShift, nodes)
+	catalog, err := km.getDriverImageCatalog(ctx, devConfig)
+	if err != nil {
+		return fmt.Errorf("failed to set KMM Module: %v", err)
+	}
+	err = setKMMModuleLoader(ctx, mod, devConfig, km.isOpenShift, nodes, catalog)
 	if err != nil {
 		return fmt.Errorf("failed to set KMM Module: %v", err)
 	}
 	return controllerutil.SetControllerReference(devConfig, mod, km.scheme)
 }
 
-func setKMMModuleLoader(ctx context.Context, mod *kmmv1beta1.Module, devConfig *amdv1alpha1.DeviceConfig, isOpenshift bool, nodes *v1.NodeList) error {
+func setKMMModuleLoader(ctx context.Context, mod *kmmv1beta1.Module, devConfig *amdv1alpha1.DeviceConfig, isOpenshift bool, nodes *v1.NodeList, catalog *DriverImageCatalog) error {
 	kmlog := log.FromContext(ctx)
 	kmlog.Info(fmt.Sprintf("isOpenshift %+v", isOpenshift))
 
-	kernelMappings, driversVersion, err := getKernelMappings(kmlog, devConfig, isOpenshift, nodes)
+	kernelMappings, driversVersion, err := getKernelMappings(kmlog, devConfig, isOpenshift, nodes, catalog)
 	if err != nil {
 		return err
 	}
@@ -434,7 +564,31 @@ func setKMMModuleLoader(ctx context.Context, mod *kmmv1beta1.Module, devConfig *
 	return nil
 }
 
-func getKernelMappings(kmlog logr.Logger, devConfig *amdv1alpha1.DeviceConfig, isOpenshift bool, nodes *v1.NodeList) ([]kmmv1beta1.KernelMapping, string, error) {
+// SetKernelMappingImages pins the kernel mappings of the given nodes to the driver images they ran before, e.g. while they
+// are rolled back to their previous driver. These images exist already and are neither built nor signed again.
+// A kernel mapping applies to every node running the kernel, the upgrade manager refuses the rollbacks that would
+// pin another image than the one of the other nodes running the same kernel
+func SetKernelMappingImages(mod *kmmv1beta1.Module, nodes *v1.NodeList, nodeImages map[string]string) {
+	if len(nodeImages) == 0 || nodes == nil {
+		return
+	}
+	kernelImages := map[string]string{}
+	for _, node := range nodes.Items {
+		if image, ok := nodeImages[node.Name]; ok {
+			kernelImages[strings.TrimSuffix(node.Status.NodeInfo.KernelVersion, "+")] = image
+		}
+	}
+	for i := range mod.Spec.ModuleLoader.Container.KernelMappings {
+		km := &mod.Spec.ModuleLoader.Container.KernelMappings[i]
+		if image, ok := kernelImages[km.Literal]; ok {
+			km.ContainerImage = image
+			km.Build = nil
+			km.Sign = nil
+		}
+	}
+}
+
+func getKernelMappings(kmlog logr.Logger, devConfig *amdv1alpha1.DeviceConfig, isOpenshift bool, nodes *v1.NodeList, catalog *DriverImageCatalog) ([]kmmv1beta1.KernelMapping, string, error) {
 
 	inTreeModuleToRemove := ""
 
@@ -445,7 +599,7 @@ func getKernelMappings(kmlog logr.Logger, devConfig *amdv1alpha1.DeviceConfig, i
 	kmSet := map[string]bool{}
 	var driversVersion string
 	for _, node := range nodes.Items {
-		km, ver, err := getKM(devConfig, node, inTreeModuleToRemove, isOpenshift)
+		km, ver, err := getKM(devConfig, node, inTreeModuleToRemove, isOpenshift, catalog)
 		if err != nil {
 			kmlog.Error(err, fmt.Sprintf("error constructing a kernel mapping for node: %s", node.Name))
 			continue
@@ -463,7 +617,63 @@ func getKernelMappings(kmlog logr.Logger, devConfig *amdv1alpha1.DeviceConfig, i
 	return kernelMappings, driversVersion, nil
 }
 
-func getKM(devConfig *amdv1alpha1.DeviceConfig, node v1.Node, inTreeModuleToRemove string, isOpenShift bool) (kmmv1beta1.KernelMapping, string, error) {
+// GetDriverImages returns the distinct driver images the kernel mappings of the selected nodes refer to
+func (km *kmmModule) GetDriverImages(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig, nodes *v1.NodeList) ([]DriverImage, error) {
+	catalog, err := km.getDriverImageCatalog(ctx, devConfig)
+	if err != nil {
+		return nil, err
+	}
+	return getDriverImages(log.FromContext(ctx), devConfig, km.isOpenShift, nodes, catalog)
+}
+
+func getDriverImages(kmlog logr.Logger, devConfig *amdv1alpha1.DeviceConfig, isOpenshift bool, nodes *v1.NodeList, catalog *DriverImageCatalog) ([]DriverImage, error) {
+	kernelMappings, _, err := getKernelMappings(kmlog, devConfig, isOpenshift, nodes, catalog)
+	if err != nil {
+		return nil, err
+	}
+	kmByKernel := map[string]kmmv1beta1.KernelMapping{}
+	for _, km := range kernelMappings {
+		kmByKernel[km.Literal] = km
+	}
+
+	images := []DriverImage{}
+	seen := map[string]bool{}
+	for _, node := range nodes.Items {
+		kernel := strings.TrimSuffix(node.Status.NodeInfo.KernelVersion, "+")
+		km, ok := kmByKernel[kernel]
+		// precompiled catalog images are not built in cluster
+		if !ok || km.Build == nil || seen[kernel] {
+			continue
+		}
+		seen[kernel] = true
+		osName, err := GetOSName(node, devConfig)
+		if err != nil {
+			continue
+		}
+		driversVersion := ""
+		for _, arg := range km.Build.BuildArgs {
+			if arg.Name == "DRIVERS_VERSION" {
+				driversVersion = arg.Value
+			}
+		}
+		images = append(images, DriverImage{
+			OSName:         osName,
+			KernelVersion:  kernel,
+			DriverVersion:  driversVersion,
+			Image:          strings.ReplaceAll(km.ContainerImage, "${KERNEL_FULL_VERSION}", kernel),
+			ContainerImage: km.ContainerImage,
+		})
+	}
+	sort.Slice(images, func(i, j int) bool {
+		if images[i].OSName != images[j].OSName {
+			return images[i].OSName < images[j].OSName
+		}
+		return images[i].KernelVersion < images[j].KernelVersion
+	})
+	return images, nil
+}
+
+func getKM(devConfig *amdv1alpha1.DeviceConfig, node v1.Node, inTreeModuleToRemove string, isOpenShift bool, catalog *DriverImageCatalog) (kmmv1beta1.KernelMapping, string, error) {
 	driversVersion := devConfig.Spec.Driver.Version
 	driversImage := devConfig.Spec.Driver.Image
 	var err error
@@ -518,6 +728,23 @@ func getKM(devConfig *amdv1alpha1.DeviceConfig, node v1.Node, inTreeModuleToRemo
 		}
 	}
 
+	// trim suffix "+" to handle the dirty build kernel version
+	// e.g., "5.15.0-76-generic+"
+	// on KMM side it is trimming the suffix "+" to read kernel mapping
+	// here we need to also trim the suffix "+"
+	// to make sure the kernel version in node info matches the kernel mapping in KMM
+	kernelLiteral := strings.TrimSuffix(node.Status.NodeInfo.KernelVersion, "+")
+
+	// precompiled images from the catalog are used as is, they are expected to be signed already
+	if catalogImage, ok := catalog.lookup(osName, kernelLiteral, driversVersion, devConfig.Spec.Driver.DriverType); ok {
+		return kmmv1beta1.KernelMapping{
+			Literal:              kernelLiteral,
+			ContainerImage:       catalogImage,
+			InTreeModuleToRemove: inTreeModuleToRemove,
+			RegistryTLS:          registryTLS,
+		}, driversVersion, nil
+	}
+
 	var kmmSign *kmmv1beta1.Sign
 	if devConfig.Spec.Driver.ImageSign.KeySecret != nil &&
 		devConfig.Spec.Driver.ImageSign.CertSecret != nil {
@@ -613,13 +840,6 @@ func getKM(devConfig *amdv1alpha1.DeviceConfig, node v1.Node, inTreeModuleToRemo
 		)
 	}
 
-	// trim suffix "+" to handle the dirty build kernel version
-	// e.g., "5.15.0-76-generic+"
-	// on KMM side it is trimming the suffix "+" to read kernel mapping
-	// here we need to also trim the suffix "+"
-	// to make sure the kernel version in node info matches the kernel mapping in KMM
-	kernelLiteral := strings.TrimSuffix(node.Status.NodeInfo.KernelVersion, "+")
-
 	return kmmv1beta1.KernelMapping{
 		Literal:              kernelLiteral,
 		ContainerImage:       driversImage,
@@ -650,6 +870,24 @@ func GetCMName(osName string, devCfg *amdv1alpha1.DeviceConfig) string {
 func GetOSName(node v1.Node, devCfg *amdv1alpha1.DeviceConfig) (string, error) {
 	osImageStr := strings.ToLower(node.Status.NodeInfo.OSImage)
 
+	// user registered distributions are checked before the built-in ones
+	if templates := devCfg.Spec.Driver.ImageBuild.DockerfileTemplates; templates != nil {
+		for _, distro := range templates.Distributions {
+			re, err := regexp.Compile(distro.OSImageRegex)
+			if err != nil {
+				return "", fmt.Errorf("invalid osImageRegex of distribution %s: %v", distro.Name, err)
+			}
+			matches := re.FindStringSubmatch(osImageStr)
+			if matches == nil {
+				continue
+			}
+			if len(matches) < 2 || matches[1] == "" || strings.Contains(matches[1], "-") {
+				return "", fmt.Errorf("osImageRegex of distribution %s must capture a non-empty OS version without '-' in its first group, OS: %s", distro.Name, osImageStr)
+			}
+			return distro.Name + "-" + matches[1], nil
+		}
+	}
+
 	// sort the key of cmNameMappers
 	// make sure in the given OS string, coreos was checked before all other types of RHEL string
 	keys := make([]string, 0, len(cmNameMappers))
@@ -36,6 +36,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"strings"
 
 	//"gopkg.in/yaml.v3"
 	"os"
@@ -139,7 +140,7 @@ var _ = Describe("setKMMModuleLoader", func() {
 			},
 		}
 
-		err = setKMMModuleLoader(context.TODO(), &mod, &input, false, testNodeList)
+		err = setKMMModuleLoader(context.TODO(), &mod, &input, false, testNodeList, nil)
 
 		Expect(err).To(BeNil())
 		Expect(mod).To(Equal(expectedMod))
@@ -213,7 +214,7 @@ var _ = Describe("setKMMModuleLoader", func() {
 			},
 		}
 
-		err = setKMMModuleLoader(context.TODO(), &mod, &input, false, testNodeList)
+		err = setKMMModuleLoader(context.TODO(), &mod, &input, false, testNodeList, nil)
 
 		Expect(err).To(BeNil())
 		Expect(mod).To(Equal(expectedMod))
@@ -663,7 +664,7 @@ var _ = Describe("getKernelMappings", func() {
 		logger := logr.New(nil)
 		for _, tc := range testGetKernelMappingsTestCases {
 			fmt.Printf("testing %v\n", tc.tcName)
-			km, driverVersion, err := getKernelMappings(logger, &testGetKernelMappingsDeviceConfig, false, &tc.nodeList)
+			km, driverVersion, err := getKernelMappings(logger, &testGetKernelMappingsDeviceConfig, false, &tc.nodeList, nil)
 			Expect(err != nil).To(Equal(tc.expectError))
 			if !reflect.DeepEqual(km, tc.expectKernelMapping) {
 				fmt.Printf("expect kernel mapping %+v \nbut got %+v\n", tc.expectKernelMapping, km)
@@ -678,6 +679,126 @@ var _ = Describe("getKernelMappings", func() {
 		}
 	})
 
+	It("test getDriverImages", func() {
+		logger := logr.New(nil)
+		newNode := func(name, kernel, osImage string) v1.Node {
+			return v1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Status: v1.NodeStatus{
+					NodeInfo: v1.NodeSystemInfo{KernelVersion: kernel, OSImage: osImage},
+				},
+			}
+		}
+		nodes := &v1.NodeList{Items: []v1.Node{
+			newNode("node-a", "6.8.0-40-generic", "Ubuntu 24.04.1 LTS"),
+			newNode("node-b", "5.15.0-40-generic+", "Ubuntu 22.04.3 LTS"),
+			newNode("node-c", "5.15.0-40-generic", "Ubuntu 22.04.3 LTS"),
+		}}
+		images, err := getDriverImages(logger, &testGetKernelMappingsDeviceConfig, false, nodes, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(images).To(Equal([]DriverImage{
+			{
+				OSName:         "ubuntu-22.04",
+				KernelVersion:  "5.15.0-40-generic",
+				DriverVersion:  "6.3",
+				Image:          "test.repo/driverImage:ubuntu-22.04-5.15.0-40-generic-6.3",
+				ContainerImage: "test.repo/driverImage:ubuntu-22.04-${KERNEL_FULL_VERSION}-6.3",
+			},
+			{
+				OSName:         "ubuntu-24.04",
+				KernelVersion:  "6.8.0-40-generic",
+				DriverVersion:  "6.3",
+				Image:          "test.repo/driverImage:ubuntu-24.04-6.8.0-40-generic-6.3",
+				ContainerImage: "test.repo/driverImage:ubuntu-24.04-${KERNEL_FULL_VERSION}-6.3",
+			},
+		}))
+
+		_, err = getDriverImages(logger, &testGetKernelMappingsDeviceConfig, false, &v1.NodeList{}, nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("test getKernelMappings with driver image catalog", func() {
+		logger := logr.New(nil)
+		catalog, err := ParseDriverImageCatalog(`
+images:
+- os: ubuntu-22.04
+  kernel: 5.15.0-40-generic
+  driverVersion: "6.3"
+  image: mirror.local/amdgpu-driver:ubuntu-22.04-5.15.0-40-generic-6.3
+- os: ubuntu-24.04
+  kernel: 6.8.0-40-generic
+  driverVersion: "6.2"
+  image: mirror.local/amdgpu-driver:ubuntu-24.04-6.8.0-40-generic-6.2
+`)
+		Expect(err).ToNot(HaveOccurred())
+		nodes := &testGetKernelMappingsTestCases[1].nodeList
+		km, driverVersion, err := getKernelMappings(logger, &testGetKernelMappingsDeviceConfig, false, nodes, catalog)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(driverVersion).To(Equal("6.3"))
+		Expect(km).To(HaveLen(2))
+		// the catalog image matches the first node, the second node driver version differs and is built
+		Expect(km[0].ContainerImage).To(Equal("mirror.local/amdgpu-driver:ubuntu-22.04-5.15.0-40-generic-6.3"))
+		Expect(km[0].Build).To(BeNil())
+		Expect(km[0].Sign).To(BeNil())
+		Expect(km[1]).To(Equal(testGetKernelMappingsTestCases[1].expectKernelMapping[1]))
+
+		// catalog images are not prebuilt
+		images, err := getDriverImages(logger, &testGetKernelMappingsDeviceConfig, false, nodes, catalog)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(images).To(HaveLen(1))
+		Expect(images[0].KernelVersion).To(Equal("6.8.0-40-generic"))
+	})
+
+	It("test SetKernelMappingImages", func() {
+		nodes := &testGetKernelMappingsTestCases[1].nodeList
+		mod := kmmv1beta1.Module{}
+		km, _, err := getKernelMappings(logr.New(nil), &testGetKernelMappingsDeviceConfig, false, nodes, nil)
+		Expect(err).ToNot(HaveOccurred())
+		mod.Spec.ModuleLoader.Container.KernelMappings = km
+		expected := mod.DeepCopy()
+
+		// no pinned images leaves the kernel mappings untouched
+		SetKernelMappingImages(&mod, nodes, nil)
+		Expect(mod).To(Equal(*expected))
+
+		pinnedNode := nodes.Items[0]
+		SetKernelMappingImages(&mod, nodes, map[string]string{
+			pinnedNode.Name: "test.repo/driverImage:previous-6.2",
+		})
+		kernelMappings := mod.Spec.ModuleLoader.Container.KernelMappings
+		Expect(kernelMappings[0].Literal).To(Equal(strings.TrimSuffix(pinnedNode.Status.NodeInfo.KernelVersion, "+")))
+		Expect(kernelMappings[0].ContainerImage).To(Equal("test.repo/driverImage:previous-6.2"))
+		Expect(kernelMappings[0].Build).To(BeNil())
+		Expect(kernelMappings[0].Sign).To(BeNil())
+		Expect(kernelMappings[1]).To(Equal(expected.Spec.ModuleLoader.Container.KernelMappings[1]))
+	})
+
+	It("test ParseDriverImageCatalog", func() {
+		testCases := []struct {
+			data        string
+			expectError bool
+		}{
+			{data: "images: []"},
+			{data: "images:\n- os: ubuntu-22.04\n  kernel: 5.15.0-40-generic\n  driverVersion: \"6.3\"\n  driverType: vf-passthrough\n  image: a:b"},
+			{data: "images:\n- os: ubuntu-22.04\n  kernel: 5.15.0-40-generic\n  image: a:b", expectError: true},
+			{data: "images:\n- os: ubuntu-22.04\n  kernel: 5.15.0-40-generic\n  driverVersion: \"6.3\"\n  image: a:b\n" +
+				"- os: ubuntu-22.04\n  kernel: 5.15.0-40-generic+\n  driverVersion: \"6.3\"\n  image: a:c", expectError: true},
+			{data: "images:\n- os: ubuntu-22.04\n  kernal: 5.15.0-40-generic", expectError: true},
+		}
+		for _, tc := range testCases {
+			_, err := ParseDriverImageCatalog(tc.data)
+			Expect(err != nil).To(Equal(tc.expectError), tc.data)
+		}
+
+		catalog, err := ParseDriverImageCatalog(testCases[1].data)
+		Expect(err).ToNot(HaveOccurred())
+		_, ok := catalog.lookup("ubuntu-22.04", "5.15.0-40-generic", "6.3", "")
+		Expect(ok).To(BeFalse())
+		image, ok := catalog.lookup("ubuntu-22.04", "5.15.0-40-generic+", "6.3", "vf-passthrough")
+		Expect(ok).To(BeTrue())
+		Expect(image).To(Equal("a:b"))
+	})
+
 	It("test parseRHELVersion", func() {
 		testCases := []struct {
 			labels   map[string]string
@@ -749,7 +870,7 @@ var _ = Describe("resolveDockerfile", func() {
 					Driver: amdv1alpha1.DriverSpec{},
 				},
 			}
-			dockerfile, err := resolveDockerfile(tc.cmName, input)
+			dockerfile, err := resolveDockerfile(tc.cmName, input, nil)
 			Expect(err).To(BeNil())
 			Expect(dockerfile).To(ContainSubstring(tc.expectedImageUrl))
 		}
@@ -773,7 +894,7 @@ var _ = Describe("resolveDockerfile", func() {
 					},
 				},
 			}
-			dockerfile, err := resolveDockerfile(tc.cmName, input)
+			dockerfile, err := resolveDockerfile(tc.cmName, input, nil)
 			Expect(err).To(BeNil())
 			Expect(dockerfile).To(ContainSubstring(tc.expectedImageUrl))
 			Expect(dockerfile).NotTo(ContainSubstring("docker.io"))
@@ -786,8 +907,81 @@ var _ = Describe("resolveDockerfile", func() {
 				Driver: amdv1alpha1.DriverSpec{},
 			},
 		}
-		_, err := resolveDockerfile("unsupported-os", input)
+		_, err := resolveDockerfile("unsupported-os", input, nil)
 		Expect(err).NotTo(BeNil())
 		Expect(err.Error()).To(ContainSubstring("not supported OS"))
 	})
+	It("should render the user supplied templates", func() {
+		input := &amdv1alpha1.DeviceConfig{
+			Spec: amdv1alpha1.DeviceConfigSpec{
+				Driver: amdv1alpha1.DriverSpec{
+					ImageBuild: amdv1alpha1.ImageBuildSpec{
+						BaseImageRegistry: "example-image-registry.com",
+					},
+				},
+			},
+		}
+		customTemplates := map[string]string{
+			"rocky": "FROM $$BASEIMG_REGISTRY/rockylinux:$$VERSION",
+			"sles":  "FROM $$BASEIMG_REGISTRY/sles:$$VERSION",
+		}
+		dockerfile, err := resolveDockerfile("rocky-9.4-dc-ns", input, customTemplates)
+		Expect(err).To(BeNil())
+		Expect(dockerfile).To(Equal("FROM example-image-registry.com/rockylinux:9.4"))
+		// user supplied templates replace the built-in ones
+		dockerfile, err = resolveDockerfile("sles-15.6-dc-ns", input, customTemplates)
+		Expect(err).To(BeNil())
+		Expect(dockerfile).To(Equal("FROM example-image-registry.com/sles:15.6"))
+	})
+})
+
+var _ = Describe("GetOSName", func() {
+	newNode := func(osImage string) v1.Node {
+		return v1.Node{Status: v1.NodeStatus{NodeInfo: v1.NodeSystemInfo{OSImage: osImage}}}
+	}
+	devCfg := &amdv1alpha1.DeviceConfig{
+		Spec: amdv1alpha1.DeviceConfigSpec{
+			Driver: amdv1alpha1.DriverSpec{
+				ImageBuild: amdv1alpha1.ImageBuildSpec{
+					DockerfileTemplates: &amdv1alpha1.DockerfileTemplatesSpec{
+						ConfigMap: v1.LocalObjectReference{Name: "dockerfiles"},
+						Distributions: []amdv1alpha1.DockerfileTemplateDistribution{
+							{Name: "rocky", OSImageRegex: `rocky linux (\d+\.\d+)`},
+							{Name: "rhel", OSImageRegex: `red hat enterprise linux (\d+\.\d+)`},
+							{Name: "debian", OSImageRegex: `debian gnu/linux ([0-9a-z]+)`},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	It("should match the user registered distributions", func() {
+		testCases := []struct {
+			osImage  string
+			expected string
+		}{
+			{"Rocky Linux 9.4 (Blue Onyx)", "rocky-9.4"},
+			{"Red Hat Enterprise Linux 9.4 (Plow)", "rhel-9.4"},
+			{"Debian GNU/Linux 12 (bookworm)", "debian-12"},
+			// built-in distributions are still supported
+			{"Ubuntu 22.04.3 LTS", "ubuntu-22.04"},
+		}
+		for _, tc := range testCases {
+			osName, err := GetOSName(newNode(tc.osImage), devCfg)
+			Expect(err).To(BeNil())
+			Expect(osName).To(Equal(tc.expected))
+		}
+	})
+
+	It("should reject versions that cannot be used in the OS name", func() {
+		cfg := devCfg.DeepCopy()
+		cfg.Spec.Driver.ImageBuild.DockerfileTemplates.Distributions = []amdv1alpha1.DockerfileTemplateDistribution{
+			{Name: "azurelinux", OSImageRegex: `azure linux ([\d.-]+)`},
+		}
+		_, err := GetOSName(newNode("Azure Linux 3.0-20240101"), cfg)
+		Expect(err).NotTo(BeNil())
+		_, err = GetOSName(newNode("Alpine Linux v3.20"), cfg)
+		Expect(err).NotTo(BeNil())
+	})
 })
@@ -57,18 +57,33 @@ func (m *MockKMMModuleAPI) EXPECT() *MockKMMModuleAPIMockRecorder {
 	return m.recorder
 }
 
+// GetDriverImages mocks base method.
+func (m *MockKMMModuleAPI) GetDriverImages(ctx context.Context, devConfig *v1alpha1.DeviceConfig, nodes *v1.NodeList) ([]DriverImage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDriverImages", ctx, devConfig, nodes)
+	ret0, _ := ret[0].([]DriverImage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDriverImages indicates an expected call of GetDriverImages.
+func (mr *MockKMMModuleAPIMockRecorder) GetDriverImages(ctx, devConfig, nodes any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDriverImages", reflect.TypeOf((*MockKMMModuleAPI)(nil).GetDriverImages), ctx, devConfig, nodes)
+}
+
 // SetBuildConfigMapAsDesired mocks base method.
-func (m *MockKMMModuleAPI) SetBuildConfigMapAsDesired(buildCM *v1.ConfigMap, devConfig *v1alpha1.DeviceConfig) error {
+func (m *MockKMMModuleAPI) SetBuildConfigMapAsDesired(ctx context.Context, buildCM *v1.ConfigMap, devConfig *v1alpha1.DeviceConfig) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SetBuildConfigMapAsDesired", buildCM, devConfig)
+	ret := m.ctrl.Call(m, "SetBuildConfigMapAsDesired", ctx, buildCM, devConfig)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // SetBuildConfigMapAsDesired indicates an expected call of SetBuildConfigMapAsDesired.
-func (mr *MockKMMModuleAPIMockRecorder) SetBuildConfigMapAsDesired(buildCM, devConfig any) *gomock.Call {
+func (mr *MockKMMModuleAPIMockRecorder) SetBuildConfigMapAsDesired(ctx, buildCM, devConfig any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetBuildConfigMapAsDesired", reflect.TypeOf((*MockKMMModuleAPI)(nil).SetBuildConfigMapAsDesired), buildCM, devConfig)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetBuildConfigMapAsDesired", reflect.TypeOf((*MockKMMModuleAPI)(nil).SetBuildConfigMapAsDesired), ctx, buildCM, devConfig)
 }
 
 // SetKMMModuleAsDesired mocks base method.
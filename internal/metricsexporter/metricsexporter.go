@@ -53,10 +53,10 @@ import (
 )
 
 const (
-	defaultMetricsExporterImage       = "docker.io/rocm/device-metrics-exporter:v0.0.1"
+	defaultMetricsExporterImage       = utils.DefaultMetricsExporterImage
 	defaultKubeRbacProxyImage         = "quay.io/brancz/kube-rbac-proxy:v0.18.1"
-	defaultInitContainerImage         = "busybox:1.36"
-	servicePort                 int32 = 5000
+	defaultInitContainerImage         = utils.DefaultInitContainerImage
+	servicePort                 int32 = utils.DefaultMetricsExporterPort
 	nobodyUser                        = 65532
 	ExporterName                      = "metrics-exporter"
 	KubeRbacName                      = "kube-rbac-proxy"
@@ -505,6 +505,9 @@ func (nl *metricsExporter) SetMetricsExporterAsDesired(ds *appsv1.DaemonSet, dev
 		})
 	}
 
+	if err := utils.ApplyPodTemplateOverrides(ds, devConfig.Spec.MetricsExporter.PodTemplateOverrides); err != nil {
+		return err
+	}
 	return controllerutil.SetControllerReference(devConfig, ds, nl.scheme)
 }
 
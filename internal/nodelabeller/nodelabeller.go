@@ -34,6 +34,7 @@ package nodelabeller
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	appsv1 "k8s.io/api/apps/v1"
@@ -53,10 +54,8 @@ const (
 	rocmUbiNodeLabellerRepo     = "rocm/k8s-node-labeller"
 	defaultNodeLabellerImage    = "rocm/k8s-device-plugin:labeller-latest"
 	defaultUbiNodeLabellerImage = "rocm/k8s-node-labeller:rhubi-latest"
-	containerCommandArgs        = "./k8s-node-labeller -vram -cu-count -simd-count -device-id -family -product-name -driver-version"
-	vfPassthroughCommandArgs    = "./k8s-node-labeller -device-id -driver-version"
-	pfPassthroughCommandArgs    = "./k8s-node-labeller -device-id"
-	defaultInitContainerImage   = "busybox:1.36"
+	nodeLabellerCommand         = "./k8s-node-labeller"
+	defaultInitContainerImage   = utils.DefaultInitContainerImage
 	defaultBlacklistFileName    = "blacklist-amdgpu.conf"
 	openShiftBlacklistFileName  = "blacklist-amdgpu-by-operator.conf"
 )
@@ -166,19 +165,7 @@ func (nl *nodeLabeller) SetNodeLabellerAsDesired(ds *appsv1.DaemonSet, devConfig
 		imagePullSecrets = append(imagePullSecrets, *devConfig.Spec.DevicePlugin.ImageRegistrySecret)
 	}
 	matchLabels := map[string]string{"daemonset-name": devConfig.Name}
-	commandArgs := containerCommandArgs
-	if devConfig.Spec.Driver.DriverType == utils.DriverTypeVFPassthrough {
-		commandArgs = vfPassthroughCommandArgs
-	} else if devConfig.Spec.Driver.DriverType == utils.DriverTypePFPassthrough {
-		commandArgs = pfPassthroughCommandArgs
-	}
-
-	nodeLabellerArguments := devConfig.Spec.DevicePlugin.NodeLabellerArguments
-	for _, label := range nodeLabellerArguments {
-		commandArgs += " -" + label
-	}
-
-	command := []string{"-c", commandArgs}
+	command := []string{"-c", getNodeLabellerCommand(devConfig)}
 
 	ds.Spec = appsv1.DaemonSetSpec{
 		Selector: &metav1.LabelSelector{MatchLabels: matchLabels},
@@ -243,10 +230,38 @@ func (nl *nodeLabeller) SetNodeLabellerAsDesired(ds *appsv1.DaemonSet, devConfig
 		ds.Spec.Template.Spec.Tolerations = nil
 	}
 
+	if err := utils.ApplyPodTemplateOverrides(ds, devConfig.Spec.DevicePlugin.NodeLabellerPodTemplateOverrides); err != nil {
+		return err
+	}
 	return controllerutil.SetControllerReference(devConfig, ds, nl.scheme)
 
 }
 
+func getNodeLabellerCommand(devConfig *amdv1alpha1.DeviceConfig) string {
+	args := []string{nodeLabellerCommand}
+	for _, kind := range utils.GetNodeLabellerKinds(devConfig) {
+		args = append(args, "-"+kind)
+	}
+	return strings.Join(args, " ")
+}
+
+// GetLabelKinds returns the kinds of labels the node labeller DaemonSet currently applies
+func GetLabelKinds(ds *appsv1.DaemonSet) []string {
+	kinds := []string{}
+	if ds == nil || len(ds.Spec.Template.Spec.Containers) == 0 {
+		return kinds
+	}
+	allKinds := utils.GetAllNodeLabellerKinds()
+	for _, arg := range ds.Spec.Template.Spec.Containers[0].Args {
+		for _, field := range strings.Fields(arg) {
+			if kind, ok := strings.CutPrefix(field, "-"); ok && slices.Contains(allKinds, kind) {
+				kinds = append(kinds, kind)
+			}
+		}
+	}
+	return kinds
+}
+
 func (nl *nodeLabeller) getNodeLabellerImage(devConfig *amdv1alpha1.DeviceConfig) string {
 	if devConfig.Spec.DevicePlugin.NodeLabellerImage != "" {
 		// if the node labeller image is clearly specified, directly use the user provided image
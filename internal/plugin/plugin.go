@@ -35,6 +35,7 @@ package plugin
 import (
 	"fmt"
 	"sort"
+	"strings"
 
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
@@ -54,10 +55,12 @@ const (
 	// check the device plugin image tags here: https://hub.docker.com/r/rocm/k8s-device-plugin/tags
 	defaultDevicePluginImage    = "rocm/k8s-device-plugin:latest"
 	defaultUbiDevicePluginImage = "rocm/k8s-device-plugin:rhubi-latest"
-	defaultInitContainerImage   = "busybox:1.36"
+	defaultInitContainerImage   = utils.DefaultInitContainerImage
+	// the device plugin binary in the working directory of the device plugin image
+	devicePluginCommand = "./k8s-device-plugin"
 
 	// check the DRA driver image tags here: https://hub.docker.com/r/rocm/k8s-gpu-dra-driver/tags
-	defaultDRADriverImage = "rocm/k8s-gpu-dra-driver:latest"
+	defaultDRADriverImage = utils.DefaultDRADriverImage
 )
 
 //go:generate mockgen -source=plugin.go -package=plugin -destination=mock_plugin.go DevicePluginAPI
@@ -105,26 +108,7 @@ func (dp *devicePlugin) SetDevicePluginAsDesired(ds *appsv1.DaemonSet, devConfig
 		kubeletDevicePluginsDir = devConfig.Spec.DevicePlugin.KubeletSocketPath
 	}
 
-	commandArgs := "./k8s-device-plugin -logtostderr=true -stderrthreshold=INFO -v=5 -pulse=30"
-
-	devicePluginArguments := devConfig.Spec.DevicePlugin.DevicePluginArguments
-
-	// Default resource_naming_strategy to "mixed" for PF and VF passthrough if not set
-	if _, exists := devicePluginArguments["resource_naming_strategy"]; !exists {
-		if devConfig.Spec.Driver.DriverType == utils.DriverTypePFPassthrough ||
-			devConfig.Spec.Driver.DriverType == utils.DriverTypeVFPassthrough {
-			if devicePluginArguments == nil {
-				devicePluginArguments = make(map[string]string)
-			}
-			devicePluginArguments["resource_naming_strategy"] = "mixed"
-		}
-	}
-
-	for key, val := range devicePluginArguments {
-		commandArgs += " -" + key + "=" + val
-	}
-
-	command := []string{"sh", "-c", commandArgs}
+	args := getDevicePluginArgs(devConfig)
 
 	nodeSelector := map[string]string{}
 	for key, val := range devConfig.Spec.Selector {
@@ -133,6 +117,27 @@ func (dp *devicePlugin) SetDevicePluginAsDesired(ds *appsv1.DaemonSet, devConfig
 	if utils.ShouldUseKMM(devConfig) {
 		nodeSelector[kmmLabels.GetKernelModuleReadyNodeLabel(devConfig.Namespace, devConfig.Name)] = ""
 	}
+	var affinity *v1.Affinity
+	if devConfig.Spec.DRADriver.IsMigrationEnabled() {
+		// nodes migrated to the DRA driver are not served by the device plugin anymore
+		affinity = &v1.Affinity{
+			NodeAffinity: &v1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+					NodeSelectorTerms: []v1.NodeSelectorTerm{
+						{
+							MatchExpressions: []v1.NodeSelectorRequirement{
+								{
+									Key:      utils.GPUResourceDriverLabel,
+									Operator: v1.NodeSelectorOpNotIn,
+									Values:   []string{utils.GPUResourceDriverDRA},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
 	imagePullSecrets := []v1.LocalObjectReference{}
 	// Add global secrets first
 	if len(devConfig.Spec.CommonConfig.ImageRegistrySecrets) > 0 {
@@ -193,7 +198,7 @@ func (dp *devicePlugin) SetDevicePluginAsDesired(ds *appsv1.DaemonSet, devConfig
 						},
 						Name:            "device-plugin",
 						WorkingDir:      "/root",
-						Command:         command,
+						Command:         []string{"sh", "-c", devicePluginCommand + " " + strings.Join(args, " ")},
 						Image:           devicePluginImage,
 						SecurityContext: &v1.SecurityContext{Privileged: ptr.To(true)},
 						VolumeMounts: []v1.VolumeMount{
@@ -215,6 +220,7 @@ func (dp *devicePlugin) SetDevicePluginAsDesired(ds *appsv1.DaemonSet, devConfig
 				ImagePullSecrets:   imagePullSecrets,
 				PriorityClassName:  "system-node-critical",
 				NodeSelector:       nodeSelector,
+				Affinity:           affinity,
 				ServiceAccountName: "amd-gpu-operator-kmm-device-plugin",
 				Volumes: []v1.Volume{
 					{
@@ -274,9 +280,38 @@ func (dp *devicePlugin) SetDevicePluginAsDesired(ds *appsv1.DaemonSet, devConfig
 	} else {
 		ds.Spec.Template.Spec.Tolerations = nil
 	}
+	if err := utils.ApplyPodTemplateOverrides(ds, devConfig.Spec.DevicePlugin.DevicePluginPodTemplateOverrides); err != nil {
+		return err
+	}
 	return controllerutil.SetControllerReference(devConfig, ds, dp.scheme)
 }
 
+// getDevicePluginArgs renders the device plugin config and arguments as the device plugin command line arguments
+func getDevicePluginArgs(devConfig *amdv1alpha1.DeviceConfig) []string {
+	cfg := devConfig.Spec.DevicePlugin.DevicePluginConfig
+	pulse := int32(utils.DefaultPulseSeconds)
+	if cfg != nil && cfg.HealthCheckPulseSeconds != nil {
+		pulse = *cfg.HealthCheckPulseSeconds
+	}
+	args := []string{"-logtostderr=true", "-stderrthreshold=INFO", "-v=5", fmt.Sprintf("-%s=%d", utils.PulseFlag, pulse)}
+
+	if strategy := utils.GetResourceNamingStrategy(devConfig); strategy != "" {
+		args = append(args, fmt.Sprintf("-%s=%s", utils.ResourceNamingStrategyFlag, strategy))
+	}
+	// remaining deprecated arguments, sorted to keep the pod template stable
+	keys := []string{}
+	for key := range devConfig.Spec.DevicePlugin.DevicePluginArguments {
+		if key != utils.ResourceNamingStrategyFlag {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, fmt.Sprintf("-%s=%s", key, devConfig.Spec.DevicePlugin.DevicePluginArguments[key]))
+	}
+	return args
+}
+
 func (dp *devicePlugin) SetDRADriverAsDesired(ds *appsv1.DaemonSet, devConfig *amdv1alpha1.DeviceConfig) error {
 	if ds == nil {
 		return fmt.Errorf("daemon set is not initialized, zero pointer")
@@ -309,6 +344,10 @@ func (dp *devicePlugin) SetDRADriverAsDesired(ds *appsv1.DaemonSet, devConfig *a
 	if utils.ShouldUseKMM(devConfig) {
 		nodeSelector[kmmLabels.GetKernelModuleReadyNodeLabel(devConfig.Namespace, devConfig.Name)] = ""
 	}
+	if devConfig.Spec.DRADriver.IsMigrationEnabled() {
+		// during the migration only the nodes switched from the device plugin are served by the DRA driver
+		nodeSelector[utils.GPUResourceDriverLabel] = utils.GPUResourceDriverDRA
+	}
 
 	imagePullSecrets := []v1.LocalObjectReference{}
 	// Add global secrets first
@@ -483,5 +522,8 @@ func (dp *devicePlugin) SetDRADriverAsDesired(ds *appsv1.DaemonSet, devConfig *a
 	} else {
 		ds.Spec.Template.Spec.Tolerations = nil
 	}
+	if err := utils.ApplyPodTemplateOverrides(ds, devConfig.Spec.DRADriver.PodTemplateOverrides); err != nil {
+		return err
+	}
 	return controllerutil.SetControllerReference(devConfig, ds, dp.scheme)
 }
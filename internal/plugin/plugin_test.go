@@ -41,6 +41,7 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
@@ -176,3 +177,133 @@ var _ = Describe("SetDevicePluginAsDesired", func() {
 		Expect(err.Error()).To(ContainSubstring("daemon set is not initialized"))
 	})
 })
+
+var _ = Describe("DRA migration", func() {
+	var dp *devicePlugin
+	enable := true
+
+	devConfig := &amdv1alpha1.DeviceConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-config",
+			Namespace: "test-namespace",
+		},
+		Spec: amdv1alpha1.DeviceConfigSpec{
+			DRADriver: amdv1alpha1.DRADriverSpec{
+				Enable:    &enable,
+				Migration: &amdv1alpha1.DRAMigrationSpec{Enable: &enable},
+			},
+		},
+	}
+
+	BeforeEach(func() {
+		dp = &devicePlugin{
+			client:      nil,
+			scheme:      scheme,
+			isOpenShift: false,
+		}
+	})
+
+	It("should keep the device plugin off the migrated nodes", func() {
+		ds := &appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-config-device-plugin", Namespace: "test-namespace"},
+		}
+
+		err := dp.SetDevicePluginAsDesired(ds, devConfig)
+		Expect(err).To(BeNil())
+
+		affinity := ds.Spec.Template.Spec.Affinity
+		Expect(affinity).NotTo(BeNil())
+		terms := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+		Expect(terms).To(HaveLen(1))
+		Expect(terms[0].MatchExpressions).To(ContainElement(v1.NodeSelectorRequirement{
+			Key:      utils.GPUResourceDriverLabel,
+			Operator: v1.NodeSelectorOpNotIn,
+			Values:   []string{utils.GPUResourceDriverDRA},
+		}))
+	})
+
+	It("should only run the DRA driver on the migrated nodes", func() {
+		ds := &appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-config-dra-driver", Namespace: "test-namespace"},
+		}
+
+		err := dp.SetDRADriverAsDesired(ds, devConfig)
+		Expect(err).To(BeNil())
+		Expect(ds.Spec.Template.Spec.NodeSelector).To(HaveKeyWithValue(utils.GPUResourceDriverLabel, utils.GPUResourceDriverDRA))
+	})
+})
+
+var _ = Describe("pod template overrides", func() {
+	It("should apply the device plugin and DRA driver overrides", func() {
+		dp := &devicePlugin{scheme: scheme}
+		enable := true
+		overrides := &amdv1alpha1.PodTemplateOverridesSpec{
+			PriorityClassName: "gpu-critical",
+			Annotations:       map[string]string{"example.com/owner": "security"},
+		}
+		devConfig := &amdv1alpha1.DeviceConfig{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-config",
+				Namespace: "test-namespace",
+			},
+			Spec: amdv1alpha1.DeviceConfigSpec{
+				DevicePlugin: amdv1alpha1.DevicePluginSpec{
+					DevicePluginPodTemplateOverrides: overrides,
+				},
+				DRADriver: amdv1alpha1.DRADriverSpec{
+					Enable:               &enable,
+					PodTemplateOverrides: overrides,
+				},
+			},
+		}
+
+		ds := &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "test-device-plugin", Namespace: "test-namespace"}}
+		Expect(dp.SetDevicePluginAsDesired(ds, devConfig)).To(Succeed())
+		Expect(ds.Spec.Template.Spec.PriorityClassName).To(Equal("gpu-critical"))
+		Expect(ds.Spec.Template.Annotations).To(HaveKeyWithValue("example.com/owner", "security"))
+
+		ds = &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "test-dra-driver", Namespace: "test-namespace"}}
+		Expect(dp.SetDRADriverAsDesired(ds, devConfig)).To(Succeed())
+		Expect(ds.Spec.Template.Spec.PriorityClassName).To(Equal("gpu-critical"))
+	})
+})
+
+var _ = Describe("getDevicePluginArgs", func() {
+	It("should render the default args", func() {
+		devConfig := &amdv1alpha1.DeviceConfig{}
+		Expect(getDevicePluginArgs(devConfig)).To(Equal([]string{"-logtostderr=true", "-stderrthreshold=INFO", "-v=5", "-pulse=30"}))
+
+		devConfig.Spec.Driver.DriverType = utils.DriverTypeVFPassthrough
+		Expect(getDevicePluginArgs(devConfig)).To(ContainElement("-resource_naming_strategy=mixed"))
+	})
+
+	It("should render the typed config before the deprecated arguments", func() {
+		pulse := int32(10)
+		devConfig := &amdv1alpha1.DeviceConfig{
+			Spec: amdv1alpha1.DeviceConfigSpec{
+				DevicePlugin: amdv1alpha1.DevicePluginSpec{
+					DevicePluginImage: "registry.example.com/k8s-device-plugin:v1",
+					DevicePluginArguments: map[string]string{
+						utils.ResourceNamingStrategyFlag: utils.SingleStrategy,
+						utils.DriverTypeFlag:             utils.DriverTypeContainer,
+					},
+					DevicePluginConfig: &amdv1alpha1.DevicePluginConfigSpec{
+						ResourceNamingStrategy:  utils.MixedStrategy,
+						HealthCheckPulseSeconds: &pulse,
+					},
+				},
+			},
+		}
+		Expect(getDevicePluginArgs(devConfig)).To(Equal([]string{
+			"-logtostderr=true", "-stderrthreshold=INFO", "-v=5", "-pulse=10",
+			"-resource_naming_strategy=mixed",
+			"-driver_type=container",
+		}))
+
+		dp := &devicePlugin{scheme: scheme}
+		ds := &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "test-device-plugin", Namespace: "test-namespace"}}
+		Expect(dp.SetDevicePluginAsDesired(ds, devConfig)).To(Succeed())
+		Expect(ds.Spec.Template.Spec.Containers[0].Command).To(Equal([]string{"sh", "-c",
+			"./k8s-device-plugin -logtostderr=true -stderrthreshold=INFO -v=5 -pulse=10 -resource_naming_strategy=mixed -driver_type=container"}))
+	})
+})
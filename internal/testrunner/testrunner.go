@@ -52,8 +52,8 @@ import (
 
 const (
 	// TODO: determine where to host the test runner image and put the registry URL here
-	defaultTestRunnerImage       = "docker.io/rocm/test-runner:v0.0.1"
-	defaultInitContainerImage    = "busybox:1.36"
+	defaultTestRunnerImage       = utils.DefaultTestRunnerImage
+	defaultInitContainerImage    = utils.DefaultInitContainerImage
 	TestRunnerName               = "test-runner"
 	defaultSAName                = "amd-gpu-operator-test-runner"
 	defaultTestRunnerDirHostPath = "/var/log/amd-test-runner"
@@ -352,5 +352,8 @@ func (nl *testRunner) SetTestRunnerAsDesired(ds *appsv1.DaemonSet, devConfig *am
 	} else {
 		ds.Spec.Template.Spec.Tolerations = nil
 	}
+	if err := utils.ApplyPodTemplateOverrides(ds, devConfig.Spec.TestRunner.PodTemplateOverrides); err != nil {
+		return err
+	}
 	return controllerutil.SetControllerReference(devConfig, ds, nl.scheme)
 }
@@ -23,14 +23,18 @@ import (
 	"fmt"
 	"net/http"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/robfig/cron/v3"
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -52,18 +56,27 @@ const (
 	ResourceNamingStrategyFlag = "resource_naming_strategy"
 	SingleStrategy             = "single"
 	MixedStrategy              = "mixed"
+	PulseFlag                  = "pulse"
+	DefaultPulseSeconds        = 30
 	// node labeller
 	experimentalAMDPrefix             = "beta.amd.com"
 	amdPrefix                         = "amd.com"
 	computePartitioningSupportedLabel = "amd.com/compute-partitioning-supported"
 	memoryPartitioningSupportedLabel  = "amd.com/memory-partitioning-supported"
-	partitionTypeLabel                = "amd.com/compute-memory-partition"
+	PartitionTypeLabel                = "amd.com/compute-memory-partition"
 	// kubevirt
 	DriverTypeFlag          = "driver_type"
 	DriverTypeContainer     = "container"
 	DriverTypeVFPassthrough = "vf-passthrough"
 	DriverTypePFPassthrough = "pf-passthrough"
 	DefaultUtilsImage       = "docker.io/rocm/gpu-operator-utils:latest"
+	// operand default images
+	DefaultInitContainerImage   = "busybox:1.36"
+	DefaultMetricsExporterImage = "docker.io/rocm/device-metrics-exporter:v0.0.1"
+	DefaultTestRunnerImage      = "docker.io/rocm/test-runner:v0.0.1"
+	DefaultConfigManagerImage   = "docker.io/rocm/device-config-manager:v0.0.1"
+	DefaultDRADriverImage       = "rocm/k8s-gpu-dra-driver:latest"
+	DefaultMetricsExporterPort  = 5000
 	// workerMgr related labels
 	LoadVFIOAction              = "loadVFIO"
 	UnloadVFIOAction            = "unloadVFIO"
@@ -71,12 +84,26 @@ const (
 	VFIOMountReadyLabelTemplate = "gpu.operator.amd.com/%v.%v.vfio.ready"
 	DriverTypeNodeLabelTemplate = "gpu.operator.amd.com/%v.%v.driver"
 	KMMModuleReadyLabelTemplate = "kmm.node.kubernetes.io/%v.%v.ready"
+	// DefaultTimeFormatLayout is the layout of the timestamps in the DeviceConfig status
+	DefaultTimeFormatLayout = "2006-01-02 15:04:05 UTC"
 	// Operand metadata
 	MetricsExporterNameSuffix = "-metrics-exporter"
 	TestRunnerNameSuffix      = "-test-runner"
 	DevicePluginNameSuffix    = "-device-plugin"
 	DRADriverNameSuffix       = "-dra-driver"
 	NodeLabellerNameSuffix    = "-node-labeller"
+	// device plugin to DRA driver migration, nodes labelled with the DRA driver value are only served by the DRA driver
+	GPUResourceDriverLabel = "operator.amd.com/gpu-resource-driver"
+	GPUResourceDriverDRA   = "dra"
+	// operands owned by a DeviceConfig on its selected nodes, each operand can only have one owner per node
+	OwnedOperandDriver          = "driver"
+	OwnedOperandDevicePlugin    = "devicePlugin"
+	OwnedOperandNodeLabeller    = "nodeLabeller"
+	OwnedOperandDRADriver       = "draDriver"
+	OwnedOperandMetricsExporter = "metricsExporter"
+	OwnedOperandTestRunner      = "testRunner"
+	OwnedOperandConfigManager   = "configManager"
+	OwnedOperandRemediation     = "remediation"
 )
 
 var (
@@ -86,6 +113,16 @@ var (
 		"driver-src-version", "device-id", "product-name",
 		"vram", "simd-count", "cu-count",
 	}
+	// partition labels are not under gpu., e.g. amd.com/compute-memory-partition
+	partitionLabellerKinds = []string{
+		"compute-partitioning-supported", "memory-partitioning-supported", "compute-memory-partition",
+	}
+	// labels applied by default per driver type
+	defaultNodeLabellerKinds = map[string][]string{
+		DriverTypeContainer:     {"vram", "cu-count", "simd-count", "device-id", "family", "product-name", "driver-version"},
+		DriverTypeVFPassthrough: {"device-id", "driver-version"},
+		DriverTypePFPassthrough: {"device-id"},
+	}
 	allAMDComLabels     = []string{}
 	allBetaAMDComLabels = []string{}
 	// kubevirt
@@ -118,15 +155,45 @@ func init() {
 func initLabelLists() {
 	// pre-generate all the available node labeller labels
 	// these 2 lists will be used to clean up old labels on the node
-	for _, name := range nodeLabellerKinds {
-		allAMDComLabels = append(allAMDComLabels, createLabelPrefix(name, false))
-		allBetaAMDComLabels = append(allBetaAMDComLabels, createLabelPrefix(name, true))
+	allAMDComLabels, allBetaAMDComLabels = getNodeLabellerLabels(GetAllNodeLabellerKinds())
+}
+
+// GetAllNodeLabellerKinds returns all the kinds of labels the node labeller can apply
+func GetAllNodeLabellerKinds() []string {
+	return append(append([]string{}, nodeLabellerKinds...), partitionLabellerKinds...)
+}
+
+// GetNodeLabellerKinds returns the kinds of labels applied by the node labeller of the DeviceConfig:
+// the allowlist or the defaults of the driver type, then the node labeller arguments, without the denylist
+func GetNodeLabellerKinds(devConfig *amdv1alpha1.DeviceConfig) []string {
+	cfg := devConfig.Spec.DevicePlugin.NodeLabellerConfig
+	candidates := []string{}
+	if cfg != nil && len(cfg.Allowlist) > 0 {
+		for _, kind := range cfg.Allowlist {
+			candidates = append(candidates, string(kind))
+		}
+	} else if defaults, ok := defaultNodeLabellerKinds[devConfig.Spec.Driver.DriverType]; ok {
+		candidates = append(candidates, defaults...)
+	} else {
+		candidates = append(candidates, defaultNodeLabellerKinds[DriverTypeContainer]...)
 	}
-	allAMDComLabels = append(allAMDComLabels,
-		computePartitioningSupportedLabel,
-		memoryPartitioningSupportedLabel,
-		partitionTypeLabel,
-	)
+	candidates = append(candidates, devConfig.Spec.DevicePlugin.NodeLabellerArguments...)
+
+	denied := map[string]bool{}
+	if cfg != nil {
+		for _, kind := range cfg.Denylist {
+			denied[string(kind)] = true
+		}
+	}
+	kinds := []string{}
+	for _, kind := range candidates {
+		if denied[kind] {
+			continue
+		}
+		denied[kind] = true
+		kinds = append(kinds, kind)
+	}
+	return kinds
 }
 
 func createLabelPrefix(name string, experimental bool) string {
@@ -139,14 +206,25 @@ func createLabelPrefix(name string, experimental bool) string {
 	return fmt.Sprintf("%s/gpu.%s", prefix, name)
 }
 
-func RemoveOldNodeLabels(node *v1.Node) bool {
-	updated := false
-	if node == nil {
-		return false
+// getNodeLabellerLabels returns the amd.com and beta.amd.com labels of the kinds
+func getNodeLabellerLabels(kinds []string) (stable, experimental []string) {
+	for _, kind := range kinds {
+		switch {
+		case slices.Contains(nodeLabellerKinds, kind):
+			stable = append(stable, createLabelPrefix(kind, false))
+			experimental = append(experimental, createLabelPrefix(kind, true))
+		case slices.Contains(partitionLabellerKinds, kind):
+			stable = append(stable, amdPrefix+"/"+kind)
+		}
 	}
+	return stable, experimental
+}
+
+func removeNodeLabels(node *v1.Node, stable, experimental []string) bool {
+	updated := false
 	// for the amd.com node labels
 	// directly remove the old labels
-	for _, label := range allAMDComLabels {
+	for _, label := range stable {
 		if _, ok := node.Labels[label]; ok {
 			delete(node.Labels, label)
 			updated = true
@@ -156,7 +234,7 @@ func RemoveOldNodeLabels(node *v1.Node) bool {
 	// if it exists, both original label and counter label need to be removed, e.g.
 	// beta.amd.com/gpu.family: AI
 	// beta.amd.com/gpu.family.AI: "1"
-	for _, label := range allBetaAMDComLabels {
+	for _, label := range experimental {
 		if val, ok := node.Labels[label]; ok {
 			delete(node.Labels, label)
 			counterLabel := fmt.Sprintf("%s.%s", label, val)
@@ -167,6 +245,42 @@ func RemoveOldNodeLabels(node *v1.Node) bool {
 	return updated
 }
 
+func RemoveOldNodeLabels(node *v1.Node) bool {
+	if node == nil {
+		return false
+	}
+	return removeNodeLabels(node, allAMDComLabels, allBetaAMDComLabels)
+}
+
+// RemoveStaleNodeLabellerLabels removes the node labeller labels the DeviceConfig no longer applies:
+// the labels of the denylisted kinds and of the previously applied kinds not applied anymore.
+// The partition labels are only removed once denylisted, the node labeller may report them without being asked to
+func RemoveStaleNodeLabellerLabels(node *v1.Node, devConfig *amdv1alpha1.DeviceConfig, previousKinds []string) bool {
+	if node == nil {
+		return false
+	}
+	enabled := GetNodeLabellerKinds(devConfig)
+	candidates := []string{}
+	if cfg := devConfig.Spec.DevicePlugin.NodeLabellerConfig; cfg != nil {
+		for _, kind := range cfg.Denylist {
+			candidates = append(candidates, string(kind))
+		}
+	}
+	for _, kind := range previousKinds {
+		if !slices.Contains(partitionLabellerKinds, kind) {
+			candidates = append(candidates, kind)
+		}
+	}
+	disabled := []string{}
+	for _, kind := range candidates {
+		if !slices.Contains(enabled, kind) && !slices.Contains(disabled, kind) {
+			disabled = append(disabled, kind)
+		}
+	}
+	stable, experimental := getNodeLabellerLabels(disabled)
+	return removeNodeLabels(node, stable, experimental)
+}
+
 func GetDriverVersion(node v1.Node, deviceConfig amdv1alpha1.DeviceConfig) (string, error) {
 	var driverVersion string
 	var err error
@@ -506,3 +620,268 @@ func ShouldUseKMM(devConfig *v1alpha1.DeviceConfig) bool {
 	}
 	return false
 }
+
+// GetOwnedOperands returns the operands enabled in the DeviceConfig, which are the operands
+// the DeviceConfig claims ownership of on its selected nodes
+func GetOwnedOperands(devConfig *amdv1alpha1.DeviceConfig) []string {
+	isTrue := func(b *bool) bool {
+		return b != nil && *b
+	}
+
+	operands := []string{}
+	if isTrue(devConfig.Spec.Driver.Enable) {
+		operands = append(operands, OwnedOperandDriver)
+	}
+	if devConfig.Spec.DevicePlugin.IsEnabled() {
+		operands = append(operands, OwnedOperandDevicePlugin)
+	}
+	if isTrue(devConfig.Spec.DevicePlugin.EnableNodeLabeller) {
+		operands = append(operands, OwnedOperandNodeLabeller)
+	}
+	if devConfig.Spec.DRADriver.IsEnabled() {
+		operands = append(operands, OwnedOperandDRADriver)
+	}
+	if isTrue(devConfig.Spec.MetricsExporter.Enable) {
+		operands = append(operands, OwnedOperandMetricsExporter)
+	}
+	if isTrue(devConfig.Spec.TestRunner.Enable) {
+		operands = append(operands, OwnedOperandTestRunner)
+	}
+	if isTrue(devConfig.Spec.ConfigManager.Enable) {
+		operands = append(operands, OwnedOperandConfigManager)
+	}
+	if isTrue(devConfig.Spec.RemediationWorkflow.Enable) {
+		operands = append(operands, OwnedOperandRemediation)
+	}
+	return operands
+}
+
+// ParseMaintenanceWindow returns the start schedule and the duration of the maintenance window
+func ParseMaintenanceWindow(window amdv1alpha1.MaintenanceWindow) (cron.Schedule, time.Duration, error) {
+	spec := strings.TrimSpace(window.Schedule)
+	if strings.HasPrefix(spec, "@every") || strings.HasPrefix(spec, "TZ=") || strings.HasPrefix(spec, "CRON_TZ=") {
+		return nil, 0, fmt.Errorf("unsupported schedule %q, use a cron expression and the timeZone field", window.Schedule)
+	}
+	timeZone := window.TimeZone
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
+	if _, err := time.LoadLocation(timeZone); err != nil {
+		return nil, 0, fmt.Errorf("invalid time zone %q: %v", window.TimeZone, err)
+	}
+	schedule, err := cron.ParseStandard(fmt.Sprintf("CRON_TZ=%s %s", timeZone, spec))
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid schedule %q: %v", window.Schedule, err)
+	}
+	duration, err := time.ParseDuration(window.Duration)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid duration %q: %v", window.Duration, err)
+	}
+	if duration <= 0 {
+		return nil, 0, fmt.Errorf("invalid duration %q: must be positive", window.Duration)
+	}
+	return schedule, duration, nil
+}
+
+// GetMaintenanceWindowStatus returns whether one of the maintenance windows is open at the given time, when it closes
+// and when the next window opens. A nil status is returned if no maintenance window is configured
+func GetMaintenanceWindowStatus(windows []amdv1alpha1.MaintenanceWindow, now time.Time) (*amdv1alpha1.MaintenanceWindowStatus, error) {
+	if len(windows) == 0 {
+		return nil, nil
+	}
+	var currentEnd, nextStart time.Time
+	for _, window := range windows {
+		schedule, duration, err := ParseMaintenanceWindow(window)
+		if err != nil {
+			return nil, err
+		}
+		// the latest start time before now is the first start after now-duration, if any
+		if start := schedule.Next(now.Add(-duration)); !start.IsZero() && !start.After(now) {
+			if end := start.Add(duration); end.After(currentEnd) {
+				currentEnd = end
+			}
+		}
+		if start := schedule.Next(now); !start.IsZero() && (nextStart.IsZero() || start.Before(nextStart)) {
+			nextStart = start
+		}
+	}
+
+	status := &amdv1alpha1.MaintenanceWindowStatus{Open: !currentEnd.IsZero()}
+	if status.Open {
+		status.CurrentWindowEnd = currentEnd.UTC().Format(DefaultTimeFormatLayout)
+	}
+	if !nextStart.IsZero() {
+		status.NextWindowStart = nextStart.UTC().Format(DefaultTimeFormatLayout)
+	}
+	return status, nil
+}
+
+// IsMaintenanceWindowOpen returns true if disruptive operations of the DeviceConfig are allowed to start at the given time
+func IsMaintenanceWindowOpen(devConfig *amdv1alpha1.DeviceConfig, now time.Time) bool {
+	status, err := GetMaintenanceWindowStatus(devConfig.Spec.MaintenanceWindows, now)
+	if err != nil {
+		// invalid windows are reported by the validator, hold the disruptive operations until they are fixed
+		return false
+	}
+	return status == nil || status.Open
+}
+
+// ApplyPodTemplateOverrides applies the pod template overrides of an operand to its DaemonSet
+func ApplyPodTemplateOverrides(ds *appsv1.DaemonSet, overrides *amdv1alpha1.PodTemplateOverridesSpec) error {
+	if overrides == nil {
+		return nil
+	}
+	template := &ds.Spec.Template
+
+	for key, val := range overrides.Labels {
+		if template.Labels == nil {
+			template.Labels = map[string]string{}
+		}
+		if _, ok := template.Labels[key]; !ok {
+			template.Labels[key] = val
+		}
+	}
+	for key, val := range overrides.Annotations {
+		if template.Annotations == nil {
+			template.Annotations = map[string]string{}
+		}
+		template.Annotations[key] = val
+	}
+	if overrides.PriorityClassName != "" {
+		template.Spec.PriorityClassName = overrides.PriorityClassName
+	}
+	if overrides.Affinity != nil {
+		template.Spec.Affinity = mergeAffinity(template.Spec.Affinity, overrides.Affinity)
+	}
+	if overrides.SecurityContext != nil {
+		template.Spec.SecurityContext = overrides.SecurityContext.DeepCopy()
+	}
+	for _, volume := range overrides.Volumes {
+		template.Spec.Volumes = upsert(template.Spec.Volumes, *volume.DeepCopy(), func(v v1.Volume) string { return v.Name })
+	}
+
+	if len(template.Spec.Containers) > 0 {
+		container := &template.Spec.Containers[0]
+		if overrides.Resources != nil {
+			container.Resources = *overrides.Resources.DeepCopy()
+		}
+		for _, env := range overrides.Env {
+			container.Env = upsert(container.Env, *env.DeepCopy(), func(e v1.EnvVar) string { return e.Name })
+		}
+		for _, mount := range overrides.VolumeMounts {
+			container.VolumeMounts = upsert(container.VolumeMounts, mount, func(m v1.VolumeMount) string { return m.MountPath })
+		}
+	}
+
+	if overrides.Patch != nil && len(overrides.Patch.Raw) > 0 {
+		operandContainer := ""
+		if len(template.Spec.Containers) > 0 {
+			operandContainer = template.Spec.Containers[0].Name
+		}
+		original, err := json.Marshal(template)
+		if err != nil {
+			return fmt.Errorf("failed to marshal pod template: %v", err)
+		}
+		patched, err := strategicpatch.StrategicMergePatch(original, overrides.Patch.Raw, v1.PodTemplateSpec{})
+		if err != nil {
+			return fmt.Errorf("failed to apply pod template patch: %v", err)
+		}
+		patchedTemplate := v1.PodTemplateSpec{}
+		if err := json.Unmarshal(patched, &patchedTemplate); err != nil {
+			return fmt.Errorf("failed to unmarshal patched pod template: %v", err)
+		}
+		// the merge puts added containers first, the operand container is kept first
+		for i, container := range patchedTemplate.Spec.Containers {
+			if i > 0 && container.Name == operandContainer {
+				copy(patchedTemplate.Spec.Containers[1:i+1], patchedTemplate.Spec.Containers[:i])
+				patchedTemplate.Spec.Containers[0] = container
+				break
+			}
+		}
+		*template = patchedTemplate
+	}
+
+	// the pods must keep matching the DaemonSet selector
+	if ds.Spec.Selector != nil {
+		for key, val := range ds.Spec.Selector.MatchLabels {
+			if template.Labels == nil {
+				template.Labels = map[string]string{}
+			}
+			template.Labels[key] = val
+		}
+	}
+	return nil
+}
+
+// mergeAffinity combines the operator node affinity with the override, so that pods have to satisfy both.
+// Pod affinity and anti affinity of the override replace the operator ones
+func mergeAffinity(affinity, override *v1.Affinity) *v1.Affinity {
+	if affinity == nil {
+		return override.DeepCopy()
+	}
+	merged := affinity.DeepCopy()
+	if override.PodAffinity != nil {
+		merged.PodAffinity = override.PodAffinity.DeepCopy()
+	}
+	if override.PodAntiAffinity != nil {
+		merged.PodAntiAffinity = override.PodAntiAffinity.DeepCopy()
+	}
+	if override.NodeAffinity == nil {
+		return merged
+	}
+	if merged.NodeAffinity == nil {
+		merged.NodeAffinity = override.NodeAffinity.DeepCopy()
+		return merged
+	}
+	merged.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(merged.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution,
+		override.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution...)
+
+	required := override.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if required == nil {
+		return merged
+	}
+	if merged.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		merged.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = required.DeepCopy()
+		return merged
+	}
+	// node selector terms are ORed, so every pair of terms is combined to AND both selectors
+	terms := []v1.NodeSelectorTerm{}
+	for _, term := range merged.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		for _, overrideTerm := range required.NodeSelectorTerms {
+			combined := term.DeepCopy()
+			combined.MatchExpressions = append(combined.MatchExpressions, overrideTerm.DeepCopy().MatchExpressions...)
+			combined.MatchFields = append(combined.MatchFields, overrideTerm.DeepCopy().MatchFields...)
+			terms = append(terms, *combined)
+		}
+	}
+	merged.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms = terms
+	return merged
+}
+
+// upsert replaces the item with the same key or appends it
+func upsert[T any](items []T, item T, key func(T) string) []T {
+	for i := range items {
+		if key(items[i]) == key(item) {
+			items[i] = item
+			return items
+		}
+	}
+	return append(items, item)
+}
+
+// GetResourceNamingStrategy returns the resource naming strategy of the device plugin,
+// the typed config takes precedence over the device plugin arguments
+func GetResourceNamingStrategy(devConfig *amdv1alpha1.DeviceConfig) string {
+	if cfg := devConfig.Spec.DevicePlugin.DevicePluginConfig; cfg != nil && cfg.ResourceNamingStrategy != "" {
+		return cfg.ResourceNamingStrategy
+	}
+	if strategy, ok := devConfig.Spec.DevicePlugin.DevicePluginArguments[ResourceNamingStrategyFlag]; ok {
+		return strategy
+	}
+	// passthrough nodes report the GPUs under their partition name by default
+	if devConfig.Spec.Driver.DriverType == DriverTypePFPassthrough ||
+		devConfig.Spec.Driver.DriverType == DriverTypeVFPassthrough {
+		return MixedStrategy
+	}
+	return ""
+}
@@ -19,16 +19,21 @@ package utils
 import (
 	"context"
 	"fmt"
+	"maps"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ROCm/gpu-operator/api/v1alpha1"
 	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 var (
@@ -152,6 +157,73 @@ func TestRemoveOldNodeLabels(t *testing.T) {
 	}
 }
 
+func TestGetNodeLabellerKinds(t *testing.T) {
+	testCases := []struct {
+		Description string
+		DriverType  string
+		Arguments   []string
+		Config      *v1alpha1.NodeLabellerConfigSpec
+		Expect      []string
+	}{
+		{
+			Description: "container defaults",
+			Expect:      []string{"vram", "cu-count", "simd-count", "device-id", "family", "product-name", "driver-version"},
+		},
+		{
+			Description: "vf-passthrough defaults with arguments",
+			DriverType:  DriverTypeVFPassthrough,
+			Arguments:   []string{"compute-memory-partition", "device-id"},
+			Expect:      []string{"device-id", "driver-version", "compute-memory-partition"},
+		},
+		{
+			Description: "allowlist and denylist",
+			Arguments:   []string{"compute-memory-partition", "firmware"},
+			Config: &v1alpha1.NodeLabellerConfigSpec{
+				Allowlist: []v1alpha1.NodeLabellerLabel{"device-id", "product-name", "vram"},
+				Denylist:  []v1alpha1.NodeLabellerLabel{"product-name", "firmware"},
+			},
+			Expect: []string{"device-id", "vram", "compute-memory-partition"},
+		},
+	}
+
+	for _, tc := range testCases {
+		devConfig := &v1alpha1.DeviceConfig{}
+		devConfig.Spec.Driver.DriverType = tc.DriverType
+		devConfig.Spec.DevicePlugin.NodeLabellerArguments = tc.Arguments
+		devConfig.Spec.DevicePlugin.NodeLabellerConfig = tc.Config
+		assert.Equal(t, tc.Expect, GetNodeLabellerKinds(devConfig), tc.Description)
+	}
+}
+
+func TestRemoveStaleNodeLabellerLabels(t *testing.T) {
+	devConfig := &v1alpha1.DeviceConfig{}
+	labels := map[string]string{
+		"amd.com/gpu.device-id":                  "74a1",
+		"amd.com/gpu.driver-src-version":         "6.12.12",
+		"amd.com/compute-memory-partition":       "spx_nps1",
+		"amd.com/compute-partitioning-supported": "true",
+		"amd.com/gpu.firmware":                   "123",
+		"beta.amd.com/gpu.family":                "AI",
+		"beta.amd.com/gpu.family.AI":             "1",
+	}
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Labels: maps.Clone(labels)}}
+	assert.False(t, RemoveStaleNodeLabellerLabels(node, devConfig, nil), "kinds never applied are not stale")
+	assert.False(t, RemoveStaleNodeLabellerLabels(node, devConfig, []string{"compute-memory-partition"}), "partition kinds are kept")
+	assert.Equal(t, labels, node.Labels)
+
+	assert.True(t, RemoveStaleNodeLabellerLabels(node, devConfig, []string{"device-id", "firmware"}))
+	assert.NotContains(t, node.Labels, "amd.com/gpu.firmware")
+	assert.Contains(t, node.Labels, "amd.com/gpu.device-id")
+
+	devConfig.Spec.DevicePlugin.NodeLabellerConfig = &v1alpha1.NodeLabellerConfigSpec{
+		Denylist: []v1alpha1.NodeLabellerLabel{"compute-memory-partition", "driver-src-version"},
+	}
+	assert.True(t, RemoveStaleNodeLabellerLabels(node, devConfig, nil))
+	assert.NotContains(t, node.Labels, "amd.com/compute-memory-partition")
+	assert.NotContains(t, node.Labels, "amd.com/gpu.driver-src-version")
+	assert.Contains(t, node.Labels, "amd.com/compute-partitioning-supported")
+}
+
 func TestHasNodeLabelTemplateMatch(t *testing.T) {
 	testCases := []struct {
 		Namespace string
@@ -497,3 +569,222 @@ func TestValidateSLESDriverVersion(t *testing.T) {
 		})
 	}
 }
+
+func TestGetMaintenanceWindowStatus(t *testing.T) {
+	fridayNight := v1alpha1.MaintenanceWindow{Schedule: "0 22 * * 5", Duration: "4h"}
+	testCases := []struct {
+		Description string
+		Windows     []v1alpha1.MaintenanceWindow
+		Now         time.Time
+		Expect      *v1alpha1.MaintenanceWindowStatus
+		ExpectErr   bool
+	}{
+		{
+			Description: "no maintenance windows",
+			Windows:     nil,
+			Now:         time.Date(2025, 3, 7, 23, 0, 0, 0, time.UTC),
+			Expect:      nil,
+		},
+		{
+			Description: "inside the window",
+			Windows:     []v1alpha1.MaintenanceWindow{fridayNight},
+			Now:         time.Date(2025, 3, 7, 23, 0, 0, 0, time.UTC),
+			Expect: &v1alpha1.MaintenanceWindowStatus{
+				Open:             true,
+				CurrentWindowEnd: "2025-03-08 02:00:00 UTC",
+				NextWindowStart:  "2025-03-14 22:00:00 UTC",
+			},
+		},
+		{
+			Description: "window start is inclusive",
+			Windows:     []v1alpha1.MaintenanceWindow{fridayNight},
+			Now:         time.Date(2025, 3, 7, 22, 0, 0, 0, time.UTC),
+			Expect: &v1alpha1.MaintenanceWindowStatus{
+				Open:             true,
+				CurrentWindowEnd: "2025-03-08 02:00:00 UTC",
+				NextWindowStart:  "2025-03-14 22:00:00 UTC",
+			},
+		},
+		{
+			Description: "after the window",
+			Windows:     []v1alpha1.MaintenanceWindow{fridayNight},
+			Now:         time.Date(2025, 3, 8, 2, 0, 0, 0, time.UTC),
+			Expect: &v1alpha1.MaintenanceWindowStatus{
+				Open:            false,
+				NextWindowStart: "2025-03-14 22:00:00 UTC",
+			},
+		},
+		{
+			Description: "window in a time zone with daylight saving change",
+			Windows:     []v1alpha1.MaintenanceWindow{{Schedule: "0 22 * * 5", Duration: "2h", TimeZone: "America/Los_Angeles"}},
+			Now:         time.Date(2025, 3, 8, 7, 0, 0, 0, time.UTC),
+			Expect: &v1alpha1.MaintenanceWindowStatus{
+				Open:             true,
+				CurrentWindowEnd: "2025-03-08 08:00:00 UTC",
+				NextWindowStart:  "2025-03-15 05:00:00 UTC",
+			},
+		},
+		{
+			Description: "earliest next start across windows",
+			Windows:     []v1alpha1.MaintenanceWindow{fridayNight, {Schedule: "30 1 * * *", Duration: "30m"}},
+			Now:         time.Date(2025, 3, 8, 3, 0, 0, 0, time.UTC),
+			Expect: &v1alpha1.MaintenanceWindowStatus{
+				Open:            false,
+				NextWindowStart: "2025-03-09 01:30:00 UTC",
+			},
+		},
+		{
+			Description: "invalid schedule",
+			Windows:     []v1alpha1.MaintenanceWindow{{Schedule: "@every 1h", Duration: "30m"}},
+			Now:         time.Date(2025, 3, 8, 3, 0, 0, 0, time.UTC),
+			ExpectErr:   true,
+		},
+		{
+			Description: "invalid time zone",
+			Windows:     []v1alpha1.MaintenanceWindow{{Schedule: "0 22 * * 5", Duration: "30m", TimeZone: "Mars/Olympus_Mons"}},
+			Now:         time.Date(2025, 3, 8, 3, 0, 0, 0, time.UTC),
+			ExpectErr:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		status, err := GetMaintenanceWindowStatus(tc.Windows, tc.Now)
+		if tc.ExpectErr {
+			assert.Error(t, err, tc.Description)
+			continue
+		}
+		assert.NoError(t, err, tc.Description)
+		assert.Equal(t, tc.Expect, status, tc.Description)
+	}
+}
+
+func TestGetOwnedOperands(t *testing.T) {
+	enabled := true
+	disabled := false
+
+	testCases := []struct {
+		Description string
+		Spec        v1alpha1.DeviceConfigSpec
+		Expect      []string
+	}{
+		{
+			Description: "nothing enabled",
+			Spec:        v1alpha1.DeviceConfigSpec{},
+			Expect:      []string{},
+		},
+		{
+			Description: "platform operands",
+			Spec: v1alpha1.DeviceConfigSpec{
+				Driver: v1alpha1.DriverSpec{Enable: &enabled},
+				DevicePlugin: v1alpha1.DevicePluginSpec{
+					EnableDevicePlugin: &enabled,
+					EnableNodeLabeller: &enabled,
+				},
+			},
+			Expect: []string{OwnedOperandDriver, OwnedOperandDevicePlugin, OwnedOperandNodeLabeller},
+		},
+		{
+			Description: "observability operands",
+			Spec: v1alpha1.DeviceConfigSpec{
+				Driver: v1alpha1.DriverSpec{Enable: &disabled},
+				DevicePlugin: v1alpha1.DevicePluginSpec{
+					EnableDevicePlugin: &disabled,
+					EnableNodeLabeller: &disabled,
+				},
+				MetricsExporter: v1alpha1.MetricsExporterSpec{Enable: &enabled},
+				TestRunner:      v1alpha1.TestRunnerSpec{Enable: &enabled},
+			},
+			Expect: []string{OwnedOperandMetricsExporter, OwnedOperandTestRunner},
+		},
+	}
+
+	for _, tc := range testCases {
+		devConfig := &v1alpha1.DeviceConfig{Spec: tc.Spec}
+		assert.Equal(t, tc.Expect, GetOwnedOperands(devConfig), tc.Description)
+	}
+}
+
+func TestApplyPodTemplateOverrides(t *testing.T) {
+	newDaemonSet := func() *appsv1.DaemonSet {
+		return &appsv1.DaemonSet{
+			Spec: appsv1.DaemonSetSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"daemonset-name": "test"}},
+				Template: v1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"daemonset-name": "test"}},
+					Spec: v1.PodSpec{
+						PriorityClassName: "system-node-critical",
+						Affinity: &v1.Affinity{
+							NodeAffinity: &v1.NodeAffinity{
+								RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+									NodeSelectorTerms: []v1.NodeSelectorTerm{
+										{MatchExpressions: []v1.NodeSelectorRequirement{{Key: "a", Operator: v1.NodeSelectorOpExists}}},
+									},
+								},
+							},
+						},
+						Containers: []v1.Container{
+							{
+								Name:         "operand",
+								Env:          []v1.EnvVar{{Name: "DS_NODE_NAME", Value: "node"}},
+								VolumeMounts: []v1.VolumeMount{{Name: "sys", MountPath: "/sys"}},
+							},
+						},
+						Volumes: []v1.Volume{{Name: "sys"}},
+					},
+				},
+			},
+		}
+	}
+
+	ds := newDaemonSet()
+	assert.NoError(t, ApplyPodTemplateOverrides(ds, nil))
+	assert.Equal(t, newDaemonSet(), ds)
+
+	overrides := &v1alpha1.PodTemplateOverridesSpec{
+		Labels:            map[string]string{"daemonset-name": "other", "team": "gpu"},
+		Annotations:       map[string]string{"example.com/scrape": "true"},
+		PriorityClassName: "gpu-critical",
+		Resources: &v1.ResourceRequirements{
+			Limits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("256Mi")},
+		},
+		Affinity: &v1.Affinity{
+			NodeAffinity: &v1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+					NodeSelectorTerms: []v1.NodeSelectorTerm{
+						{MatchExpressions: []v1.NodeSelectorRequirement{{Key: "b", Operator: v1.NodeSelectorOpExists}}},
+						{MatchExpressions: []v1.NodeSelectorRequirement{{Key: "c", Operator: v1.NodeSelectorOpExists}}},
+					},
+				},
+			},
+		},
+		Env:          []v1.EnvVar{{Name: "DS_NODE_NAME", Value: "override"}, {Name: "EXTRA", Value: "1"}},
+		Volumes:      []v1.Volume{{Name: "sys", VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/host/sys"}}}, {Name: "certs"}},
+		VolumeMounts: []v1.VolumeMount{{Name: "certs", MountPath: "/certs"}},
+		Patch: &runtime.RawExtension{Raw: []byte(`{"metadata":{"labels":{"daemonset-name":"patched"}},` +
+			`"spec":{"containers":[{"name":"sidecar","image":"sidecar:latest"}]}}`)},
+	}
+	ds = newDaemonSet()
+	assert.NoError(t, ApplyPodTemplateOverrides(ds, overrides))
+	spec := ds.Spec.Template.Spec
+	assert.Equal(t, map[string]string{"daemonset-name": "test", "team": "gpu"}, ds.Spec.Template.Labels)
+	assert.Equal(t, "true", ds.Spec.Template.Annotations["example.com/scrape"])
+	assert.Equal(t, "gpu-critical", spec.PriorityClassName)
+	assert.Equal(t, []string{"operand", "sidecar"}, []string{spec.Containers[0].Name, spec.Containers[1].Name})
+	assert.Equal(t, resource.MustParse("256Mi"), spec.Containers[0].Resources.Limits[v1.ResourceMemory])
+	assert.Equal(t, []v1.EnvVar{{Name: "DS_NODE_NAME", Value: "override"}, {Name: "EXTRA", Value: "1"}}, spec.Containers[0].Env)
+	// volumes with the same name replace the ones set by the operator
+	assert.Equal(t, []v1.Volume{{Name: "sys", VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/host/sys"}}}, {Name: "certs"}}, spec.Volumes)
+	assert.Len(t, spec.Containers[0].VolumeMounts, 2)
+	terms := spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	assert.Len(t, terms, 2)
+	for _, term := range terms {
+		assert.Len(t, term.MatchExpressions, 2)
+		assert.Equal(t, "a", term.MatchExpressions[0].Key)
+	}
+
+	ds = newDaemonSet()
+	err := ApplyPodTemplateOverrides(ds, &v1alpha1.PodTemplateOverridesSpec{
+		Patch: &runtime.RawExtension{Raw: []byte(`{"spec":{"containers":"invalid"}}`)},
+	})
+	assert.Error(t, err)
+}
@@ -49,6 +49,11 @@ func ValidateDriverSpec(ctx context.Context, client client.Client, devConfig *am
 		return nil
 	}
 
+	// catalog images are used as is, KMM only signs the images it builds
+	if dSpec.ImageCatalog != nil && (dSpec.ImageSign.KeySecret != nil || dSpec.ImageSign.CertSecret != nil) {
+		return fmt.Errorf("spec.driver.imageCatalog cannot be combined with spec.driver.imageSign, the catalog images must be signed already")
+	}
+
 	if dSpec.ImageRegistrySecret != nil {
 		if err := validateSecret(ctx, client, dSpec.ImageRegistrySecret, devConfig.Namespace); err != nil {
 			return fmt.Errorf("ImageRegistrySecret: %v", err)
@@ -67,12 +72,34 @@ func ValidateDriverSpec(ctx context.Context, client client.Client, devConfig *am
 		}
 	}
 
+	if dSpec.ImageBuild.DockerfileTemplates != nil {
+		if err := validateDockerfileTemplates(ctx, client, dSpec.ImageBuild.DockerfileTemplates, devConfig.Namespace); err != nil {
+			return fmt.Errorf("spec.driver.imageBuild.dockerfileTemplates: %v", err)
+		}
+	}
+
+	if dSpec.ImageCatalog != nil {
+		if err := validateDriverImageCatalog(ctx, client, dSpec.ImageCatalog, devConfig.Namespace); err != nil {
+			return fmt.Errorf("spec.driver.imageCatalog: %v", err)
+		}
+	}
+
 	if dSpec.Version != "" {
 		if err := validateSLESDriverVersion(ctx, client, devConfig, dSpec.Version); err != nil {
 			return err
 		}
 	}
 
+	if dSpec.UpgradePolicy != nil && dSpec.UpgradePolicy.Hooks != nil {
+		hooks := dSpec.UpgradePolicy.Hooks
+		if err := validateUpgradeHook(ctx, client, hooks.PreUpgrade, devConfig.Namespace); err != nil {
+			return fmt.Errorf("spec.driver.upgradePolicy.hooks.preUpgrade: %v", err)
+		}
+		if err := validateUpgradeHook(ctx, client, hooks.PostUpgrade, devConfig.Namespace); err != nil {
+			return fmt.Errorf("spec.driver.upgradePolicy.hooks.postUpgrade: %v", err)
+		}
+	}
+
 	return nil
 }
 
@@ -115,20 +142,77 @@ func ValidateDRADriverSpec(ctx context.Context, client client.Client, devConfig
 	draEnabled := draSpec.IsEnabled()
 	pluginEnabled := pluginSpec.IsEnabled()
 
-	if draEnabled && pluginEnabled {
-		return fmt.Errorf("DRADriver and DevicePlugin cannot be enabled at the same time")
+	// during the migration every node is served by either the device plugin or the DRA driver
+	if draEnabled && pluginEnabled && !draSpec.IsMigrationEnabled() {
+		return fmt.Errorf("DRADriver and DevicePlugin cannot be enabled at the same time unless spec.draDriver.migration is enabled")
 	}
 
 	if !draEnabled {
 		return nil
 	}
 
+	if draSpec.IsMigrationEnabled() {
+		for key, value := range draSpec.Migration.NodeSelector {
+			if len(validation.IsQualifiedName(key)) > 0 {
+				return fmt.Errorf("migration: invalid node selector key: %s", key)
+			}
+			if len(validation.IsValidLabelValue(value)) > 0 {
+				return fmt.Errorf("migration: invalid node selector value: %s", value)
+			}
+		}
+		if draSpec.Migration.NodeDrainPolicy != nil && draSpec.Migration.NodeDrainPolicy.Mode == amdv1alpha1.DrainModeWaitForCompletion {
+			return fmt.Errorf("spec.draDriver.migration.nodeDrainPolicy.mode %v is only supported for driver upgrades", draSpec.Migration.NodeDrainPolicy.Mode)
+		}
+	}
+
 	if draSpec.ImageRegistrySecret != nil {
 		if err := validateSecret(ctx, client, draSpec.ImageRegistrySecret, devConfig.Namespace); err != nil {
 			return fmt.Errorf("ImageRegistrySecret: %v", err)
 		}
 	}
 
+	names := map[string]bool{}
+	for i, deviceClass := range draSpec.DeviceClasses {
+		// the default DeviceClass is managed by the helm chart or the operator on OpenShift
+		if deviceClass.Name == defaultDeviceClassName {
+			return fmt.Errorf("deviceClasses[%d]: DeviceClass %v is reserved for the default DeviceClass", i, deviceClass.Name)
+		}
+		if names[deviceClass.Name] {
+			return fmt.Errorf("deviceClasses[%d]: duplicate DeviceClass name %v", i, deviceClass.Name)
+		}
+		names[deviceClass.Name] = true
+		for j, expression := range deviceClass.Selectors {
+			if strings.TrimSpace(expression) == "" {
+				return fmt.Errorf("deviceClasses[%d].selectors[%d]: empty CEL expression", i, j)
+			}
+		}
+	}
+
+	templates := map[string]bool{}
+	for i, claimTemplate := range draSpec.ClaimTemplates {
+		namespaces := claimTemplate.Namespaces
+		if len(namespaces) == 0 {
+			namespaces = []string{devConfig.Namespace}
+		}
+		for _, namespace := range namespaces {
+			if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+				return fmt.Errorf("claimTemplates[%d]: invalid namespace %v: %s", i, namespace, strings.Join(errs, "; "))
+			}
+			if templates[namespace+"/"+claimTemplate.Name] {
+				return fmt.Errorf("claimTemplates[%d]: duplicate ResourceClaimTemplate %v in namespace %v", i, claimTemplate.Name, namespace)
+			}
+			templates[namespace+"/"+claimTemplate.Name] = true
+		}
+		if claimTemplate.AllocationMode == amdv1alpha1.DRAClaimAllocationModeAll && claimTemplate.Count > 1 {
+			return fmt.Errorf("claimTemplates[%d]: count cannot be set with allocation mode %v", i, claimTemplate.AllocationMode)
+		}
+		for j, expression := range claimTemplate.Selectors {
+			if strings.TrimSpace(expression) == "" {
+				return fmt.Errorf("claimTemplates[%d].selectors[%d]: empty CEL expression", i, j)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -141,8 +225,8 @@ func ValidateDevicePluginSpec(ctx context.Context, client client.Client, devConf
 	draEnabled := draSpec.IsEnabled()
 	pluginEnabled := pluginSpec.IsEnabled()
 
-	if draEnabled && pluginEnabled {
-		return fmt.Errorf("DRADriver and DevicePlugin cannot be enabled at the same time")
+	if draEnabled && pluginEnabled && !draSpec.IsMigrationEnabled() {
+		return fmt.Errorf("DRADriver and DevicePlugin cannot be enabled at the same time unless spec.draDriver.migration is enabled")
 	}
 
 	if !pluginEnabled {
@@ -181,6 +265,10 @@ func ValidateDevicePluginSpec(ctx context.Context, client client.Client, devConf
 		}
 	}
 
+	if err := validateDevicePluginConfig(devConfig); err != nil {
+		return fmt.Errorf("spec.devicePlugin.devicePluginConfig: %v", err)
+	}
+
 	return nil
 }
 
@@ -243,6 +331,10 @@ func ValidateRemediationWorkflowSpec(ctx context.Context, client client.Client,
 		}
 	}
 
+	if rSpec.NodeDrainPolicy != nil && rSpec.NodeDrainPolicy.Mode == amdv1alpha1.DrainModeWaitForCompletion {
+		return fmt.Errorf("spec.remediationWorkflow.nodeDrainPolicy.mode %v is only supported for driver upgrades", rSpec.NodeDrainPolicy.Mode)
+	}
+
 	return nil
 }
 
@@ -261,3 +353,85 @@ func ValidateCommonConfigSpec(ctx context.Context, client client.Client, devConf
 
 	return nil
 }
+
+// MaintenanceWindows validation
+func ValidateMaintenanceWindowsSpec(ctx context.Context, client client.Client, devConfig *amdv1alpha1.DeviceConfig) error {
+	for i, window := range devConfig.Spec.MaintenanceWindows {
+		if _, _, err := utils.ParseMaintenanceWindow(window); err != nil {
+			return fmt.Errorf("maintenanceWindows[%d]: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// ConfigManagerSpec validation
+func ValidateConfigManagerSpec(ctx context.Context, client client.Client, devConfig *amdv1alpha1.DeviceConfig) error {
+	cmSpec := devConfig.Spec.ConfigManager
+
+	if cmSpec.Enable == nil || !*cmSpec.Enable || len(cmSpec.PartitionProfiles) == 0 {
+		return nil
+	}
+
+	if cmSpec.Config != nil && cmSpec.Config.Name != "" {
+		return fmt.Errorf("spec.configManager.config and spec.configManager.partitionProfiles cannot be specified at the same time")
+	}
+
+	// the node labeller reports the applied partition of the nodes
+	if devConfig.Spec.DevicePlugin.EnableNodeLabeller == nil || !*devConfig.Spec.DevicePlugin.EnableNodeLabeller {
+		return fmt.Errorf("spec.devicePlugin.enableNodeLabeller must be enabled to use spec.configManager.partitionProfiles")
+	}
+
+	names := map[string]bool{}
+	for i, profile := range cmSpec.PartitionProfiles {
+		if names[profile.Name] {
+			return fmt.Errorf("partitionProfiles[%d]: duplicate profile name %v", i, profile.Name)
+		}
+		names[profile.Name] = true
+		for key, value := range profile.NodeSelector {
+			if len(validation.IsQualifiedName(key)) > 0 {
+				return fmt.Errorf("partitionProfiles[%d]: invalid node selector key: %s", i, key)
+			}
+			if len(validation.IsValidLabelValue(value)) > 0 {
+				return fmt.Errorf("partitionProfiles[%d]: invalid node selector value: %s", i, value)
+			}
+		}
+	}
+
+	if policy := cmSpec.PartitionPolicy; policy != nil && policy.NodeDrainPolicy != nil && policy.NodeDrainPolicy.Mode == amdv1alpha1.DrainModeWaitForCompletion {
+		return fmt.Errorf("spec.configManager.partitionPolicy.nodeDrainPolicy.mode %v is only supported for driver upgrades", policy.NodeDrainPolicy.Mode)
+	}
+
+	return nil
+}
+
+// PodTemplateOverridesSpec validation of every operand
+func ValidatePodTemplateOverridesSpec(ctx context.Context, client client.Client, devConfig *amdv1alpha1.DeviceConfig) error {
+	overrides := []struct {
+		path      string
+		overrides *amdv1alpha1.PodTemplateOverridesSpec
+	}{
+		{"spec.devicePlugin.devicePluginPodTemplateOverrides", devConfig.Spec.DevicePlugin.DevicePluginPodTemplateOverrides},
+		{"spec.devicePlugin.nodeLabellerPodTemplateOverrides", devConfig.Spec.DevicePlugin.NodeLabellerPodTemplateOverrides},
+		{"spec.draDriver.podTemplateOverrides", devConfig.Spec.DRADriver.PodTemplateOverrides},
+		{"spec.metricsExporter.podTemplateOverrides", devConfig.Spec.MetricsExporter.PodTemplateOverrides},
+		{"spec.testRunner.podTemplateOverrides", devConfig.Spec.TestRunner.PodTemplateOverrides},
+		{"spec.configManager.podTemplateOverrides", devConfig.Spec.ConfigManager.PodTemplateOverrides},
+	}
+	for _, o := range overrides {
+		if err := validatePodTemplateOverrides(o.overrides); err != nil {
+			return fmt.Errorf("%s: %v", o.path, err)
+		}
+	}
+	return nil
+}
+
+func ValidateNodeLabellerSpec(ctx context.Context, client client.Client, devConfig *amdv1alpha1.DeviceConfig) error {
+	enableNodeLabeller := devConfig.Spec.DevicePlugin.EnableNodeLabeller
+	if enableNodeLabeller == nil || !*enableNodeLabeller {
+		return nil
+	}
+	if err := validateNodeLabellerConfig(devConfig); err != nil {
+		return fmt.Errorf("spec.devicePlugin.nodeLabellerConfig: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validator
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+
+	amdv1alpha1 "github.com/ROCm/gpu-operator/api/v1alpha1"
+)
+
+var _ = Describe("ValidateConfigManagerSpec", func() {
+	var devConfig *amdv1alpha1.DeviceConfig
+
+	BeforeEach(func() {
+		enable := true
+		devConfig = &amdv1alpha1.DeviceConfig{
+			Spec: amdv1alpha1.DeviceConfigSpec{
+				DevicePlugin: amdv1alpha1.DevicePluginSpec{EnableNodeLabeller: &enable},
+				ConfigManager: amdv1alpha1.ConfigManagerSpec{
+					Enable: &enable,
+					PartitionProfiles: []amdv1alpha1.PartitionProfileSpec{
+						{Name: "cpx-nps4", ComputePartition: "CPX", MemoryPartition: "NPS4", NodeSelector: map[string]string{"gpu": "mi300"}},
+					},
+				},
+			},
+		}
+	})
+
+	It("accepts valid partition profiles", func() {
+		Expect(ValidateConfigManagerSpec(context.TODO(), nil, devConfig)).To(Succeed())
+	})
+
+	It("ignores the partition profiles of a disabled config manager", func() {
+		disable := false
+		devConfig.Spec.ConfigManager.Enable = &disable
+		devConfig.Spec.DevicePlugin.EnableNodeLabeller = nil
+		Expect(ValidateConfigManagerSpec(context.TODO(), nil, devConfig)).To(Succeed())
+	})
+
+	It("rejects a ConfigMap combined with partition profiles", func() {
+		devConfig.Spec.ConfigManager.Config = &v1.LocalObjectReference{Name: "dcm-config"}
+		Expect(ValidateConfigManagerSpec(context.TODO(), nil, devConfig)).To(MatchError(ContainSubstring("cannot be specified at the same time")))
+	})
+
+	It("requires the node labeller", func() {
+		devConfig.Spec.DevicePlugin.EnableNodeLabeller = nil
+		Expect(ValidateConfigManagerSpec(context.TODO(), nil, devConfig)).To(MatchError(ContainSubstring("enableNodeLabeller")))
+	})
+
+	It("rejects duplicate profile names", func() {
+		profiles := devConfig.Spec.ConfigManager.PartitionProfiles
+		devConfig.Spec.ConfigManager.PartitionProfiles = append(profiles, profiles[0])
+		Expect(ValidateConfigManagerSpec(context.TODO(), nil, devConfig)).To(MatchError(ContainSubstring("duplicate profile name")))
+	})
+
+	It("rejects invalid node selectors", func() {
+		devConfig.Spec.ConfigManager.PartitionProfiles[0].NodeSelector = map[string]string{"gpu": "mi 300"}
+		Expect(ValidateConfigManagerSpec(context.TODO(), nil, devConfig)).To(MatchError(ContainSubstring("invalid node selector value")))
+	})
+
+	It("rejects the WaitForCompletion drain mode", func() {
+		devConfig.Spec.ConfigManager.PartitionPolicy = &amdv1alpha1.PartitionPolicySpec{
+			NodeDrainPolicy: &amdv1alpha1.DrainSpec{Mode: amdv1alpha1.DrainModeWaitForCompletion},
+		}
+		Expect(ValidateConfigManagerSpec(context.TODO(), nil, devConfig)).To(MatchError(ContainSubstring("only supported for driver upgrades")))
+	})
+})
+
+var _ = Describe("ValidateDriverSpec", func() {
+	It("rejects an image catalog combined with image signing", func() {
+		enable := true
+		devConfig := &amdv1alpha1.DeviceConfig{
+			Spec: amdv1alpha1.DeviceConfigSpec{
+				Driver: amdv1alpha1.DriverSpec{
+					Enable:       &enable,
+					ImageCatalog: &v1.LocalObjectReference{Name: "driver-catalog"},
+					ImageSign: amdv1alpha1.ImageSignSpec{
+						KeySecret:  &v1.LocalObjectReference{Name: "sign-key"},
+						CertSecret: &v1.LocalObjectReference{Name: "sign-cert"},
+					},
+				},
+			},
+		}
+		Expect(ValidateDriverSpec(context.TODO(), nil, devConfig)).To(MatchError(ContainSubstring("cannot be combined with spec.driver.imageSign")))
+	})
+
+	It("only validates the spec of the referenced objects without a client", func() {
+		enable := true
+		devConfig := &amdv1alpha1.DeviceConfig{
+			Spec: amdv1alpha1.DeviceConfigSpec{
+				Driver: amdv1alpha1.DriverSpec{
+					Enable:              &enable,
+					ImageRegistrySecret: &v1.LocalObjectReference{Name: "registry"},
+					ImageCatalog:        &v1.LocalObjectReference{Name: "driver-catalog"},
+					ImageBuild: amdv1alpha1.ImageBuildSpec{
+						DockerfileTemplates: &amdv1alpha1.DockerfileTemplatesSpec{
+							ConfigMap:     v1.LocalObjectReference{Name: "dockerfiles"},
+							Distributions: []amdv1alpha1.DockerfileTemplateDistribution{{Name: "rocky", OSImageRegex: `^Rocky Linux (\d+\.\d+)`}},
+						},
+					},
+					UpgradePolicy: &amdv1alpha1.DriverUpgradePolicySpec{
+						Hooks: &amdv1alpha1.UpgradeHooksSpec{
+							PreUpgrade: &amdv1alpha1.UpgradeHookSpec{WorkflowTemplate: "checkpoint"},
+						},
+					},
+				},
+			},
+		}
+		Expect(ValidateDriverSpec(context.TODO(), nil, devConfig)).To(Succeed())
+
+		devConfig.Spec.Driver.ImageBuild.DockerfileTemplates.Distributions[0].OSImageRegex = "^Rocky Linux"
+		Expect(ValidateDriverSpec(context.TODO(), nil, devConfig)).To(MatchError(ContainSubstring("must capture the OS version")))
+	})
+})
+
+var _ = Describe("ValidateDevicePluginSpec", func() {
+	var devConfig *amdv1alpha1.DeviceConfig
+
+	BeforeEach(func() {
+		enable := true
+		devConfig = &amdv1alpha1.DeviceConfig{
+			Spec: amdv1alpha1.DeviceConfigSpec{
+				DevicePlugin: amdv1alpha1.DevicePluginSpec{
+					EnableDevicePlugin: &enable,
+					DevicePluginImage:  "registry.example.com/k8s-device-plugin:v1",
+					DevicePluginConfig: &amdv1alpha1.DevicePluginConfigSpec{
+						ResourceNamingStrategy: "mixed",
+					},
+				},
+			},
+		}
+	})
+
+	It("accepts a resource naming strategy matching the deprecated device plugin arguments", func() {
+		Expect(ValidateDevicePluginSpec(context.TODO(), nil, devConfig)).To(Succeed())
+		devConfig.Spec.DevicePlugin.DevicePluginArguments = map[string]string{"resource_naming_strategy": "mixed"}
+		Expect(ValidateDevicePluginSpec(context.TODO(), nil, devConfig)).To(Succeed())
+	})
+
+	It("rejects a resource naming strategy conflicting with the deprecated device plugin arguments", func() {
+		devConfig.Spec.DevicePlugin.DevicePluginArguments = map[string]string{"resource_naming_strategy": "single"}
+		Expect(ValidateDevicePluginSpec(context.TODO(), nil, devConfig)).To(MatchError(ContainSubstring("conflicts with devicePluginArguments")))
+	})
+})
@@ -19,15 +19,21 @@ package validator
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"slices"
 
 	amdv1alpha1 "github.com/ROCm/gpu-operator/api/v1alpha1"
 	utils "github.com/ROCm/gpu-operator/internal"
+	"github.com/ROCm/gpu-operator/internal/kmmmodule"
+	workflowv1alpha1 "github.com/argoproj/argo-workflows/v4/pkg/apis/workflow/v1alpha1"
 	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -35,12 +41,16 @@ const (
 	ServiceMonitorCRDName    = "servicemonitors.monitoring.coreos.com"
 	ServiceMonitorCRDGroup   = "monitoring.coreos.com"
 	ServiceMonitorCRDVersion = "v1"
+	defaultDeviceClassName   = "gpu.amd.com"
 )
 
 // validateSLESDriverVersion lists nodes matching devConfig's selector and, for any
 // SLES node found, checks that driverVersion is available on the SUSE registry.
 // Validation is skipped when baseImageRegistry is set (custom/air-gapped mirror).
 func validateSLESDriverVersion(ctx context.Context, cli client.Client, devConfig *amdv1alpha1.DeviceConfig, driverVersion string) error {
+	if cli == nil {
+		return nil
+	}
 	if devConfig.Spec.Driver.ImageBuild.BaseImageRegistry != "" {
 		logr.FromContextOrDiscard(ctx).Info("Skipping SLES driver version registry validation: custom base image registry configured",
 			"baseImageRegistry", devConfig.Spec.Driver.ImageBuild.BaseImageRegistry)
@@ -65,10 +75,175 @@ func validateSLESDriverVersion(ctx context.Context, cli client.Client, devConfig
 	return nil
 }
 
+// validateUpgradeHook checks that the hook runs exactly one of a Job or an existing Argo WorkflowTemplate
+func validateUpgradeHook(ctx context.Context, cli client.Client, hook *amdv1alpha1.UpgradeHookSpec, namespace string) error {
+	if hook == nil {
+		return nil
+	}
+	if (hook.JobTemplate == nil) == (hook.WorkflowTemplate == "") {
+		return fmt.Errorf("exactly one of jobTemplate or workflowTemplate must be specified")
+	}
+	if hook.JobTemplate != nil && len(hook.JobTemplate.Spec.Template.Spec.Containers) == 0 {
+		return fmt.Errorf("jobTemplate must have at least one container")
+	}
+	if hook.WorkflowTemplate != "" && cli != nil {
+		wfTemplate := &workflowv1alpha1.WorkflowTemplate{}
+		err := cli.Get(ctx, types.NamespacedName{Namespace: namespace, Name: hook.WorkflowTemplate}, wfTemplate)
+		if err != nil {
+			if k8serrors.IsNotFound(err) {
+				return fmt.Errorf("WorkflowTemplate %s not found in namespace %s", hook.WorkflowTemplate, namespace)
+			}
+			return fmt.Errorf("failed to get WorkflowTemplate %s: %v", hook.WorkflowTemplate, err)
+		}
+	}
+	return nil
+}
+
+var dockerfileFromRegexp = regexp.MustCompile(`(?mi)^\s*FROM\s`)
+
+// validateDockerfileTemplates checks that every distribution has a usable match rule and a Dockerfile template in the ConfigMap
+func validateDockerfileTemplates(ctx context.Context, cli client.Client, templates *amdv1alpha1.DockerfileTemplatesSpec, namespace string) error {
+	names := map[string]bool{}
+	for _, distro := range templates.Distributions {
+		if names[distro.Name] {
+			return fmt.Errorf("duplicate distribution %s", distro.Name)
+		}
+		names[distro.Name] = true
+
+		re, err := regexp.Compile(distro.OSImageRegex)
+		if err != nil {
+			return fmt.Errorf("distribution %s: invalid osImageRegex: %v", distro.Name, err)
+		}
+		if re.NumSubexp() < 1 {
+			return fmt.Errorf("distribution %s: osImageRegex must capture the OS version in its first group", distro.Name)
+		}
+	}
+	if cli == nil {
+		return nil
+	}
+
+	cm := &v1.ConfigMap{}
+	if err := cli.Get(ctx, types.NamespacedName{Namespace: namespace, Name: templates.ConfigMap.Name}, cm); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return fmt.Errorf("ConfigMap %s not found in namespace %s", templates.ConfigMap.Name, namespace)
+		}
+		return fmt.Errorf("failed to get ConfigMap %s: %v", templates.ConfigMap.Name, err)
+	}
+	for _, distro := range templates.Distributions {
+		dockerfile, ok := cm.Data[distro.Name]
+		if !ok {
+			return fmt.Errorf("distribution %s: no template in ConfigMap %s", distro.Name, cm.Name)
+		}
+		if !dockerfileFromRegexp.MatchString(dockerfile) {
+			return fmt.Errorf("distribution %s: template in ConfigMap %s has no FROM instruction", distro.Name, cm.Name)
+		}
+	}
+	return nil
+}
+
+// validateDriverImageCatalog checks that the catalog ConfigMap exists and holds a well formed catalog
+func validateDriverImageCatalog(ctx context.Context, cli client.Client, catalogRef *v1.LocalObjectReference, namespace string) error {
+	if catalogRef.Name == "" {
+		return fmt.Errorf("ConfigMap name is empty")
+	}
+	if cli == nil {
+		return nil
+	}
+	cm := &v1.ConfigMap{}
+	if err := cli.Get(ctx, types.NamespacedName{Namespace: namespace, Name: catalogRef.Name}, cm); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return fmt.Errorf("ConfigMap %s not found in namespace %s", catalogRef.Name, namespace)
+		}
+		return fmt.Errorf("failed to get ConfigMap %s: %v", catalogRef.Name, err)
+	}
+	data, ok := cm.Data[kmmmodule.DriverImageCatalogKey]
+	if !ok {
+		return fmt.Errorf("ConfigMap %s has no %s key", cm.Name, kmmmodule.DriverImageCatalogKey)
+	}
+	_, err := kmmmodule.ParseDriverImageCatalog(data)
+	return err
+}
+
+// validatePodTemplateOverrides checks that the overrides are complete and the patch applies to a pod template
+func validatePodTemplateOverrides(overrides *amdv1alpha1.PodTemplateOverridesSpec) error {
+	if overrides == nil {
+		return nil
+	}
+	for key, val := range overrides.Labels {
+		if len(validation.IsQualifiedName(key)) > 0 || len(validation.IsValidLabelValue(val)) > 0 {
+			return fmt.Errorf("invalid label %s=%s", key, val)
+		}
+	}
+	for _, env := range overrides.Env {
+		if env.Name == "" {
+			return fmt.Errorf("env requires a name")
+		}
+	}
+	for _, volume := range overrides.Volumes {
+		if volume.Name == "" {
+			return fmt.Errorf("volumes require a name")
+		}
+	}
+	for _, mount := range overrides.VolumeMounts {
+		if mount.Name == "" || mount.MountPath == "" {
+			return fmt.Errorf("volumeMounts require a name and a mountPath")
+		}
+	}
+	ds := &appsv1.DaemonSet{
+		Spec: appsv1.DaemonSetSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{Containers: []v1.Container{{Name: "operand"}}},
+			},
+		},
+	}
+	return utils.ApplyPodTemplateOverrides(ds, overrides)
+}
+
+// validateDevicePluginConfig checks the device plugin config against the deprecated device plugin arguments
+func validateDevicePluginConfig(devConfig *amdv1alpha1.DeviceConfig) error {
+	cfg := devConfig.Spec.DevicePlugin.DevicePluginConfig
+	if cfg == nil {
+		return nil
+	}
+	if strategy, ok := devConfig.Spec.DevicePlugin.DevicePluginArguments[utils.ResourceNamingStrategyFlag]; ok &&
+		cfg.ResourceNamingStrategy != "" && cfg.ResourceNamingStrategy != strategy {
+		return fmt.Errorf("resourceNamingStrategy %s conflicts with devicePluginArguments %s=%s",
+			cfg.ResourceNamingStrategy, utils.ResourceNamingStrategyFlag, strategy)
+	}
+	return nil
+}
+
+// validateNodeLabellerConfig checks the allowlist / denylist of the node labeller
+func validateNodeLabellerConfig(devConfig *amdv1alpha1.DeviceConfig) error {
+	cfg := devConfig.Spec.DevicePlugin.NodeLabellerConfig
+	if cfg == nil {
+		return nil
+	}
+	allowed := map[amdv1alpha1.NodeLabellerLabel]bool{}
+	for _, label := range cfg.Allowlist {
+		if !slices.Contains(utils.GetAllNodeLabellerKinds(), string(label)) {
+			return fmt.Errorf("unsupported label %s in allowlist", label)
+		}
+		allowed[label] = true
+	}
+	for _, label := range cfg.Denylist {
+		if !slices.Contains(utils.GetAllNodeLabellerKinds(), string(label)) {
+			return fmt.Errorf("unsupported label %s in denylist", label)
+		}
+		if allowed[label] {
+			return fmt.Errorf("label %s is both in the allowlist and the denylist", label)
+		}
+	}
+	return nil
+}
+
 func validateSecret(ctx context.Context, client client.Client, secretRef *v1.LocalObjectReference, namespace string) error {
 	if secretRef == nil || secretRef.Name == "" {
 		return fmt.Errorf("Secret reference is nil or empty")
 	}
+	if client == nil {
+		return nil
+	}
 
 	secret := &v1.Secret{}
 	err := client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: secretRef.Name}, secret)
@@ -86,6 +261,9 @@ func validateConfigMap(ctx context.Context, client client.Client, mapRef string,
 	if mapRef == "" {
 		return fmt.Errorf("No ConfigMap name provided for validation")
 	}
+	if client == nil {
+		return nil
+	}
 
 	configMap := &v1.ConfigMap{}
 	err := client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: mapRef}, configMap)
@@ -101,6 +279,9 @@ func validateConfigMap(ctx context.Context, client client.Client, mapRef string,
 
 // validateServiceMonitorCRD checks if the ServiceMonitor CRD is available in the cluster
 func validateServiceMonitorCRD(ctx context.Context, c client.Client) error {
+	if c == nil {
+		return nil
+	}
 	// Define the ServiceMonitor CRD we want to check
 	crd := &apiextensionsv1.CustomResourceDefinition{}
 	err := c.Get(ctx, client.ObjectKey{Name: ServiceMonitorCRDName}, crd)
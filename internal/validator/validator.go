@@ -25,6 +25,9 @@ import (
 )
 
 //go:generate mockgen -source=validator.go -package=validator -destination=mock_validator.go ValidatorAPI
+
+// ValidatorAPI validates the DeviceConfig spec, the objects referenced by the spec are only looked up with a non nil client,
+// a nil client restricts the validation to the spec itself, e.g. at admission time when the references may be created later
 type ValidatorAPI interface {
 	ValidateDeviceConfigAll(ctx context.Context, client client.Client, devConfig *amdv1alpha1.DeviceConfig) []string
 	ValidateDeviceConfigSpec(ctx context.Context, client client.Client, devConfig *amdv1alpha1.DeviceConfig, specs []string) []string
@@ -37,12 +40,16 @@ type validator struct {
 func NewValidator() ValidatorAPI {
 	// Map of spec names to their respective validation functions
 	specValidationFuncs := map[string]func(context.Context, client.Client, *amdv1alpha1.DeviceConfig) error{
-		"driver":              ValidateDriverSpec,
-		"metricsExporter":     ValidateMetricsExporterSpec,
-		"devicePlugin":        ValidateDevicePluginSpec,
-		"draDriver":           ValidateDRADriverSpec,
-		"remediationWorkflow": ValidateRemediationWorkflowSpec,
-		"commonConfig":        ValidateCommonConfigSpec,
+		"driver":               ValidateDriverSpec,
+		"metricsExporter":      ValidateMetricsExporterSpec,
+		"devicePlugin":         ValidateDevicePluginSpec,
+		"draDriver":            ValidateDRADriverSpec,
+		"remediationWorkflow":  ValidateRemediationWorkflowSpec,
+		"commonConfig":         ValidateCommonConfigSpec,
+		"maintenanceWindows":   ValidateMaintenanceWindowsSpec,
+		"configManager":        ValidateConfigManagerSpec,
+		"podTemplateOverrides": ValidatePodTemplateOverridesSpec,
+		"nodeLabeller":         ValidateNodeLabellerSpec,
 	}
 	vInst := &validator{
 		specValidationFuncs: specValidationFuncs,
@@ -0,0 +1,201 @@
+/*
+Copyright (c) Advanced Micro Devices, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the \"License\");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an \"AS IS\" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	amdv1alpha1 "github.com/ROCm/gpu-operator/api/v1alpha1"
+	utils "github.com/ROCm/gpu-operator/internal"
+	"github.com/ROCm/gpu-operator/internal/validator"
+)
+
+//+kubebuilder:webhook:path=/mutate-amd-com-v1alpha1-deviceconfig,mutating=true,failurePolicy=fail,sideEffects=None,groups=amd.com,resources=deviceconfigs,verbs=create;update,versions=v1alpha1,name=mdeviceconfig.amd.com,admissionReviewVersions=v1
+//+kubebuilder:webhook:path=/validate-amd-com-v1alpha1-deviceconfig,mutating=false,failurePolicy=fail,sideEffects=None,groups=amd.com,resources=deviceconfigs,verbs=create;update,versions=v1alpha1,name=vdeviceconfig.amd.com,admissionReviewVersions=v1
+
+type deviceConfigWebhook struct {
+	client    client.Client
+	validator validator.ValidatorAPI
+}
+
+// SetupDeviceConfigWebhookWithManager registers the defaulting and validating admission webhooks for DeviceConfig
+func SetupDeviceConfigWebhookWithManager(mgr ctrl.Manager) error {
+	w := &deviceConfigWebhook{
+		client:    mgr.GetClient(),
+		validator: validator.NewValidator(),
+	}
+	return ctrl.NewWebhookManagedBy(mgr, &amdv1alpha1.DeviceConfig{}).
+		WithDefaulter(w).
+		WithValidator(w).
+		Complete()
+}
+
+// Default applies the operand defaults that are otherwise resolved by the operand builders at reconcile time,
+// so that the effective configuration is visible on the DeviceConfig itself.
+// The operand builders keep resolving the same defaults for the DeviceConfigs admitted without the webhook
+func (w *deviceConfigWebhook) Default(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig) error {
+	// leave a DeviceConfig under deletion untouched, so that the finalizer can be removed
+	if !devConfig.DeletionTimestamp.IsZero() {
+		return nil
+	}
+	logger := log.FromContext(ctx)
+	logger.Info(fmt.Sprintf("applying defaults to DeviceConfig %s/%s", devConfig.Namespace, devConfig.Name))
+	setDeviceConfigDefaults(devConfig)
+	return nil
+}
+
+func setDeviceConfigDefaults(devConfig *amdv1alpha1.DeviceConfig) {
+	spec := &devConfig.Spec
+
+	if spec.Driver.DriverType == "" {
+		spec.Driver.DriverType = utils.DriverTypeContainer
+	}
+	if spec.CommonConfig.InitContainerImage == "" {
+		spec.CommonConfig.InitContainerImage = utils.DefaultInitContainerImage
+	}
+	if spec.DRADriver.IsEnabled() && spec.DRADriver.Image == "" {
+		spec.DRADriver.Image = utils.DefaultDRADriverImage
+	}
+	if isEnabled(spec.MetricsExporter.Enable) {
+		if spec.MetricsExporter.Image == "" {
+			spec.MetricsExporter.Image = utils.DefaultMetricsExporterImage
+		}
+		if spec.MetricsExporter.Port == 0 {
+			spec.MetricsExporter.Port = utils.DefaultMetricsExporterPort
+		}
+	}
+	if isEnabled(spec.TestRunner.Enable) && spec.TestRunner.Image == "" {
+		spec.TestRunner.Image = utils.DefaultTestRunnerImage
+	}
+	if isEnabled(spec.ConfigManager.Enable) && spec.ConfigManager.Image == "" {
+		spec.ConfigManager.Image = utils.DefaultConfigManagerImage
+	}
+}
+
+func isEnabled(enable *bool) bool {
+	return enable != nil && *enable
+}
+
+// ValidateCreate validates the DeviceConfig spec and rejects operands already owned by other DeviceConfigs
+func (w *deviceConfigWebhook) ValidateCreate(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig) (admission.Warnings, error) {
+	return nil, w.validate(ctx, devConfig)
+}
+
+// ValidateUpdate validates the updated DeviceConfig spec and rejects operands already owned by other DeviceConfigs
+func (w *deviceConfigWebhook) ValidateUpdate(ctx context.Context, oldDevConfig, devConfig *amdv1alpha1.DeviceConfig) (admission.Warnings, error) {
+	// always admit updates on a DeviceConfig under deletion, so that the finalizer can be removed
+	if !devConfig.DeletionTimestamp.IsZero() {
+		return nil, nil
+	}
+	return nil, w.validate(ctx, devConfig)
+}
+
+// ValidateDelete always admits the deletion, cleanup is handled by the reconciler finalizer
+func (w *deviceConfigWebhook) ValidateDelete(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (w *deviceConfigWebhook) validate(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig) error {
+	// spec validators may adjust fields in place, the admitted object must stay untouched.
+	// Only the spec is validated, the referenced Secrets, ConfigMaps and WorkflowTemplates may be applied after the DeviceConfig,
+	// the reconciler looks them up and reports the missing ones in the Error condition
+	failedValidations := w.validator.ValidateDeviceConfigAll(ctx, nil, devConfig.DeepCopy())
+	if err := w.validateOperandOwnership(ctx, devConfig); err != nil {
+		failedValidations = append(failedValidations, err.Error())
+	}
+	if len(failedValidations) == 0 {
+		return nil
+	}
+	sort.Strings(failedValidations)
+	return fmt.Errorf("DeviceConfig %s/%s validation failed: %s", devConfig.Namespace, devConfig.Name, strings.Join(failedValidations, "; "))
+}
+
+// validateOperandOwnership rejects a DeviceConfig enabling an operand that another DeviceConfig already owns
+// on any of the selected nodes, DeviceConfigs owning different operands may select the same nodes
+func (w *deviceConfigWebhook) validateOperandOwnership(ctx context.Context, devConfig *amdv1alpha1.DeviceConfig) error {
+	operands := utils.GetOwnedOperands(devConfig)
+	if len(operands) == 0 {
+		return nil
+	}
+
+	devConfigList := &amdv1alpha1.DeviceConfigList{}
+	if err := w.client.List(ctx, devConfigList); err != nil {
+		return fmt.Errorf("failed to list DeviceConfigs: %v", err)
+	}
+
+	nodes, err := w.getNodes(ctx, devConfig.Spec.Selector)
+	if err != nil {
+		return err
+	}
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	namespacedName := types.NamespacedName{Namespace: devConfig.Namespace, Name: devConfig.Name}
+	conflicts := []string{}
+	for _, other := range devConfigList.Items {
+		otherName := types.NamespacedName{Namespace: other.Namespace, Name: other.Name}
+		if otherName == namespacedName || !other.DeletionTimestamp.IsZero() {
+			continue
+		}
+		otherOperands := utils.GetOwnedOperands(&other)
+		shared := []string{}
+		for _, operand := range operands {
+			if slices.Contains(otherOperands, operand) {
+				shared = append(shared, operand)
+			}
+		}
+		if len(shared) == 0 {
+			continue
+		}
+		otherSelector := labels.SelectorFromSet(labels.Set(other.Spec.Selector))
+		for _, node := range nodes {
+			if otherSelector.Matches(labels.Set(node.Labels)) {
+				for _, operand := range shared {
+					conflicts = append(conflicts, fmt.Sprintf("operand %s on node %s already owned by DeviceConfig %s", operand, node.Name, otherName.String()))
+				}
+				break
+			}
+		}
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(conflicts, "; "))
+}
+
+func (w *deviceConfigWebhook) getNodes(ctx context.Context, selector map[string]string) ([]v1.Node, error) {
+	nodeList := &v1.NodeList{}
+	opts := &client.ListOptions{LabelSelector: labels.SelectorFromSet(labels.Set(selector))}
+	if err := w.client.List(ctx, nodeList, opts); err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %v", err)
+	}
+	return nodeList.Items, nil
+}
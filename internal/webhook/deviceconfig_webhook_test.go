@@ -0,0 +1,168 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+
+package webhook
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	amdv1alpha1 "github.com/ROCm/gpu-operator/api/v1alpha1"
+	mock_client "github.com/ROCm/gpu-operator/internal/client"
+	utils "github.com/ROCm/gpu-operator/internal"
+	"github.com/ROCm/gpu-operator/internal/validator"
+)
+
+var _ = Describe("deviceConfigWebhook", func() {
+	var (
+		kubeClient    *mock_client.MockClient
+		mockValidator *validator.MockValidatorAPI
+		w             *deviceConfigWebhook
+	)
+
+	BeforeEach(func() {
+		ctrl := gomock.NewController(GinkgoT())
+		kubeClient = mock_client.NewMockClient(ctrl)
+		mockValidator = validator.NewMockValidatorAPI(ctrl)
+		w = &deviceConfigWebhook{client: kubeClient, validator: mockValidator}
+	})
+
+	ctx := context.Background()
+	enable := true
+	now := metav1.Now()
+	newDeviceConfig := func(name string) *amdv1alpha1.DeviceConfig {
+		return &amdv1alpha1.DeviceConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kube-amd-gpu"},
+			Spec: amdv1alpha1.DeviceConfigSpec{
+				Selector:     map[string]string{"feature.node.kubernetes.io/amd-gpu": "true"},
+				DevicePlugin: amdv1alpha1.DevicePluginSpec{EnableDevicePlugin: &enable},
+			},
+		}
+	}
+	gpuNode := v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "gpu-node", Labels: map[string]string{"feature.node.kubernetes.io/amd-gpu": "true"}}}
+	expectLists := func(others []amdv1alpha1.DeviceConfig, nodes []v1.Node) {
+		gomock.InOrder(
+			kubeClient.EXPECT().List(ctx, gomock.Any()).Do(
+				func(_ interface{}, list *amdv1alpha1.DeviceConfigList, _ ...client.ListOption) {
+					list.Items = others
+				},
+			).Return(nil),
+			kubeClient.EXPECT().List(ctx, gomock.Any(), gomock.Any()).Do(
+				func(_ interface{}, list *v1.NodeList, _ ...client.ListOption) {
+					list.Items = nodes
+				},
+			).Return(nil),
+		)
+	}
+
+	It("applies the operand defaults of the enabled operands", func() {
+		devConfig := newDeviceConfig("default")
+		devConfig.Spec.MetricsExporter.Enable = &enable
+		devConfig.Spec.TestRunner.Image = "registry.example.com/test-runner:v1"
+
+		Expect(w.Default(ctx, devConfig)).To(Succeed())
+		Expect(devConfig.Spec.Driver.DriverType).To(Equal(utils.DriverTypeContainer))
+		Expect(devConfig.Spec.CommonConfig.InitContainerImage).To(Equal(utils.DefaultInitContainerImage))
+		Expect(devConfig.Spec.MetricsExporter.Image).To(Equal(utils.DefaultMetricsExporterImage))
+		Expect(devConfig.Spec.MetricsExporter.Port).To(Equal(int32(utils.DefaultMetricsExporterPort)))
+		// the user supplied values and the disabled operands are left untouched
+		Expect(devConfig.Spec.TestRunner.Image).To(Equal("registry.example.com/test-runner:v1"))
+		Expect(devConfig.Spec.ConfigManager.Image).To(BeEmpty())
+		Expect(devConfig.Spec.DRADriver.Image).To(BeEmpty())
+	})
+
+	It("does not default a DeviceConfig under deletion", func() {
+		devConfig := newDeviceConfig("default")
+		devConfig.DeletionTimestamp = &now
+		original := devConfig.DeepCopy()
+
+		Expect(w.Default(ctx, devConfig)).To(Succeed())
+		Expect(devConfig).To(Equal(original))
+	})
+
+	It("admits a valid DeviceConfig without changing it and without looking up the objects it references", func() {
+		devConfig := newDeviceConfig("default")
+		original := devConfig.DeepCopy()
+		mockValidator.EXPECT().ValidateDeviceConfigAll(ctx, gomock.Nil(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ client.Client, dc *amdv1alpha1.DeviceConfig) []string {
+				// the spec validators may adjust the spec they are given
+				dc.Spec.Selector = nil
+				return nil
+			},
+		)
+		expectLists([]amdv1alpha1.DeviceConfig{*devConfig}, []v1.Node{gpuNode})
+
+		warnings, err := w.ValidateCreate(ctx, devConfig)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(warnings).To(BeEmpty())
+		Expect(devConfig).To(Equal(original))
+	})
+
+	It("rejects a DeviceConfig failing the spec validation", func() {
+		devConfig := newDeviceConfig("default")
+		mockValidator.EXPECT().ValidateDeviceConfigAll(ctx, gomock.Nil(), gomock.Any()).Return([]string{"spec.metricsExporter: invalid", "spec.driver: invalid"})
+		expectLists(nil, []v1.Node{gpuNode})
+
+		_, err := w.ValidateCreate(ctx, devConfig)
+		Expect(err).To(MatchError("DeviceConfig kube-amd-gpu/default validation failed: spec.driver: invalid; spec.metricsExporter: invalid"))
+	})
+
+	It("rejects an operand already owned by another DeviceConfig on a selected node", func() {
+		devConfig := newDeviceConfig("inference")
+		mockValidator.EXPECT().ValidateDeviceConfigAll(ctx, gomock.Nil(), gomock.Any()).Return(nil)
+		expectLists([]amdv1alpha1.DeviceConfig{*newDeviceConfig("default")}, []v1.Node{gpuNode})
+
+		_, err := w.ValidateUpdate(ctx, devConfig, devConfig)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("operand devicePlugin on node gpu-node already owned by DeviceConfig kube-amd-gpu/default"))
+	})
+
+	It("ignores the DeviceConfigs under deletion", func() {
+		devConfig := newDeviceConfig("inference")
+		other := newDeviceConfig("default")
+		other.DeletionTimestamp = &now
+		mockValidator.EXPECT().ValidateDeviceConfigAll(ctx, gomock.Nil(), gomock.Any()).Return(nil)
+		expectLists([]amdv1alpha1.DeviceConfig{*other}, []v1.Node{gpuNode})
+
+		_, err := w.ValidateCreate(ctx, devConfig)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("admits the updates of a DeviceConfig under deletion", func() {
+		devConfig := newDeviceConfig("default")
+		devConfig.DeletionTimestamp = &now
+
+		_, err := w.ValidateUpdate(ctx, devConfig, devConfig)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("rejects the DeviceConfig when the DeviceConfigs cannot be listed", func() {
+		devConfig := newDeviceConfig("default")
+		mockValidator.EXPECT().ValidateDeviceConfigAll(ctx, gomock.Nil(), gomock.Any()).Return(nil)
+		kubeClient.EXPECT().List(ctx, gomock.Any()).Return(errors.New("api server unavailable"))
+
+		_, err := w.ValidateCreate(ctx, devConfig)
+		Expect(err).To(MatchError(ContainSubstring("failed to list DeviceConfigs: api server unavailable")))
+	})
+})